@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// batchRequest is one input line of batch mode.
+type batchRequest struct {
+	Tool    string          `json:"tool"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// batchResponse is one output line of batch mode. Result is the tool's usual JSON; Error is set
+// instead when the line could not be dispatched (bad JSON, unknown tool, no Docker client).
+type batchResponse struct {
+	Line   int         `json:"line"` // 1-based input line number
+	Tool   string      `json:"tool,omitempty"`
+	Failed bool        `json:"failed,omitempty"` // the call would have exited with status 1
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// batchExcluded lists tools that cannot run inside a batch.
+var batchExcluded = map[string]string{
+	"serve": "serve is long-running",
+	"batch": "batches do not nest",
+}
+
+// runBatch reads NDJSON requests from in and runs them in order, writing one response line per
+// non-blank input line. A failing call does not stop the batch; failed reports whether any did.
+// Each call gets its own cliTimeout derived from ctx.
+func runBatch(ctx context.Context, env *toolEnv, in io.Reader, out io.Writer) (failed bool, err error) {
+	r := bufio.NewReader(in) // no line-length limit: payloads may carry whole files
+	for lineNo := 1; ; lineNo++ {
+		line, readErr := r.ReadString('\n')
+		if strings.TrimSpace(line) != "" {
+			resp := runBatchLine(ctx, env, lineNo, line)
+			failed = failed || resp.Failed || resp.Error != ""
+			if err := writeJSON(out, resp); err != nil {
+				return failed, err
+			}
+		}
+		if readErr == io.EOF {
+			return failed, nil
+		}
+		if readErr != nil {
+			return failed, readErr
+		}
+		if ctx.Err() != nil {
+			return failed, ctx.Err()
+		}
+	}
+}
+
+func runBatchLine(ctx context.Context, env *toolEnv, lineNo int, line string) batchResponse {
+	var req batchRequest
+	if err := json.Unmarshal([]byte(line), &req); err != nil {
+		return batchResponse{Line: lineNo, Error: fmt.Sprintf("invalid request: %v", err)}
+	}
+	resp := batchResponse{Line: lineNo, Tool: req.Tool}
+	if req.Tool == "" {
+		resp.Error = "tool is required"
+		return resp
+	}
+	if why, ok := batchExcluded[req.Tool]; ok {
+		resp.Error = fmt.Sprintf("%s cannot run in batch mode: %s", req.Tool, why)
+		return resp
+	}
+	payload := "{}"
+	if len(req.Payload) > 0 && string(req.Payload) != "null" {
+		payload = string(req.Payload)
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, cliTimeout*time.Second)
+	defer cancel()
+	result, callFailed, err := runTool(callCtx, env, req.Tool, payload)
+	switch {
+	case errors.Is(err, errUnknownTool):
+		resp.Error = fmt.Sprintf("unknown tool %q", req.Tool)
+	case err != nil:
+		resp.Error = fmt.Sprintf("docker client: %v", err)
+	default:
+		resp.Result, resp.Failed = result, callFailed
+	}
+	return resp
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRunBatchOneResultPerLine(t *testing.T) {
+	in := strings.Join([]string{
+		`{"tool":"list_languages"}`,
+		`{"tool":"prepare_build_context","payload":{"files":{"Dockerfile":"FROM busybox\n"}}}`,
+		``, // blank lines are skipped
+		`not json`,
+		`{"tool":"prepare_build_context","payload":{"files":{"a.txt":"x"},"content_encoding":"rot13"}}`,
+		`{"tool":"serve"}`,
+		`{"tool":"config","payload":null}`,
+	}, "\n")
+	var out strings.Builder
+	env := &toolEnv{listen: "127.0.0.1:8765"}
+	failed, err := runBatch(context.Background(), env, strings.NewReader(in), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !failed {
+		t.Error("batch with failing lines should report failure")
+	}
+
+	var lines []batchResponse
+	sc := bufio.NewScanner(strings.NewReader(out.String()))
+	for sc.Scan() {
+		var r struct {
+			batchResponse
+			Result json.RawMessage `json:"result"`
+		}
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			t.Fatalf("output line is not JSON: %q", sc.Text())
+		}
+		r.batchResponse.Result = r.Result
+		lines = append(lines, r.batchResponse)
+	}
+	if len(lines) != 6 {
+		t.Fatalf("got %d result lines, want 6:\n%s", len(lines), out.String())
+	}
+
+	want := []struct {
+		line     int
+		tool     string
+		failed   bool
+		hasError bool
+	}{
+		{1, "list_languages", false, false},
+		{2, "prepare_build_context", false, false},
+		{4, "", false, true},
+		{5, "prepare_build_context", true, false},
+		{6, "serve", false, true},
+		{7, "config", false, false},
+	}
+	for i, w := range want {
+		got := lines[i]
+		if got.Line != w.line || got.Tool != w.tool || got.Failed != w.failed || (got.Error != "") != w.hasError {
+			t.Errorf("line %d: got %+v, want %+v", i, got, w)
+		}
+	}
+
+	var prep struct {
+		ContextID string `json:"context_id"`
+	}
+	if err := json.Unmarshal(lines[1].Result.(json.RawMessage), &prep); err != nil || prep.ContextID == "" {
+		t.Errorf("prepare_build_context result: %s", lines[1].Result)
+	} else {
+		os.RemoveAll(prep.ContextID)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+
+	"adde/pkg/executor"
+)
+
+// version is the adde release, set at build time: go build -ldflags "-X main.version=v1.4.0" ./cmd/adde
+var version = "dev"
+
+// toolParams lists every tool runTool dispatches with the payload type it decodes (nil: no payload).
+// capabilities reports the params from these types, so a new field shows up without further wiring.
+var toolParams = []struct {
+	name   string
+	params interface{}
+}{
+	{"pull_image", executor.PullImageParams{}},
+	{"check_registry_auth", executor.CheckRegistryAuthParams{}},
+	{"image_config", executor.ImageConfigParams{}},
+	{"image_metadata", executor.ImageMetadataParams{}},
+	{"create_runtime_env", executor.CreateRuntimeEnvParams{}},
+	{"get_or_create_env", executor.GetOrCreateEnvParams{}},
+	{"execute_code_block", executor.ExecuteCodeBlockParams{}},
+	{"execute_cells", executor.ExecuteCellsParams{}},
+	{"wait_container", executor.WaitContainerParams{}},
+	{"get_container_logs", executor.GetContainerLogsParams{}},
+	{"get_container_env", executor.GetContainerEnvParams{}},
+	{"container_diff", executor.ContainerDiffParams{}},
+	{"get_transcript", executor.GetTranscriptParams{}},
+	{"start_detached_exec", executor.StartDetachedExecParams{}},
+	{"poll_exec", executor.PollExecParams{}},
+	{"verify_workspace", executor.VerifyWorkspaceParams{}},
+	{"check_dependency", executor.CheckDependencyParams{}},
+	{"check_port", executor.CheckPortParams{}},
+	{"tail_file", executor.TailFileParams{}},
+	{"copy_from_container", executor.CopyFromContainerParams{}},
+	{"pause_container", executor.PauseContainerParams{}},
+	{"unpause_container", executor.UnpauseContainerParams{}},
+	{"stop_container", executor.StopContainerParams{}},
+	{"kill_container", executor.KillContainerParams{}},
+	{"cleanup_env", executor.CleanupEnvParams{}},
+	{"prepare_build_context", executor.PrepareBuildContextParams{}},
+	{"hash_build_context", executor.HashBuildContextParams{}},
+	{"build_image_from_context", executor.BuildImageFromContextParams{}},
+	{"build_image_from_path", executor.BuildImageFromPathParams{}},
+	{"run_from_dockerfile", executor.RunFromDockerfileParams{}},
+	{"list_languages", nil},
+	{"list_agent_images", executor.ListAgentImagesParams{}},
+	{"prune_build_cache", executor.PruneBuildCacheParams{}},
+	{"prune_all", executor.PruneAllParams{}},
+	{"list_volumes", nil},
+	{"prune_volumes", executor.PruneVolumesParams{}},
+	{"gc_workspaces", executor.GCWorkspacesParams{}},
+	{"delete_image", executor.DeleteImageParams{}},
+	{"config", nil},
+	{"capabilities", nil},
+}
+
+// capabilitiesResult is the output of adde capabilities: what this build accepts, for feature detection.
+type capabilitiesResult struct {
+	Version string           `json:"version"`
+	Tools   []toolCapability `json:"tools"`
+}
+
+type toolCapability struct {
+	Name   string   `json:"name"`
+	Params []string `json:"params"` // payload fields this build reads; any other field is ignored
+}
+
+func capabilities() capabilitiesResult {
+	res := capabilitiesResult{Version: version, Tools: make([]toolCapability, 0, len(toolParams))}
+	for _, t := range toolParams {
+		params := []string{}
+		if t.params != nil {
+			params = jsonFields(reflect.TypeOf(t.params))
+		}
+		res.Tools = append(res.Tools, toolCapability{Name: t.name, Params: params})
+	}
+	return res
+}
+
+// jsonFields returns the JSON names encoding/json decodes into struct type t, in declaration order,
+// including the fields of embedded structs (as in GetOrCreateEnvParams).
+func jsonFields(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			names = append(names, jsonFields(f.Type)...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
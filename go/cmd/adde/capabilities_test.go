@@ -0,0 +1,91 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestCapabilitiesListsToolsAndParams(t *testing.T) {
+	res := capabilities()
+	if res.Version != version {
+		t.Errorf("version = %q, want %q", res.Version, version)
+	}
+	params := map[string][]string{}
+	for _, tool := range res.Tools {
+		params[tool.Name] = tool.Params
+	}
+	if !contains(params["execute_code_block"], "working_dir") || !contains(params["execute_code_block"], "container_id") {
+		t.Errorf("execute_code_block params = %q", params["execute_code_block"])
+	}
+	// get_or_create_env embeds CreateRuntimeEnvParams; its fields are top-level in the payload.
+	if !contains(params["get_or_create_env"], "memory_mb") {
+		t.Errorf("get_or_create_env params = %q, want the embedded create_runtime_env fields", params["get_or_create_env"])
+	}
+	if p, ok := params["list_languages"]; !ok || len(p) != 0 {
+		t.Errorf("list_languages: present=%v params=%q, want present with none", ok, p)
+	}
+}
+
+// TestToolParamsMatchRunTool keeps the capabilities table in step with the dispatch in tools.go:
+// the same tool names, each decoding the payload type the table claims.
+func TestToolParamsMatchRunTool(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "tools.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dispatched := map[string]string{} // tool -> executor params type ("" when it takes no payload)
+	ast.Inspect(f, func(n ast.Node) bool {
+		clause, ok := n.(*ast.CaseClause)
+		if !ok || len(clause.List) != 1 {
+			return true
+		}
+		lit, ok := clause.List[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		name, _ := strconv.Unquote(lit.Value)
+		dispatched[name] = ""
+		ast.Inspect(clause, func(n ast.Node) bool {
+			if spec, ok := n.(*ast.ValueSpec); ok {
+				if sel, ok := spec.Type.(*ast.SelectorExpr); ok {
+					dispatched[name] = sel.Sel.Name
+				}
+			}
+			return true
+		})
+		return true
+	})
+
+	listed := map[string]bool{}
+	for _, tool := range toolParams {
+		listed[tool.name] = true
+		want := ""
+		if tool.params != nil {
+			want = reflect.TypeOf(tool.params).Name()
+		}
+		got, ok := dispatched[tool.name]
+		if !ok {
+			t.Errorf("%s is in toolParams but runTool does not dispatch it", tool.name)
+		} else if got != want {
+			t.Errorf("%s: runTool decodes %q, toolParams says %q", tool.name, got, want)
+		}
+	}
+	for name := range dispatched {
+		if !listed[name] {
+			t.Errorf("runTool dispatches %s but toolParams does not list it", name)
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -9,12 +9,16 @@
 package main
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 func findExe(t *testing.T) string {
@@ -265,3 +269,1052 @@ func TestExeE2EBusyBox(t *testing.T) {
 		t.Errorf("expected stdout to contain 42; got %q", execRes.Log.Stdout)
 	}
 }
+
+// TestExeInterruptAbortsExec starts a long-running exec and sends an interrupt;
+// the CLI should cancel the operation promptly, kill the program and exit non-zero.
+func TestExeInterruptAbortsExec(t *testing.T) {
+	exe := findExe(t)
+	createPayload := `{"image":"busybox","dependencies":[],"env_vars":{},"network":false}`
+	stdout, stderr, code := runAdde(t, exe, "create_runtime_env", createPayload)
+	if code != 0 {
+		t.Logf("create_runtime_env failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+		t.Skip("interrupt test needs Docker; skipping")
+	}
+	var createRes struct {
+		ContainerID string `json:"container_id"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &createRes); err != nil || createRes.ContainerID == "" {
+		t.Fatalf("create result invalid: %v\nraw: %s", err, stdout)
+	}
+	cid := createRes.ContainerID
+	defer func() {
+		runAdde(t, exe, "cleanup_env", `{"container_id":"`+cid+`"}`)
+	}()
+
+	execPayload := `{"container_id":"` + cid + `","filename":"t.sh","code_content":"sleep 120","timeout_sec":120}`
+	cmd := exec.Command(exe, "execute_code_block", execPayload)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start adde: %v", err)
+	}
+	time.Sleep(2 * time.Second)
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		_ = cmd.Process.Kill()
+		t.Skipf("cannot send interrupt on this platform: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected non-zero exit after interrupt")
+		}
+	case <-time.After(15 * time.Second):
+		_ = cmd.Process.Kill()
+		t.Fatal("adde did not exit promptly after interrupt")
+	}
+	// Dropping the attach stream is not enough: the program itself must be gone from the container.
+	out, err := exec.Command("docker", "exec", cid, "ps").Output()
+	if err != nil {
+		t.Skipf("docker CLI unavailable to check the container's processes: %v", err)
+	}
+	if strings.Contains(string(out), "sleep 120") {
+		t.Errorf("exec still running after interrupt:\n%s", out)
+	}
+}
+
+// dockerContainerIDs returns the IDs of all containers (running or not) via the docker CLI.
+func dockerContainerIDs(t *testing.T) map[string]bool {
+	t.Helper()
+	out, err := exec.Command("docker", "ps", "-aq", "--no-trunc").Output()
+	if err != nil {
+		t.Skipf("docker CLI unavailable: %v", err)
+	}
+	ids := make(map[string]bool)
+	for _, id := range strings.Fields(string(out)) {
+		ids[id] = true
+	}
+	return ids
+}
+
+// TestExeInterruptDuringCreateLeavesNoContainer interrupts create_runtime_env while
+// dependencies are installing and asserts the half-created container was removed.
+func TestExeInterruptDuringCreateLeavesNoContainer(t *testing.T) {
+	exe := findExe(t)
+	before := dockerContainerIDs(t)
+
+	// network=false makes pip retry for a while, giving us a window between create and return.
+	createPayload := `{"image":"python:3.11-slim","dependencies":["requests"],"env_vars":{},"network":false}`
+	cmd := exec.Command(exe, "create_runtime_env", createPayload)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start adde: %v", err)
+	}
+	time.Sleep(3 * time.Second)
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		_ = cmd.Process.Kill()
+		t.Skipf("cannot send interrupt on this platform: %v", err)
+	}
+	if err := cmd.Wait(); err == nil {
+		t.Skip("create finished before the interrupt arrived; nothing to roll back")
+	}
+
+	for id := range dockerContainerIDs(t) {
+		if !before[id] {
+			t.Errorf("container %s left behind after interrupted create", id)
+		}
+	}
+}
+
+// TestExeJobMode runs hello-world as a one-shot job, waits for it, and collects its output.
+func TestExeJobMode(t *testing.T) {
+	exe := findExe(t)
+	if _, _, code := runAdde(t, exe, "pull_image", `{"image":"hello-world"}`); code != 0 {
+		t.Skip("job mode e2e needs Docker and the hello-world image; skipping")
+	}
+	stdout, stderr, code := runAdde(t, exe, "create_runtime_env", `{"image":"hello-world","job_mode":true}`)
+	if code != 0 {
+		t.Fatalf("create_runtime_env failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+	}
+	var createRes struct {
+		ContainerID string `json:"container_id"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &createRes); err != nil || createRes.ContainerID == "" {
+		t.Fatalf("create result invalid: %v\nraw: %s", err, stdout)
+	}
+	cid := createRes.ContainerID
+	defer func() {
+		runAdde(t, exe, "cleanup_env", `{"container_id":"`+cid+`"}`)
+	}()
+
+	stdout, stderr, code = runAdde(t, exe, "wait_container", `{"container_id":"`+cid+`","timeout_sec":60}`)
+	if code != 0 {
+		t.Fatalf("wait_container failed: %d stderr=%s stdout=%s", code, stderr, stdout)
+	}
+	var waitRes struct {
+		ExitCode int `json:"exit_code"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &waitRes); err != nil {
+		t.Fatalf("wait result not JSON: %v\nraw: %s", err, stdout)
+	}
+	if waitRes.ExitCode != 0 {
+		t.Errorf("expected job exit code 0, got %d", waitRes.ExitCode)
+	}
+
+	stdout, _, code = runAdde(t, exe, "get_container_logs", `{"container_id":"`+cid+`","source":"container"}`)
+	if code != 0 {
+		t.Fatalf("get_container_logs failed: %d", code)
+	}
+	var logsRes struct {
+		Log *struct {
+			ExitCode int    `json:"exit_code"`
+			Stdout   string `json:"stdout"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &logsRes); err != nil || logsRes.Log == nil {
+		t.Fatalf("logs result invalid: %v\nraw: %s", err, stdout)
+	}
+	if !strings.Contains(logsRes.Log.Stdout, "Hello from Docker") {
+		t.Errorf("expected job output in stdout; got %q", logsRes.Log.Stdout)
+	}
+}
+
+// TestExeOutputFlag checks that --output writes the result to a file and stdout carries only a pointer.
+func TestExePrettyFlag(t *testing.T) {
+	exe := findExe(t)
+	stdout, _, code := runAdde(t, exe, "list_languages", "")
+	if code != 0 {
+		t.Fatalf("list_languages failed: %d", code)
+	}
+	if lines := strings.Split(strings.TrimSpace(stdout), "\n"); len(lines) != 1 {
+		t.Errorf("default output is %d lines, want compact single-line JSON", len(lines))
+	}
+
+	cmd := exec.Command(exe, "--pretty", "list_languages")
+	var pretty strings.Builder
+	cmd.Stdout = &pretty
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("list_languages --pretty failed: %v", err)
+	}
+	out := pretty.String()
+	if !strings.HasPrefix(out, "{\n  \"") || strings.Count(out, "\n") < 5 {
+		t.Errorf("--pretty output not indented:\n%s", out)
+	}
+	var compact, indented interface{}
+	json.Unmarshal([]byte(stdout), &compact)
+	if err := json.Unmarshal([]byte(out), &indented); err != nil || fmt.Sprint(compact) != fmt.Sprint(indented) {
+		t.Errorf("--pretty changed the content: %v", err)
+	}
+}
+
+func TestExeOutputFlag(t *testing.T) {
+	exe := findExe(t)
+	outPath := filepath.Join(t.TempDir(), "result.json")
+	cmd := exec.Command(exe, "--output", outPath, "prepare_build_context", `{"files":{"main.py":"print(1)"}}`)
+	var stdout strings.Builder
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("prepare_build_context with --output failed: %v", err)
+	}
+	var pointer struct {
+		Output string `json:"output"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout.String())), &pointer); err != nil {
+		t.Fatalf("stdout not JSON: %v\nraw: %s", err, stdout.String())
+	}
+	if pointer.Output != outPath {
+		t.Errorf("stdout pointer = %q, want %q", pointer.Output, outPath)
+	}
+	raw, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("read output file: %v", err)
+	}
+	var res struct {
+		ContextID string `json:"context_id"`
+	}
+	if err := json.Unmarshal(raw, &res); err != nil || res.ContextID == "" {
+		t.Fatalf("output file does not hold the result: %v\nraw: %s", err, raw)
+	}
+	os.RemoveAll(res.ContextID)
+}
+
+// TestExePruneAllDryRun checks that a dry run lists a stopped managed container without removing it.
+func TestExePruneAllDryRun(t *testing.T) {
+	exe := findExe(t)
+	stdout, stderr, code := runAdde(t, exe, "create_runtime_env", `{"image":"busybox","job_mode":true}`)
+	if code != 0 {
+		t.Logf("create_runtime_env failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+		t.Skip("prune_all dry run needs Docker; skipping")
+	}
+	var createRes struct {
+		ContainerID string `json:"container_id"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &createRes); err != nil || createRes.ContainerID == "" {
+		t.Fatalf("create result invalid: %v\nraw: %s", err, stdout)
+	}
+	cid := createRes.ContainerID
+	defer func() {
+		runAdde(t, exe, "cleanup_env", `{"container_id":"`+cid+`"}`)
+	}()
+	runAdde(t, exe, "wait_container", `{"container_id":"`+cid+`","timeout_sec":30}`)
+
+	stdout, stderr, code = runAdde(t, exe, "prune_all", `{"dry_run":true}`)
+	if code != 0 {
+		t.Fatalf("prune_all dry run failed: %d stderr=%s stdout=%s", code, stderr, stdout)
+	}
+	var pruneRes struct {
+		DryRun     bool     `json:"dry_run"`
+		Containers []string `json:"containers"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &pruneRes); err != nil {
+		t.Fatalf("prune_all result not JSON: %v\nraw: %s", err, stdout)
+	}
+	found := false
+	for _, id := range pruneRes.Containers {
+		found = found || id == cid
+	}
+	if !pruneRes.DryRun || !found {
+		t.Errorf("expected dry run to report %s; got %s", cid, stdout)
+	}
+	if _, _, code := runAdde(t, exe, "get_container_logs", `{"container_id":"`+cid+`","source":"container"}`); code != 0 {
+		t.Error("container was removed by a dry run")
+	}
+}
+
+// TestExeExecuteWithTTY checks that tty:true makes the program see a terminal on stdout.
+func TestExeExecuteWithTTY(t *testing.T) {
+	exe := findExe(t)
+	stdout, stderr, code := runAdde(t, exe, "create_runtime_env", `{"image":"busybox","dependencies":[],"env_vars":{},"network":false}`)
+	if code != 0 {
+		t.Logf("create_runtime_env failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+		t.Skip("TTY test needs Docker; skipping")
+	}
+	var createRes struct {
+		ContainerID string `json:"container_id"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &createRes); err != nil || createRes.ContainerID == "" {
+		t.Fatalf("create result invalid: %v\nraw: %s", err, stdout)
+	}
+	cid := createRes.ContainerID
+	defer func() {
+		runAdde(t, exe, "cleanup_env", `{"container_id":"`+cid+`"}`)
+	}()
+
+	script := `if [ -t 1 ]; then echo is-tty; else echo no-tty; fi`
+	for _, tc := range []struct {
+		tty  bool
+		want string
+	}{{false, "no-tty"}, {true, "is-tty"}} {
+		payload := fmt.Sprintf(`{"container_id":%q,"filename":"tty.sh","code_content":%q,"tty":%v}`, cid, script, tc.tty)
+		stdout, stderr, code = runAdde(t, exe, "execute_code_block", payload)
+		if code != 0 {
+			t.Fatalf("execute_code_block (tty=%v) failed: %d stderr=%s", tc.tty, code, stderr)
+		}
+		var res struct {
+			Log *struct {
+				Stdout string `json:"stdout"`
+			} `json:"log"`
+		}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &res); err != nil || res.Log == nil {
+			t.Fatalf("execute result invalid: %v\nraw: %s", err, stdout)
+		}
+		if !strings.Contains(res.Log.Stdout, tc.want) {
+			t.Errorf("tty=%v: expected %q in stdout, got %q", tc.tty, tc.want, res.Log.Stdout)
+		}
+	}
+}
+
+// TestExeExecuteWithProfile runs a Python script with profile: true and checks the cProfile summary is returned.
+func TestExeExecuteWithProfile(t *testing.T) {
+	exe := findExe(t)
+	stdout, stderr, code := runAdde(t, exe, "create_runtime_env", `{"image":"python:3.11-slim","dependencies":[],"env_vars":{},"network":false}`)
+	if code != 0 {
+		t.Logf("create_runtime_env failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+		t.Skip("profile test needs Docker; skipping")
+	}
+	var createRes struct {
+		ContainerID string `json:"container_id"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &createRes); err != nil || createRes.ContainerID == "" {
+		t.Fatalf("create result invalid: %v\nraw: %s", err, stdout)
+	}
+	cid := createRes.ContainerID
+	defer func() {
+		runAdde(t, exe, "cleanup_env", `{"container_id":"`+cid+`"}`)
+	}()
+
+	script := "def fib(n):\n    return n if n < 2 else fib(n - 1) + fib(n - 2)\n\nprint(fib(20))\n"
+	payload := fmt.Sprintf(`{"container_id":%q,"filename":"fib.py","code_content":%q,"profile":true}`, cid, script)
+	stdout, stderr, code = runAdde(t, exe, "execute_code_block", payload)
+	if code != 0 {
+		t.Fatalf("execute_code_block failed: %d stderr=%s stdout=%s", code, stderr, stdout)
+	}
+	var res struct {
+		Log *struct {
+			Stdout  string `json:"stdout"`
+			Profile string `json:"profile"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &res); err != nil || res.Log == nil {
+		t.Fatalf("execute result invalid: %v\nraw: %s", err, stdout)
+	}
+	if strings.TrimSpace(res.Log.Stdout) != "6765" {
+		t.Errorf("program output changed by profiling: %q", res.Log.Stdout)
+	}
+	if !strings.Contains(res.Log.Profile, "function calls") || !strings.Contains(res.Log.Profile, "fib") {
+		t.Errorf("profile summary missing: %q", res.Log.Profile)
+	}
+}
+
+// TestExeExecuteWithCoverage runs a Python script under coverage.py and checks a percentage comes back.
+func TestExeExecuteWithCoverage(t *testing.T) {
+	exe := findExe(t)
+	stdout, stderr, code := runAdde(t, exe, "create_runtime_env", `{"image":"python:3.11-slim","dependencies":["coverage"],"env_vars":{},"network":true}`)
+	if code != 0 {
+		t.Logf("create_runtime_env failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+		t.Skip("coverage test needs Docker and PyPI access; skipping")
+	}
+	var createRes struct {
+		ContainerID string `json:"container_id"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &createRes); err != nil || createRes.ContainerID == "" {
+		t.Fatalf("create result invalid: %v\nraw: %s", err, stdout)
+	}
+	cid := createRes.ContainerID
+	defer func() {
+		runAdde(t, exe, "cleanup_env", `{"container_id":"`+cid+`"}`)
+	}()
+
+	// One of the two branches never runs, so coverage is below 100%.
+	script := "def sign(n):\n    if n < 0:\n        return -1\n    return 1\n\nassert sign(5) == 1\nprint('ok')\n"
+	payload := fmt.Sprintf(`{"container_id":%q,"filename":"test_sign.py","code_content":%q,"coverage":true}`, cid, script)
+	stdout, stderr, code = runAdde(t, exe, "execute_code_block", payload)
+	if code != 0 {
+		t.Fatalf("execute_code_block failed: %d stderr=%s stdout=%s", code, stderr, stdout)
+	}
+	var res struct {
+		Coverage *struct {
+			Percent float64 `json:"percent"`
+			Files   []struct {
+				Path string `json:"path"`
+			} `json:"files"`
+			Error string `json:"error"`
+		} `json:"coverage"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &res); err != nil || res.Coverage == nil {
+		t.Fatalf("execute result invalid: %v\nraw: %s", err, stdout)
+	}
+	if res.Coverage.Error != "" {
+		t.Fatalf("coverage error: %s", res.Coverage.Error)
+	}
+	if res.Coverage.Percent <= 0 || res.Coverage.Percent >= 100 {
+		t.Errorf("percent = %v, want between 0 and 100", res.Coverage.Percent)
+	}
+	if len(res.Coverage.Files) != 1 || res.Coverage.Files[0].Path != "test_sign.py" {
+		t.Errorf("files = %+v", res.Coverage.Files)
+	}
+}
+
+// TestExeCaptureArtifacts runs a script that writes files and checks only the matching one is reported.
+func TestExeCaptureArtifacts(t *testing.T) {
+	exe := findExe(t)
+	stdout, stderr, code := runAdde(t, exe, "create_runtime_env", `{"image":"busybox","dependencies":[],"env_vars":{},"network":false}`)
+	if code != 0 {
+		t.Logf("create_runtime_env failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+		t.Skip("capture_artifacts test needs Docker; skipping")
+	}
+	var createRes struct {
+		ContainerID string `json:"container_id"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &createRes); err != nil || createRes.ContainerID == "" {
+		t.Fatalf("create result invalid: %v\nraw: %s", err, stdout)
+	}
+	cid := createRes.ContainerID
+	defer func() {
+		runAdde(t, exe, "cleanup_env", `{"container_id":"`+cid+`"}`)
+	}()
+
+	script := `mkdir -p out && printf 'a,b\n1,2\n' > out/data.csv && echo scratch > notes.txt`
+	payload := fmt.Sprintf(`{"container_id":%q,"filename":"gen.sh","code_content":%q,"capture_artifacts":["out/**"]}`, cid, script)
+	stdout, stderr, code = runAdde(t, exe, "execute_code_block", payload)
+	if code != 0 {
+		t.Fatalf("execute_code_block failed: %d stderr=%s stdout=%s", code, stderr, stdout)
+	}
+	var res struct {
+		Artifacts []struct {
+			Path      string `json:"path"`
+			SizeBytes int64  `json:"size_bytes"`
+		} `json:"artifacts"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &res); err != nil {
+		t.Fatalf("execute result not JSON: %v\nraw: %s", err, stdout)
+	}
+	if len(res.Artifacts) != 1 || res.Artifacts[0].Path != "out/data.csv" || res.Artifacts[0].SizeBytes != 8 {
+		t.Errorf("expected only out/data.csv (8 bytes); got %+v", res.Artifacts)
+	}
+}
+
+// TestExeWorkspaceGuard runs a script that keeps growing a file and checks max_workspace_mb kills it.
+func TestExeWorkspaceGuard(t *testing.T) {
+	exe := findExe(t)
+	stdout, stderr, code := runAdde(t, exe, "create_runtime_env", `{"image":"busybox","dependencies":[],"env_vars":{},"network":false}`)
+	if code != 0 {
+		t.Logf("create_runtime_env failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+		t.Skip("workspace guard test needs Docker; skipping")
+	}
+	var createRes struct {
+		ContainerID string `json:"container_id"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &createRes); err != nil || createRes.ContainerID == "" {
+		t.Fatalf("create result invalid: %v\nraw: %s", err, stdout)
+	}
+	cid := createRes.ContainerID
+	defer func() {
+		runAdde(t, exe, "cleanup_env", `{"container_id":"`+cid+`"}`)
+	}()
+
+	script := `while true; do head -c 1048576 /dev/zero >> big.bin; sleep 0.1; done`
+	payload := fmt.Sprintf(`{"container_id":%q,"filename":"fill.sh","code_content":%q,"timeout_sec":60,"max_workspace_mb":5}`, cid, script)
+	start := time.Now()
+	stdout, _, code = runAdde(t, exe, "execute_code_block", payload)
+	if code == 0 {
+		t.Fatalf("expected non-zero exit when the guard trips; stdout=%s", stdout)
+	}
+	if !strings.Contains(stdout, "WORKSPACE_LIMIT_EXCEEDED") {
+		t.Errorf("expected WORKSPACE_LIMIT_EXCEEDED in result; got %s", stdout)
+	}
+	if elapsed := time.Since(start); elapsed > 30*time.Second {
+		t.Errorf("guard took %s to trip; expected well under the 60s timeout", elapsed)
+	}
+}
+
+func TestExeMaxExecutionsRecycles(t *testing.T) {
+	exe := findExe(t)
+	stdout, stderr, code := runAdde(t, exe, "create_runtime_env", `{"image":"busybox","dependencies":[],"env_vars":{},"network":false,"max_executions":2}`)
+	if code != 0 {
+		t.Logf("create_runtime_env failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+		t.Skip("max_executions test needs Docker; skipping")
+	}
+	var createRes struct {
+		ContainerID string `json:"container_id"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &createRes); err != nil || createRes.ContainerID == "" {
+		t.Fatalf("create result invalid: %v\nraw: %s", err, stdout)
+	}
+	cid := createRes.ContainerID
+	defer func() {
+		runAdde(t, exe, "cleanup_env", `{"container_id":"`+cid+`"}`)
+	}()
+
+	payload := `{"container_id":"` + cid + `","filename":"t.sh","code_content":"echo run","timeout_sec":15}`
+	for run := 1; run <= 2; run++ {
+		stdout, stderr, code = runAdde(t, exe, "execute_code_block", payload)
+		if code != 0 {
+			t.Fatalf("run %d failed: code=%d stderr=%s stdout=%s", run, code, stderr, stdout)
+		}
+		var res struct {
+			Log *struct {
+				Stdout string `json:"stdout"`
+			} `json:"log"`
+			Recycled bool `json:"recycled"`
+		}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &res); err != nil {
+			t.Fatalf("run %d: invalid result: %v\nraw: %s", run, err, stdout)
+		}
+		if res.Log == nil || !strings.Contains(res.Log.Stdout, "run") {
+			t.Errorf("run %d: expected log with output; got %s", run, stdout)
+		}
+		if res.Recycled != (run == 2) {
+			t.Errorf("run %d: recycled=%v", run, res.Recycled)
+		}
+	}
+	if dockerContainerIDs(t)[cid] {
+		t.Errorf("container %s still exists after reaching max_executions", cid)
+	}
+	if _, _, code = runAdde(t, exe, "execute_code_block", payload); code == 0 {
+		t.Error("expected execute on a recycled container to fail")
+	}
+}
+
+func TestExeIdempotencyKeyReusesContainer(t *testing.T) {
+	exe := findExe(t)
+	key := fmt.Sprintf("exe-test-%d", time.Now().UnixNano())
+	payload := `{"image":"busybox","dependencies":[],"env_vars":{},"network":false,"idempotency_key":"` + key + `"}`
+	type createResult struct {
+		ContainerID string `json:"container_id"`
+		Reused      bool   `json:"reused"`
+	}
+	create := func() createResult {
+		stdout, stderr, code := runAdde(t, exe, "create_runtime_env", payload)
+		if code != 0 {
+			t.Logf("create_runtime_env failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+			t.Skip("idempotency test needs Docker; skipping")
+		}
+		var res createResult
+		if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &res); err != nil || res.ContainerID == "" {
+			t.Fatalf("create result invalid: %v\nraw: %s", err, stdout)
+		}
+		return res
+	}
+
+	first := create()
+	defer func() {
+		runAdde(t, exe, "cleanup_env", `{"container_id":"`+first.ContainerID+`"}`)
+	}()
+	second := create()
+	if second.ContainerID != first.ContainerID {
+		t.Errorf("second create returned %s, want %s", second.ContainerID, first.ContainerID)
+	}
+	if first.Reused || !second.Reused {
+		t.Errorf("reused flags: first=%v second=%v, want false/true", first.Reused, second.Reused)
+	}
+}
+
+func TestExeStdinFileStreamsLargeInput(t *testing.T) {
+	exe := findExe(t)
+	stdout, stderr, code := runAdde(t, exe, "create_runtime_env", `{"image":"busybox","dependencies":[],"env_vars":{},"network":false}`)
+	if code != 0 {
+		t.Logf("create_runtime_env failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+		t.Skip("stdin_file test needs Docker; skipping")
+	}
+	var createRes struct {
+		ContainerID string `json:"container_id"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &createRes); err != nil || createRes.ContainerID == "" {
+		t.Fatalf("create result invalid: %v\nraw: %s", err, stdout)
+	}
+	cid := createRes.ContainerID
+	defer func() {
+		runAdde(t, exe, "cleanup_env", `{"container_id":"`+cid+`"}`)
+	}()
+
+	// ~5 MB: 100000 lines of 50 bytes.
+	const lines = 100000
+	input := filepath.Join(t.TempDir(), "input.txt")
+	line := strings.Repeat("x", 49) + "\n"
+	if err := os.WriteFile(input, []byte(strings.Repeat(line, lines)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	payload := fmt.Sprintf(`{"container_id":%q,"filename":"count.sh","code_content":"wc -l","timeout_sec":60,"stdin_file":%q}`, cid, input)
+	stdout, stderr, code = runAdde(t, exe, "execute_code_block", payload)
+	if code != 0 {
+		t.Fatalf("execute_code_block failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+	}
+	var res struct {
+		Log struct {
+			Stdout string `json:"stdout"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &res); err != nil {
+		t.Fatalf("invalid result: %v\nraw: %s", err, stdout)
+	}
+	if got := strings.TrimSpace(res.Log.Stdout); got != fmt.Sprint(lines) {
+		t.Errorf("wc -l = %q, want %d", got, lines)
+	}
+}
+
+func TestExeRunFromDockerfile(t *testing.T) {
+	exe := findExe(t)
+	tag := fmt.Sprintf("agent-env:run-exe-test-%d", time.Now().UnixNano())
+	payload := fmt.Sprintf(`{"dockerfile":"FROM busybox\nCOPY greeting.txt /greeting.txt\n","files":{"greeting.txt":"hello from the image"},"tag":%q,"filename":"t.sh","code_content":"cat /greeting.txt","timeout_sec":30}`, tag)
+	if stdout, stderr, code := runAdde(t, exe, "list_agent_images", `{}`); code != 0 {
+		t.Logf("list_agent_images failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+		t.Skip("run_from_dockerfile test needs Docker; skipping")
+	}
+	stdout, stderr, code := runAdde(t, exe, "run_from_dockerfile", payload)
+	if code != 0 {
+		t.Fatalf("run_from_dockerfile failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+	}
+	var res struct {
+		Build struct {
+			Status string `json:"status"`
+		} `json:"build"`
+		Log struct {
+			ExitCode int    `json:"exit_code"`
+			Stdout   string `json:"stdout"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &res); err != nil {
+		t.Fatalf("invalid result: %v\nraw: %s", err, stdout)
+	}
+	if res.Build.Status != "success" || res.Log.ExitCode != 0 || !strings.Contains(res.Log.Stdout, "hello from the image") {
+		t.Errorf("unexpected result: %s", stdout)
+	}
+	if out, err := exec.Command("docker", "image", "inspect", tag).CombinedOutput(); err == nil {
+		t.Errorf("image %s still exists after run_from_dockerfile: %s", tag, out)
+	}
+}
+
+func TestExePullAndBuildReportDuration(t *testing.T) {
+	exe := findExe(t)
+	stdout, stderr, code := runAdde(t, exe, "pull_image", `{"image":"busybox"}`)
+	if code != 0 {
+		t.Logf("pull_image failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+		t.Skip("duration test needs Docker; skipping")
+	}
+	var pullRes struct {
+		DurationMillis *int64 `json:"duration_ms"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &pullRes); err != nil {
+		t.Fatalf("invalid pull result: %v\nraw: %s", err, stdout)
+	}
+	if pullRes.DurationMillis == nil || *pullRes.DurationMillis < 0 {
+		t.Errorf("pull duration_ms missing or negative: %s", stdout)
+	}
+
+	stdout, _, code = runAdde(t, exe, "prepare_build_context", `{"files":{"Dockerfile":"FROM busybox\nRUN true\n"}}`)
+	if code != 0 {
+		t.Fatalf("prepare_build_context failed: %s", stdout)
+	}
+	var prep struct {
+		ContextID string `json:"context_id"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &prep); err != nil {
+		t.Fatalf("invalid prepare result: %v", err)
+	}
+	defer os.RemoveAll(prep.ContextID)
+	tag := fmt.Sprintf("agent-env:duration-test-%d", time.Now().UnixNano())
+	stdout, stderr, code = runAdde(t, exe, "build_image_from_context", fmt.Sprintf(`{"context_id":%q,"tag":%q}`, prep.ContextID, tag))
+	if code != 0 {
+		t.Fatalf("build failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+	}
+	defer runAdde(t, exe, "delete_image", `{"image":"`+tag+`","force":true}`)
+	var buildRes struct {
+		DurationMillis *int64 `json:"duration_ms"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &buildRes); err != nil {
+		t.Fatalf("invalid build result: %v\nraw: %s", err, stdout)
+	}
+	if buildRes.DurationMillis == nil || *buildRes.DurationMillis < 0 {
+		t.Errorf("build duration_ms missing or negative: %s", stdout)
+	}
+}
+
+func TestExeCustomHostname(t *testing.T) {
+	exe := findExe(t)
+	stdout, stderr, code := runAdde(t, exe, "create_runtime_env", `{"image":"busybox","dependencies":[],"env_vars":{},"network":false,"hostname":"worker-7","domainname":"sandbox.local"}`)
+	if code != 0 {
+		t.Logf("create_runtime_env failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+		t.Skip("hostname test needs Docker; skipping")
+	}
+	var createRes struct {
+		ContainerID string `json:"container_id"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &createRes); err != nil || createRes.ContainerID == "" {
+		t.Fatalf("create result invalid: %v\nraw: %s", err, stdout)
+	}
+	cid := createRes.ContainerID
+	defer func() {
+		runAdde(t, exe, "cleanup_env", `{"container_id":"`+cid+`"}`)
+	}()
+
+	stdout, stderr, code = runAdde(t, exe, "execute_code_block", `{"container_id":"`+cid+`","filename":"h.sh","code_content":"hostname","timeout_sec":15}`)
+	if code != 0 {
+		t.Fatalf("execute_code_block failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+	}
+	var res struct {
+		Log struct {
+			Stdout string `json:"stdout"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &res); err != nil {
+		t.Fatalf("invalid result: %v\nraw: %s", err, stdout)
+	}
+	if got := strings.TrimSpace(res.Log.Stdout); got != "worker-7" {
+		t.Errorf("hostname = %q, want worker-7", got)
+	}
+}
+
+func TestExeStdoutBase64RoundTrip(t *testing.T) {
+	exe := findExe(t)
+	stdout, stderr, code := runAdde(t, exe, "create_runtime_env", `{"image":"busybox","dependencies":[],"env_vars":{},"network":false}`)
+	if code != 0 {
+		t.Logf("create_runtime_env failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+		t.Skip("stdout_encoding test needs Docker; skipping")
+	}
+	var createRes struct {
+		ContainerID string `json:"container_id"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &createRes); err != nil || createRes.ContainerID == "" {
+		t.Fatalf("create result invalid: %v\nraw: %s", err, stdout)
+	}
+	cid := createRes.ContainerID
+	defer func() {
+		runAdde(t, exe, "cleanup_env", `{"container_id":"`+cid+`"}`)
+	}()
+
+	// PNG signature plus bytes that are not valid UTF-8.
+	script := `printf '\211PNG\r\n\032\n\377\376\000end'`
+	payload := fmt.Sprintf(`{"container_id":%q,"filename":"bin.sh","code_content":%q,"timeout_sec":15,"stdout_encoding":"base64"}`, cid, script)
+	stdout, stderr, code = runAdde(t, exe, "execute_code_block", payload)
+	if code != 0 {
+		t.Fatalf("execute_code_block failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+	}
+	var res struct {
+		Log struct {
+			Stdout   string `json:"stdout"`
+			Encoding string `json:"encoding"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &res); err != nil {
+		t.Fatalf("invalid result: %v\nraw: %s", err, stdout)
+	}
+	if res.Log.Encoding != "base64" {
+		t.Errorf("encoding = %q, want base64", res.Log.Encoding)
+	}
+	got, err := base64.StdEncoding.DecodeString(res.Log.Stdout)
+	if err != nil {
+		t.Fatalf("stdout is not base64: %v", err)
+	}
+	if want := "\x89PNG\r\n\x1a\n\xff\xfe\x00end"; string(got) != want {
+		t.Errorf("stdout bytes = %q, want %q", got, want)
+	}
+}
+
+func TestExeContainerInheritsProvenanceLabels(t *testing.T) {
+	exe := findExe(t)
+	stdout, _, code := runAdde(t, exe, "prepare_build_context", `{"files":{"Dockerfile":"FROM busybox\n"}}`)
+	if code != 0 {
+		t.Fatalf("prepare_build_context failed: %s", stdout)
+	}
+	var prep struct {
+		ContextID string `json:"context_id"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &prep); err != nil {
+		t.Fatalf("invalid prepare result: %v", err)
+	}
+	defer os.RemoveAll(prep.ContextID)
+	tag := fmt.Sprintf("agent-env:provenance-test-%d", time.Now().UnixNano())
+	stdout, stderr, code := runAdde(t, exe, "build_image_from_context", fmt.Sprintf(`{"context_id":%q,"tag":%q,"task_id":"task-42"}`, prep.ContextID, tag))
+	if code != 0 {
+		t.Logf("build failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+		t.Skip("provenance test needs Docker; skipping")
+	}
+	defer runAdde(t, exe, "delete_image", `{"image":"`+tag+`","force":true}`)
+
+	stdout, stderr, code = runAdde(t, exe, "create_runtime_env", fmt.Sprintf(`{"image":%q,"dependencies":[],"env_vars":{},"network":false}`, tag))
+	if code != 0 {
+		t.Fatalf("create_runtime_env failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+	}
+	var createRes struct {
+		ContainerID string `json:"container_id"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &createRes); err != nil || createRes.ContainerID == "" {
+		t.Fatalf("create result invalid: %v\nraw: %s", err, stdout)
+	}
+	cid := createRes.ContainerID
+	defer func() {
+		runAdde(t, exe, "cleanup_env", `{"container_id":"`+cid+`"}`)
+	}()
+
+	out, err := exec.Command("docker", "inspect", "--format", "{{json .Config.Labels}}", cid).Output()
+	if err != nil {
+		t.Fatalf("docker inspect: %v", err)
+	}
+	var labels map[string]string
+	if err := json.Unmarshal(out, &labels); err != nil {
+		t.Fatalf("invalid labels: %v\nraw: %s", err, out)
+	}
+	if labels["adde.task_id"] != "task-42" {
+		t.Errorf("adde.task_id = %q, want task-42", labels["adde.task_id"])
+	}
+	if labels["adde.context_id"] != filepath.Clean(prep.ContextID) {
+		t.Errorf("adde.context_id = %q, want %q", labels["adde.context_id"], prep.ContextID)
+	}
+}
+
+// TestExeDependencyInstallFailureLog asserts a failed pip install comes back as install_log
+// (exit code and output) and the container is removed.
+func TestExeDependencyInstallFailureLog(t *testing.T) {
+	exe := findExe(t)
+	if stdout, stderr, code := runAdde(t, exe, "pull_image", `{"image":"python:3.11-slim"}`); code != 0 {
+		t.Logf("pull_image failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+		t.Skip("install failure test needs Docker; skipping")
+	}
+	before := dockerContainerIDs(t)
+
+	// No network, so pip cannot reach the index and exits non-zero.
+	stdout, _, code := runAdde(t, exe, "create_runtime_env", `{"image":"python:3.11-slim","dependencies":["adde-no-such-package"],"env_vars":{},"network":false}`)
+	if code == 0 {
+		t.Fatalf("create_runtime_env succeeded despite failing install: %s", stdout)
+	}
+	var res struct {
+		ContainerID string `json:"container_id"`
+		InstallLog  *struct {
+			ExitCode int    `json:"exit_code"`
+			Stderr   string `json:"stderr"`
+		} `json:"install_log"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &res); err != nil {
+		t.Fatalf("invalid result: %v\nraw: %s", err, stdout)
+	}
+	if res.InstallLog == nil || res.InstallLog.ExitCode == 0 || res.InstallLog.Stderr == "" || res.Error == "" {
+		t.Errorf("want structured install failure, got: %s", stdout)
+	}
+	if res.ContainerID != "" {
+		t.Errorf("container_id %q returned for a failed create", res.ContainerID)
+	}
+	for id := range dockerContainerIDs(t) {
+		if !before[id] {
+			t.Errorf("container %s left behind after failed install", id)
+		}
+	}
+}
+
+func TestExeCustomNameAndConflict(t *testing.T) {
+	exe := findExe(t)
+	name := fmt.Sprintf("adde-name-test-%d", time.Now().UnixNano())
+	payload := `{"image":"busybox","dependencies":[],"env_vars":{},"network":false,"name":"` + name + `"}`
+	stdout, stderr, code := runAdde(t, exe, "create_runtime_env", payload)
+	if code != 0 {
+		t.Logf("create_runtime_env failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+		t.Skip("name test needs Docker; skipping")
+	}
+	var createRes struct {
+		ContainerID string `json:"container_id"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &createRes); err != nil || createRes.ContainerID == "" {
+		t.Fatalf("create result invalid: %v\nraw: %s", err, stdout)
+	}
+	cid := createRes.ContainerID
+	defer func() {
+		runAdde(t, exe, "cleanup_env", `{"container_id":"`+cid+`"}`)
+	}()
+
+	out, err := exec.Command("docker", "inspect", "--format", "{{.Name}}", cid).Output()
+	if err != nil {
+		t.Fatalf("docker inspect: %v", err)
+	}
+	if got := strings.TrimSpace(string(out)); got != "/"+name {
+		t.Errorf("container name = %q, want /%s", got, name)
+	}
+
+	before := dockerContainerIDs(t)
+	stdout, _, code = runAdde(t, exe, "create_runtime_env", payload)
+	if code == 0 {
+		t.Fatalf("second create with the same name succeeded: %s", stdout)
+	}
+	if !strings.Contains(stdout, "already in use") || !strings.Contains(stdout, name) {
+		t.Errorf("conflict error not reported clearly: %s", stdout)
+	}
+	for id := range dockerContainerIDs(t) {
+		if !before[id] {
+			t.Errorf("container %s left behind after name conflict", id)
+		}
+	}
+}
+
+func TestExePidsLimitConstrainsForks(t *testing.T) {
+	exe := findExe(t)
+	stdout, stderr, code := runAdde(t, exe, "create_runtime_env", `{"image":"busybox","dependencies":[],"env_vars":{},"network":false}`)
+	if code != 0 {
+		t.Logf("create_runtime_env failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+		t.Skip("pids_limit test needs Docker; skipping")
+	}
+	var createRes struct {
+		ContainerID string `json:"container_id"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &createRes); err != nil || createRes.ContainerID == "" {
+		t.Fatalf("create result invalid: %v\nraw: %s", err, stdout)
+	}
+	cid := createRes.ContainerID
+	defer func() {
+		runAdde(t, exe, "cleanup_env", `{"container_id":"`+cid+`"}`)
+	}()
+
+	// Try to start 40 background sleeps; with pids_limit 10 most forks must fail.
+	script := `n=0; i=0; while [ $i -lt 40 ]; do if sleep 30 & then n=$((n+1)); fi; i=$((i+1)); done; echo "$n"`
+	payload := fmt.Sprintf(`{"container_id":%q,"filename":"fork.sh","code_content":%q,"timeout_sec":20,"pids_limit":10}`, cid, script)
+	stdout, stderr, code = runAdde(t, exe, "execute_code_block", payload)
+	if code != 0 {
+		t.Fatalf("execute_code_block failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+	}
+	var res struct {
+		Log struct {
+			Stdout string `json:"stdout"`
+			Stderr string `json:"stderr"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &res); err != nil {
+		t.Fatalf("invalid result: %v\nraw: %s", err, stdout)
+	}
+	started, _ := strconv.Atoi(strings.TrimSpace(res.Log.Stdout))
+	if started >= 40 || !strings.Contains(res.Log.Stderr, "fork") {
+		t.Errorf("forks not constrained: started=%q stderr=%q", res.Log.Stdout, res.Log.Stderr)
+	}
+
+	out, err := exec.Command("docker", "inspect", "--format", "{{.HostConfig.PidsLimit}}", cid).Output()
+	if err != nil {
+		t.Fatalf("docker inspect: %v", err)
+	}
+	if got := strings.TrimSpace(string(out)); got != "<nil>" && got != "0" && got != "-1" {
+		t.Errorf("pids limit not restored after the exec: %s", got)
+	}
+}
+
+// TestExeTimezone creates a container with timezone and locale set and checks what a script sees.
+func TestExeTimezone(t *testing.T) {
+	exe := findExe(t)
+	stdout, stderr, code := runAdde(t, exe, "create_runtime_env", `{"image":"python:3.11-slim","timezone":"Asia/Tokyo","locale":"C.UTF-8"}`)
+	if code != 0 {
+		t.Logf("create_runtime_env failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+		t.Skip("timezone e2e needs Docker; skipping")
+	}
+	var createRes struct {
+		ContainerID string `json:"container_id"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &createRes); err != nil || createRes.ContainerID == "" {
+		t.Fatalf("create result invalid: %v\nraw: %s", err, stdout)
+	}
+	cid := createRes.ContainerID
+	defer runAdde(t, exe, "cleanup_env", `{"container_id":"`+cid+`"}`)
+
+	script := `import os, time; print(time.strftime("%Z"), os.environ["TZ"], os.environ["LC_ALL"])`
+	payload := fmt.Sprintf(`{"container_id":%q,"filename":"tz.py","code_content":%q,"timeout_sec":15}`, cid, script)
+	stdout, stderr, code = runAdde(t, exe, "execute_code_block", payload)
+	if code != 0 {
+		t.Fatalf("execute_code_block failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+	}
+	var res struct {
+		Log struct {
+			Stdout string `json:"stdout"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &res); err != nil {
+		t.Fatalf("invalid result: %v\nraw: %s", err, stdout)
+	}
+	if got := strings.TrimSpace(res.Log.Stdout); got != "JST Asia/Tokyo C.UTF-8" {
+		t.Errorf("script saw %q, want \"JST Asia/Tokyo C.UTF-8\"", got)
+	}
+}
+
+// TestExeJUnitPytest runs a trivial pytest suite with result_format junit and checks the parsed counts.
+func TestExeJUnitPytest(t *testing.T) {
+	exe := findExe(t)
+	stdout, stderr, code := runAdde(t, exe, "create_runtime_env", `{"image":"python:3.11-slim","dependencies":["pytest"],"network":true}`)
+	if code != 0 {
+		t.Logf("create_runtime_env failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+		t.Skip("pytest e2e needs Docker and network access; skipping")
+	}
+	var createRes struct {
+		ContainerID string `json:"container_id"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &createRes); err != nil || createRes.ContainerID == "" {
+		t.Fatalf("create result invalid: %v\nraw: %s", err, stdout)
+	}
+	cid := createRes.ContainerID
+	defer runAdde(t, exe, "cleanup_env", `{"container_id":"`+cid+`"}`)
+
+	suite := "import pytest\n\ndef test_add():\n    assert 1 + 1 == 2\n\ndef test_sub():\n    assert 2 - 1 == 2\n\n@pytest.mark.skip\ndef test_later():\n    pass\n"
+	payload := fmt.Sprintf(`{"container_id":%q,"filename":"test_math.py","code_content":%q,"timeout_sec":60,"result_format":"junit"}`, cid, suite)
+	stdout, stderr, code = runAdde(t, exe, "execute_code_block", payload)
+	if code != 0 {
+		t.Fatalf("execute_code_block failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+	}
+	var res struct {
+		Log struct {
+			ExitCode int `json:"exit_code"`
+		} `json:"log"`
+		TestReport *struct {
+			Tests, Passed, Failed, Skipped int
+			FailedTests                    []string `json:"failed_tests"`
+			Error                          string
+		} `json:"test_report"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &res); err != nil || res.TestReport == nil {
+		t.Fatalf("invalid result: %v\nraw: %s", err, stdout)
+	}
+	r := res.TestReport
+	if r.Error != "" || r.Tests != 3 || r.Passed != 1 || r.Failed != 1 || r.Skipped != 1 {
+		t.Errorf("test_report = %+v", r)
+	}
+	if len(r.FailedTests) != 1 || r.FailedTests[0] != "test_math::test_sub" {
+		t.Errorf("failed_tests = %q", r.FailedTests)
+	}
+	if res.Log.ExitCode != 1 {
+		t.Errorf("exit_code = %d, want pytest's 1 for a failed test", res.Log.ExitCode)
+	}
+}
+
+// TestExeCheckPort publishes nginx's port 80 and checks it is reachable over HTTP from the host.
+func TestExeCheckPort(t *testing.T) {
+	exe := findExe(t)
+	stdout, stderr, code := runAdde(t, exe, "create_runtime_env", `{"image":"nginx:alpine","use_image_cmd":true,"network":true,"port_bindings":{"80":"18089"}}`)
+	if code != 0 {
+		t.Logf("create_runtime_env failed: code=%d stderr=%s stdout=%s", code, stderr, stdout)
+		t.Skip("check_port e2e needs Docker; skipping")
+	}
+	var createRes struct {
+		ContainerID string `json:"container_id"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &createRes); err != nil || createRes.ContainerID == "" {
+		t.Fatalf("create result invalid: %v\nraw: %s", err, stdout)
+	}
+	cid := createRes.ContainerID
+	defer runAdde(t, exe, "cleanup_env", `{"container_id":"`+cid+`"}`)
+
+	var res struct {
+		Reachable   bool   `json:"reachable"`
+		HostAddress string `json:"host_address"`
+		StatusCode  int    `json:"status_code"`
+		Reason      string `json:"reason"`
+		Error       string `json:"error"`
+	}
+	// nginx needs a moment to start listening.
+	for i := 0; i < 20; i++ {
+		stdout, _, _ = runAdde(t, exe, "check_port", `{"container_id":"`+cid+`","container_port":"80","path":"/"}`)
+		if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &res); err != nil {
+			t.Fatalf("invalid result: %v\nraw: %s", err, stdout)
+		}
+		if res.Reachable || res.Error != "" {
+			break
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+	if !res.Reachable || res.StatusCode != 200 || !strings.HasSuffix(res.HostAddress, ":18089") {
+		t.Errorf("check_port = %+v", res)
+	}
+	stdout, _, _ = runAdde(t, exe, "check_port", `{"container_id":"`+cid+`","container_port":"81"}`)
+	if !strings.Contains(stdout, "not published") {
+		t.Errorf("unpublished port: %s", stdout)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/docker/docker/client"
+)
+
+// clientOptions are the CLI-level overrides for how the Docker client is built.
+// Zero values keep the client.FromEnv defaults (DOCKER_HOST, DOCKER_TLS_VERIFY, ...).
+type clientOptions struct {
+	Host string // overrides DOCKER_HOST for this invocation, e.g. tcp://10.0.0.5:2375
+
+	// TLS material for a remote daemon when DOCKER_CERT_PATH/DOCKER_TLS_VERIFY are not set.
+	TLSCACert string
+	TLSCert   string
+	TLSKey    string
+
+	// APIVersion pins the Engine API version (e.g. "1.41") instead of negotiating it with the daemon.
+	APIVersion string
+}
+
+// apiVersionRe matches Engine API versions such as 1.41.
+var apiVersionRe = regexp.MustCompile(`^[0-9]+\.[0-9]+$`)
+
+// newDockerClient builds the Docker client from env, then applies any explicit overrides.
+func newDockerClient(o clientOptions) (*client.Client, error) {
+	opts := []client.Opt{client.FromEnv}
+	if o.APIVersion != "" {
+		if !apiVersionRe.MatchString(o.APIVersion) {
+			return nil, fmt.Errorf("invalid --api-version %q: want e.g. 1.41", o.APIVersion)
+		}
+		opts = append(opts, client.WithVersion(o.APIVersion))
+	} else {
+		opts = append(opts, client.WithAPIVersionNegotiation())
+	}
+	if o.Host != "" {
+		if _, err := client.ParseHostURL(o.Host); err != nil {
+			return nil, fmt.Errorf("invalid --host %q: %v", o.Host, err)
+		}
+		opts = append(opts, client.WithHost(o.Host))
+	}
+	if o.TLSCACert != "" || o.TLSCert != "" || o.TLSKey != "" {
+		if err := validateTLSFiles(o); err != nil {
+			return nil, err
+		}
+		opts = append(opts, client.WithTLSClientConfig(o.TLSCACert, o.TLSCert, o.TLSKey))
+	}
+	return client.NewClientWithOpts(opts...)
+}
+
+// validateTLSFiles checks that every configured TLS file is readable and that cert and key come as a pair.
+func validateTLSFiles(o clientOptions) error {
+	if (o.TLSCert == "") != (o.TLSKey == "") {
+		return fmt.Errorf("--tlscert and --tlskey must be set together")
+	}
+	for _, f := range []struct{ name, path string }{
+		{"--tlscacert", o.TLSCACert},
+		{"--tlscert", o.TLSCert},
+		{"--tlskey", o.TLSKey},
+	} {
+		if f.path == "" {
+			continue
+		}
+		if _, err := os.ReadFile(f.path); err != nil {
+			return fmt.Errorf("%s: %v", f.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestNewDockerClientHostOverridesEnv(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "tcp://10.0.0.1:2375")
+
+	cli, err := newDockerClient(clientOptions{})
+	if err != nil {
+		t.Fatalf("newDockerClient: %v", err)
+	}
+	if got := cli.DaemonHost(); got != "tcp://10.0.0.1:2375" {
+		t.Errorf("env host: got %q", got)
+	}
+
+	cli, err = newDockerClient(clientOptions{Host: "tcp://10.0.0.2:2376"})
+	if err != nil {
+		t.Fatalf("newDockerClient with host: %v", err)
+	}
+	if got := cli.DaemonHost(); got != "tcp://10.0.0.2:2376" {
+		t.Errorf("override host: got %q, want tcp://10.0.0.2:2376", got)
+	}
+}
+
+func TestNewDockerClientRejectsInvalidHost(t *testing.T) {
+	if _, err := newDockerClient(clientOptions{Host: "not a url"}); err == nil {
+		t.Error("expected error for invalid host")
+	}
+}
+
+// writeTestCert writes a self-signed certificate and its key as PEM files into dir.
+func writeTestCert(t *testing.T, dir string) (certPath, keyPath string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "adde-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPath = filepath.Join(dir, "cert.pem")
+	keyPath = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certPath, keyPath
+}
+
+func TestNewDockerClientWithTLSConfig(t *testing.T) {
+	certPath, keyPath := writeTestCert(t, t.TempDir())
+	cli, err := newDockerClient(clientOptions{
+		Host:      "tcp://10.0.0.2:2376",
+		TLSCACert: certPath,
+		TLSCert:   certPath,
+		TLSKey:    keyPath,
+	})
+	if err != nil {
+		t.Fatalf("newDockerClient: %v", err)
+	}
+	tr, ok := cli.HTTPClient().Transport.(*http.Transport)
+	if !ok || tr.TLSClientConfig == nil {
+		t.Fatal("expected TLS config on the client transport")
+	}
+	if len(tr.TLSClientConfig.Certificates) != 1 {
+		t.Errorf("expected 1 client certificate, got %d", len(tr.TLSClientConfig.Certificates))
+	}
+}
+
+func TestNewDockerClientTLSValidation(t *testing.T) {
+	certPath, _ := writeTestCert(t, t.TempDir())
+	if _, err := newDockerClient(clientOptions{TLSCert: certPath}); err == nil {
+		t.Error("expected error when --tlskey is missing")
+	}
+	if _, err := newDockerClient(clientOptions{TLSCACert: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Error("expected error for unreadable CA file")
+	}
+}
+
+func TestNewDockerClientPinnedAPIVersion(t *testing.T) {
+	t.Setenv("DOCKER_API_VERSION", "")
+	t.Setenv("ADDE_DOCKER_API_VERSION", "1.41")
+
+	// main uses the env var as the --api-version default.
+	cli, err := newDockerClient(clientOptions{APIVersion: os.Getenv("ADDE_DOCKER_API_VERSION")})
+	if err != nil {
+		t.Fatalf("newDockerClient: %v", err)
+	}
+	if got := cli.ClientVersion(); got != "1.41" {
+		t.Errorf("ClientVersion = %q, want pinned 1.41", got)
+	}
+	// A pinned version must survive a negotiation attempt.
+	cli.NegotiateAPIVersionPing(types.Ping{APIVersion: "1.43"})
+	if got := cli.ClientVersion(); got != "1.41" {
+		t.Errorf("after ping: ClientVersion = %q, want 1.41", got)
+	}
+
+	if _, err := newDockerClient(clientOptions{APIVersion: "v1.41"}); err == nil {
+		t.Error("expected error for malformed version")
+	}
+}
+
+func TestNewDockerClientNegotiatesByDefault(t *testing.T) {
+	t.Setenv("DOCKER_API_VERSION", "")
+	cli, err := newDockerClient(clientOptions{})
+	if err != nil {
+		t.Fatalf("newDockerClient: %v", err)
+	}
+	cli.NegotiateAPIVersionPing(types.Ping{APIVersion: "1.40"})
+	if got := cli.ClientVersion(); got != "1.40" {
+		t.Errorf("ClientVersion = %q, want negotiated 1.40", got)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/client"
+
+	"adde/pkg/executor"
+)
+
+// cliTimeout bounds every tool call except serve.
+const cliTimeout = 10 * 60 // seconds
+
+// configured stands in for secret values in the config output.
+const configured = "configured"
+
+// effectiveConfig is what `adde config` prints: the settings a tool call would actually use.
+// Secrets (credentials, key files) are reported only as "configured".
+type effectiveConfig struct {
+	DockerHost       string                `json:"docker_host"`
+	DockerHostSource string                `json:"docker_host_source"` // flag | env | default
+	APIVersion       string                `json:"api_version"`        // --api-version, DOCKER_API_VERSION or "negotiated"
+	TLS              tlsConfigSummary      `json:"tls"`
+	Engine           string                `json:"engine"` // docker | podman | auto-detect
+	Listen           string                `json:"listen"`
+	Defaults         configDefaults        `json:"defaults"`
+	RegistryAuth     []registryAuthSummary `json:"registry_auth"`
+	Env              map[string]string     `json:"env"` // ADDE_* / DOCKER_* vars that are set, redacted where secret
+}
+
+type tlsConfigSummary struct {
+	Verify     bool   `json:"verify"`              // DOCKER_TLS_VERIFY
+	CertPath   string `json:"cert_path,omitempty"` // DOCKER_CERT_PATH
+	CACert     string `json:"ca_cert,omitempty"`   // --tlscacert / ADDE_DOCKER_CA
+	ClientCert string `json:"client_cert,omitempty"`
+	ClientKey  string `json:"client_key,omitempty"` // "configured" when set
+}
+
+type configDefaults struct {
+	MemoryMB       int     `json:"memory_mb"`
+	CPUs           float64 `json:"cpus"`
+	Network        string  `json:"network"`
+	ExecTimeoutSec int     `json:"exec_timeout_sec"`
+	WaitTimeoutSec int     `json:"wait_timeout_sec"`
+	CLITimeoutSec  int     `json:"cli_timeout_sec"`
+	Workspace      string  `json:"workspace"`
+	MaxInstalls    int     `json:"max_installs"`   // concurrent dependency installs (ADDE_MAX_INSTALLS)
+	WorkspaceRoot  string  `json:"workspace_root"` // host parent dir of workspace/build temp dirs (ADDE_WORKSPACE_ROOT)
+	Image          string  `json:"image"`          // create_runtime_env image when none is given (ADDE_DEFAULT_IMAGE); empty = required
+}
+
+type registryAuthSummary struct {
+	Registry string `json:"registry"`
+	Source   string `json:"source"` // auths | credsStore | credHelpers
+}
+
+// secretEnv lists env vars whose values are never printed.
+var secretEnv = map[string]bool{"ADDE_DOCKER_KEY": true, "DOCKER_AUTH_CONFIG": true, "ADDE_REGISTRY_PASSWORD": true, "ADDE_REGISTRIES_JSON": true}
+
+// reportedEnv lists env vars shown in the env section when set.
+var reportedEnv = []string{
+	"ADDE_DOCKER_CA", "ADDE_DOCKER_CERT", "ADDE_DOCKER_KEY", "ADDE_ENGINE", "ADDE_MAX_INSTALLS", "ADDE_DOCKER_API_VERSION",
+	"ADDE_LOG_LEVEL", "ADDE_LOG_REDACT_ALLOW", "ADDE_WORKSPACE_ROOT", "ADDE_DEFAULT_IMAGE",
+	"ADDE_REGISTRY_URL", "ADDE_REGISTRY_USERNAME", "ADDE_REGISTRY_PASSWORD", "ADDE_REGISTRIES_JSON",
+	"DOCKER_HOST", "DOCKER_API_VERSION", "DOCKER_CERT_PATH", "DOCKER_TLS_VERIFY", "DOCKER_CONFIG", "DOCKER_AUTH_CONFIG",
+}
+
+// resolveConfig computes the effective configuration from flags and the environment.
+func resolveConfig(o clientOptions, engine, listen string, getenv func(string) string) effectiveConfig {
+	c := effectiveConfig{
+		DockerHost:       client.DefaultDockerHost,
+		DockerHostSource: "default",
+		APIVersion:       "negotiated",
+		Engine:           "auto-detect",
+		Listen:           listen,
+		Defaults: configDefaults{
+			MemoryMB:       executor.DefaultMemoryLimitBytes / (1024 * 1024),
+			CPUs:           float64(executor.DefaultNanoCPUs) / 1e9,
+			Network:        "none",
+			ExecTimeoutSec: int(executor.DefaultExecutionTimeout.Seconds()),
+			WaitTimeoutSec: int(executor.DefaultWaitTimeout.Seconds()),
+			CLITimeoutSec:  cliTimeout,
+			Workspace:      executor.WorkspacePathInsideContainer,
+			MaxInstalls:    executor.MaxConcurrentInstalls(),
+			WorkspaceRoot:  executor.TempRoot(),
+			Image:          executor.DefaultImage(),
+		},
+		RegistryAuth: []registryAuthSummary{},
+		Env:          map[string]string{},
+	}
+	if h := getenv("DOCKER_HOST"); h != "" {
+		c.DockerHost, c.DockerHostSource = h, "env"
+	}
+	if o.Host != "" {
+		c.DockerHost, c.DockerHostSource = o.Host, "flag"
+	}
+	if v := getenv("DOCKER_API_VERSION"); v != "" {
+		c.APIVersion = v
+	}
+	if o.APIVersion != "" {
+		c.APIVersion = o.APIVersion
+	}
+	if engine != "" {
+		c.Engine = engine
+	}
+	c.TLS = tlsConfigSummary{
+		Verify:     getenv("DOCKER_TLS_VERIFY") != "",
+		CertPath:   getenv("DOCKER_CERT_PATH"),
+		CACert:     o.TLSCACert,
+		ClientCert: o.TLSCert,
+	}
+	if o.TLSKey != "" {
+		c.TLS.ClientKey = configured
+	}
+	for _, name := range reportedEnv {
+		v := getenv(name)
+		if v == "" {
+			continue
+		}
+		if secretEnv[name] {
+			v = configured
+		}
+		c.Env[name] = v
+	}
+	c.RegistryAuth = registryAuthFromDockerConfig(executor.DockerConfigPath(getenv))
+	return c
+}
+
+// applyMaxInstalls sets the dependency-install concurrency limit from ADDE_MAX_INSTALLS, if set.
+func applyMaxInstalls(getenv func(string) string) error {
+	v := getenv("ADDE_MAX_INSTALLS")
+	if v == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("ADDE_MAX_INSTALLS: %q is not a number", v)
+	}
+	if err := executor.SetMaxConcurrentInstalls(n); err != nil {
+		return fmt.Errorf("ADDE_MAX_INSTALLS: %v", err)
+	}
+	return nil
+}
+
+// applyWorkspaceRoot points new workspace and build temp dirs at ADDE_WORKSPACE_ROOT, if set.
+func applyWorkspaceRoot(getenv func(string) string) error {
+	if err := executor.SetTempRoot(getenv("ADDE_WORKSPACE_ROOT")); err != nil {
+		return fmt.Errorf("ADDE_WORKSPACE_ROOT: %v", err)
+	}
+	return nil
+}
+
+// applyDefaultImage sets the create_runtime_env default image from ADDE_DEFAULT_IMAGE, if set.
+func applyDefaultImage(getenv func(string) string) error {
+	if err := executor.SetDefaultImage(getenv("ADDE_DEFAULT_IMAGE")); err != nil {
+		return fmt.Errorf("ADDE_DEFAULT_IMAGE: %v", err)
+	}
+	return nil
+}
+
+// applyLogLevel turns on debug logging to stderr when ADDE_LOG_LEVEL=debug. Secret-looking env values
+// are redacted in the debug output except for the comma-separated names in ADDE_LOG_REDACT_ALLOW.
+func applyLogLevel(getenv func(string) string, stderr io.Writer) error {
+	switch level := strings.ToLower(getenv("ADDE_LOG_LEVEL")); level {
+	case "", "info":
+		return nil
+	case "debug":
+		var allow []string
+		if v := getenv("ADDE_LOG_REDACT_ALLOW"); v != "" {
+			allow = strings.Split(v, ",")
+		}
+		executor.SetDebugLog(stderr, allow)
+		return nil
+	default:
+		return fmt.Errorf("ADDE_LOG_LEVEL: unknown level %q (want info or debug)", level)
+	}
+}
+
+// registryAuthFromDockerConfig lists registries with credentials in the Docker client config,
+// without reading the credentials themselves. A missing or unreadable file yields an empty list.
+func registryAuthFromDockerConfig(path string) []registryAuthSummary {
+	out := []registryAuthSummary{}
+	if path == "" {
+		return out
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return out
+	}
+	var cfg struct {
+		Auths       map[string]json.RawMessage `json:"auths"`
+		CredsStore  string                     `json:"credsStore"`
+		CredHelpers map[string]string          `json:"credHelpers"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return out
+	}
+	seen := make(map[string]bool)
+	for reg := range cfg.CredHelpers {
+		out = append(out, registryAuthSummary{Registry: reg, Source: "credHelpers"})
+		seen[reg] = true
+	}
+	for reg := range cfg.Auths {
+		if seen[reg] {
+			continue
+		}
+		source := "auths"
+		if cfg.CredsStore != "" {
+			source = "credsStore"
+		}
+		out = append(out, registryAuthSummary{Registry: reg, Source: source})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Registry < out[j].Registry })
+	return out
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"adde/pkg/executor"
+)
+
+func TestResolveConfigRedactsSecrets(t *testing.T) {
+	dockerCfg := t.TempDir()
+	// auth is base64("user:s3cr3t-password")
+	cfgJSON := `{"auths":{"registry.example.com":{"auth":"dXNlcjpzM2NyM3QtcGFzc3dvcmQ="}},"credHelpers":{"123.dkr.ecr.us-east-1.amazonaws.com":"ecr-login"}}`
+	if err := os.WriteFile(filepath.Join(dockerCfg, "config.json"), []byte(cfgJSON), 0600); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"DOCKER_HOST":            "tcp://10.0.0.5:2376",
+		"DOCKER_CONFIG":          dockerCfg,
+		"DOCKER_AUTH_CONFIG":     `{"auths":{"x":{"auth":"c2VjcmV0LXRva2Vu"}}}`,
+		"ADDE_DOCKER_KEY":        "/secrets/client-key.pem",
+		"ADDE_REGISTRY_PASSWORD": "legacy-pw",
+		"ADDE_REGISTRIES_JSON":   `{"harbor.internal":{"username":"u","password":"json-pw"}}`,
+	}
+	opts := clientOptions{TLSKey: env["ADDE_DOCKER_KEY"]}
+	c := resolveConfig(opts, "podman", "127.0.0.1:8765", func(k string) string { return env[k] })
+
+	raw, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(raw)
+	for _, secret := range []string{"dXNlcjpzM2NyM3QtcGFzc3dvcmQ=", "s3cr3t", "c2VjcmV0LXRva2Vu", "client-key.pem", "legacy-pw", "json-pw"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("config output leaks %q: %s", secret, out)
+		}
+	}
+	if c.Env["ADDE_DOCKER_KEY"] != configured || c.Env["DOCKER_AUTH_CONFIG"] != configured || c.TLS.ClientKey != configured {
+		t.Errorf("secret env not reported as configured: env=%v tls=%+v", c.Env, c.TLS)
+	}
+	if c.DockerHost != "tcp://10.0.0.5:2376" || c.DockerHostSource != "env" || c.Engine != "podman" {
+		t.Errorf("host/engine: %s (%s) %s", c.DockerHost, c.DockerHostSource, c.Engine)
+	}
+	if len(c.RegistryAuth) != 2 ||
+		c.RegistryAuth[0] != (registryAuthSummary{"123.dkr.ecr.us-east-1.amazonaws.com", "credHelpers"}) ||
+		c.RegistryAuth[1] != (registryAuthSummary{"registry.example.com", "auths"}) {
+		t.Errorf("registry_auth = %+v", c.RegistryAuth)
+	}
+}
+
+func TestResolveConfigDefaults(t *testing.T) {
+	c := resolveConfig(clientOptions{}, "", "127.0.0.1:8765", func(string) string { return "" })
+	if c.DockerHostSource != "default" || c.Engine != "auto-detect" || c.APIVersion != "negotiated" {
+		t.Errorf("unexpected defaults: %+v", c)
+	}
+	if c.Defaults.MemoryMB != 512 || c.Defaults.CPUs != 0.5 || c.Defaults.ExecTimeoutSec != 30 || c.Defaults.Network != "none" {
+		t.Errorf("unexpected resource defaults: %+v", c.Defaults)
+	}
+	if len(c.Env) != 0 {
+		t.Errorf("env should be empty, got %v", c.Env)
+	}
+}
+
+func TestApplyMaxInstalls(t *testing.T) {
+	defer executor.SetMaxConcurrentInstalls(executor.DefaultMaxConcurrentInstalls)
+	env := map[string]string{"ADDE_MAX_INSTALLS": "2"}
+	if err := applyMaxInstalls(func(k string) string { return env[k] }); err != nil {
+		t.Fatal(err)
+	}
+	c := resolveConfig(clientOptions{}, "", "127.0.0.1:8765", func(k string) string { return env[k] })
+	if c.Defaults.MaxInstalls != 2 || c.Env["ADDE_MAX_INSTALLS"] != "2" {
+		t.Errorf("max_installs = %d, env = %v", c.Defaults.MaxInstalls, c.Env)
+	}
+	for _, bad := range []string{"0", "-1", "many"} {
+		env["ADDE_MAX_INSTALLS"] = bad
+		if err := applyMaxInstalls(func(k string) string { return env[k] }); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestApplyLogLevel(t *testing.T) {
+	defer executor.SetDebugLog(nil, nil)
+	var buf strings.Builder
+	env := map[string]string{"ADDE_LOG_LEVEL": "debug", "ADDE_LOG_REDACT_ALLOW": "PUBLIC_KEY"}
+	if err := applyLogLevel(func(k string) string { return env[k] }, &buf); err != nil {
+		t.Fatal(err)
+	}
+	c := resolveConfig(clientOptions{}, "", "127.0.0.1:8765", func(k string) string { return env[k] })
+	if c.Env["ADDE_LOG_LEVEL"] != "debug" {
+		t.Errorf("env = %v", c.Env)
+	}
+	env["ADDE_LOG_LEVEL"] = "verbose"
+	if err := applyLogLevel(func(k string) string { return env[k] }, &buf); err == nil {
+		t.Error("unknown level: expected error")
+	}
+}
+
+func TestApplyWorkspaceRoot(t *testing.T) {
+	defer executor.SetTempRoot("")
+	root := t.TempDir()
+	env := map[string]string{"ADDE_WORKSPACE_ROOT": root}
+	if err := applyWorkspaceRoot(func(k string) string { return env[k] }); err != nil {
+		t.Fatal(err)
+	}
+	c := resolveConfig(clientOptions{}, "", "127.0.0.1:8765", func(k string) string { return env[k] })
+	if c.Defaults.WorkspaceRoot != root {
+		t.Errorf("workspace_root = %q, want %q", c.Defaults.WorkspaceRoot, root)
+	}
+	env["ADDE_WORKSPACE_ROOT"] = filepath.Join(root, "missing")
+	if err := applyWorkspaceRoot(func(k string) string { return env[k] }); err == nil {
+		t.Error("missing dir: expected error")
+	}
+}
+
+func TestApplyDefaultImage(t *testing.T) {
+	defer executor.SetDefaultImage("")
+	env := map[string]string{"ADDE_DEFAULT_IMAGE": "python:3.11-slim"}
+	if err := applyDefaultImage(func(k string) string { return env[k] }); err != nil {
+		t.Fatal(err)
+	}
+	c := resolveConfig(clientOptions{}, "", "127.0.0.1:8765", func(k string) string { return env[k] })
+	if c.Defaults.Image != "python:3.11-slim" || c.Env["ADDE_DEFAULT_IMAGE"] != "python:3.11-slim" {
+		t.Errorf("image = %q, env = %v", c.Defaults.Image, c.Env)
+	}
+	env["ADDE_DEFAULT_IMAGE"] = "UPPER:case"
+	if err := applyDefaultImage(func(k string) string { return env[k] }); err == nil {
+		t.Error("invalid ref: expected error")
+	}
+}
@@ -4,27 +4,48 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"adde/pkg/executor"
-
-	"github.com/docker/docker/client"
 )
 
+// outputPath, when set via --output, receives the result JSON instead of stdout.
+var outputPath string
+
+// prettyJSON, set via --pretty, indents the result JSON for reading in a terminal.
+var prettyJSON bool
+
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprintf(os.Stderr, "usage: adde <tool> [json_payload]\n")
-		fmt.Fprintf(os.Stderr, "  tool: pull_image | create_runtime_env | execute_code_block | get_container_logs | cleanup_env | prepare_build_context | build_image_from_context | build_image_from_path | list_agent_images | prune_build_cache | delete_image\n")
-		fmt.Fprintf(os.Stderr, "  json_payload: JSON object for the tool, or omit to read from stdin\n")
+	var copts clientOptions
+	flag.StringVar(&copts.Host, "host", "", "Docker daemon URL for this call (overrides DOCKER_HOST), e.g. tcp://10.0.0.5:2375")
+	flag.StringVar(&copts.TLSCACert, "tlscacert", os.Getenv("ADDE_DOCKER_CA"), "CA certificate for a TLS daemon (env ADDE_DOCKER_CA)")
+	flag.StringVar(&copts.TLSCert, "tlscert", os.Getenv("ADDE_DOCKER_CERT"), "client certificate for a TLS daemon (env ADDE_DOCKER_CERT)")
+	flag.StringVar(&copts.TLSKey, "tlskey", os.Getenv("ADDE_DOCKER_KEY"), "client key for a TLS daemon (env ADDE_DOCKER_KEY)")
+	flag.StringVar(&copts.APIVersion, "api-version", os.Getenv("ADDE_DOCKER_API_VERSION"), "pin the Docker Engine API version, e.g. 1.41 (env ADDE_DOCKER_API_VERSION); empty = negotiate")
+	engine := flag.String("engine", os.Getenv("ADDE_ENGINE"), "container engine: docker | podman (env ADDE_ENGINE); empty = detect")
+	listen := flag.String("listen", "127.0.0.1:8765", "address for server mode (adde serve)")
+	flag.StringVar(&outputPath, "output", "", "write the result JSON to this file; stdout gets only {\"output\":\"<path>\"}")
+	flag.BoolVar(&prettyJSON, "pretty", false, "indent the result JSON for humans (default: compact, one line)")
+	flag.Usage = usage
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 1 {
+		usage()
 		os.Exit(2)
 	}
-	tool := os.Args[1]
+	tool := args[0]
 	var payload string
-	if len(os.Args) >= 3 {
-		payload = os.Args[2]
-	} else {
+	if len(args) >= 2 {
+		payload = args[1]
+	} else if tool != "serve" && tool != "batch" && tool != "list_languages" && tool != "config" && tool != "capabilities" && tool != "list_volumes" { // these take no payload
 		scanner := bufio.NewScanner(os.Stdin)
 		for scanner.Scan() {
 			payload += scanner.Text()
@@ -35,160 +56,130 @@ func main() {
 		}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	if err := applyMaxInstalls(os.Getenv); err != nil {
+		fmt.Fprintf(os.Stderr, "adde: %v\n", err)
+		os.Exit(2)
+	}
+	if err := applyLogLevel(os.Getenv, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "adde: %v\n", err)
+		os.Exit(2)
+	}
+	if err := applyWorkspaceRoot(os.Getenv); err != nil {
+		fmt.Fprintf(os.Stderr, "adde: %v\n", err)
+		os.Exit(2)
+	}
+	if err := applyDefaultImage(os.Getenv); err != nil {
+		fmt.Fprintf(os.Stderr, "adde: %v\n", err)
+		os.Exit(2)
+	}
+
+	// SIGINT/SIGTERM cancel the root context so in-flight Docker calls abort and
+	// executor functions can roll back (e.g. remove a half-created container).
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(sigCtx, cliTimeout*time.Second)
 	defer cancel()
 
-	// Tools that don't need Docker client
+	env := &toolEnv{copts: copts, engine: *engine, listen: *listen}
+	defer env.close()
+
 	switch tool {
-	case "prepare_build_context":
-		var p executor.PrepareBuildContextParams
-		if err := json.Unmarshal([]byte(payload), &p); err != nil {
-			outErr(err)
-			return
+	case "serve":
+		cli, err := env.client()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "adde: docker client: %v\n", err)
+			os.Exit(1)
+		}
+		// Long-running: only SIGINT/SIGTERM stop the server, not the per-call timeout.
+		fmt.Fprintf(os.Stderr, "adde: serving on http://%s\n", *listen)
+		err = runServer(sigCtx, *listen, hostClients(cli, copts), executor.StreamContainerLogs, executor.StreamFileTail,
+			executor.BuildImageFromContext, executor.StreamExecuteCodeBlock, executor.KillExec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "adde: serve: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	case "batch":
+		// Each call gets its own cliTimeout; only SIGINT/SIGTERM stop the whole batch.
+		failed, err := runBatch(sigCtx, env, os.Stdin, os.Stdout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "adde: batch: %v\n", err)
+			os.Exit(1)
 		}
-		result := executor.PrepareBuildContext(p)
-		outJSON(result)
-		if result.Error != "" {
+		if failed {
 			os.Exit(1)
 		}
 		return
 	}
 
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-	if err != nil {
+	result, failed, err := runTool(ctx, env, tool, payload)
+	switch {
+	case errors.Is(err, errUnknownTool):
+		fmt.Fprintf(os.Stderr, "adde: unknown tool %q\n", tool)
+		os.Exit(2)
+	case err != nil:
 		fmt.Fprintf(os.Stderr, "adde: docker client: %v\n", err)
 		os.Exit(1)
 	}
-	defer cli.Close()
+	if pe, ok := result.(payloadErrorResult); ok {
+		fmt.Fprintf(os.Stderr, "adde: %s\n", pe.Error)
+	}
+	outJSON(result)
+	if failed {
+		os.Exit(1)
+	}
+}
 
-	switch tool {
-	case "pull_image":
-		var p executor.PullImageParams
-		if err := json.Unmarshal([]byte(payload), &p); err != nil {
-			outErr(err)
-			return
-		}
-		result := executor.PullImage(ctx, cli, p)
-		outJSON(result)
-		if result.Error != "" {
-			os.Exit(1)
-		}
-	case "create_runtime_env":
-		var p executor.CreateRuntimeEnvParams
-		if err := json.Unmarshal([]byte(payload), &p); err != nil {
-			outErr(err)
-			return
-		}
-		result := executor.CreateRuntimeEnv(ctx, cli, p)
-		outJSON(result)
-		if result.Error != "" {
-			os.Exit(1)
-		}
-	case "execute_code_block":
-		var p executor.ExecuteCodeBlockParams
-		if err := json.Unmarshal([]byte(payload), &p); err != nil {
-			outErr(err)
-			return
-		}
-		result := executor.ExecuteCodeBlock(ctx, cli, p)
-		outJSON(result)
-		if result.Error != "" {
-			os.Exit(1)
-		}
-	case "get_container_logs":
-		var p executor.GetContainerLogsParams
-		if err := json.Unmarshal([]byte(payload), &p); err != nil {
-			outErr(err)
-			return
-		}
-		result := executor.GetContainerLogs(ctx, cli, p)
-		outJSON(result)
-		if result.Error != "" {
-			os.Exit(1)
-		}
-	case "cleanup_env":
-		var p executor.CleanupEnvParams
-		if err := json.Unmarshal([]byte(payload), &p); err != nil {
-			outErr(err)
-			return
-		}
-		result := executor.CleanupEnv(ctx, cli, p)
-		outJSON(result)
-		if result.Error != "" {
-			os.Exit(1)
-		}
-	case "build_image_from_context":
-		var p executor.BuildImageFromContextParams
-		if err := json.Unmarshal([]byte(payload), &p); err != nil {
-			outErr(err)
-			return
-		}
-		result := executor.BuildImageFromContext(ctx, cli, p)
-		outJSON(result)
-		if result.Status == "error" || result.Error != "" {
-			os.Exit(1)
-		}
-	case "build_image_from_path":
-		var p executor.BuildImageFromPathParams
-		if err := json.Unmarshal([]byte(payload), &p); err != nil {
-			outErr(err)
-			return
-		}
-		result := executor.BuildImageFromPath(ctx, cli, p)
-		outJSON(result)
-		if result.Status == "error" || result.Error != "" {
-			os.Exit(1)
-		}
-	case "list_agent_images":
-		var p executor.ListAgentImagesParams
-		if err := json.Unmarshal([]byte(payload), &p); err != nil {
-			outErr(err)
-			return
-		}
-		result := executor.ListAgentImages(ctx, cli, p)
-		outJSON(result)
-		if result.Error != "" {
-			os.Exit(1)
-		}
-	case "prune_build_cache":
-		var p executor.PruneBuildCacheParams
-		if err := json.Unmarshal([]byte(payload), &p); err != nil {
-			outErr(err)
-			return
-		}
-		result := executor.PruneBuildCache(ctx, cli, p)
-		outJSON(result)
-		if result.Error != "" {
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: adde [flags] <tool> [json_payload]\n")
+	fmt.Fprintf(os.Stderr, "  tool: pull_image | check_registry_auth | image_config | image_metadata | create_runtime_env | get_or_create_env | execute_code_block | execute_cells | wait_container | get_container_logs | get_container_env | container_diff | get_transcript | start_detached_exec | poll_exec | verify_workspace | check_dependency | check_port | tail_file | copy_from_container | pause_container | unpause_container | stop_container | kill_container | cleanup_env | prepare_build_context | hash_build_context | build_image_from_context | build_image_from_path | run_from_dockerfile | list_languages | list_agent_images | prune_build_cache | prune_all | list_volumes | prune_volumes | gc_workspaces | delete_image\n")
+	fmt.Fprintf(os.Stderr, "  json_payload: JSON object for the tool, or omit to read from stdin\n")
+	fmt.Fprintf(os.Stderr, "       adde [flags] serve   (HTTP server mode on --listen)\n")
+	fmt.Fprintf(os.Stderr, "       adde [flags] batch   (NDJSON: one {\"tool\":...,\"payload\":{...}} per stdin line, one result line each)\n")
+	fmt.Fprintf(os.Stderr, "       adde [flags] config  (print the effective configuration, secrets redacted)\n")
+	fmt.Fprintf(os.Stderr, "       adde [flags] capabilities  (print the version and the tools and params this build supports)\n")
+	fmt.Fprintf(os.Stderr, "flags:\n")
+	flag.PrintDefaults()
+}
+
+func outJSON(v interface{}) {
+	if outputPath != "" {
+		f, err := os.Create(outputPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "adde: output: %v\n", err)
 			os.Exit(1)
 		}
-	case "delete_image":
-		var p executor.DeleteImageParams
-		if err := json.Unmarshal([]byte(payload), &p); err != nil {
-			outErr(err)
-			return
+		err = writeResult(f, v)
+		if cerr := f.Close(); err == nil {
+			err = cerr
 		}
-		result := executor.DeleteImage(ctx, cli, p)
-		outJSON(result)
-		if result.Error != "" {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "adde: encode: %v\n", err)
 			os.Exit(1)
 		}
-	default:
-		fmt.Fprintf(os.Stderr, "adde: unknown tool %q\n", tool)
-		os.Exit(2)
+		v = struct {
+			Output string `json:"output"`
+		}{outputPath}
+	}
+	if err := writeResult(os.Stdout, v); err != nil {
+		fmt.Fprintf(os.Stderr, "adde: encode: %v\n", err)
+		os.Exit(1)
 	}
 }
 
-func outJSON(v interface{}) {
-	enc := json.NewEncoder(os.Stdout)
+// writeResult is writeJSON for the CLI result, indented with --pretty. Batch and serve output stay
+// compact: NDJSON needs one line per result.
+func writeResult(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
 	enc.SetEscapeHTML(false)
-	if err := enc.Encode(v); err != nil {
-		fmt.Fprintf(os.Stderr, "adde: encode: %v\n", err)
-		os.Exit(1)
+	if prettyJSON {
+		enc.SetIndent("", "  ")
 	}
+	return enc.Encode(v)
 }
 
-func outErr(err error) {
-	fmt.Fprintf(os.Stderr, "adde: %v\n", err)
-	outJSON(struct{ Error string }{err.Error()})
-	os.Exit(1)
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
 }
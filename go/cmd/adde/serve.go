@@ -0,0 +1,485 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"adde/pkg/executor"
+
+	"github.com/docker/docker/client"
+)
+
+// streamLogsFunc follows a container's output and emits each line; executor.StreamContainerLogs in production.
+type streamLogsFunc func(ctx context.Context, cli *client.Client, containerID string, emit executor.LineEmitter) error
+
+// tailFileFunc follows a file inside a container and emits each line; executor.StreamFileTail in production.
+type tailFileFunc func(ctx context.Context, cli *client.Client, containerID, path string, lines int, emit executor.LineEmitter) error
+
+// streamExecFunc runs execute_code_block emitting output lines and reporting the running exec to
+// started; executor.StreamExecuteCodeBlock in production.
+type streamExecFunc func(ctx context.Context, cli *client.Client, p executor.ExecuteCodeBlockParams, emit executor.LineEmitter, started func(executor.RunningExec)) (*executor.LogEntry, error)
+
+// killExecFunc signals a running exec's process; executor.KillExec in production.
+type killExecFunc func(ctx context.Context, cli *client.Client, e executor.RunningExec, signal string) (string, error)
+
+// buildFunc runs build_image_from_context; executor.BuildImageFromContext in production.
+type buildFunc func(ctx context.Context, cli *client.Client, p executor.BuildImageFromContextParams) executor.BuildImageFromContextResult
+
+// clientFunc returns the Docker client for a request's optional host: the server's own client when
+// host is empty. release is called once the request is done with the client.
+type clientFunc func(host string) (cli *client.Client, release func(), err error)
+
+// hostClients serves the startup client for requests without a host and builds one per request
+// otherwise, the way --host does for a single call (same validation and TLS/API version flags).
+func hostClients(base *client.Client, o clientOptions) clientFunc {
+	return func(host string) (*client.Client, func(), error) {
+		if host == "" {
+			return base, func() {}, nil
+		}
+		o.Host = host
+		cli, err := newDockerClient(o)
+		if err != nil {
+			return nil, nil, err
+		}
+		return cli, func() { cli.Close() }, nil
+	}
+}
+
+// requestClient resolves the client for host, answering 400 when host is invalid; ok is false then.
+func requestClient(w http.ResponseWriter, clients clientFunc, host string) (cli *client.Client, release func(), ok bool) {
+	cli, release, err := clients(host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, nil, false
+	}
+	return cli, release, true
+}
+
+// newServeMux builds the HTTP handlers for server mode (adde serve). Every endpoint that talks to
+// Docker takes an optional host (query parameter on GET, body field on POST) overriding the server's.
+func newServeMux(clients clientFunc, streamLogs streamLogsFunc, tailFile tailFileFunc, build buildFunc, streamExec streamExecFunc, killExec killExecFunc) *http.ServeMux {
+	builds := &inflightBuilds{cancels: map[string]context.CancelCauseFunc{}}
+	execs := &runningExecs{byContainer: map[string]map[string]executor.RunningExec{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stream_logs", streamLogsHandler(clients, streamLogs))
+	mux.HandleFunc("/tail_file", tailFileHandler(clients, tailFile))
+	mux.HandleFunc("/stream_execute", streamExecuteHandler(clients, streamExec, execs))
+	mux.HandleFunc("/list_execs", listExecsHandler(execs))
+	mux.HandleFunc("/kill_exec", killExecHandler(clients, killExec, execs))
+	mux.HandleFunc("/build_image_from_context", buildHandler(clients, build, builds))
+	mux.HandleFunc("/cancel_build", cancelBuildHandler(builds))
+	mux.HandleFunc("/list_languages", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, executor.ListLanguages())
+	})
+	return mux
+}
+
+// streamLogsHandler serves GET /stream_logs?container_id=... as Server-Sent Events: one event per
+// output line (event name = stdout|stderr), then an "end" event. The stream ends when the container
+// stops or the client disconnects.
+func streamLogsHandler(clients clientFunc, streamLogs streamLogsFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		id := r.URL.Query().Get("container_id")
+		if id == "" {
+			http.Error(w, "container_id is required", http.StatusBadRequest)
+			return
+		}
+		cli, release, ok := requestClient(w, clients, r.URL.Query().Get("host"))
+		if !ok {
+			return
+		}
+		defer release()
+		serveSSE(w, r, func(emit executor.LineEmitter) error {
+			return streamLogs(r.Context(), cli, id, emit)
+		})
+	}
+}
+
+// tailFileHandler serves GET /tail_file?container_id=...&path=...&lines=N as Server-Sent Events
+// (tail -f): one "stdout" event per line ("stderr" for tail's own messages), then an "end" event. The stream ends when the container
+// stops or the client disconnects.
+func tailFileHandler(clients clientFunc, tailFile tailFileFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		q := r.URL.Query()
+		id, path := q.Get("container_id"), q.Get("path")
+		if id == "" || path == "" {
+			http.Error(w, "container_id and path are required", http.StatusBadRequest)
+			return
+		}
+		lines := 0
+		if v := q.Get("lines"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				http.Error(w, "lines must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			lines = n
+		}
+		cli, release, ok := requestClient(w, clients, q.Get("host"))
+		if !ok {
+			return
+		}
+		defer release()
+		serveSSE(w, r, func(emit executor.LineEmitter) error {
+			return tailFile(r.Context(), cli, id, path, lines, emit)
+		})
+	}
+}
+
+// streamExecuteHandler serves POST /stream_execute with the execute_code_block payload as the body,
+// as Server-Sent Events: one event per output line while the program runs, then an "end" event with
+// exit_code and execution_time (or error). A client that disconnects stops the read, not the program.
+// While it runs the exec is listed by /list_execs and can be stopped with /kill_exec.
+func streamExecuteHandler(clients clientFunc, streamExec streamExecFunc, execs *runningExecs) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var body struct {
+			executor.ExecuteCodeBlockParams
+			Host string `json:"host"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		p := body.ExecuteCodeBlockParams
+		if p.ContainerID == "" || p.Filename == "" {
+			http.Error(w, "container_id and filename are required", http.StatusBadRequest)
+			return
+		}
+		cli, release, ok := requestClient(w, clients, body.Host)
+		if !ok {
+			return
+		}
+		defer release()
+		serveSSEEnd(w, r, func(emit executor.LineEmitter) (sseEnd, error) {
+			var execID string
+			defer func() { execs.remove(p.ContainerID, execID) }()
+			log, err := streamExec(r.Context(), cli, p, emit, func(e executor.RunningExec) {
+				execID = e.ExecID
+				e.Host = body.Host // so /kill_exec reaches the same daemon
+				execs.add(e)
+			})
+			if err != nil {
+				return sseEnd{}, err
+			}
+			return sseEnd{ExitCode: &log.ExitCode, ExecutionTime: log.ExecutionTime}, nil
+		})
+	}
+}
+
+// runningExecs tracks the execs started through /stream_execute, keyed by container and exec ID.
+type runningExecs struct {
+	mu          sync.Mutex
+	byContainer map[string]map[string]executor.RunningExec
+}
+
+func (x *runningExecs) add(e executor.RunningExec) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.byContainer[e.ContainerID] == nil {
+		x.byContainer[e.ContainerID] = map[string]executor.RunningExec{}
+	}
+	x.byContainer[e.ContainerID][e.ExecID] = e
+}
+
+func (x *runningExecs) remove(containerID, execID string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	delete(x.byContainer[containerID], execID)
+	if len(x.byContainer[containerID]) == 0 {
+		delete(x.byContainer, containerID)
+	}
+}
+
+// list returns the running execs of containerID (all containers when empty), oldest first.
+func (x *runningExecs) list(containerID string) []executor.RunningExec {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	out := []executor.RunningExec{}
+	for cid, execs := range x.byContainer {
+		if containerID != "" && cid != containerID {
+			continue
+		}
+		for _, e := range execs {
+			out = append(out, e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartedAt.Before(out[j].StartedAt) })
+	return out
+}
+
+func (x *runningExecs) find(execID string) (executor.RunningExec, bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, execs := range x.byContainer {
+		if e, ok := execs[execID]; ok {
+			return e, true
+		}
+	}
+	return executor.RunningExec{}, false
+}
+
+// listExecsResult is the response of /list_execs.
+type listExecsResult struct {
+	Execs []executor.RunningExec `json:"execs"`
+}
+
+// listExecsHandler serves GET /list_execs[?container_id=...]: the /stream_execute runs in progress.
+func listExecsHandler(execs *runningExecs) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, listExecsResult{Execs: execs.list(r.URL.Query().Get("container_id"))})
+	}
+}
+
+// killExecResult is the response of /kill_exec.
+type killExecResult struct {
+	ExecID string `json:"exec_id"`
+	Killed bool   `json:"killed"` // false: no running exec has that id, or the signal could not be sent (see error)
+	Signal string `json:"signal,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// killExecHandler serves POST /kill_exec with body {"exec_id":"...","signal":"SIGTERM"}: it signals
+// the process of that running exec (default SIGKILL). The exec leaves /list_execs once it has exited.
+func killExecHandler(clients clientFunc, killExec killExecFunc, execs *runningExecs) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var p struct {
+			ExecID string `json:"exec_id"`
+			Signal string `json:"signal"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil || p.ExecID == "" {
+			http.Error(w, "exec_id is required", http.StatusBadRequest)
+			return
+		}
+		res := killExecResult{ExecID: p.ExecID}
+		if e, ok := execs.find(p.ExecID); ok {
+			cli, release, ok := requestClient(w, clients, e.Host)
+			if !ok {
+				return
+			}
+			defer release()
+			sig, err := killExec(r.Context(), cli, e, p.Signal)
+			res.Signal = sig
+			if err != nil {
+				res.Error = err.Error()
+			} else {
+				res.Killed = true
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, res)
+	}
+}
+
+// errBuildCancelled is the cancel cause of a build stopped through /cancel_build.
+var errBuildCancelled = errors.New("build cancelled by cancel_build")
+
+// inflightBuilds maps the build_id of each running build to the cancel func of its context.
+type inflightBuilds struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelCauseFunc
+}
+
+// add registers id; it fails if a build with the same id is still running.
+func (b *inflightBuilds) add(id string, cancel context.CancelCauseFunc) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.cancels[id]; ok {
+		return false
+	}
+	b.cancels[id] = cancel
+	return true
+}
+
+func (b *inflightBuilds) remove(id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.cancels, id)
+}
+
+// cancel aborts the build registered as id and reports whether there was one.
+func (b *inflightBuilds) cancel(id string) bool {
+	b.mu.Lock()
+	cancel, ok := b.cancels[id]
+	delete(b.cancels, id)
+	b.mu.Unlock()
+	if ok {
+		cancel(errBuildCancelled)
+	}
+	return ok
+}
+
+// buildHandler serves POST /build_image_from_context with the tool's JSON payload as the body and
+// its JSON result as the response. A build with a build_id can be aborted through /cancel_build;
+// one whose client disconnects is aborted too.
+func buildHandler(clients clientFunc, build buildFunc, builds *inflightBuilds) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var body struct {
+			executor.BuildImageFromContextParams
+			Host string `json:"host"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		p := body.BuildImageFromContextParams
+		cli, release, ok := requestClient(w, clients, body.Host)
+		if !ok {
+			return
+		}
+		defer release()
+		ctx, cancel := context.WithCancelCause(r.Context())
+		defer cancel(nil)
+		if p.BuildID != "" {
+			if !builds.add(p.BuildID, cancel) {
+				http.Error(w, fmt.Sprintf("build_id %q is already running", p.BuildID), http.StatusConflict)
+				return
+			}
+			defer builds.remove(p.BuildID)
+		}
+		result := build(ctx, cli, p)
+		if errors.Is(context.Cause(ctx), errBuildCancelled) {
+			result.Status, result.Error = "error", errBuildCancelled.Error()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, result)
+	}
+}
+
+// cancelBuildResult is the response of /cancel_build.
+type cancelBuildResult struct {
+	BuildID   string `json:"build_id"`
+	Cancelled bool   `json:"cancelled"` // false: no running build has that id (unknown or already finished)
+}
+
+// cancelBuildHandler serves POST /cancel_build with body {"build_id":"..."}: it cancels the
+// context of that running build, which makes the daemon abort it.
+func cancelBuildHandler(builds *inflightBuilds) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var p struct {
+			BuildID string `json:"build_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil || p.BuildID == "" {
+			http.Error(w, "build_id is required", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, cancelBuildResult{BuildID: p.BuildID, Cancelled: builds.cancel(p.BuildID)})
+	}
+}
+
+// serveSSE streams the lines run emits as events named after their stream, then an "end" event
+// carrying {"error":"..."} if run failed. Nothing more is written once the client has gone away.
+func serveSSE(w http.ResponseWriter, r *http.Request, run func(emit executor.LineEmitter) error) {
+	serveSSEEnd(w, r, func(emit executor.LineEmitter) (sseEnd, error) {
+		return sseEnd{}, run(emit)
+	})
+}
+
+// sseEnd is the data of the final "end" event; {} when a stream simply finished.
+type sseEnd struct {
+	ExitCode      *int   `json:"exit_code,omitempty"`
+	ExecutionTime string `json:"execution_time,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// serveSSEEnd is serveSSE for streams whose end event carries a result, e.g. an exit code.
+func serveSSEEnd(w http.ResponseWriter, r *http.Request, run func(emit executor.LineEmitter) (sseEnd, error)) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	end, err := run(func(stream, line string) error {
+		if err := writeSSE(w, stream, line); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+	if r.Context().Err() != nil {
+		return // client went away
+	}
+	if err != nil {
+		end.Error = err.Error()
+	}
+	data, _ := json.Marshal(end)
+	writeSSE(w, "end", string(data))
+	flusher.Flush()
+}
+
+// writeSSE writes one event; data lines containing CR/LF are split so the event stays well-formed.
+func writeSSE(w http.ResponseWriter, event, data string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "event: %s\n", event)
+	for _, l := range strings.Split(strings.ReplaceAll(data, "\r", ""), "\n") {
+		fmt.Fprintf(&b, "data: %s\n", l)
+	}
+	b.WriteString("\n")
+	_, err := w.Write([]byte(b.String()))
+	return err
+}
+
+// runServer listens on addr until ctx is cancelled (SIGINT/SIGTERM), then shuts down gracefully.
+func runServer(ctx context.Context, addr string, clients clientFunc, streamLogs streamLogsFunc, tailFile tailFileFunc, build buildFunc, streamExec streamExecFunc, killExec killExecFunc) error {
+	srv := &http.Server{Addr: addr, Handler: newServeMux(clients, streamLogs, tailFile, build, streamExec, killExec), ReadHeaderTimeout: 10 * time.Second}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+		if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"adde/pkg/executor"
+
+	"github.com/docker/docker/client"
+)
+
+// noClients is the clientFunc for handler tests whose fakes never touch Docker.
+func noClients(string) (*client.Client, func(), error) { return nil, func() {}, nil }
+
+func TestStreamLogsEmitsOneEventPerLine(t *testing.T) {
+	var gotID string
+	fake := func(ctx context.Context, _ *client.Client, id string, emit executor.LineEmitter) error {
+		gotID = id
+		for _, l := range []struct{ stream, line string }{
+			{executor.StreamStdout, "step 1"},
+			{executor.StreamStderr, "warning"},
+			{executor.StreamStdout, "step 2"},
+		} {
+			if err := emit(l.stream, l.line); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	srv := httptest.NewServer(newServeMux(noClients, fake, nil, nil, nil, nil))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/stream_logs?container_id=abc123")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if gotID != "abc123" {
+		t.Errorf("container id = %q", gotID)
+	}
+	want := "event: stdout\ndata: step 1\n\n" +
+		"event: stderr\ndata: warning\n\n" +
+		"event: stdout\ndata: step 2\n\n" +
+		"event: end\ndata: {}\n\n"
+	if string(body) != want {
+		t.Errorf("body:\n%s\nwant:\n%s", body, want)
+	}
+}
+
+func TestStreamLogsReportsErrorInEndEvent(t *testing.T) {
+	fake := func(ctx context.Context, _ *client.Client, id string, emit executor.LineEmitter) error {
+		return errors.New("No such container: " + id)
+	}
+	srv := httptest.NewServer(newServeMux(noClients, fake, nil, nil, nil, nil))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/stream_logs?container_id=nope")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), `event: end`+"\n"+`data: {"error":"No such container: nope"}`) {
+		t.Errorf("unexpected body: %s", body)
+	}
+}
+
+func TestStreamLogsRequiresContainerID(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newServeMux(noClients, nil, nil, nil, nil, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stream_logs", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestWriteSSESplitsMultilineData(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeSSE(rec, "stdout", "a\r\nb")
+	if got, want := rec.Body.String(), "event: stdout\ndata: a\ndata: b\n\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestListLanguagesEndpoint(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newServeMux(noClients, nil, nil, nil, nil, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/list_languages", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"extension":".py"`) {
+		t.Errorf("status %d, body %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestTailFileStreamsLines(t *testing.T) {
+	var gotID, gotPath string
+	var gotLines int
+	fake := func(ctx context.Context, _ *client.Client, id, path string, lines int, emit executor.LineEmitter) error {
+		gotID, gotPath, gotLines = id, path, lines
+		for _, l := range []string{"GET / 200", "GET /health 200"} {
+			if err := emit(executor.StreamStdout, l); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	srv := httptest.NewServer(newServeMux(noClients, nil, fake, nil, nil, nil))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/tail_file?container_id=abc&path=/var/log/app.log&lines=2")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if gotID != "abc" || gotPath != "/var/log/app.log" || gotLines != 2 {
+		t.Errorf("args = %q %q %d", gotID, gotPath, gotLines)
+	}
+	want := "event: stdout\ndata: GET / 200\n\n" +
+		"event: stdout\ndata: GET /health 200\n\n" +
+		"event: end\ndata: {}\n\n"
+	if string(body) != want {
+		t.Errorf("body:\n%s\nwant:\n%s", body, want)
+	}
+}
+
+func TestTailFileRejectsBadQuery(t *testing.T) {
+	for _, q := range []string{"", "?container_id=abc", "?path=app.log", "?container_id=abc&path=app.log&lines=x"} {
+		rec := httptest.NewRecorder()
+		newServeMux(noClients, nil, nil, nil, nil, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tail_file"+q, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want 400", q, rec.Code)
+		}
+	}
+}
+
+func TestCancelBuildAbortsRunningBuild(t *testing.T) {
+	started := make(chan struct{})
+	fake := func(ctx context.Context, _ *client.Client, p executor.BuildImageFromContextParams) executor.BuildImageFromContextResult {
+		close(started)
+		<-ctx.Done() // a hung build: only cancellation ends it
+		return executor.BuildImageFromContextResult{Status: "error", Error: ctx.Err().Error()}
+	}
+	srv := httptest.NewServer(newServeMux(noClients, nil, nil, fake, nil, nil))
+	defer srv.Close()
+
+	type buildResp struct {
+		res executor.BuildImageFromContextResult
+		err error
+	}
+	done := make(chan buildResp, 1)
+	go func() {
+		resp, err := http.Post(srv.URL+"/build_image_from_context", "application/json",
+			strings.NewReader(`{"context_id":"/tmp/ctx","build_id":"b1"}`))
+		if err != nil {
+			done <- buildResp{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		var r buildResp
+		r.err = json.NewDecoder(resp.Body).Decode(&r.res)
+		done <- r
+	}()
+	<-started
+
+	cancel := func(id string) cancelBuildResult {
+		t.Helper()
+		resp, err := http.Post(srv.URL+"/cancel_build", "application/json", strings.NewReader(`{"build_id":"`+id+`"}`))
+		if err != nil {
+			t.Fatalf("POST /cancel_build: %v", err)
+		}
+		defer resp.Body.Close()
+		var res cancelBuildResult
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	if got := cancel("other"); got.Cancelled {
+		t.Errorf("unknown build_id reported as cancelled: %+v", got)
+	}
+	if got := cancel("b1"); !got.Cancelled || got.BuildID != "b1" {
+		t.Errorf("cancel_build = %+v, want cancelled", got)
+	}
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		if r.res.Status != "error" || r.res.Error != errBuildCancelled.Error() {
+			t.Errorf("build result = %+v", r.res)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("build did not return after cancel_build")
+	}
+	if got := cancel("b1"); got.Cancelled {
+		t.Error("finished build reported as cancelled again")
+	}
+}
+
+func TestCancelBuildRequiresBuildID(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newServeMux(noClients, nil, nil, nil, nil, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/cancel_build", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestStreamExecuteEmitsLinesBeforeExit(t *testing.T) {
+	firstRead := make(chan struct{})
+	var got executor.ExecuteCodeBlockParams
+	fake := func(ctx context.Context, _ *client.Client, p executor.ExecuteCodeBlockParams, emit executor.LineEmitter, started func(executor.RunningExec)) (*executor.LogEntry, error) {
+		got = p
+		if err := emit(executor.StreamStdout, "epoch 1"); err != nil {
+			return nil, err
+		}
+		// Still "running": the client must already have the first line.
+		select {
+		case <-firstRead:
+		case <-time.After(5 * time.Second):
+			return nil, errors.New("first line was not delivered while the program ran")
+		}
+		if err := emit(executor.StreamStderr, "warning: slow"); err != nil {
+			return nil, err
+		}
+		return &executor.LogEntry{ExitCode: 3, ExecutionTime: "1.50s"}, nil
+	}
+	srv := httptest.NewServer(newServeMux(noClients, nil, nil, nil, fake, nil))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/stream_execute", "application/json",
+		strings.NewReader(`{"container_id":"c1","filename":"train.py","code_content":"print(1)"}`))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	r := bufio.NewReader(resp.Body)
+	var first strings.Builder
+	for !strings.HasSuffix(first.String(), "\n\n") {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read first event: %v", err)
+		}
+		first.WriteString(line)
+	}
+	if first.String() != "event: stdout\ndata: epoch 1\n\n" {
+		t.Errorf("first event = %q", first.String())
+	}
+	close(firstRead)
+	rest, _ := io.ReadAll(r)
+	want := "event: stderr\ndata: warning: slow\n\n" +
+		"event: end\ndata: {\"exit_code\":3,\"execution_time\":\"1.50s\"}\n\n"
+	if string(rest) != want {
+		t.Errorf("rest:\n%s\nwant:\n%s", rest, want)
+	}
+	if got.ContainerID != "c1" || got.Filename != "train.py" {
+		t.Errorf("params = %+v", got)
+	}
+}
+
+func TestStreamExecuteReportsErrorAndValidates(t *testing.T) {
+	fake := func(ctx context.Context, _ *client.Client, p executor.ExecuteCodeBlockParams, emit executor.LineEmitter, started func(executor.RunningExec)) (*executor.LogEntry, error) {
+		return nil, errors.New("No such container: " + p.ContainerID)
+	}
+	srv := httptest.NewServer(newServeMux(noClients, nil, nil, nil, fake, nil))
+	defer srv.Close()
+	resp, err := http.Post(srv.URL+"/stream_execute", "application/json", strings.NewReader(`{"container_id":"nope","filename":"a.py"}`))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "event: end\ndata: {\"error\":\"No such container: nope\"}\n\n" {
+		t.Errorf("body = %q", body)
+	}
+
+	for _, payload := range []string{`{"filename":"a.py"}`, `{"container_id":"c1"}`, `not json`} {
+		rec := httptest.NewRecorder()
+		newServeMux(noClients, nil, nil, nil, fake, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/stream_execute", strings.NewReader(payload)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", payload, rec.Code)
+		}
+	}
+}
+
+func TestListAndKillExec(t *testing.T) {
+	killed := make(chan struct{})
+	streamExec := func(ctx context.Context, _ *client.Client, p executor.ExecuteCodeBlockParams, emit executor.LineEmitter, started func(executor.RunningExec)) (*executor.LogEntry, error) {
+		started(executor.RunningExec{ExecID: "e1", ContainerID: p.ContainerID, Filename: p.Filename, StartedAt: time.Now(), PIDFile: "/tmp/.adde-exec-1.pid"})
+		if err := emit(executor.StreamStdout, "looping"); err != nil {
+			return nil, err
+		}
+		select {
+		case <-killed:
+			return &executor.LogEntry{ExitCode: 143, ExecutionTime: "2.00s"}, nil
+		case <-time.After(5 * time.Second):
+			return nil, errors.New("never killed")
+		}
+	}
+	var gotExec executor.RunningExec
+	var gotSignal string
+	killExec := func(ctx context.Context, _ *client.Client, e executor.RunningExec, signal string) (string, error) {
+		gotExec, gotSignal = e, signal
+		close(killed)
+		return "SIGTERM", nil
+	}
+	srv := httptest.NewServer(newServeMux(noClients, nil, nil, nil, streamExec, killExec))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/stream_execute", "application/json", strings.NewReader(`{"container_id":"c1","filename":"loop.py"}`))
+	if err != nil {
+		t.Fatalf("POST /stream_execute: %v", err)
+	}
+	defer resp.Body.Close()
+	r := bufio.NewReader(resp.Body)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read first event: %v", err)
+		}
+		if line == "\n" {
+			break // the exec is running and registered
+		}
+	}
+
+	listExecs := func(query string) []executor.RunningExec {
+		t.Helper()
+		resp, err := http.Get(srv.URL + "/list_execs" + query)
+		if err != nil {
+			t.Fatalf("GET /list_execs: %v", err)
+		}
+		defer resp.Body.Close()
+		var res listExecsResult
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return res.Execs
+	}
+	if execs := listExecs("?container_id=c1"); len(execs) != 1 || execs[0].ExecID != "e1" || execs[0].Filename != "loop.py" {
+		t.Errorf("list_execs c1 = %+v", execs)
+	}
+	if execs := listExecs("?container_id=c2"); len(execs) != 0 {
+		t.Errorf("list_execs c2 = %+v, want none", execs)
+	}
+
+	kill := func(body string) killExecResult {
+		t.Helper()
+		resp, err := http.Post(srv.URL+"/kill_exec", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST /kill_exec: %v", err)
+		}
+		defer resp.Body.Close()
+		var res killExecResult
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return res
+	}
+	if res := kill(`{"exec_id":"e1","signal":"TERM"}`); !res.Killed || res.Signal != "SIGTERM" {
+		t.Errorf("kill_exec = %+v", res)
+	}
+	if gotExec.PIDFile != "/tmp/.adde-exec-1.pid" || gotSignal != "TERM" {
+		t.Errorf("killExec got %+v, %q", gotExec, gotSignal)
+	}
+	rest, _ := io.ReadAll(r)
+	if !strings.Contains(string(rest), `"exit_code":143`) {
+		t.Errorf("end event = %q", rest)
+	}
+	if execs := listExecs(""); len(execs) != 0 {
+		t.Errorf("list_execs after exit = %+v, want none", execs)
+	}
+	if res := kill(`{"exec_id":"e1"}`); res.Killed || res.Error != "" {
+		t.Errorf("kill_exec of a finished exec = %+v, want killed false", res)
+	}
+}
+
+func TestServeRequestHostOverridesDefault(t *testing.T) {
+	base, err := client.NewClientWithOpts(client.WithHost("tcp://10.0.0.1:2375"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hosts []string
+	streamLogs := func(ctx context.Context, cli *client.Client, id string, emit executor.LineEmitter) error {
+		hosts = append(hosts, cli.DaemonHost())
+		return nil
+	}
+	build := func(ctx context.Context, cli *client.Client, p executor.BuildImageFromContextParams) executor.BuildImageFromContextResult {
+		hosts = append(hosts, cli.DaemonHost())
+		return executor.BuildImageFromContextResult{Status: "success"}
+	}
+	mux := newServeMux(hostClients(base, clientOptions{}), streamLogs, nil, build, nil, nil)
+	serve := func(method, target, body string) int {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec.Code
+	}
+
+	serve(http.MethodGet, "/stream_logs?container_id=c1", "")
+	serve(http.MethodGet, "/stream_logs?container_id=c1&host=tcp://10.0.0.2:2376", "")
+	serve(http.MethodPost, "/build_image_from_context", `{"context_id":"/tmp/ctx","host":"tcp://10.0.0.3:2376"}`)
+	want := []string{"tcp://10.0.0.1:2375", "tcp://10.0.0.2:2376", "tcp://10.0.0.3:2376"}
+	if strings.Join(hosts, " ") != strings.Join(want, " ") {
+		t.Errorf("daemon hosts = %q, want %q", hosts, want)
+	}
+
+	if code := serve(http.MethodGet, "/stream_logs?container_id=c1&host=not+a+url", ""); code != http.StatusBadRequest {
+		t.Errorf("invalid host: status = %d, want 400", code)
+	}
+	if len(hosts) != 3 {
+		t.Error("handler ran for an invalid host")
+	}
+}
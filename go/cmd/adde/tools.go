@@ -0,0 +1,331 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/docker/docker/client"
+
+	"adde/pkg/executor"
+)
+
+// errUnknownTool is returned by runTool for a tool name it does not dispatch.
+var errUnknownTool = errors.New("unknown tool")
+
+// payloadErrorResult is the result of a call whose JSON payload did not decode.
+type payloadErrorResult struct{ Error string }
+
+func payloadError(err error) (interface{}, bool, error) {
+	return payloadErrorResult{err.Error()}, true, nil
+}
+
+// toolEnv carries what tool calls share: flag settings and a Docker client created on first use,
+// so one process (e.g. batch mode) can serve many calls with a single client.
+type toolEnv struct {
+	copts  clientOptions
+	engine string
+	listen string
+	cli    *client.Client
+}
+
+func (e *toolEnv) client() (*client.Client, error) {
+	if e.cli == nil {
+		cli, err := newDockerClient(e.copts)
+		if err != nil {
+			return nil, err
+		}
+		e.cli = cli
+	}
+	return e.cli, nil
+}
+
+func (e *toolEnv) close() {
+	if e.cli != nil {
+		e.cli.Close()
+	}
+}
+
+// runTool runs one tool call and returns its result and whether the call failed (exit status 1).
+// err is only set when the call could not be dispatched: an unknown tool or no Docker client.
+func runTool(ctx context.Context, env *toolEnv, tool, payload string) (result interface{}, failed bool, err error) {
+	// Tools that don't need Docker client
+	switch tool {
+	case "prepare_build_context":
+		var p executor.PrepareBuildContextParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.PrepareBuildContext(p)
+		return result, result.Error != "", nil
+	case "hash_build_context":
+		var p executor.HashBuildContextParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.HashBuildContext(p)
+		return result, result.Error != "", nil
+	case "list_languages":
+		return executor.ListLanguages(), false, nil
+	case "config":
+		return resolveConfig(env.copts, env.engine, env.listen, os.Getenv), false, nil
+	case "capabilities":
+		return capabilities(), false, nil
+	}
+	cli, err := env.client()
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch tool {
+	case "pull_image":
+		var p executor.PullImageParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.PullImage(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "create_runtime_env":
+		var p executor.CreateRuntimeEnvParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.CreateRuntimeEnv(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "get_or_create_env":
+		var p executor.GetOrCreateEnvParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.GetOrCreateEnv(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "execute_code_block":
+		var p executor.ExecuteCodeBlockParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.ExecuteCodeBlock(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "execute_cells":
+		var p executor.ExecuteCellsParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.ExecuteCells(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "wait_container":
+		var p executor.WaitContainerParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.WaitContainer(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "get_container_logs":
+		var p executor.GetContainerLogsParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.GetContainerLogs(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "cleanup_env":
+		var p executor.CleanupEnvParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.CleanupEnv(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "build_image_from_context":
+		var p executor.BuildImageFromContextParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.BuildImageFromContext(ctx, cli, p)
+		return result, result.Status == "error" || result.Error != "", nil
+	case "build_image_from_path":
+		var p executor.BuildImageFromPathParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.BuildImageFromPath(ctx, cli, p)
+		return result, result.Status == "error" || result.Error != "", nil
+	case "list_agent_images":
+		var p executor.ListAgentImagesParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.ListAgentImages(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "prune_build_cache":
+		var p executor.PruneBuildCacheParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		if p.Engine == "" {
+			p.Engine = env.engine
+		}
+		result := executor.PruneBuildCache(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "prune_all":
+		var p executor.PruneAllParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		if p.Engine == "" {
+			p.Engine = env.engine
+		}
+		result := executor.PruneAll(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "run_from_dockerfile":
+		var p executor.RunFromDockerfileParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.RunFromDockerfile(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "verify_workspace":
+		var p executor.VerifyWorkspaceParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.VerifyWorkspace(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "check_port":
+		var p executor.CheckPortParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.CheckPort(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "check_dependency":
+		var p executor.CheckDependencyParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.CheckDependency(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "check_registry_auth":
+		var p executor.CheckRegistryAuthParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.CheckRegistryAuth(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "get_transcript":
+		var p executor.GetTranscriptParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.GetTranscript(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "start_detached_exec":
+		var p executor.StartDetachedExecParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.StartDetachedExec(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "poll_exec":
+		var p executor.PollExecParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.PollExec(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "tail_file":
+		var p executor.TailFileParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.TailFile(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "copy_from_container":
+		var p executor.CopyFromContainerParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.CopyFromContainer(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "list_volumes":
+		result := executor.ListVolumes(ctx, cli)
+		return result, result.Error != "", nil
+	case "prune_volumes":
+		var p executor.PruneVolumesParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.PruneVolumes(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "gc_workspaces":
+		var p executor.GCWorkspacesParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.GCWorkspaces(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "image_config":
+		var p executor.ImageConfigParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.ImageConfig(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "image_metadata":
+		var p executor.ImageMetadataParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.ImageMetadata(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "get_container_env":
+		var p executor.GetContainerEnvParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.GetContainerEnv(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "container_diff":
+		var p executor.ContainerDiffParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.ContainerDiff(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "pause_container":
+		var p executor.PauseContainerParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.PauseContainer(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "unpause_container":
+		var p executor.UnpauseContainerParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.UnpauseContainer(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "stop_container":
+		var p executor.StopContainerParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.StopContainer(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "kill_container":
+		var p executor.KillContainerParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.KillContainer(ctx, cli, p)
+		return result, result.Error != "", nil
+	case "delete_image":
+		var p executor.DeleteImageParams
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return payloadError(err)
+		}
+		result := executor.DeleteImage(ctx, cli, p)
+		return result, result.Error != "", nil
+	}
+	return nil, false, errUnknownTool
+}
@@ -2,15 +2,17 @@ module adde
 
 go 1.21
 
-require github.com/docker/docker v24.0.7+incompatible
+require (
+	github.com/docker/distribution v2.8.3+incompatible
+	github.com/docker/docker v24.0.7+incompatible
+	github.com/docker/go-connections v0.4.0
+)
 
 // Fix build: docker/distribution v2.8.3 reference_deprecated.go calls reference.SplitHostname which was removed.
 replace github.com/docker/distribution => github.com/docker/distribution v2.8.2+incompatible
 
 require (
 	github.com/Microsoft/go-winio v0.6.1 // indirect
-	github.com/docker/distribution v2.8.3+incompatible // indirect
-	github.com/docker/go-connections v0.4.0 // indirect
 	github.com/docker/go-units v0.5.0 // indirect
 	github.com/gogo/protobuf v1.3.2 // indirect
 	github.com/moby/term v0.5.0 // indirect
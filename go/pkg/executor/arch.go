@@ -0,0 +1,58 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// ArchMismatchCode prefixes the create_runtime_env warning for an image built for another CPU architecture.
+const ArchMismatchCode = "ARCH_MISMATCH"
+
+// unameArch maps the daemon's uname-style architecture (types.Info.Architecture) to the OCI/GOARCH
+// name used in image configs.
+var unameArch = map[string]string{
+	"x86_64":  "amd64",
+	"amd64":   "amd64",
+	"aarch64": "arm64",
+	"arm64":   "arm64",
+	"armv7l":  "arm",
+	"armv6l":  "arm",
+	"i386":    "386",
+	"i686":    "386",
+	"ppc64le": "ppc64le",
+	"s390x":   "s390x",
+	"riscv64": "riscv64",
+}
+
+// archMismatchWarning returns an ARCH_MISMATCH warning when the image's platform differs from the
+// daemon's, or "" when they match or either side is unknown. Without emulation every exec in such
+// a container fails with "exec format error".
+func archMismatchWarning(ref string, img types.ImageInspect, info types.Info) string {
+	daemonArch, ok := unameArch[info.Architecture]
+	if !ok || img.Architecture == "" {
+		return ""
+	}
+	if img.Architecture == daemonArch && (img.Os == "" || info.OSType == "" || img.Os == info.OSType) {
+		return ""
+	}
+	imagePlatform := img.Os + "/" + img.Architecture
+	if img.Variant != "" {
+		imagePlatform += "/" + img.Variant
+	}
+	daemonPlatform := info.OSType + "/" + daemonArch
+	return fmt.Sprintf("%s: image %s is %s but the daemon runs %s; without emulation execs fail with \"exec format error\". "+
+		"Pull the %s variant of the image, or install QEMU emulation (docker run --privileged --rm tonistiigi/binfmt --install all)",
+		ArchMismatchCode, ref, imagePlatform, daemonPlatform, daemonPlatform)
+}
+
+// checkImageArch compares a local image with the daemon. An info failure yields no warning.
+func checkImageArch(ctx context.Context, cli *client.Client, ref string, img types.ImageInspect) string {
+	info, err := cli.Info(ctx)
+	if err != nil {
+		return ""
+	}
+	return archMismatchWarning(ref, img, info)
+}
@@ -0,0 +1,31 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestArchMismatchWarning(t *testing.T) {
+	arm := types.Info{OSType: "linux", Architecture: "aarch64"}
+	w := archMismatchWarning("docker.io/library/app:1", types.ImageInspect{Os: "linux", Architecture: "amd64"}, arm)
+	if !strings.HasPrefix(w, ArchMismatchCode+":") || !strings.Contains(w, "linux/amd64") || !strings.Contains(w, "linux/arm64") || !strings.Contains(w, "QEMU") {
+		t.Errorf("mismatch warning = %q", w)
+	}
+
+	matching := []struct {
+		img  types.ImageInspect
+		info types.Info
+	}{
+		{types.ImageInspect{Os: "linux", Architecture: "arm64", Variant: "v8"}, arm},
+		{types.ImageInspect{Os: "linux", Architecture: "amd64"}, types.Info{OSType: "linux", Architecture: "x86_64"}},
+		{types.ImageInspect{Os: "linux", Architecture: "amd64"}, types.Info{OSType: "linux", Architecture: "mips-unknown"}}, // unknown daemon arch
+		{types.ImageInspect{}, arm}, // image without platform info
+	}
+	for _, m := range matching {
+		if w := archMismatchWarning("img", m.img, m.info); w != "" {
+			t.Errorf("%+v on %+v: unexpected warning %q", m.img, m.info, w)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package executor
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// safeExtractTar extracts a tar stream into destDir on the host, as copy_from_container does with
+// dest_dir. Entries with absolute paths, ".." components that escape destDir, or symlink/hardlink
+// targets outside destDir are rejected (zip-slip), and so is any entry whose parent directory
+// resolves outside destDir on disk, e.g. through chained symlinks extracted earlier. Returns the
+// extracted paths relative to destDir.
+func safeExtractTar(r io.Reader, destDir string) ([]string, error) {
+	absDest, err := filepath.Abs(destDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(absDest, 0755); err != nil {
+		return nil, err
+	}
+	realDest, err := filepath.EvalSymlinks(absDest)
+	if err != nil {
+		return nil, err
+	}
+
+	var extracted []string
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return extracted, err
+		}
+		target, err := resolveInside(absDest, hdr.Name)
+		if err != nil {
+			return extracted, err
+		}
+		rel, _ := filepath.Rel(absDest, target)
+		realParent, err := resolveExistingParent(realDest, target)
+		if err != nil {
+			return extracted, fmt.Errorf("tar entry %q: %v", hdr.Name, err)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return extracted, err
+			}
+			continue
+		case tar.TypeReg, tar.TypeRegA:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return extracted, err
+			}
+			if fi, err := os.Lstat(target); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+				// Writing through a link would land wherever it points.
+				return extracted, fmt.Errorf("tar entry %q: overwrites a symlink", hdr.Name)
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode)&0755|0600)
+			if err != nil {
+				return extracted, err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return extracted, err
+			}
+		case tar.TypeSymlink:
+			// Resolve against where the link really lives, not its textual path, so a link placed
+			// under an earlier link cannot climb out with "..".
+			linkTarget := hdr.Linkname
+			if !filepath.IsAbs(linkTarget) {
+				linkTarget = filepath.Join(realParent, linkTarget)
+			}
+			if !isInside(realDest, filepath.Clean(linkTarget)) {
+				return extracted, fmt.Errorf("tar entry %q: symlink target %q escapes destination", hdr.Name, hdr.Linkname)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return extracted, err
+			}
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return extracted, err
+			}
+		case tar.TypeLink:
+			linkTarget, err := resolveInside(absDest, hdr.Linkname)
+			if err == nil {
+				_, err = resolveExistingParent(realDest, linkTarget)
+			}
+			if err != nil {
+				return extracted, fmt.Errorf("tar entry %q: hardlink target %q escapes destination", hdr.Name, hdr.Linkname)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return extracted, err
+			}
+			if err := os.Link(linkTarget, target); err != nil {
+				return extracted, err
+			}
+		default:
+			// Devices, fifos etc. are never needed host-side; skip them.
+			continue
+		}
+		extracted = append(extracted, filepath.ToSlash(rel))
+	}
+	return extracted, nil
+}
+
+// readSingleFileFromTar returns the contents of the first regular file in a tar stream,
+// as produced by CopyFromContainer for a file path. It writes nothing to disk, so entry names
+// are never used as host paths and need no zip-slip checks.
+func readSingleFileFromTar(r io.Reader) ([]byte, error) {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("archive contains no regular file")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeReg || hdr.Typeflag == tar.TypeRegA {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// resolveInside joins name onto absDest and rejects absolute names or names that escape absDest.
+func resolveInside(absDest, name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(clean) || strings.HasPrefix(filepath.ToSlash(name), "/") {
+		return "", fmt.Errorf("tar entry %q: absolute paths are not allowed", name)
+	}
+	target := filepath.Join(absDest, clean)
+	if !isInside(absDest, target) {
+		return "", fmt.Errorf("tar entry %q: path escapes destination", name)
+	}
+	return target, nil
+}
+
+// resolveExistingParent returns where target's parent directory really is: its deepest existing
+// ancestor resolved through any symlinks, plus the missing directories below it, which are created
+// fresh and so cannot be links. Fails when that lies outside realDest.
+func resolveExistingParent(realDest, target string) (string, error) {
+	dir, missing := filepath.Dir(target), ""
+	for {
+		if _, err := os.Lstat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		missing = filepath.Join(filepath.Base(dir), missing)
+		dir = parent
+	}
+	real, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+	real = filepath.Join(real, missing)
+	if !isInside(realDest, real) {
+		return "", fmt.Errorf("parent directory escapes destination through a symlink")
+	}
+	return real, nil
+}
+
+// isInside reports whether path is absDest itself or a descendant of it.
+func isInside(absDest, path string) bool {
+	rel, err := filepath.Rel(absDest, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
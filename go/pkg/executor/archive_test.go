@@ -0,0 +1,124 @@
+package executor
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type tarEntry struct {
+	name     string
+	typeflag byte
+	body     string
+	linkname string
+}
+
+func buildTestTar(t *testing.T, entries []tarEntry) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typeflag, Mode: 0644, Size: int64(len(e.body)), Linkname: e.linkname}
+		if e.typeflag != tar.TypeReg {
+			hdr.Size = 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("write header: %v", err)
+		}
+		if hdr.Size > 0 {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatalf("write body: %v", err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+	return &buf
+}
+
+func TestSafeExtractTarExtractsNestedFiles(t *testing.T) {
+	dest := t.TempDir()
+	buf := buildTestTar(t, []tarEntry{
+		{name: "out/", typeflag: tar.TypeDir},
+		{name: "out/result.txt", typeflag: tar.TypeReg, body: "42"},
+		{name: "out/latest", typeflag: tar.TypeSymlink, linkname: "result.txt"},
+	})
+	got, err := safeExtractTar(buf, dest)
+	if err != nil {
+		t.Fatalf("safeExtractTar: %v", err)
+	}
+	if len(got) != 2 || got[0] != "out/result.txt" || got[1] != "out/latest" {
+		t.Errorf("unexpected extracted list: %v", got)
+	}
+	data, err := os.ReadFile(filepath.Join(dest, "out", "result.txt"))
+	if err != nil || string(data) != "42" {
+		t.Errorf("result.txt = %q, %v", data, err)
+	}
+}
+
+func TestSafeExtractTarRejectsMaliciousEntries(t *testing.T) {
+	cases := []struct {
+		name    string
+		entries []tarEntry
+	}{
+		{"dotdot", []tarEntry{{name: "../evil.txt", typeflag: tar.TypeReg, body: "x"}}},
+		{"nested dotdot", []tarEntry{{name: "a/../../evil.txt", typeflag: tar.TypeReg, body: "x"}}},
+		{"absolute", []tarEntry{{name: "/tmp/evil.txt", typeflag: tar.TypeReg, body: "x"}}},
+		{"symlink absolute", []tarEntry{{name: "link", typeflag: tar.TypeSymlink, linkname: "/etc/passwd"}}},
+		{"symlink relative escape", []tarEntry{{name: "a/link", typeflag: tar.TypeSymlink, linkname: "../../outside"}}},
+		{"hardlink escape", []tarEntry{{name: "hard", typeflag: tar.TypeLink, linkname: "../outside"}}},
+		// Each link looks harmless textually, but d/a/x is really dest/.. once d/a -> .. exists.
+		{"chained symlinks", []tarEntry{
+			{name: "d/", typeflag: tar.TypeDir},
+			{name: "d/a", typeflag: tar.TypeSymlink, linkname: ".."},
+			{name: "d/a/x", typeflag: tar.TypeSymlink, linkname: ".."},
+			{name: "d/a/x/evil.txt", typeflag: tar.TypeReg, body: "x"},
+		}},
+		// x -> . passes, and x/x/x/../../.. cleans to dest textually but climbs three levels on disk.
+		{"file under escaping symlink", []tarEntry{
+			{name: "x", typeflag: tar.TypeSymlink, linkname: "."},
+			{name: "up", typeflag: tar.TypeSymlink, linkname: "x/x/x/../../.."},
+			{name: "up/evil.txt", typeflag: tar.TypeReg, body: "x"},
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Nest dest so an escape of up to three levels still lands inside parent, where it is checked.
+			parent := t.TempDir()
+			dest := filepath.Join(parent, "a", "b", "dest")
+			_, err := safeExtractTar(buildTestTar(t, tc.entries), dest)
+			if err == nil {
+				t.Fatal("expected rejection, got nil error")
+			}
+			if !strings.Contains(err.Error(), "escapes destination") && !strings.Contains(err.Error(), "absolute paths") {
+				t.Errorf("unexpected error: %v", err)
+			}
+			for _, dir := range []string{parent, filepath.Join(parent, "a"), filepath.Join(parent, "a", "b")} {
+				if _, statErr := os.Lstat(filepath.Join(dir, "evil.txt")); statErr == nil {
+					t.Errorf("file was written outside the destination, in %s", dir)
+				}
+			}
+		})
+	}
+}
+
+func TestReadSingleFileFromTarLargeFile(t *testing.T) {
+	// A multi-megabyte last-run log must come back byte for byte.
+	body := strings.Repeat(`{"stdout":"`+strings.Repeat("x", 1000)+`"}`+"\n", 8*1024)
+	buf := buildTestTar(t, []tarEntry{{name: lastRunPath, typeflag: tar.TypeReg, body: body}})
+	got, err := readSingleFileFromTar(buf)
+	if err != nil {
+		t.Fatalf("readSingleFileFromTar: %v", err)
+	}
+	if string(got) != body {
+		t.Errorf("read %d bytes, want %d intact", len(got), len(body))
+	}
+
+	if _, err := readSingleFileFromTar(buildTestTar(t, []tarEntry{{name: "dir/", typeflag: tar.TypeDir}})); err == nil {
+		t.Error("directory-only archive: expected error")
+	}
+}
@@ -0,0 +1,85 @@
+package executor
+
+import (
+	"context"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/client"
+)
+
+// listArtifacts finds workspace files matching any of patterns and returns their workspace-relative
+// paths and sizes. Patterns without a "/" match the base name at any depth ("*.png"); patterns with
+// one match the whole relative path, where "**" spans any number of directories ("out/**").
+func listArtifacts(ctx context.Context, cli *client.Client, containerID string, patterns []string) ([]ArtifactInfo, error) {
+	// stat -c is available in both coreutils and busybox images.
+	stdout, _, _, _, err := runExec(ctx, cli, containerID, []string{"sh", "-c", "find . -type f -exec stat -c '%s %n' {} +"}, 30)
+	if err != nil {
+		return nil, err
+	}
+	var out []ArtifactInfo
+	for _, f := range parseStatOutput(stdout) {
+		if f.Path == lastRunPath {
+			continue
+		}
+		for _, pat := range patterns {
+			if matchArtifact(pat, f.Path) {
+				out = append(out, f)
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// parseStatOutput parses "<size> ./<path>" lines from stat -c '%s %n'.
+func parseStatOutput(s string) []ArtifactInfo {
+	var out []ArtifactInfo
+	for _, line := range strings.Split(s, "\n") {
+		sizeStr, name, ok := strings.Cut(strings.TrimRight(line, "\r"), " ")
+		if !ok {
+			continue
+		}
+		size, err := strconv.ParseInt(sizeStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		out = append(out, ArtifactInfo{Path: strings.TrimPrefix(name, "./"), SizeBytes: size})
+	}
+	return out
+}
+
+// matchArtifact reports whether the workspace-relative path rel matches pattern.
+func matchArtifact(pattern, rel string) bool {
+	pattern = strings.TrimPrefix(strings.TrimSpace(pattern), "./")
+	if pattern == "" {
+		return false
+	}
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(rel))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+func matchSegments(pat, segs []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			for i := 0; i <= len(segs); i++ {
+				if matchSegments(pat[1:], segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], segs[0]); !ok {
+			return false
+		}
+		pat, segs = pat[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
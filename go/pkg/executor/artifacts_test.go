@@ -0,0 +1,32 @@
+package executor
+
+import "testing"
+
+func TestMatchArtifact(t *testing.T) {
+	cases := []struct {
+		pattern, rel string
+		want         bool
+	}{
+		{"*.png", "plot.png", true},
+		{"*.png", "out/plots/plot.png", true},
+		{"*.png", "plot.jpg", false},
+		{"out/**", "out/a.csv", true},
+		{"out/**", "out/deep/b.csv", true},
+		{"out/**", "other/a.csv", false},
+		{"out/*.csv", "out/deep/b.csv", false},
+		{"**/*.csv", "a.csv", true},
+		{"./report.txt", "report.txt", true},
+	}
+	for _, tc := range cases {
+		if got := matchArtifact(tc.pattern, tc.rel); got != tc.want {
+			t.Errorf("matchArtifact(%q, %q) = %v, want %v", tc.pattern, tc.rel, got, tc.want)
+		}
+	}
+}
+
+func TestParseStatOutput(t *testing.T) {
+	got := parseStatOutput("12 ./out/a b.csv\n0 ./empty\nnot a line\n")
+	if len(got) != 2 || got[0].Path != "out/a b.csv" || got[0].SizeBytes != 12 || got[1].Path != "empty" {
+		t.Errorf("unexpected parse: %+v", got)
+	}
+}
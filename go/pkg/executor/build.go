@@ -4,7 +4,9 @@ import (
 	"archive/tar"
 	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -31,7 +33,23 @@ func BuildImageFromContext(ctx context.Context, cli *client.Client, p BuildImage
 	if p.ContextID == "" {
 		return BuildImageFromContextResult{Status: "error", Error: "context_id is required"}
 	}
-	return buildImageFromDir(ctx, cli, filepath.Clean(p.ContextID), p.Tag, p.BuildArgs, "context_id")
+	spec := buildSpec{tag: p.Tag, buildArgs: p.BuildArgs, compress: p.Compress, keepFailedContainers: p.KeepFailedContainers, strictArgs: p.StrictArgs}
+	if err := validateUserLabels(p.Labels); err != nil {
+		return BuildImageFromContextResult{Status: "error", Error: err.Error()}
+	}
+	spec.labels = buildLabels(filepath.Clean(p.ContextID), p.TaskID, p.Labels)
+	cacheFrom, err := normalizeCacheFrom(p.CacheFrom)
+	if err != nil {
+		return BuildImageFromContextResult{Status: "error", Error: err.Error()}
+	}
+	spec.cacheFrom = cacheFrom
+	spec.pullCacheFrom = p.PullCacheFrom
+	spec.hashTag = p.HashTag
+	if p.Stream {
+		// stdout carries the JSON result; progress goes to stderr so it never corrupts it.
+		spec.echo = os.Stderr
+	}
+	return buildImageFromDir(ctx, cli, filepath.Clean(p.ContextID), spec, "context_id")
 }
 
 // BuildImageFromPath runs docker build from an existing directory on disk (e.g. a cloned repo).
@@ -44,11 +62,29 @@ func BuildImageFromPath(ctx context.Context, cli *client.Client, p BuildImageFro
 	if err != nil {
 		return BuildImageFromContextResult{Status: "error", Error: fmt.Sprintf("path invalid: %v", err)}
 	}
-	return buildImageFromDir(ctx, cli, absDir, p.Tag, p.BuildArgs, "path")
+	if err := validateUserLabels(p.Labels); err != nil {
+		return BuildImageFromContextResult{Status: "error", Error: err.Error()}
+	}
+	spec := buildSpec{tag: p.Tag, buildArgs: p.BuildArgs, labels: buildLabels("", p.TaskID, p.Labels)}
+	return buildImageFromDir(ctx, cli, absDir, spec, "path")
+}
+
+// buildSpec holds the per-call build settings shared by BuildImageFromContext and BuildImageFromPath.
+type buildSpec struct {
+	tag                  string
+	buildArgs            map[string]string
+	compress             bool              // gzip the tar context before sending it to the daemon
+	keepFailedContainers bool              // keep intermediate containers so a failed RUN can be inspected
+	strictArgs           bool              // fail before building when a required ARG has no build_arg
+	labels               map[string]string // labels stamped on the image (see buildLabels)
+	cacheFrom            []string          // images whose layers may satisfy cache lookups
+	pullCacheFrom        bool              // pull cacheFrom images that are not present locally
+	hashTag              bool              // derive an empty tag from the context's content hash
+	echo                 io.Writer         // if set, receives each build output line as it arrives
 }
 
 // buildImageFromDir is the shared build logic: validate Dockerfile, tar dir, run ImageBuild, return handshake.
-func buildImageFromDir(ctx context.Context, cli *client.Client, absDir, tag string, buildArgs map[string]string, paramName string) BuildImageFromContextResult {
+func buildImageFromDir(ctx context.Context, cli *client.Client, absDir string, spec buildSpec, paramName string) (res BuildImageFromContextResult) {
 	info, err := os.Stat(absDir)
 	if err != nil || !info.IsDir() {
 		return BuildImageFromContextResult{Status: "error", Error: fmt.Sprintf("%s is not a valid directory: %v", paramName, err)}
@@ -63,49 +99,81 @@ func buildImageFromDir(ctx context.Context, cli *client.Client, absDir, tag stri
 		return BuildImageFromContextResult{Status: "error", Error: err.Error()}
 	}
 
+	args := analyzeBuildArgs(string(dfContent), spec.buildArgs)
+	defer func() {
+		res.ConsumedBuildArgs = args.consumed
+		res.IgnoredBuildArgs = args.ignored
+		res.BuildArgWarnings = args.warnings()
+	}()
+	if spec.strictArgs && len(args.missing) > 0 {
+		return BuildImageFromContextResult{Status: "error", Error: "strict_args: missing build_args for " + strings.Join(args.missing, ", ")}
+	}
+
 	tarBuf, err := tarContextFromDir(absDir)
 	if err != nil {
 		return BuildImageFromContextResult{Status: "error", Error: fmt.Sprintf("failed to create build context: %v", err)}
 	}
+	if spec.compress {
+		// The daemon detects gzip contexts on its own; no extra build option is needed.
+		tarBuf, err = gzipContext(tarBuf)
+		if err != nil {
+			return BuildImageFromContextResult{Status: "error", Error: fmt.Sprintf("failed to compress build context: %v", err)}
+		}
+	}
 
-	tag = strings.TrimSpace(tag)
+	tag := strings.TrimSpace(spec.tag)
+	if tag == "" && spec.hashTag {
+		if tag, err = contextHashTag(absDir, spec.buildArgs); err != nil {
+			return BuildImageFromContextResult{Status: "error", Error: fmt.Sprintf("failed to hash build context: %v", err)}
+		}
+	}
 	if tag == "" {
 		tag = "agent-env:build-" + fmt.Sprintf("%d", time.Now().Unix())
 	}
 	if !strings.HasPrefix(tag, "agent-env:") {
 		tag = "agent-env:" + tag
 	}
-
-	buildOpts := types.ImageBuildOptions{
-		Tags:       []string{tag},
-		Dockerfile: "Dockerfile",
-		Remove:     true,
+	if _, err := validateImageRef(tag); err != nil {
+		return BuildImageFromContextResult{Status: "error", Error: err.Error()}
 	}
-	if len(buildArgs) > 0 {
-		buildOpts.BuildArgs = make(map[string]*string)
-		for k, v := range buildArgs {
-			s := v
-			buildOpts.BuildArgs[k] = &s
+
+	if spec.pullCacheFrom {
+		if err := pullMissingImages(ctx, spec.cacheFrom, imagePresent(cli), imagePuller(cli)); err != nil {
+			return BuildImageFromContextResult{Status: "error", Error: err.Error()}
 		}
 	}
 
+	buildOpts := imageBuildOptions(tag, spec)
+
 	buildCtx, cancel := context.WithTimeout(ctx, 15*time.Minute)
 	defer cancel()
 
+	start := time.Now()
 	resp, err := cli.ImageBuild(buildCtx, tarBuf, buildOpts)
 	if err != nil {
-		return BuildImageFromContextResult{Status: "error", Error: err.Error()}
+		return BuildImageFromContextResult{Status: "error", Error: err.Error(), DurationMillis: time.Since(start).Milliseconds()}
 	}
 	defer resp.Body.Close()
 
-	summary, failedLayer, buildErr := parseBuildOutput(resp.Body)
+	out, buildErr := parseBuildOutput(resp.Body, spec.echo)
+	duration := time.Since(start).Milliseconds()
 	if buildErr != nil {
-		return BuildImageFromContextResult{
+		res := BuildImageFromContextResult{
 			Status:          "error",
 			Error:           buildErr.Error(),
-			BuildLogSummary: summary,
-			FailedLayer:     failedLayer,
+			BuildLogSummary: out.summary,
+			FailedLayer:     out.failedLayer,
+			DurationMillis:  duration,
+		}
+		if spec.keepFailedContainers {
+			res.FailedContainerID = out.lastContainerID
+			removeBuildContainers(cli, out.containerIDs, out.lastContainerID)
 		}
+		return res
+	}
+
+	if spec.keepFailedContainers {
+		removeBuildContainers(cli, out.containerIDs, "")
 	}
 
 	imageID, sizeMB := getImageInfo(buildCtx, cli, tag)
@@ -114,10 +182,41 @@ func buildImageFromDir(ctx context.Context, cli *client.Client, absDir, tag stri
 		ImageID:         imageID,
 		Tag:             tag,
 		SizeMB:          sizeMB,
-		BuildLogSummary: summary,
+		BuildLogSummary: out.summary,
+		DurationMillis:  duration,
+	}
+}
+
+// removeBuildContainers removes the intermediate containers a build left behind, except keep. With
+// Remove off the daemon keeps every step's container, not only the failed one, so without this a
+// keep_failed_containers build would leak one container per RUN.
+func removeBuildContainers(cli *client.Client, ids []string, keep string) {
+	for _, id := range ids {
+		if id != keep {
+			forceRemoveContainer(cli, id)
+		}
 	}
 }
 
+// imageBuildOptions maps a buildSpec onto the daemon's build options.
+func imageBuildOptions(tag string, spec buildSpec) types.ImageBuildOptions {
+	opts := types.ImageBuildOptions{
+		Tags:       []string{tag},
+		Dockerfile: "Dockerfile",
+		Remove:     !spec.keepFailedContainers,
+		Labels:     spec.labels,
+		CacheFrom:  spec.cacheFrom,
+	}
+	if len(spec.buildArgs) > 0 {
+		opts.BuildArgs = make(map[string]*string)
+		for k, v := range spec.buildArgs {
+			s := v
+			opts.BuildArgs[k] = &s
+		}
+	}
+	return opts
+}
+
 func validateDockerfile(content string) error {
 	for _, re := range forbiddenDockerfilePatterns {
 		if re.MatchString(content) {
@@ -127,10 +226,16 @@ func validateDockerfile(content string) error {
 	return nil
 }
 
+// tarContextFromDir tars dir for the daemon, leaving out .dockerignore matches so large ignored
+// trees (node_modules, .git) are never sent. Dockerfile and .dockerignore are always included.
 func tarContextFromDir(dir string) (io.Reader, error) {
+	ignore, err := readDockerignore(filepath.Join(dir, ".dockerignore"))
+	if err != nil {
+		return nil, err
+	}
 	var buf bytes.Buffer
 	tw := tar.NewWriter(&buf)
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -143,6 +248,12 @@ func tarContextFromDir(dir string) (io.Reader, error) {
 		}
 		// Use forward slashes for tar (Docker expects that)
 		rel = filepath.ToSlash(rel)
+		if rel != "Dockerfile" && rel != ".dockerignore" && ignore.excludes(rel) {
+			if info.IsDir() && !ignore.hasExceptions() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 		if info.IsDir() {
 			tw.WriteHeader(&tar.Header{Name: rel + "/", Mode: 0755, Typeflag: tar.TypeDir})
 			return nil
@@ -172,7 +283,33 @@ func tarContextFromDir(dir string) (io.Reader, error) {
 	return &buf, nil
 }
 
-func parseBuildOutput(r io.Reader) (summary string, failedLayer string, err error) {
+// gzipContext compresses a tar build context. Costs CPU on the client but cuts the bytes
+// sent to the daemon, which matters over DOCKER_HOST=tcp://... links.
+func gzipContext(r io.Reader) (io.Reader, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := io.Copy(zw, r); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+// buildOutput is what parseBuildOutput extracts from the daemon's JSON build stream.
+type buildOutput struct {
+	summary         string
+	failedLayer     string
+	lastContainerID string   // last intermediate container ("Running in <id>"), for keep_failed_containers
+	containerIDs    []string // every intermediate container, in build order
+}
+
+var runningInContainer = regexp.MustCompile(`Running in ([0-9a-f]{12,64})`)
+
+// parseBuildOutput reads the whole build stream. If echo is non-nil, each stream and status message is
+// also written to it as soon as it is read, so a long build shows progress before the result.
+func parseBuildOutput(r io.Reader, echo io.Writer) (out buildOutput, err error) {
 	scanner := bufio.NewScanner(r)
 	var lastStream string
 	var lastError string
@@ -182,31 +319,70 @@ func parseBuildOutput(r io.Reader) (summary string, failedLayer string, err erro
 		lineCount++
 		// Docker build stream is JSON lines: {"stream": "..."} or {"error": "..."}
 		if strings.HasPrefix(line, "{") {
+			if echo != nil {
+				echoBuildLine(echo, line)
+			}
 			if strings.Contains(line, `"error"`) {
 				lastError = line
 			}
 			if strings.Contains(line, `"stream"`) {
 				lastStream = line
+				if m := runningInContainer.FindStringSubmatch(line); m != nil {
+					out.lastContainerID = m[1]
+					out.containerIDs = append(out.containerIDs, m[1])
+				}
 			}
 		}
 	}
 	if err := scanner.Err(); err != nil {
-		return "", "", err
+		return out, err
 	}
 	if lastError != "" {
 		// Extract error message for failed_layer / summary
 		if idx := strings.Index(lastError, `"error"`); idx >= 0 {
-			summary = strings.TrimSpace(lastError)
-			failedLayer = summary
+			out.summary = strings.TrimSpace(lastError)
+			out.failedLayer = out.summary
 		}
-		return summary, failedLayer, fmt.Errorf("build failed: %s", summary)
+		return out, fmt.Errorf("build failed: %s", out.summary)
 	}
 	if lastStream != "" {
-		summary = lastStream
+		out.summary = lastStream
 	} else {
-		summary = fmt.Sprintf("Build completed. %d lines of output.", lineCount)
+		out.summary = fmt.Sprintf("Build completed. %d lines of output.", lineCount)
+	}
+	return out, nil
+}
+
+// echoBuildLine writes the human-readable part of one build stream line: the text of a "stream"
+// message, or "<id>: <status> <progress>" for a pull/push "status" message. Other lines are skipped.
+func echoBuildLine(w io.Writer, line string) {
+	var msg struct {
+		Stream   string `json:"stream"`
+		Status   string `json:"status"`
+		ID       string `json:"id"`
+		Progress string `json:"progress"`
+	}
+	if json.Unmarshal([]byte(line), &msg) != nil {
+		return
+	}
+	if msg.Stream != "" {
+		io.WriteString(w, msg.Stream)
+		if !strings.HasSuffix(msg.Stream, "\n") {
+			io.WriteString(w, "\n")
+		}
+		return
+	}
+	if msg.Status == "" {
+		return
+	}
+	status := msg.Status
+	if msg.ID != "" {
+		status = msg.ID + ": " + status
+	}
+	if msg.Progress != "" {
+		status += " " + msg.Progress
 	}
-	return summary, "", nil
+	fmt.Fprintln(w, status)
 }
 
 func getImageInfo(ctx context.Context, cli *client.Client, tag string) (imageID string, sizeMB float64) {
@@ -214,7 +390,7 @@ func getImageInfo(ctx context.Context, cli *client.Client, tag string) (imageID
 	if err != nil {
 		return "", 0
 	}
-	imageID = inspect.ID
+	imageID = normalizeImageID(inspect.ID)
 	if inspect.Size > 0 {
 		sizeMB = float64(inspect.Size) / (1024 * 1024)
 	}
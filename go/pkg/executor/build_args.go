@@ -0,0 +1,86 @@
+package executor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// predefinedBuildArgs are accepted by the daemon without an ARG declaration.
+var predefinedBuildArgs = map[string]bool{
+	"HTTP_PROXY": true, "http_proxy": true, "HTTPS_PROXY": true, "https_proxy": true,
+	"FTP_PROXY": true, "ftp_proxy": true, "NO_PROXY": true, "no_proxy": true,
+	"ALL_PROXY": true, "all_proxy": true,
+}
+
+// buildArgReport compares the Dockerfile's ARG declarations with the build_args a caller passed.
+type buildArgReport struct {
+	consumed []string // passed and declared (or predefined)
+	ignored  []string // passed but never declared; the daemon drops them
+	missing  []string // declared without a default and not passed
+}
+
+func (r buildArgReport) warnings() []string {
+	var out []string
+	for _, name := range r.missing {
+		out = append(out, fmt.Sprintf("ARG %s has no default and no build_arg was given; it will be empty", name))
+	}
+	return out
+}
+
+// analyzeBuildArgs parses ARG instructions (multi-arg lines and line continuations included).
+func analyzeBuildArgs(dockerfile string, passed map[string]string) buildArgReport {
+	declared := make(map[string]bool) // name -> has default
+	for _, line := range dockerfileInstructions(dockerfile) {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "ARG") {
+			continue
+		}
+		for _, f := range fields[1:] {
+			name, _, hasDefault := strings.Cut(f, "=")
+			declared[name] = declared[name] || hasDefault
+		}
+	}
+
+	var r buildArgReport
+	for name := range passed {
+		if _, ok := declared[name]; ok || predefinedBuildArgs[name] {
+			r.consumed = append(r.consumed, name)
+		} else {
+			r.ignored = append(r.ignored, name)
+		}
+	}
+	for name, hasDefault := range declared {
+		if _, ok := passed[name]; !ok && !hasDefault {
+			r.missing = append(r.missing, name)
+		}
+	}
+	sort.Strings(r.consumed)
+	sort.Strings(r.ignored)
+	sort.Strings(r.missing)
+	return r
+}
+
+// dockerfileInstructions joins backslash-continued lines and drops comments and blank lines.
+func dockerfileInstructions(dockerfile string) []string {
+	var out []string
+	var cur strings.Builder
+	for _, raw := range strings.Split(dockerfile, "\n") {
+		line := strings.TrimSpace(strings.TrimSuffix(raw, "\r"))
+		if cur.Len() == 0 && (line == "" || strings.HasPrefix(line, "#")) {
+			continue
+		}
+		if strings.HasSuffix(line, "\\") {
+			cur.WriteString(strings.TrimSuffix(line, "\\"))
+			cur.WriteString(" ")
+			continue
+		}
+		cur.WriteString(line)
+		out = append(out, cur.String())
+		cur.Reset()
+	}
+	if cur.Len() > 0 {
+		out = append(out, cur.String())
+	}
+	return out
+}
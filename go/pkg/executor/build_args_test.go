@@ -0,0 +1,59 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeBuildArgs(t *testing.T) {
+	dockerfile := `# syntax=docker/dockerfile:1
+ARG BASE=python:3.11-slim
+FROM $BASE
+ARG APP_VERSION
+arg REGION=eu \
+    API_TOKEN
+RUN echo $APP_VERSION
+`
+	r := analyzeBuildArgs(dockerfile, map[string]string{
+		"BASE":        "python:3.12-slim",
+		"REGION":      "us",
+		"TYPO_ARG":    "x",
+		"HTTPS_PROXY": "http://proxy:3128",
+	})
+	if want := []string{"BASE", "HTTPS_PROXY", "REGION"}; !reflect.DeepEqual(r.consumed, want) {
+		t.Errorf("consumed = %v, want %v", r.consumed, want)
+	}
+	if want := []string{"TYPO_ARG"}; !reflect.DeepEqual(r.ignored, want) {
+		t.Errorf("ignored = %v, want %v", r.ignored, want)
+	}
+	if want := []string{"API_TOKEN", "APP_VERSION"}; !reflect.DeepEqual(r.missing, want) {
+		t.Errorf("missing = %v, want %v", r.missing, want)
+	}
+	w := r.warnings()
+	if len(w) != 2 || !strings.Contains(w[0], "API_TOKEN") {
+		t.Errorf("warnings = %v", w)
+	}
+
+	if r := analyzeBuildArgs(dockerfile, map[string]string{"APP_VERSION": "1", "API_TOKEN": "t"}); len(r.missing) != 0 {
+		t.Errorf("all required passed: missing = %v", r.missing)
+	}
+}
+
+func TestBuildStrictArgsFailsBeforeBuilding(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM busybox\nARG API_TOKEN\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// No daemon is needed: the strict check runs before the context is sent.
+	res := buildImageFromDir(context.Background(), nil, dir, buildSpec{tag: "agent-env:strict", strictArgs: true}, "context_id")
+	if res.Status != "error" || !strings.Contains(res.Error, "API_TOKEN") {
+		t.Errorf("expected strict_args error naming API_TOKEN, got %+v", res)
+	}
+	if len(res.BuildArgWarnings) != 1 {
+		t.Errorf("warnings = %v", res.BuildArgWarnings)
+	}
+}
@@ -0,0 +1,315 @@
+package executor
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestGzipContextProducesValidTar(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM busybox\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := tarContextFromDir(dir)
+	if err != nil {
+		t.Fatalf("tarContextFromDir: %v", err)
+	}
+	compressed, err := gzipContext(raw)
+	if err != nil {
+		t.Fatalf("gzipContext: %v", err)
+	}
+	zr, err := gzip.NewReader(compressed)
+	if err != nil {
+		t.Fatalf("output is not gzip: %v", err)
+	}
+	hdr, err := tar.NewReader(zr).Next()
+	if err != nil {
+		t.Fatalf("gzip payload is not a tar stream: %v", err)
+	}
+	if hdr.Name != "Dockerfile" {
+		t.Errorf("first entry = %q, want Dockerfile", hdr.Name)
+	}
+}
+
+// TestBuildImageFromContextCompressedContext checks that compress: true reaches the daemon as a
+// gzipped tar it can read, and that the build still reports success.
+func TestBuildImageFromContextCompressedContext(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM busybox\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var firstEntry string
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/build"):
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, `{"message":"context is not gzip"}`, http.StatusBadRequest)
+				return
+			}
+			hdr, err := tar.NewReader(zr).Next()
+			if err != nil {
+				http.Error(w, `{"message":"gzip payload is not a tar stream"}`, http.StatusBadRequest)
+				return
+			}
+			firstEntry = hdr.Name
+			w.Write([]byte(`{"stream":"Successfully built abc123def456\n"}` + "\n"))
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/images/agent-env:gz/json"):
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"Id":"sha256:abc123def456","Size":1048576}`))
+		default:
+			http.Error(w, `{"message":"not implemented"}`, http.StatusNotImplemented)
+		}
+	}))
+
+	res := BuildImageFromContext(context.Background(), cli, BuildImageFromContextParams{ContextID: dir, Tag: "agent-env:gz", Compress: true})
+	if res.Status != "success" {
+		t.Fatalf("status = %q, error = %q", res.Status, res.Error)
+	}
+	if firstEntry != "Dockerfile" {
+		t.Errorf("first context entry = %q, want Dockerfile", firstEntry)
+	}
+	if res.Tag != "agent-env:gz" || res.SizeMB != 1 {
+		t.Errorf("result = %+v", res)
+	}
+}
+
+func TestTarContextFromDirHonorsDockerignore(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"Dockerfile":                 "FROM busybox\n",
+		".dockerignore":              "node_modules\n*.log\nDockerfile\n",
+		"app.py":                     "print(1)\n",
+		"debug.log":                  "noise\n",
+		"node_modules/left/index.js": "x\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	raw, err := tarContextFromDir(dir)
+	if err != nil {
+		t.Fatalf("tarContextFromDir: %v", err)
+	}
+	got := map[string]bool{}
+	tr := tar.NewReader(raw)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		got[hdr.Name] = true
+	}
+	for _, want := range []string{"Dockerfile", ".dockerignore", "app.py"} {
+		if !got[want] {
+			t.Errorf("context missing %s; got %v", want, got)
+		}
+	}
+	for _, ignored := range []string{"debug.log", "node_modules/", "node_modules/left/index.js"} {
+		if got[ignored] {
+			t.Errorf("context contains ignored %s", ignored)
+		}
+	}
+}
+
+func TestImageBuildOptionsKeepFailedContainers(t *testing.T) {
+	opts := imageBuildOptions("agent-env:t", buildSpec{})
+	if !opts.Remove || opts.ForceRemove {
+		t.Errorf("default: Remove=%v ForceRemove=%v, want true/false", opts.Remove, opts.ForceRemove)
+	}
+	opts = imageBuildOptions("agent-env:t", buildSpec{keepFailedContainers: true})
+	if opts.Remove || opts.ForceRemove {
+		t.Errorf("keep: Remove=%v ForceRemove=%v, want false/false", opts.Remove, opts.ForceRemove)
+	}
+}
+
+func TestKeepFailedContainersRemovesTheRest(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM busybox\nRUN true\nRUN false\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name    string
+		last    string
+		removed string
+		status  string
+	}{
+		{"success", `{"stream":"Successfully built abc123def456\n"}`, "aaaaaaaaaaaa,bbbbbbbbbbbb", "success"},
+		{"failure", `{"errorDetail":{"code":1},"error":"The command '/bin/sh -c false' returned a non-zero code: 1"}`, "aaaaaaaaaaaa", "error"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var removed []string
+			cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/build"):
+					w.Write([]byte(`{"stream":" ---> Running in aaaaaaaaaaaa\n"}` + "\n" +
+						`{"stream":" ---> Running in bbbbbbbbbbbb\n"}` + "\n" + tc.last + "\n"))
+				case r.Method == http.MethodDelete:
+					mu.Lock()
+					removed = append(removed, strings.TrimPrefix(r.URL.Path, "/v1.43/containers/"))
+					mu.Unlock()
+					w.WriteHeader(http.StatusNoContent)
+				case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/json"):
+					w.Header().Set("Content-Type", "application/json")
+					w.Write([]byte(`{"Id":"sha256:abc123def456"}`))
+				default:
+					http.Error(w, `{"message":"not implemented"}`, http.StatusNotImplemented)
+				}
+			}))
+			res := BuildImageFromContext(context.Background(), cli, BuildImageFromContextParams{ContextID: dir, Tag: "agent-env:keep", KeepFailedContainers: true})
+			if res.Status != tc.status {
+				t.Fatalf("status = %q, error = %q", res.Status, res.Error)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if got := strings.Join(removed, ","); got != tc.removed {
+				t.Errorf("removed = %q, want %q", got, tc.removed)
+			}
+			if tc.status == "error" && res.FailedContainerID != "bbbbbbbbbbbb" {
+				t.Errorf("failed_container_id = %q", res.FailedContainerID)
+			}
+		})
+	}
+}
+
+func TestParseBuildOutputReportsFailedContainer(t *testing.T) {
+	stream := `{"stream":"Step 2/2 : RUN false"}
+{"stream":" ---> Running in 4f2a9c1b7d3e\n"}
+{"errorDetail":{"code":1},"error":"The command '/bin/sh -c false' returned a non-zero code: 1"}
+`
+	out, err := parseBuildOutput(strings.NewReader(stream), nil)
+	if err == nil {
+		t.Fatal("expected build error")
+	}
+	if out.lastContainerID != "4f2a9c1b7d3e" {
+		t.Errorf("lastContainerID = %q", out.lastContainerID)
+	}
+}
+
+func TestParseBuildOutputEchoesLines(t *testing.T) {
+	stream := `{"stream":"Step 1/2 : FROM python:3-alpine\n"}
+{"status":"Pulling fs layer","id":"a1b2c3"}
+{"status":"Downloading","progressDetail":{"current":1,"total":2},"progress":"[=====>     ]","id":"a1b2c3"}
+{"aux":{"ID":"sha256:abc"}}
+{"stream":"Successfully built abc"}
+`
+	var echo strings.Builder
+	out, err := parseBuildOutput(strings.NewReader(stream), &echo)
+	if err != nil {
+		t.Fatalf("parseBuildOutput: %v", err)
+	}
+	want := "Step 1/2 : FROM python:3-alpine\na1b2c3: Pulling fs layer\na1b2c3: Downloading [=====>     ]\nSuccessfully built abc\n"
+	if echo.String() != want {
+		t.Errorf("echo = %q, want %q", echo.String(), want)
+	}
+	if out.summary != `{"stream":"Successfully built abc"}` {
+		t.Errorf("summary changed by streaming: %q", out.summary)
+	}
+}
+
+func TestImageBuildOptionsCacheFrom(t *testing.T) {
+	refs, err := normalizeCacheFrom([]string{"registry.example.com/app:cache", "busybox"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := imageBuildOptions("agent-env:t", buildSpec{cacheFrom: refs})
+	want := []string{"registry.example.com/app:cache", "docker.io/library/busybox:latest"}
+	if len(opts.CacheFrom) != 2 || opts.CacheFrom[0] != want[0] || opts.CacheFrom[1] != want[1] {
+		t.Errorf("CacheFrom = %q, want %q", opts.CacheFrom, want)
+	}
+	if _, err := normalizeCacheFrom([]string{"Bad Ref"}); err == nil {
+		t.Error("expected error for invalid cache_from ref")
+	}
+}
+
+func TestPullMissingImages(t *testing.T) {
+	local := map[string]bool{"docker.io/library/busybox:latest": true}
+	var pulled []string
+	present := func(_ context.Context, ref string) bool { return local[ref] }
+	pull := func(_ context.Context, ref string) error {
+		pulled = append(pulled, ref)
+		return nil
+	}
+	refs := []string{"docker.io/library/busybox:latest", "registry.example.com/app:cache"}
+	if err := pullMissingImages(context.Background(), refs, present, pull); err != nil {
+		t.Fatal(err)
+	}
+	if len(pulled) != 1 || pulled[0] != "registry.example.com/app:cache" {
+		t.Errorf("pulled = %q, want only the missing image", pulled)
+	}
+
+	failing := func(context.Context, string) error { return errors.New("unauthorized") }
+	err := pullMissingImages(context.Background(), refs, present, failing)
+	if err == nil || !strings.Contains(err.Error(), "registry.example.com/app:cache") {
+		t.Errorf("pull failure: err = %v", err)
+	}
+}
+
+// BenchmarkGzipContext measures compress: true on a context shaped like a typical agent build: a
+// Dockerfile, requirements, ~2 MB of source across 200 files and a 512 KB already-compressed asset.
+// MB/s is compression throughput over the raw tar; ratio is raw/compressed bytes.
+func BenchmarkGzipContext(b *testing.B) {
+	dir := b.TempDir()
+	write := func(name string, data []byte) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			b.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	write("Dockerfile", []byte("FROM python:3.11-slim\nCOPY requirements.txt .\nRUN pip install -r requirements.txt\nCOPY . /app\n"))
+	write("requirements.txt", []byte("numpy==1.26.4\npandas==2.2.1\nrequests==2.31.0\npytest==8.1.1\n"))
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		var src strings.Builder
+		for src.Len() < 10*1024 {
+			fmt.Fprintf(&src, "def handler_%d_%d(payload, retries=%d):\n    \"\"\"Validate payload and forward it.\"\"\"\n"+
+				"    if not payload.get(%q):\n        raise ValueError(\"missing field\")\n    return forward(payload, timeout=%d)\n\n",
+				i, rng.Intn(1e6), rng.Intn(5), fmt.Sprintf("field_%d", rng.Intn(100)), rng.Intn(60))
+		}
+		write(fmt.Sprintf("app/pkg%d/module_%d.py", i%10, i), []byte(src.String()))
+	}
+	asset := make([]byte, 512*1024)
+	rng.Read(asset)
+	write("app/static/model.bin", asset)
+
+	raw, err := tarContextFromDir(dir)
+	if err != nil {
+		b.Fatal(err)
+	}
+	tarBytes, err := io.ReadAll(raw)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(tarBytes)))
+	b.ResetTimer()
+	var compressedLen int
+	for i := 0; i < b.N; i++ {
+		out, err := gzipContext(bytes.NewReader(tarBytes))
+		if err != nil {
+			b.Fatal(err)
+		}
+		compressedLen = out.(*bytes.Buffer).Len()
+	}
+	b.ReportMetric(float64(len(tarBytes))/float64(compressedLen), "ratio")
+}
@@ -0,0 +1,117 @@
+package executor
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/pem"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// caCertFileName is the name of the PEM bundle written into the container's trust store.
+const caCertFileName = "adde-custom-ca.crt"
+
+// caTrustStore says where a distro family keeps extra CA certificates and how it rebuilds the bundle.
+type caTrustStore struct {
+	dir       string
+	updateCmd []string
+}
+
+// caTrustStoreFor picks the trust store from /etc/os-release content (ID and ID_LIKE).
+// Debian/Ubuntu/Alpine use update-ca-certificates; RHEL/Fedora/CentOS/Amazon Linux use update-ca-trust.
+func caTrustStoreFor(osRelease string) (caTrustStore, error) {
+	fields := parseOSRelease(osRelease)
+	ids := append([]string{fields["ID"]}, strings.Fields(fields["ID_LIKE"])...)
+	for _, id := range ids {
+		switch id {
+		case "debian", "ubuntu", "alpine":
+			return caTrustStore{dir: "/usr/local/share/ca-certificates", updateCmd: []string{"update-ca-certificates"}}, nil
+		case "rhel", "fedora", "centos", "rocky", "almalinux", "amzn":
+			return caTrustStore{dir: "/etc/pki/ca-trust/source/anchors", updateCmd: []string{"update-ca-trust", "extract"}}, nil
+		}
+	}
+	if fields["ID"] == "" {
+		return caTrustStore{}, fmt.Errorf("ca_certs: cannot detect distro (no /etc/os-release)")
+	}
+	return caTrustStore{}, fmt.Errorf("ca_certs: unsupported distro %q", fields["ID"])
+}
+
+// parseOSRelease parses KEY=value lines, stripping optional quotes.
+func parseOSRelease(s string) map[string]string {
+	out := make(map[string]string)
+	for _, line := range strings.Split(s, "\n") {
+		k, v, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || strings.HasPrefix(k, "#") {
+			continue
+		}
+		out[k] = strings.ToLower(strings.Trim(v, `"'`))
+	}
+	return out
+}
+
+// validateCACerts checks that pemData holds at least one certificate and nothing that is not PEM.
+func validateCACerts(pemData string) error {
+	rest := []byte(pemData)
+	n := 0
+	for {
+		block, r := pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return fmt.Errorf("ca_certs: unexpected PEM block %q (only CERTIFICATE is allowed)", block.Type)
+		}
+		n++
+		rest = r
+	}
+	if n == 0 || len(bytes.TrimSpace(rest)) > 0 {
+		return fmt.Errorf("ca_certs: expected PEM-encoded CERTIFICATE blocks")
+	}
+	return nil
+}
+
+// installCACerts adds pemData to the container's system trust store. Runs as the image's default user,
+// which must be able to write the trust store (root in official images).
+func installCACerts(ctx context.Context, cli *client.Client, containerID, pemData string) error {
+	osRelease, _, _, _, err := runExec(ctx, cli, containerID, []string{"cat", "/etc/os-release"}, 10)
+	if err != nil {
+		return fmt.Errorf("ca_certs: %w", err)
+	}
+	store, err := caTrustStoreFor(osRelease)
+	if err != nil {
+		return err
+	}
+	if _, stderr, code, _, err := runExec(ctx, cli, containerID, []string{"mkdir", "-p", store.dir}, 10); err != nil || code != 0 {
+		return execFailure("ca_certs: mkdir "+store.dir, stderr, code, err)
+	}
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: caCertFileName, Mode: 0644, Size: int64(len(pemData))}); err != nil {
+		return err
+	}
+	if _, err := tw.Write([]byte(pemData)); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := cli.CopyToContainer(ctx, containerID, store.dir, &buf, types.CopyToContainerOptions{}); err != nil {
+		return fmt.Errorf("ca_certs: copy %s: %w", path.Join(store.dir, caCertFileName), err)
+	}
+	if _, stderr, code, _, err := runExec(ctx, cli, containerID, store.updateCmd, 60); err != nil || code != 0 {
+		return execFailure("ca_certs: "+strings.Join(store.updateCmd, " "), stderr, code, err)
+	}
+	return nil
+}
+
+func execFailure(what, stderr string, code int, err error) error {
+	if err != nil {
+		return fmt.Errorf("%s: %w", what, err)
+	}
+	return fmt.Errorf("%s: exit %d: %s", what, code, strings.TrimSpace(stderr))
+}
@@ -0,0 +1,56 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/client"
+)
+
+// normalizeCacheFrom validates cache_from references and returns them in canonical form.
+func normalizeCacheFrom(refs []string) ([]string, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	out := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		norm, err := normalizeImageRef(ref)
+		if err != nil {
+			return nil, fmt.Errorf("cache_from: %v", err)
+		}
+		out = append(out, norm)
+	}
+	return out, nil
+}
+
+// pullMissingImages pulls every ref for which present reports false. The classic builder only uses
+// cache_from images that are already local, so on a fresh host they must be pulled first.
+func pullMissingImages(ctx context.Context, refs []string, present func(context.Context, string) bool, pull func(context.Context, string) error) error {
+	for _, ref := range refs {
+		if present(ctx, ref) {
+			continue
+		}
+		if err := pull(ctx, ref); err != nil {
+			return fmt.Errorf("pull cache_from image %s: %v", ref, err)
+		}
+	}
+	return nil
+}
+
+// imagePresent reports whether ref exists in the daemon's local image store.
+func imagePresent(cli *client.Client) func(context.Context, string) bool {
+	return func(ctx context.Context, ref string) bool {
+		_, _, err := cli.ImageInspectWithRaw(ctx, ref)
+		return err == nil
+	}
+}
+
+// imagePuller pulls ref via PullImage.
+func imagePuller(cli *client.Client) func(context.Context, string) error {
+	return func(ctx context.Context, ref string) error {
+		if res := PullImage(ctx, cli, PullImageParams{Image: ref}); res.Error != "" {
+			return fmt.Errorf("%s", res.Error)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,47 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/client"
+)
+
+// ExecuteCells runs notebook-style cells in order in the same container via ExecuteCodeBlock,
+// returning one log per cell that ran. With StopOnError, the first non-zero exit ends the run.
+func ExecuteCells(ctx context.Context, cli *client.Client, p ExecuteCellsParams) ExecuteCellsResult {
+	if len(p.Cells) == 0 {
+		return ExecuteCellsResult{Error: "cells must not be empty"}
+	}
+	return runCells(p.Cells, p.StopOnError, func(c Cell) ExecuteCodeBlockResult {
+		return ExecuteCodeBlock(ctx, cli, ExecuteCodeBlockParams{
+			ContainerID: p.ContainerID,
+			Filename:    c.Filename,
+			CodeContent: c.CodeContent,
+			TimeoutSec:  c.TimeoutSec,
+			Transcript:  p.Transcript,
+			WorkingDir:  p.WorkingDir,
+		})
+	})
+}
+
+// runCells drives the cell loop; run executes a single cell.
+func runCells(cells []Cell, stopOnError bool, run func(Cell) ExecuteCodeBlockResult) ExecuteCellsResult {
+	res := ExecuteCellsResult{Logs: []LogEntry{}}
+	for i, c := range cells {
+		r := run(c)
+		if r.Log != nil {
+			res.Logs = append(res.Logs, *r.Log)
+		}
+		if r.Error != "" {
+			// The toolkit itself failed (not the cell's code); later cells would fail the same way.
+			res.Error = fmt.Sprintf("cell %d (%s): %s", i, c.Filename, r.Error)
+			return res
+		}
+		if stopOnError && r.Log != nil && r.Log.ExitCode != 0 {
+			res.Stopped = true
+			return res
+		}
+	}
+	return res
+}
@@ -0,0 +1,40 @@
+package executor
+
+import "testing"
+
+func TestRunCellsStopOnError(t *testing.T) {
+	cells := []Cell{
+		{Filename: "1.py", CodeContent: "x = 1"},
+		{Filename: "2.py", CodeContent: "raise SystemExit(3)"},
+		{Filename: "3.py", CodeContent: "print(x)"},
+	}
+	exitCodes := map[string]int{"1.py": 0, "2.py": 3, "3.py": 0}
+	var ran []string
+	run := func(c Cell) ExecuteCodeBlockResult {
+		ran = append(ran, c.Filename)
+		return ExecuteCodeBlockResult{Log: &LogEntry{ExitCode: exitCodes[c.Filename]}}
+	}
+
+	res := runCells(cells, true, run)
+	if len(ran) != 2 || len(res.Logs) != 2 || !res.Stopped {
+		t.Fatalf("stop_on_error: ran %v, %d logs, stopped=%v", ran, len(res.Logs), res.Stopped)
+	}
+	if res.Logs[1].ExitCode != 3 || res.Error != "" {
+		t.Errorf("second cell: exit %d, error %q", res.Logs[1].ExitCode, res.Error)
+	}
+
+	ran = nil
+	res = runCells(cells, false, run)
+	if len(ran) != 3 || len(res.Logs) != 3 || res.Stopped {
+		t.Errorf("without stop_on_error: ran %v, %d logs, stopped=%v", ran, len(res.Logs), res.Stopped)
+	}
+}
+
+func TestRunCellsToolkitErrorStops(t *testing.T) {
+	res := runCells([]Cell{{Filename: "a.sh"}, {Filename: "b.sh"}}, false, func(c Cell) ExecuteCodeBlockResult {
+		return ExecuteCodeBlockResult{Error: "No such container: x"}
+	})
+	if len(res.Logs) != 0 || res.Error != "cell 0 (a.sh): No such container: x" {
+		t.Errorf("got %+v", res)
+	}
+}
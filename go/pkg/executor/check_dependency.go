@@ -0,0 +1,78 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/docker/docker/client"
+)
+
+// Languages accepted by check_dependency.
+const (
+	DependencyPython = "python" // pip show
+	DependencyNode   = "node"   // npm ls -g (create_runtime_env installs node deps globally)
+	DependencySystem = "system" // which, for binaries on PATH
+)
+
+var npmLsVersionRe = regexp.MustCompile(`@([^\s@]+)\s*$`)
+
+// CheckDependency reports whether a package is present in the container without installing anything.
+// A non-zero exit from the probe means "not installed"; only exec failures are returned as errors.
+func CheckDependency(ctx context.Context, cli *client.Client, p CheckDependencyParams) CheckDependencyResult {
+	lang, cmd, err := dependencyProbeCommand(p.Language, p.Package)
+	if err != nil {
+		return CheckDependencyResult{Error: err.Error()}
+	}
+	stdout, _, exitCode, _, err := runExec(ctx, cli, p.ContainerID, cmd, 30)
+	if err != nil {
+		return CheckDependencyResult{Error: err.Error()}
+	}
+	if exitCode != 0 {
+		return CheckDependencyResult{Installed: false}
+	}
+	return CheckDependencyResult{Installed: true, Version: parseDependencyVersion(lang, p.Package, stdout)}
+}
+
+// dependencyProbeCommand picks the probe for language (with common aliases) and returns the canonical language.
+func dependencyProbeCommand(language, pkg string) (string, []string, error) {
+	pkg = strings.TrimSpace(pkg)
+	if pkg == "" {
+		return "", nil, fmt.Errorf("package is required")
+	}
+	if strings.HasPrefix(pkg, "-") || strings.ContainsAny(pkg, " \t\n") {
+		return "", nil, fmt.Errorf("invalid package name %q", pkg)
+	}
+	switch strings.ToLower(strings.TrimSpace(language)) {
+	case "python", "py", "pip":
+		return DependencyPython, []string{"pip", "show", pkg}, nil
+	case "node", "nodejs", "javascript", "js", "npm":
+		return DependencyNode, []string{"npm", "ls", "-g", "--depth=0", pkg}, nil
+	case "system", "binary", "bin", "sh":
+		return DependencySystem, []string{"which", pkg}, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported language %q (use python, node, or system)", language)
+	}
+}
+
+// parseDependencyVersion extracts the version from probe output; empty when unknown (e.g. which).
+func parseDependencyVersion(lang, pkg, out string) string {
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		switch lang {
+		case DependencyPython:
+			if v, ok := strings.CutPrefix(line, "Version:"); ok {
+				return strings.TrimSpace(v)
+			}
+		case DependencyNode:
+			// e.g. "└── lodash@4.17.21"
+			if i := strings.LastIndex(line, " "+pkg+"@"); i >= 0 {
+				if m := npmLsVersionRe.FindStringSubmatch(line[i:]); m != nil {
+					return m[1]
+				}
+			}
+		}
+	}
+	return ""
+}
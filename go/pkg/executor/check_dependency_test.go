@@ -0,0 +1,58 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDependencyProbeCommand(t *testing.T) {
+	cases := []struct {
+		language, pkg string
+		wantLang      string
+		wantCmd       string
+	}{
+		{"python", "requests", DependencyPython, "pip show requests"},
+		{"PY", "numpy", DependencyPython, "pip show numpy"},
+		{"node", "lodash", DependencyNode, "npm ls -g --depth=0 lodash"},
+		{"javascript", "@types/node", DependencyNode, "npm ls -g --depth=0 @types/node"},
+		{"system", "git", DependencySystem, "which git"},
+	}
+	for _, tc := range cases {
+		lang, cmd, err := dependencyProbeCommand(tc.language, tc.pkg)
+		if err != nil {
+			t.Errorf("%s/%s: %v", tc.language, tc.pkg, err)
+			continue
+		}
+		if lang != tc.wantLang || strings.Join(cmd, " ") != tc.wantCmd {
+			t.Errorf("%s/%s: got %s %q, want %s %q", tc.language, tc.pkg, lang, strings.Join(cmd, " "), tc.wantLang, tc.wantCmd)
+		}
+	}
+
+	for _, bad := range []struct{ language, pkg string }{
+		{"ruby", "rails"},
+		{"python", ""},
+		{"python", "--index-url=http://evil"},
+		{"system", "git; rm -rf /"},
+	} {
+		if _, _, err := dependencyProbeCommand(bad.language, bad.pkg); err == nil {
+			t.Errorf("%s/%q: expected error", bad.language, bad.pkg)
+		}
+	}
+}
+
+func TestParseDependencyVersion(t *testing.T) {
+	pip := "Name: requests\nVersion: 2.31.0\nSummary: Python HTTP for Humans.\n"
+	if got := parseDependencyVersion(DependencyPython, "requests", pip); got != "2.31.0" {
+		t.Errorf("pip: got %q", got)
+	}
+	npm := "/usr/local/lib\n├── corepack@0.20.0\n└── @types/node@20.8.2\n"
+	if got := parseDependencyVersion(DependencyNode, "@types/node", npm); got != "20.8.2" {
+		t.Errorf("npm scoped: got %q", got)
+	}
+	if got := parseDependencyVersion(DependencyNode, "node", npm); got != "" {
+		t.Errorf("npm prefix match: got %q, want empty", got)
+	}
+	if got := parseDependencyVersion(DependencySystem, "git", "/usr/bin/git\n"); got != "" {
+		t.Errorf("which: got %q, want empty", got)
+	}
+}
@@ -0,0 +1,101 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+)
+
+// DefaultCheckPortTimeoutSec bounds the connect (and, with a path, the whole HTTP request) of check_port.
+const DefaultCheckPortTimeoutSec = 5
+
+// CheckPort resolves the host port a container port is published on and connects to it from this
+// machine: a TCP connect, or an HTTP GET of Path when given. An unreachable port is a result
+// (reachable: false with reason), not an error; Error is for a container or port that cannot be resolved.
+func CheckPort(ctx context.Context, cli *client.Client, p CheckPortParams) CheckPortResult {
+	if p.ContainerID == "" || p.ContainerPort == "" {
+		return CheckPortResult{Error: "container_id and container_port are required"}
+	}
+	port, err := nat.NewPort(nat.SplitProtoPort(p.ContainerPort))
+	if err != nil || port.Proto() != "tcp" {
+		return CheckPortResult{Error: fmt.Sprintf("container_port %q must be a TCP port, e.g. \"3000\" or \"3000/tcp\"", p.ContainerPort)}
+	}
+	inspect, err := cli.ContainerInspect(ctx, p.ContainerID)
+	if err != nil {
+		return CheckPortResult{Error: err.Error()}
+	}
+	if inspect.NetworkSettings == nil || len(inspect.NetworkSettings.Ports[port]) == 0 || inspect.NetworkSettings.Ports[port][0].HostPort == "" {
+		return CheckPortResult{Error: fmt.Sprintf("container port %s is not published to the host (set port_bindings or auto_publish_exposed)", port)}
+	}
+	binding := inspect.NetworkSettings.Ports[port][0]
+	addr := net.JoinHostPort(dialHost(binding.HostIP, cli.DaemonHost()), binding.HostPort)
+
+	timeout := time.Duration(DefaultCheckPortTimeoutSec) * time.Second
+	if p.TimeoutSec > 0 {
+		timeout = time.Duration(p.TimeoutSec) * time.Second
+	}
+	res := CheckPortResult{HostAddress: addr}
+	start := time.Now()
+	if p.Path == "" {
+		conn, err := (&net.Dialer{Timeout: timeout}).DialContext(ctx, "tcp", addr)
+		if err != nil {
+			res.Reason = err.Error()
+			return res
+		}
+		conn.Close()
+	} else {
+		status, err := httpProbe(ctx, addr, p.Path, timeout)
+		if err != nil {
+			res.Reason = err.Error()
+			return res
+		}
+		res.StatusCode = status
+	}
+	res.Reachable = true
+	res.LatencyMS = time.Since(start).Milliseconds()
+	return res
+}
+
+// dialHost is the address to connect to for a port bound on hostIP. Wildcard and loopback bindings
+// are on the daemon's machine: this one for a local socket, the daemon's host for tcp://.
+func dialHost(hostIP, daemonHost string) string {
+	ip := net.ParseIP(hostIP)
+	if hostIP != "" && ip != nil && !ip.IsUnspecified() && !ip.IsLoopback() {
+		return hostIP
+	}
+	if u, err := url.Parse(daemonHost); err == nil && u.Scheme == "tcp" && u.Hostname() != "" {
+		return u.Hostname()
+	}
+	if ip != nil && ip.To4() == nil {
+		return "::1"
+	}
+	return "127.0.0.1"
+}
+
+// httpProbe GETs path on addr and returns the status code. Redirects are not followed: any
+// response proves the server is up, and the target may not be reachable from here.
+func httpProbe(ctx context.Context, addr, path string, timeout time.Duration) (int, error) {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr+path, nil)
+	if err != nil {
+		return 0, fmt.Errorf("path: %v", err)
+	}
+	hc := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,116 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/client"
+)
+
+// portDaemon fakes a daemon whose container c1 publishes 3000/tcp on hostPort (127.0.0.1) and
+// 9229/tcp on no host port.
+func portDaemon(t *testing.T, hostPort string) *client.Client {
+	t.Helper()
+	return newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1.43/containers/c1/json" {
+			http.Error(w, `{"message":"No such container"}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"Id":"c1","NetworkSettings":{"Ports":{"3000/tcp":[{"HostIp":"127.0.0.1","HostPort":%q}],"9229/tcp":null}}}`, hostPort)
+	}))
+}
+
+func TestCheckPortHTTP(t *testing.T) {
+	// Stands in for the server the container publishes on 127.0.0.1.
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			w.Write([]byte("ok"))
+		case "/old":
+			http.Redirect(w, r, "http://10.255.255.1/new", http.StatusMovedPermanently)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer app.Close()
+	_, hostPort, _ := net.SplitHostPort(strings.TrimPrefix(app.URL, "http://"))
+	cli := portDaemon(t, hostPort)
+
+	res := CheckPort(context.Background(), cli, CheckPortParams{ContainerID: "c1", ContainerPort: "3000", Path: "/health"})
+	if res.Error != "" || !res.Reachable || res.StatusCode != http.StatusOK {
+		t.Errorf("GET /health: %+v", res)
+	}
+	if res.HostAddress != "127.0.0.1:"+hostPort {
+		t.Errorf("host_address = %q", res.HostAddress)
+	}
+	if res := CheckPort(context.Background(), cli, CheckPortParams{ContainerID: "c1", ContainerPort: "3000/tcp", Path: "missing"}); !res.Reachable || res.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /missing: %+v", res)
+	}
+	if res := CheckPort(context.Background(), cli, CheckPortParams{ContainerID: "c1", ContainerPort: "3000", Path: "/old"}); !res.Reachable || res.StatusCode != http.StatusMovedPermanently {
+		t.Errorf("redirect followed or lost: %+v", res)
+	}
+	if res := CheckPort(context.Background(), cli, CheckPortParams{ContainerID: "c1", ContainerPort: "3000"}); !res.Reachable || res.StatusCode != 0 {
+		t.Errorf("TCP check: %+v", res)
+	}
+}
+
+func TestCheckPortUnreachable(t *testing.T) {
+	// A port that was just free: nothing listens on it.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, hostPort, _ := net.SplitHostPort(l.Addr().String())
+	l.Close()
+	cli := portDaemon(t, hostPort)
+
+	res := CheckPort(context.Background(), cli, CheckPortParams{ContainerID: "c1", ContainerPort: "3000", TimeoutSec: 2})
+	if res.Error != "" || res.Reachable || res.Reason == "" {
+		t.Errorf("closed port: %+v", res)
+	}
+}
+
+func TestCheckPortErrors(t *testing.T) {
+	cli := portDaemon(t, "8080")
+	for _, p := range []CheckPortParams{
+		{ContainerPort: "3000"},
+		{ContainerID: "c1"},
+		{ContainerID: "c1", ContainerPort: "53/udp"},
+		{ContainerID: "c1", ContainerPort: "http"},
+	} {
+		if res := CheckPort(context.Background(), cli, p); res.Error == "" {
+			t.Errorf("%+v: expected error", p)
+		}
+	}
+	if res := CheckPort(context.Background(), cli, CheckPortParams{ContainerID: "c1", ContainerPort: "9229"}); !strings.Contains(res.Error, "not published") {
+		t.Errorf("unpublished port: %+v", res)
+	}
+	if res := CheckPort(context.Background(), cli, CheckPortParams{ContainerID: "c1", ContainerPort: "4000"}); !strings.Contains(res.Error, "not published") {
+		t.Errorf("unknown port: %+v", res)
+	}
+	if res := CheckPort(context.Background(), cli, CheckPortParams{ContainerID: "nope", ContainerPort: "3000"}); !strings.Contains(res.Error, "No such container") {
+		t.Errorf("missing container: %+v", res)
+	}
+}
+
+func TestDialHost(t *testing.T) {
+	cases := []struct{ hostIP, daemon, want string }{
+		{"127.0.0.1", "unix:///var/run/docker.sock", "127.0.0.1"},
+		{"0.0.0.0", "npipe:////./pipe/docker_engine", "127.0.0.1"},
+		{"", "unix:///var/run/docker.sock", "127.0.0.1"},
+		{"::", "unix:///var/run/docker.sock", "::1"},
+		{"127.0.0.1", "tcp://build-box:2376", "build-box"},
+		{"10.0.0.5", "tcp://build-box:2376", "10.0.0.5"},
+	}
+	for _, c := range cases {
+		if got := dialHost(c.hostIP, c.daemon); got != c.want {
+			t.Errorf("dialHost(%q, %q) = %q, want %q", c.hostIP, c.daemon, got, c.want)
+		}
+	}
+}
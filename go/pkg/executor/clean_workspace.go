@@ -0,0 +1,113 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+)
+
+// copiedFilesPath lists, one absolute path per line, the code files execute_code_block has copied
+// into the container, so clean_workspace can remove exactly those. Like the transcript it lives
+// outside /workspace.
+const copiedFilesPath = "/var/adde/copied-files"
+
+// prepareCodeFile runs before fullPath is copied in for a run in workDir. With cleanWorkspace it
+// removes the code files earlier runs copied in (not fullPath, which is about to be overwritten),
+// and with cleanArtifacts everything else in workDir too. It then records fullPath in the manifest.
+// Recording is best effort: without clean_workspace a failure never fails the run.
+func prepareCodeFile(ctx context.Context, cli *client.Client, containerID, workDir, fullPath string, cleanWorkspace, cleanArtifacts bool) error {
+	if cleanArtifacts && !cleanWorkspace {
+		return fmt.Errorf("clean_artifacts requires clean_workspace")
+	}
+	copied, err := readCopiedFiles(ctx, cli, containerID)
+	if err != nil {
+		if cleanWorkspace {
+			return fmt.Errorf("clean_workspace: %v", err)
+		}
+		return nil
+	}
+	if cleanWorkspace {
+		var stale []string
+		for _, f := range copied {
+			if f != fullPath {
+				stale = append(stale, f)
+			}
+		}
+		if len(stale) > 0 {
+			if err := removeInContainer(ctx, cli, containerID, append([]string{"rm", "-f", "--"}, stale...)); err != nil {
+				return fmt.Errorf("clean_workspace: %v", err)
+			}
+		}
+		if cleanArtifacts {
+			if workDir == "/" {
+				return fmt.Errorf("clean_artifacts: refusing to clear / (set working_dir)")
+			}
+			// Everything directly in workDir except the last-run log get_container_logs reads.
+			cmd := []string{"find", workDir, "-mindepth", "1", "-maxdepth", "1", "!", "-name", lastRunPath, "-exec", "rm", "-rf", "--", "{}", "+"}
+			if err := removeInContainer(ctx, cli, containerID, cmd); err != nil {
+				return fmt.Errorf("clean_artifacts: %v", err)
+			}
+		}
+		copied = nil
+	}
+	for _, f := range copied {
+		if f == fullPath {
+			return nil
+		}
+	}
+	err = writeCopiedFiles(ctx, cli, containerID, append(copied, fullPath))
+	if err != nil && cleanWorkspace {
+		return fmt.Errorf("clean_workspace: %v", err)
+	}
+	return nil
+}
+
+// removeInContainer runs a removal command (argv, no shell) and turns a non-zero exit into an error.
+func removeInContainer(ctx context.Context, cli *client.Client, containerID string, cmd []string) error {
+	_, stderr, exitCode, _, err := runExec(ctx, cli, containerID, cmd, 30)
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("%s exited %d: %s", cmd[0], exitCode, strings.TrimSpace(stderr))
+	}
+	return nil
+}
+
+// readCopiedFiles returns the manifest entries; none when no run has written it yet.
+func readCopiedFiles(ctx context.Context, cli *client.Client, containerID string) ([]string, error) {
+	rc, _, err := cli.CopyFromContainer(ctx, containerID, copiedFilesPath)
+	if errdefs.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := readSingleFileFromTar(rc)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %v", copiedFilesPath, err)
+	}
+	var files []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// writeCopiedFiles replaces the manifest through the archive API, so the container needs no shell.
+func writeCopiedFiles(ctx context.Context, cli *client.Client, containerID string, files []string) error {
+	content := strings.Join(files, "\n") + "\n"
+	// Extracted at /, so the archive recreates /var/adde when it does not exist yet.
+	tarBuf, err := buildTarStream(strings.TrimPrefix(copiedFilesPath, "/"), content)
+	if err != nil {
+		return err
+	}
+	return cli.CopyToContainer(ctx, containerID, "/", tarBuf, types.CopyToContainerOptions{})
+}
@@ -0,0 +1,140 @@
+package executor
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// workspaceDaemon fakes a container whose manifest lists manifest. Every exec exits 0 with no output.
+// It returns the client and a func giving the calls so far: "exec <cmd>", "put <path> <entry>" or
+// "manifest <content>".
+func workspaceDaemon(t *testing.T, manifest string) (*client.Client, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var events []string
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/containers/c1/archive"):
+			if r.URL.Query().Get("path") != copiedFilesPath || manifest == "" {
+				http.Error(w, `{"message":"Could not find the file"}`, http.StatusNotFound)
+				return
+			}
+			tarBuf, _ := buildTarStream("copied-files", manifest)
+			w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString([]byte(`{"name":"copied-files"}`)))
+			w.Write(tarBuf.Bytes())
+		case r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/containers/c1/archive"):
+			tr := tar.NewReader(r.Body)
+			hdr, err := tr.Next()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if "/"+hdr.Name == copiedFilesPath {
+				data, _ := io.ReadAll(tr)
+				events = append(events, "manifest "+string(data))
+				return
+			}
+			events = append(events, "put "+r.URL.Query().Get("path")+" "+hdr.Name)
+		case strings.HasSuffix(r.URL.Path, "/containers/c1/exec"):
+			var cfg types.ExecConfig
+			json.NewDecoder(r.Body).Decode(&cfg)
+			events = append(events, "exec "+strings.Join(programCommand(cfg.Cmd), " "))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"Id":"e1"}`))
+		case strings.HasSuffix(r.URL.Path, "/exec/e1/start") && r.Header.Get("Upgrade") != "":
+			io.Copy(io.Discard, r.Body)
+			conn, rw, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			rw.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.multiplexed-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+			rw.Flush()
+			conn.Close()
+		case strings.HasSuffix(r.URL.Path, "/exec/e1/start"):
+			w.WriteHeader(http.StatusOK)
+		case strings.HasSuffix(r.URL.Path, "/exec/e1/json"):
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"ExitCode":0}`))
+		default:
+			http.Error(w, `{"message":"not implemented"}`, http.StatusNotImplemented)
+		}
+	}))
+	return cli, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), events...)
+	}
+}
+
+func TestExecuteCodeBlockCleanWorkspaceRemovesStaleFiles(t *testing.T) {
+	// A previous run copied helper.py (and main.py); the new main.py no longer has a helper.
+	cli, events := workspaceDaemon(t, "/workspace/helper.py\n/workspace/main.py\n")
+	res := ExecuteCodeBlock(context.Background(), cli, ExecuteCodeBlockParams{ContainerID: "c1", Filename: "main.py", CodeContent: "print(1)", CleanWorkspace: true})
+	if res.Error != "" {
+		t.Fatalf("unexpected error: %s", res.Error)
+	}
+	got := events()
+	want := []string{
+		"exec rm -f -- /workspace/helper.py",
+		"manifest /workspace/main.py\n",
+		"put /workspace main.py",
+		"exec python /workspace/main.py",
+	}
+	if len(got) < len(want) {
+		t.Fatalf("calls = %q, want them to start with %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("call %d = %q, want %q (all: %q)", i, got[i], want[i], got)
+		}
+	}
+}
+
+func TestExecuteCodeBlockRecordsCopiedFiles(t *testing.T) {
+	// Without clean_workspace nothing is removed, but the file joins the manifest for a later clean.
+	cli, events := workspaceDaemon(t, "/workspace/helper.py\n")
+	ExecuteCodeBlock(context.Background(), cli, ExecuteCodeBlockParams{ContainerID: "c1", Filename: "main.py", CodeContent: "print(1)"})
+	got := events()
+	if len(got) < 1 || got[0] != "manifest /workspace/helper.py\n/workspace/main.py\n" {
+		t.Errorf("calls = %q, want the manifest extended first", got)
+	}
+	for _, e := range got {
+		if strings.HasPrefix(e, "exec rm -f --") || strings.HasPrefix(e, "exec find") {
+			t.Errorf("removed files without clean_workspace: %q", e)
+		}
+	}
+}
+
+func TestExecuteCodeBlockCleanArtifacts(t *testing.T) {
+	cli, events := workspaceDaemon(t, "")
+	res := ExecuteCodeBlock(context.Background(), cli, ExecuteCodeBlockParams{ContainerID: "c1", Filename: "main.py", CodeContent: "print(1)", CleanWorkspace: true, CleanArtifacts: true})
+	if res.Error != "" {
+		t.Fatalf("unexpected error: %s", res.Error)
+	}
+	if got := events(); len(got) == 0 || got[0] != "exec find /workspace -mindepth 1 -maxdepth 1 ! -name .adde_last_run.json -exec rm -rf -- {} +" {
+		t.Errorf("calls = %q, want the working dir cleared first", got)
+	}
+
+	res = ExecuteCodeBlock(context.Background(), cli, ExecuteCodeBlockParams{ContainerID: "c1", Filename: "main.py", CodeContent: "print(1)", CleanArtifacts: true})
+	if !strings.Contains(res.Error, "requires clean_workspace") {
+		t.Errorf("clean_artifacts alone: error = %q", res.Error)
+	}
+	res = ExecuteCodeBlock(context.Background(), cli, ExecuteCodeBlockParams{ContainerID: "c1", Filename: "main.py", CodeContent: "print(1)", CleanWorkspace: true, CleanArtifacts: true, WorkingDir: "/"})
+	if !strings.Contains(res.Error, "refusing to clear /") {
+		t.Errorf("clean_artifacts in /: error = %q", res.Error)
+	}
+}
@@ -25,3 +25,11 @@ func CleanupEnv(ctx context.Context, cli *client.Client, p CleanupEnvParams) Cle
 	}
 	return CleanupEnvResult{OK: true}
 }
+
+// forceRemoveContainer removes a container using a fresh context so rollback still
+// runs when the caller's context has already been cancelled (e.g. Ctrl-C in the CLI).
+func forceRemoveContainer(cli *client.Client, containerID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_ = cli.ContainerRemove(ctx, containerID, types.ContainerRemoveOptions{Force: true})
+}
@@ -0,0 +1,42 @@
+package executor
+
+import (
+	"context"
+	"sort"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// ContainerDiff lists the paths added, changed and deleted in the container's writable layer since it
+// was created (docker diff). /workspace is a bind mount, not part of the layer, so changes there are
+// not listed; they are visible on the host directly.
+func ContainerDiff(ctx context.Context, cli *client.Client, p ContainerDiffParams) ContainerDiffResult {
+	if p.ContainerID == "" {
+		return ContainerDiffResult{Error: "container_id is required"}
+	}
+	changes, err := cli.ContainerDiff(ctx, p.ContainerID)
+	if err != nil {
+		return ContainerDiffResult{Error: err.Error()}
+	}
+	return containerDiff(changes)
+}
+
+// containerDiff sorts the daemon's changes into the result's lists, each sorted by path.
+func containerDiff(changes []container.FilesystemChange) ContainerDiffResult {
+	res := ContainerDiffResult{Added: []string{}, Changed: []string{}, Deleted: []string{}}
+	for _, c := range changes {
+		switch c.Kind {
+		case container.ChangeAdd:
+			res.Added = append(res.Added, c.Path)
+		case container.ChangeModify:
+			res.Changed = append(res.Changed, c.Path)
+		case container.ChangeDelete:
+			res.Deleted = append(res.Deleted, c.Path)
+		}
+	}
+	sort.Strings(res.Added)
+	sort.Strings(res.Changed)
+	sort.Strings(res.Deleted)
+	return res
+}
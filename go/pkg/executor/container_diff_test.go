@@ -0,0 +1,42 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestContainerDiff(t *testing.T) {
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1.43/containers/c1/changes" {
+			http.Error(w, `{"message":"No such container"}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		// Kind: 0 = modified, 1 = added, 2 = deleted.
+		w.Write([]byte(`[{"Path":"/tmp","Kind":0},{"Path":"/tmp/out.csv","Kind":1},{"Path":"/etc","Kind":0},` +
+			`{"Path":"/etc/hosts.bak","Kind":2},{"Path":"/root/.cache","Kind":1},{"Path":"/etc/app.conf","Kind":0}]`))
+	}))
+
+	res := ContainerDiff(context.Background(), cli, ContainerDiffParams{ContainerID: "c1"})
+	if res.Error != "" {
+		t.Fatalf("unexpected error: %s", res.Error)
+	}
+	if got := strings.Join(res.Added, ","); got != "/root/.cache,/tmp/out.csv" {
+		t.Errorf("added = %s", got)
+	}
+	if got := strings.Join(res.Changed, ","); got != "/etc,/etc/app.conf,/tmp" {
+		t.Errorf("changed = %s", got)
+	}
+	if got := strings.Join(res.Deleted, ","); got != "/etc/hosts.bak" {
+		t.Errorf("deleted = %s", got)
+	}
+
+	if res := ContainerDiff(context.Background(), cli, ContainerDiffParams{ContainerID: "nope"}); !strings.Contains(res.Error, "No such container") {
+		t.Errorf("missing container error not surfaced: %+v", res)
+	}
+	if res := ContainerDiff(context.Background(), cli, ContainerDiffParams{}); res.Error == "" {
+		t.Error("expected error without container_id")
+	}
+}
@@ -0,0 +1,48 @@
+package executor
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/client"
+)
+
+// GetContainerEnv returns the env the container was created with (image ENV plus env_vars), as a map.
+// With redact, values whose names look secret (FOO_TOKEN, DB_PASSWORD, API_KEY, ...) are masked.
+// Variables a program exports at runtime are not visible here.
+func GetContainerEnv(ctx context.Context, cli *client.Client, p GetContainerEnvParams) GetContainerEnvResult {
+	if p.ContainerID == "" {
+		return GetContainerEnvResult{Error: "container_id is required"}
+	}
+	info, err := cli.ContainerInspect(ctx, p.ContainerID)
+	if err != nil {
+		return GetContainerEnvResult{Error: err.Error()}
+	}
+	if info.Config == nil {
+		return GetContainerEnvResult{Env: map[string]string{}}
+	}
+	return containerEnv(info.Config.Env, p.Redact)
+}
+
+// containerEnv turns a KEY=VALUE list into the result map; later duplicates win, as in the kernel's env.
+func containerEnv(env []string, redact bool) GetContainerEnvResult {
+	res := GetContainerEnvResult{Env: make(map[string]string, len(env))}
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		if name == "" {
+			continue
+		}
+		res.Env[name] = value
+	}
+	if redact {
+		for name := range res.Env {
+			if secretKeyRe.MatchString(name) {
+				res.Env[name] = redactedValue
+				res.Redacted = append(res.Redacted, name)
+			}
+		}
+		sort.Strings(res.Redacted)
+	}
+	return res
+}
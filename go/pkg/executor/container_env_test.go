@@ -0,0 +1,51 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGetContainerEnv(t *testing.T) {
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1.43/containers/c1/json" {
+			http.Error(w, `{"message":"No such container"}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"Id":"c1","Config":{"Env":["PATH=/usr/bin:/bin","GITHUB_TOKEN=ghp_x","DB_PASSWORD=p=w","MODE=dev","MODE=test","EMPTY="]}}`))
+	}))
+
+	res := GetContainerEnv(context.Background(), cli, GetContainerEnvParams{ContainerID: "c1"})
+	if res.Error != "" {
+		t.Fatalf("unexpected error: %s", res.Error)
+	}
+	want := map[string]string{"PATH": "/usr/bin:/bin", "GITHUB_TOKEN": "ghp_x", "DB_PASSWORD": "p=w", "MODE": "test", "EMPTY": ""}
+	for k, v := range want {
+		if got, ok := res.Env[k]; !ok || got != v {
+			t.Errorf("env[%s] = %q, want %q", k, got, v)
+		}
+	}
+	if len(res.Env) != len(want) || len(res.Redacted) != 0 {
+		t.Errorf("env = %v, redacted = %v", res.Env, res.Redacted)
+	}
+
+	res = GetContainerEnv(context.Background(), cli, GetContainerEnvParams{ContainerID: "c1", Redact: true})
+	if res.Env["GITHUB_TOKEN"] != redactedValue || res.Env["DB_PASSWORD"] != redactedValue {
+		t.Errorf("secrets not redacted: %v", res.Env)
+	}
+	if res.Env["PATH"] != "/usr/bin:/bin" || res.Env["MODE"] != "test" {
+		t.Errorf("non-secret values changed: %v", res.Env)
+	}
+	if strings.Join(res.Redacted, ",") != "DB_PASSWORD,GITHUB_TOKEN" {
+		t.Errorf("redacted = %v", res.Redacted)
+	}
+
+	if res := GetContainerEnv(context.Background(), cli, GetContainerEnvParams{ContainerID: "nope"}); !strings.Contains(res.Error, "No such container") {
+		t.Errorf("missing container error not surfaced: %+v", res)
+	}
+	if res := GetContainerEnv(context.Background(), cli, GetContainerEnvParams{}); res.Error == "" {
+		t.Error("expected error without container_id")
+	}
+}
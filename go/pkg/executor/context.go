@@ -21,6 +21,17 @@ node_modules
 `
 )
 
+// Base images of the Dockerfile PrepareBuildContext generates. Python defaults to a glibc (Debian slim)
+// image: on musl-based alpine, pip finds no prebuilt wheels for many packages and falls back to
+// compiling them, which fails without a toolchain.
+const (
+	DefaultPythonBaseImage = "python:3-slim"
+	DefaultNodeBaseImage   = "node:20-alpine"
+)
+
+// buildDirPrefix is the temp-dir prefix PrepareBuildContext uses for build contexts.
+const buildDirPrefix = "adde-build-"
+
 // PrepareBuildContext stages files into a temporary directory for Docker build.
 // If no Dockerfile is provided but requirements.txt or package.json exists, injects a standard template.
 // Writes a .dockerignore if not already in files to prevent bloat.
@@ -28,7 +39,15 @@ func PrepareBuildContext(p PrepareBuildContextParams) PrepareBuildContextResult
 	if len(p.Files) == 0 {
 		return PrepareBuildContextResult{Error: "files map is required and must not be empty"}
 	}
-	dir, err := os.MkdirTemp("", "adde-build-")
+	pythonBase, err := templateBaseImage(p.PythonBaseImage, DefaultPythonBaseImage)
+	if err != nil {
+		return PrepareBuildContextResult{Error: fmt.Sprintf("python_base_image: %v", err)}
+	}
+	nodeBase, err := templateBaseImage(p.NodeBaseImage, DefaultNodeBaseImage)
+	if err != nil {
+		return PrepareBuildContextResult{Error: fmt.Sprintf("node_base_image: %v", err)}
+	}
+	dir, err := os.MkdirTemp(TempRoot(), buildDirPrefix)
 	if err != nil {
 		return PrepareBuildContextResult{Error: fmt.Sprintf("failed to create temp dir: %v", err)}
 	}
@@ -56,6 +75,11 @@ func PrepareBuildContext(p PrepareBuildContextParams) PrepareBuildContextResult
 
 	// Write each file (support nested paths)
 	for path, content := range p.Files {
+		data, err := decodeContent(content, p.ContentEncoding)
+		if err != nil {
+			os.RemoveAll(absDir)
+			return PrepareBuildContextResult{Error: fmt.Sprintf("file %q: %v", path, err)}
+		}
 		path = filepath.Clean(path)
 		if path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
 			continue // skip path traversal
@@ -65,15 +89,26 @@ func PrepareBuildContext(p PrepareBuildContextParams) PrepareBuildContextResult
 			os.RemoveAll(absDir)
 			return PrepareBuildContextResult{Error: fmt.Sprintf("failed to create dir for %q: %v", path, err)}
 		}
-		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		if err := os.WriteFile(full, data, 0644); err != nil {
 			os.RemoveAll(absDir)
 			return PrepareBuildContextResult{Error: fmt.Sprintf("failed to write %q: %v", path, err)}
 		}
 	}
 
-	// Auto-generate .dockerignore if not in files
-	if _, ok := p.Files[".dockerignore"]; !ok {
-		if err := os.WriteFile(filepath.Join(absDir, ".dockerignore"), []byte(defaultDockerignore), 0644); err != nil {
+	// Auto-generate .dockerignore if not in files; extra_dockerignore lines go after either one
+	ignorePath := filepath.Join(absDir, ".dockerignore")
+	_, userIgnore := p.Files[".dockerignore"]
+	if !userIgnore || len(p.ExtraDockerignore) > 0 {
+		ignore := defaultDockerignore
+		if userIgnore {
+			data, err := os.ReadFile(ignorePath)
+			if err != nil {
+				os.RemoveAll(absDir)
+				return PrepareBuildContextResult{Error: fmt.Sprintf("failed to read .dockerignore: %v", err)}
+			}
+			ignore = string(data)
+		}
+		if err := os.WriteFile(ignorePath, []byte(appendDockerignore(ignore, p.ExtraDockerignore)), 0644); err != nil {
 			os.RemoveAll(absDir)
 			return PrepareBuildContextResult{Error: fmt.Sprintf("failed to write .dockerignore: %v", err)}
 		}
@@ -81,23 +116,58 @@ func PrepareBuildContext(p PrepareBuildContextParams) PrepareBuildContextResult
 
 	// Inject standard Dockerfile if codebase has requirements.txt or package.json but no Dockerfile
 	if !hasDockerfile && (hasRequirementsTxt || hasPackageJson) {
-		dockerfile := standardTemplateDockerfile(hasRequirementsTxt, hasPackageJson)
+		dockerfile := standardTemplateDockerfile(hasRequirementsTxt, hasPackageJson, pythonBase, nodeBase)
 		if err := os.WriteFile(filepath.Join(absDir, "Dockerfile"), []byte(dockerfile), 0644); err != nil {
 			os.RemoveAll(absDir)
 			return PrepareBuildContextResult{Error: fmt.Sprintf("failed to write generated Dockerfile: %v", err)}
 		}
 	}
 
-	return PrepareBuildContextResult{ContextID: absDir}
+	hash, _, err := contextContentHash(absDir)
+	if err != nil {
+		os.RemoveAll(absDir)
+		return PrepareBuildContextResult{Error: fmt.Sprintf("failed to hash context: %v", err)}
+	}
+	return PrepareBuildContextResult{ContextID: absDir, ContentHash: hash}
+}
+
+// appendDockerignore adds one line per non-blank pattern to base, after a newline if base lacks one.
+func appendDockerignore(base string, extra []string) string {
+	var b strings.Builder
+	b.WriteString(base)
+	for _, pattern := range extra {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteByte('\n')
+		}
+		b.WriteString(pattern)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// templateBaseImage returns image, or def when it is empty, after checking it is a valid reference.
+func templateBaseImage(image, def string) (string, error) {
+	image = strings.TrimSpace(image)
+	if image == "" {
+		return def, nil
+	}
+	if _, err := validateImageRef(image); err != nil {
+		return "", err
+	}
+	return image, nil
 }
 
-func standardTemplateDockerfile(python, node bool) string {
+func standardTemplateDockerfile(python, node bool, pythonBase, nodeBase string) string {
 	// Prefer Python if both; otherwise Node; otherwise minimal Alpine.
 	if python {
-		return "FROM python:3-alpine\nWORKDIR /app\nCOPY requirements.txt .\nRUN pip install --no-cache-dir -r requirements.txt\nCOPY . .\n"
+		return "FROM " + pythonBase + "\nWORKDIR /app\nCOPY requirements.txt .\nRUN pip install --no-cache-dir -r requirements.txt\nCOPY . .\n"
 	}
 	if node {
-		return "FROM node:20-alpine\nWORKDIR /app\nCOPY package.json .\nRUN npm install\nCOPY . .\n"
+		return "FROM " + nodeBase + "\nWORKDIR /app\nCOPY package.json .\nRUN npm install\nCOPY . .\n"
 	}
 	return "FROM alpine:latest\nWORKDIR /app\nCOPY . .\n"
 }
@@ -0,0 +1,220 @@
+package executor
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// HashBuildContext returns the content hash of a build context directory, as prepare_build_context
+// reports it, so an agent can reuse an existing agent-env: tag when nothing changed.
+func HashBuildContext(p HashBuildContextParams) HashBuildContextResult {
+	if p.ContextID == "" {
+		return HashBuildContextResult{Error: "context_id is required"}
+	}
+	dir := filepath.Clean(p.ContextID)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return HashBuildContextResult{Error: fmt.Sprintf("context_id %q is not a directory", p.ContextID)}
+	}
+	hash, files, err := contextContentHash(dir)
+	if err != nil {
+		return HashBuildContextResult{Error: err.Error()}
+	}
+	return HashBuildContextResult{ContentHash: hash, Files: files}
+}
+
+// contextContentHash is "sha256:<hex>" over the sorted relative paths and contents of the regular
+// files the daemon would receive, i.e. excluding .dockerignore matches (Dockerfile and .dockerignore
+// always count, as the daemon always reads them). Modes and timestamps are ignored.
+func contextContentHash(dir string) (string, int, error) {
+	ignore, err := readDockerignore(filepath.Join(dir, ".dockerignore"))
+	if err != nil {
+		return "", 0, err
+	}
+	var files []string
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel != "Dockerfile" && rel != ".dockerignore" && ignore.excludes(rel) {
+			if info.IsDir() && !ignore.hasExceptions() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	sort.Strings(files)
+	h := sha256.New()
+	for _, rel := range files {
+		f, err := os.Open(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err != nil {
+			return "", 0, err
+		}
+		fh := sha256.New()
+		_, err = io.Copy(fh, f)
+		f.Close()
+		if err != nil {
+			return "", 0, err
+		}
+		// One line per file; paths cannot contain NUL, so entries cannot run into each other.
+		fmt.Fprintf(h, "%s\x00%x\n", rel, fh.Sum(nil))
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), len(files), nil
+}
+
+// hashTagLength is how many hex digits of the hash go into a hash_tag tag (as in short image IDs).
+const hashTagLength = 12
+
+// contextHashTag is "agent-env:<hash prefix>" for dir. Build args change the image, so when any are
+// set they are folded into the hash; without them the prefix is that of the context's content_hash.
+func contextHashTag(dir string, buildArgs map[string]string) (string, error) {
+	hash, _, err := contextContentHash(dir)
+	if err != nil {
+		return "", err
+	}
+	sum := strings.TrimPrefix(hash, "sha256:")
+	if len(buildArgs) > 0 {
+		keys := make([]string, 0, len(buildArgs))
+		for k := range buildArgs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		h := sha256.New()
+		io.WriteString(h, hash)
+		for _, k := range keys {
+			fmt.Fprintf(h, "\n%s=%s\x00", k, buildArgs[k])
+		}
+		sum = hex.EncodeToString(h.Sum(nil))
+	}
+	return "agent-env:" + sum[:hashTagLength], nil
+}
+
+// dockerignore holds .dockerignore rules in order; the last matching rule decides, "!" re-includes.
+type dockerignore []ignoreRule
+
+type ignoreRule struct {
+	re     *regexp.Regexp
+	negate bool
+}
+
+// readDockerignore parses path; a missing file means nothing is ignored.
+func readDockerignore(path string) (dockerignore, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var rules dockerignore
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		negate := strings.HasPrefix(line, "!")
+		pattern := filepath.ToSlash(filepath.Clean(strings.TrimPrefix(line, "!")))
+		pattern = strings.TrimPrefix(pattern, "/")
+		re, err := ignorePatternRegexp(pattern)
+		if err != nil {
+			return nil, fmt.Errorf(".dockerignore: invalid pattern %q: %v", line, err)
+		}
+		rules = append(rules, ignoreRule{re: re, negate: negate})
+	}
+	return rules, sc.Err()
+}
+
+// excludes reports whether rel (slash-separated) is ignored. A rule matching a parent directory
+// matches everything below it, as in Docker.
+func (d dockerignore) excludes(rel string) bool {
+	excluded := false
+	for _, r := range d {
+		if r.matches(rel) {
+			excluded = !r.negate
+		}
+	}
+	return excluded
+}
+
+func (d dockerignore) hasExceptions() bool {
+	for _, r := range d {
+		if r.negate {
+			return true
+		}
+	}
+	return false
+}
+
+func (r ignoreRule) matches(rel string) bool {
+	for p := rel; ; {
+		if r.re.MatchString(p) {
+			return true
+		}
+		i := strings.LastIndexByte(p, '/')
+		if i < 0 {
+			return false
+		}
+		p = p[:i]
+	}
+}
+
+// ignorePatternRegexp translates a Docker ignore pattern: "**" spans directories, "*" and "?" stay
+// within one path element, "[...]" is a character class and "\" escapes.
+func ignorePatternRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '*' && strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			j := strings.IndexByte(pattern[i:], ']')
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated character class")
+			}
+			class := pattern[i+1 : i+j]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += j
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
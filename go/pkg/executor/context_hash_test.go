@@ -0,0 +1,124 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func prepareForHash(t *testing.T, files map[string]string) PrepareBuildContextResult {
+	t.Helper()
+	res := PrepareBuildContext(PrepareBuildContextParams{Files: files})
+	if res.Error != "" {
+		t.Fatalf("PrepareBuildContext: %s", res.Error)
+	}
+	t.Cleanup(func() { os.RemoveAll(res.ContextID) })
+	return res
+}
+
+func TestBuildContextContentHash(t *testing.T) {
+	files := map[string]string{
+		"Dockerfile":  "FROM busybox\nCOPY . /app\n",
+		"main.py":     "print(1)\n",
+		"pkg/util.py": "X = 1\n",
+	}
+	a := prepareForHash(t, files)
+	b := prepareForHash(t, files)
+	if a.ContentHash == "" || a.ContentHash != b.ContentHash {
+		t.Fatalf("same files, different hashes: %q vs %q", a.ContentHash, b.ContentHash)
+	}
+	if got := HashBuildContext(HashBuildContextParams{ContextID: a.ContextID}); got.Error != "" || got.ContentHash != a.ContentHash {
+		t.Fatalf("HashBuildContext = %+v, want %s", got, a.ContentHash)
+	}
+
+	// Ignored files (the default .dockerignore excludes *.md, *.log, __pycache__) do not count.
+	for name, content := range map[string]string{"README.md": "docs", "run.log": "x", "__pycache__/m.pyc": "y"} {
+		path := filepath.Join(a.ContextID, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := HashBuildContext(HashBuildContextParams{ContextID: a.ContextID}); got.ContentHash != a.ContentHash {
+		t.Errorf("ignored files changed the hash: %s", got.ContentHash)
+	}
+
+	changed := map[string]string{}
+	for k, v := range files {
+		changed[k] = v
+	}
+	changed["pkg/util.py"] = "X = 2\n"
+	if c := prepareForHash(t, changed); c.ContentHash == a.ContentHash {
+		t.Error("changed file content kept the same hash")
+	}
+	delete(changed, "pkg/util.py")
+	changed["pkg/other.py"] = "X = 1\n"
+	if c := prepareForHash(t, changed); c.ContentHash == a.ContentHash {
+		t.Error("renamed file kept the same hash")
+	}
+}
+
+func TestDockerignoreExcludes(t *testing.T) {
+	dir := t.TempDir()
+	rules := "# comment\n*.md\n!KEEP.md\n**/*.tmp\nbuild\n/docs/*.txt\n"
+	if err := os.WriteFile(filepath.Join(dir, ".dockerignore"), []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ignore, err := readDockerignore(filepath.Join(dir, ".dockerignore"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]bool{
+		"README.md":       true,
+		"KEEP.md":         false,
+		"sub/README.md":   false, // *.md only matches at the root
+		"a/b/c.tmp":       true,
+		"x.tmp":           true,
+		"build":           true,
+		"build/out/bin":   true,
+		"builder/main.go": false,
+		"docs/a.txt":      true,
+		"docs/sub/a.txt":  false,
+		"main.py":         false,
+	} {
+		if got := ignore.excludes(path); got != want {
+			t.Errorf("excludes(%q) = %v, want %v", path, got, want)
+		}
+	}
+	if got := HashBuildContext(HashBuildContextParams{ContextID: filepath.Join(dir, "missing")}); got.Error == "" {
+		t.Error("expected error for a missing context directory")
+	}
+}
+
+func TestContextHashTag(t *testing.T) {
+	files := map[string]string{"Dockerfile": "FROM busybox\nARG V\n", "main.py": "print(1)\n"}
+	a := prepareForHash(t, files)
+	b := prepareForHash(t, files)
+	tagA, err := contextHashTag(a.ContextID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagB, _ := contextHashTag(b.ContextID, nil)
+	if tagA != tagB {
+		t.Fatalf("identical contexts got tags %q and %q", tagA, tagB)
+	}
+	if want := "agent-env:" + strings.TrimPrefix(a.ContentHash, "sha256:")[:hashTagLength]; tagA != want {
+		t.Errorf("tag = %q, want %q", tagA, want)
+	}
+	if _, err := validateImageRef(tagA); err != nil {
+		t.Errorf("hash tag is not a valid reference: %v", err)
+	}
+
+	withArgs, _ := contextHashTag(a.ContextID, map[string]string{"V": "1"})
+	sameArgs, _ := contextHashTag(b.ContextID, map[string]string{"V": "1"})
+	otherArgs, _ := contextHashTag(b.ContextID, map[string]string{"V": "2"})
+	if withArgs == tagA || withArgs != sameArgs || withArgs == otherArgs {
+		t.Errorf("build args: got %q, %q, %q (no args %q)", withArgs, sameArgs, otherArgs, tagA)
+	}
+
+	files["main.py"] = "print(2)\n"
+	if tagC, _ := contextHashTag(prepareForHash(t, files).ContextID, nil); tagC == tagA {
+		t.Error("changed context kept the same tag")
+	}
+}
@@ -0,0 +1,122 @@
+package executor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func gzipBase64(t *testing.T, s string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestPrepareBuildContextGzipContent(t *testing.T) {
+	data := strings.Repeat("x,y,z\n", 1000)
+	res := PrepareBuildContext(PrepareBuildContextParams{
+		Files:           map[string]string{"data/big.csv": gzipBase64(t, data)},
+		ContentEncoding: ContentEncodingGzipBase64,
+	})
+	if res.Error != "" {
+		t.Fatalf("PrepareBuildContext: %s", res.Error)
+	}
+	defer os.RemoveAll(res.ContextID)
+	got, err := os.ReadFile(filepath.Join(res.ContextID, "data", "big.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != data {
+		t.Errorf("decompressed content mismatch: got %d bytes, want %d", len(got), len(data))
+	}
+}
+
+func TestPrepareBuildContextExtraDockerignore(t *testing.T) {
+	res := PrepareBuildContext(PrepareBuildContextParams{
+		Files:             map[string]string{"main.py": "print(1)\n"},
+		ExtraDockerignore: []string{"dist/", " *.tmp ", ""},
+	})
+	if res.Error != "" {
+		t.Fatalf("PrepareBuildContext: %s", res.Error)
+	}
+	defer os.RemoveAll(res.ContextID)
+	got, err := os.ReadFile(filepath.Join(res.ContextID, ".dockerignore"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := defaultDockerignore + "dist/\n*.tmp\n"; string(got) != want {
+		t.Errorf(".dockerignore = %q, want %q", got, want)
+	}
+}
+
+func TestPrepareBuildContextExtraDockerignoreAppendsToUserFile(t *testing.T) {
+	res := PrepareBuildContext(PrepareBuildContextParams{
+		Files:             map[string]string{"main.py": "print(1)\n", ".dockerignore": "secrets"},
+		ExtraDockerignore: []string{"dist/"},
+	})
+	if res.Error != "" {
+		t.Fatalf("PrepareBuildContext: %s", res.Error)
+	}
+	defer os.RemoveAll(res.ContextID)
+	got, err := os.ReadFile(filepath.Join(res.ContextID, ".dockerignore"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "secrets\ndist/\n"; string(got) != want {
+		t.Errorf(".dockerignore = %q, want %q", got, want)
+	}
+}
+
+func TestPrepareBuildContextTemplateBaseImage(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		params PrepareBuildContextParams
+		want   string
+	}{
+		{"python default", PrepareBuildContextParams{Files: map[string]string{"requirements.txt": "numpy\n"}}, "FROM " + DefaultPythonBaseImage + "\n"},
+		{"python custom", PrepareBuildContextParams{Files: map[string]string{"requirements.txt": "numpy\n"}, PythonBaseImage: "python:3.12-bookworm"}, "FROM python:3.12-bookworm\n"},
+		{"node custom", PrepareBuildContextParams{Files: map[string]string{"package.json": "{}"}, NodeBaseImage: " node:22-slim "}, "FROM node:22-slim\n"},
+	} {
+		res := PrepareBuildContext(tc.params)
+		if res.Error != "" {
+			t.Fatalf("%s: %s", tc.name, res.Error)
+		}
+		got, err := os.ReadFile(filepath.Join(res.ContextID, "Dockerfile"))
+		os.RemoveAll(res.ContextID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(got), tc.want) {
+			t.Errorf("%s: Dockerfile = %q, want it to start with %q", tc.name, got, tc.want)
+		}
+	}
+
+	res := PrepareBuildContext(PrepareBuildContextParams{Files: map[string]string{"requirements.txt": ""}, PythonBaseImage: "python:3\nRUN id"})
+	if !strings.Contains(res.Error, ErrCodeInvalidImageRef) || res.ContextID != "" {
+		t.Errorf("invalid base image: %+v", res)
+	}
+}
+
+func TestDecodeContentRejectsBadGzip(t *testing.T) {
+	if _, err := decodeContent("not base64!", ContentEncodingGzipBase64); err == nil || !strings.Contains(err.Error(), "base64") {
+		t.Errorf("bad base64: %v", err)
+	}
+	plain := base64.StdEncoding.EncodeToString([]byte("not gzip"))
+	if _, err := decodeContent(plain, ContentEncodingGzipBase64); err == nil || !strings.Contains(err.Error(), "gzip") {
+		t.Errorf("bad gzip: %v", err)
+	}
+	if _, err := decodeContent("x", "brotli"); err == nil {
+		t.Error("unknown encoding: expected error")
+	}
+}
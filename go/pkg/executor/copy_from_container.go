@@ -0,0 +1,133 @@
+package executor
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+)
+
+// MaxCopyFromContainerBytes caps the file copy_from_container returns; the base64 content travels in
+// the JSON result, so larger outputs belong on the /workspace mount instead.
+const MaxCopyFromContainerBytes = 64 * 1024 * 1024
+
+// CopyFromContainer returns one file from the container, base64-encoded, e.g. a plot or CSV a program
+// run by execute_code_block wrote; with AsTar it returns a file or directory as a tar archive instead,
+// and with DestDir it extracts one onto the host. Relative paths resolve against /workspace.
+func CopyFromContainer(ctx context.Context, cli *client.Client, p CopyFromContainerParams) CopyFromContainerResult {
+	if p.ContainerID == "" {
+		return CopyFromContainerResult{Error: "container_id is required"}
+	}
+	if strings.TrimSpace(p.Path) == "" {
+		return CopyFromContainerResult{Error: "path is required"}
+	}
+	if strings.ContainsRune(p.Path, 0) {
+		return CopyFromContainerResult{Error: "path contains a NUL byte"}
+	}
+	if p.Gzip && !p.AsTar {
+		return CopyFromContainerResult{Error: "gzip requires as_tar"}
+	}
+	if p.AsTar && p.DestDir != "" {
+		return CopyFromContainerResult{Error: "as_tar and dest_dir are mutually exclusive"}
+	}
+	file := p.Path
+	if !path.IsAbs(file) {
+		file = path.Join(WorkspacePathInsideContainer, file)
+	}
+	rc, stat, err := cli.CopyFromContainer(ctx, p.ContainerID, file)
+	if errdefs.IsNotFound(err) {
+		return CopyFromContainerResult{Error: fmt.Sprintf("%s does not exist in container %s", file, p.ContainerID)}
+	}
+	if err != nil {
+		return CopyFromContainerResult{Error: err.Error()}
+	}
+	defer rc.Close()
+	if p.DestDir != "" {
+		extracted, err := safeExtractTar(rc, p.DestDir)
+		if err != nil {
+			return CopyFromContainerResult{Extracted: extracted, Error: fmt.Sprintf("extract %s into %s: %v", file, p.DestDir, err)}
+		}
+		return CopyFromContainerResult{Extracted: extracted}
+	}
+	if p.AsTar {
+		return archiveResult(rc, file, p.Gzip)
+	}
+	if stat.Mode.IsDir() {
+		return CopyFromContainerResult{Error: fmt.Sprintf("%s is a directory; set as_tar to copy it as an archive", file)}
+	}
+	if stat.Mode&os.ModeSymlink != 0 {
+		// The archive holds the link itself, not what it points to.
+		return CopyFromContainerResult{Error: fmt.Sprintf("%s is a symlink to %s; copy the target instead", file, stat.LinkTarget)}
+	}
+	if stat.Size > MaxCopyFromContainerBytes {
+		return CopyFromContainerResult{Error: fmt.Sprintf("%s is %d bytes, over the %d byte limit", file, stat.Size, MaxCopyFromContainerBytes)}
+	}
+	data, err := readSingleFileFromTar(rc)
+	if err != nil {
+		return CopyFromContainerResult{Error: fmt.Sprintf("read %s: %v", file, err)}
+	}
+	return CopyFromContainerResult{Content: base64.StdEncoding.EncodeToString(data), Size: int64(len(data))}
+}
+
+// archiveResult reads the daemon's tar of file (at most MaxCopyFromContainerBytes), lists its entries
+// and returns it, gzipped if asked.
+func archiveResult(rc io.Reader, file string, gz bool) CopyFromContainerResult {
+	raw, err := io.ReadAll(io.LimitReader(rc, MaxCopyFromContainerBytes+1))
+	if err != nil {
+		return CopyFromContainerResult{Error: fmt.Sprintf("read %s: %v", file, err)}
+	}
+	if len(raw) > MaxCopyFromContainerBytes {
+		return CopyFromContainerResult{Error: fmt.Sprintf("archive of %s is over the %d byte limit", file, MaxCopyFromContainerBytes)}
+	}
+	manifest, err := tarManifest(bytes.NewReader(raw))
+	if err != nil {
+		return CopyFromContainerResult{Error: fmt.Sprintf("read %s: %v", file, err)}
+	}
+	if gz {
+		zr, err := gzipContext(bytes.NewReader(raw))
+		if err == nil {
+			raw, err = io.ReadAll(zr)
+		}
+		if err != nil {
+			return CopyFromContainerResult{Error: fmt.Sprintf("compress %s: %v", file, err)}
+		}
+	}
+	return CopyFromContainerResult{Content: base64.StdEncoding.EncodeToString(raw), Size: int64(len(raw)), Manifest: manifest}
+}
+
+// tarManifest lists the entries of a tar stream.
+func tarManifest(r io.Reader) ([]TarEntry, error) {
+	var entries []TarEntry
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		e := TarEntry{Name: hdr.Name}
+		switch hdr.Typeflag {
+		case tar.TypeReg, tar.TypeRegA:
+			e.Type, e.Size = "file", hdr.Size
+		case tar.TypeDir:
+			e.Type = "dir"
+		case tar.TypeSymlink:
+			e.Type, e.LinkTarget = "symlink", hdr.Linkname
+		case tar.TypeLink:
+			e.Type, e.LinkTarget = "hardlink", hdr.Linkname
+		default:
+			e.Type = "other"
+		}
+		entries = append(entries, e)
+	}
+}
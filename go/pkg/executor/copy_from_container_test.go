@@ -0,0 +1,174 @@
+package executor
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCopyFromContainer(t *testing.T) {
+	const csv = "a,b\n1,2\n"
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/containers/c1/archive") {
+			http.Error(w, `{"message":"not implemented"}`, http.StatusNotImplemented)
+			return
+		}
+		var stat string
+		switch r.URL.Query().Get("path") {
+		case "/workspace/out/result.csv":
+			stat = `{"name":"result.csv","size":8,"mode":420}`
+		case "/workspace/out":
+			stat = `{"name":"out","size":4096,"mode":2147484141}` // os.ModeDir|0755
+		case "/workspace/latest.csv":
+			stat = `{"name":"latest.csv","mode":134218239,"linkTarget":"/workspace/out/result.csv"}` // os.ModeSymlink|0777
+		default:
+			http.Error(w, `{"message":"Could not find the file"}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString([]byte(stat)))
+		tarBuf, _ := buildTarStream("result.csv", csv)
+		w.Write(tarBuf.Bytes())
+	}))
+	ctx := context.Background()
+
+	// Relative paths resolve against /workspace.
+	res := CopyFromContainer(ctx, cli, CopyFromContainerParams{ContainerID: "c1", Path: "out/result.csv"})
+	if res.Error != "" {
+		t.Fatalf("unexpected error: %s", res.Error)
+	}
+	data, err := base64.StdEncoding.DecodeString(res.Content)
+	if err != nil || string(data) != csv || res.Size != int64(len(csv)) {
+		t.Errorf("content %q (err %v), size %d", data, err, res.Size)
+	}
+
+	for path, want := range map[string]string{
+		"/workspace/out":         "is a directory",
+		"/workspace/latest.csv":  "is a symlink to /workspace/out/result.csv",
+		"/workspace/missing.png": "does not exist",
+		"":                       "path is required",
+	} {
+		res := CopyFromContainer(ctx, cli, CopyFromContainerParams{ContainerID: "c1", Path: path})
+		if !strings.Contains(res.Error, want) || res.Content != "" {
+			t.Errorf("%q: error = %q, want it to contain %q", path, res.Error, want)
+		}
+	}
+}
+
+func TestCopyFromContainerAsTar(t *testing.T) {
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	for _, e := range []struct{ name, body string }{{"out/", ""}, {"out/a.txt", "alpha\n"}, {"out/sub/", ""}, {"out/sub/b.csv", "1,2\n"}} {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+		if strings.HasSuffix(e.name, "/") {
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(e.body))
+	}
+	tw.Close()
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/containers/c1/archive") || r.URL.Query().Get("path") != "/workspace/out" {
+			http.Error(w, `{"message":"Could not find the file"}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString([]byte(`{"name":"out","size":4096,"mode":2147484141}`)))
+		w.Write(archive.Bytes())
+	}))
+	want := []string{"out/", "out/a.txt", "out/sub/", "out/sub/b.csv"}
+
+	for _, gz := range []bool{false, true} {
+		res := CopyFromContainer(context.Background(), cli, CopyFromContainerParams{ContainerID: "c1", Path: "out", AsTar: true, Gzip: gz})
+		if res.Error != "" {
+			t.Fatalf("gzip=%v: %s", gz, res.Error)
+		}
+		var names []string
+		for _, e := range res.Manifest {
+			names = append(names, e.Name)
+		}
+		if strings.Join(names, " ") != strings.Join(want, " ") || res.Manifest[1].Type != "file" || res.Manifest[1].Size != 6 || res.Manifest[2].Type != "dir" {
+			t.Errorf("gzip=%v: manifest = %+v", gz, res.Manifest)
+		}
+		data, err := base64.StdEncoding.DecodeString(res.Content)
+		if err != nil || res.Size != int64(len(data)) {
+			t.Fatalf("gzip=%v: content does not decode (%v) or size %d is off", gz, err, res.Size)
+		}
+		var r io.Reader = bytes.NewReader(data)
+		if gz {
+			if r, err = gzip.NewReader(r); err != nil {
+				t.Fatalf("content is not gzip: %v", err)
+			}
+		}
+		files := map[string]string{}
+		tr := tar.NewReader(r)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("gzip=%v: content is not a tar: %v", gz, err)
+			}
+			body, _ := io.ReadAll(tr)
+			files[hdr.Name] = string(body)
+		}
+		if len(files) != len(want) || files["out/a.txt"] != "alpha\n" || files["out/sub/b.csv"] != "1,2\n" {
+			t.Errorf("gzip=%v: archive entries = %q", gz, files)
+		}
+	}
+
+	if res := CopyFromContainer(context.Background(), cli, CopyFromContainerParams{ContainerID: "c1", Path: "out", Gzip: true}); res.Error != "gzip requires as_tar" {
+		t.Errorf("gzip without as_tar: error = %q", res.Error)
+	}
+}
+
+func TestCopyFromContainerDestDir(t *testing.T) {
+	archives := map[string]*bytes.Buffer{
+		"/workspace/out": buildTestTar(t, []tarEntry{
+			{name: "out/", typeflag: tar.TypeDir},
+			{name: "out/a.txt", typeflag: tar.TypeReg, body: "alpha\n"},
+		}),
+		"/workspace/evil": buildTestTar(t, []tarEntry{
+			{name: "evil/", typeflag: tar.TypeDir},
+			{name: "evil/up", typeflag: tar.TypeSymlink, linkname: ".."},
+			{name: "evil/up/up", typeflag: tar.TypeSymlink, linkname: ".."},
+			{name: "evil/up/up/escaped.txt", typeflag: tar.TypeReg, body: "x"},
+		}),
+	}
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		archive, ok := archives[r.URL.Query().Get("path")]
+		if !strings.HasSuffix(r.URL.Path, "/containers/c1/archive") || !ok {
+			http.Error(w, `{"message":"Could not find the file"}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString([]byte(`{"name":"out","mode":2147484141}`)))
+		w.Write(archive.Bytes())
+	}))
+	parent := t.TempDir()
+	dest := filepath.Join(parent, "dest")
+
+	res := CopyFromContainer(context.Background(), cli, CopyFromContainerParams{ContainerID: "c1", Path: "out", DestDir: dest})
+	if res.Error != "" || strings.Join(res.Extracted, " ") != "out/a.txt" || res.Content != "" {
+		t.Fatalf("result = %+v", res)
+	}
+	if data, err := os.ReadFile(filepath.Join(dest, "out", "a.txt")); err != nil || string(data) != "alpha\n" {
+		t.Errorf("out/a.txt = %q, %v", data, err)
+	}
+
+	res = CopyFromContainer(context.Background(), cli, CopyFromContainerParams{ContainerID: "c1", Path: "evil", DestDir: dest})
+	if !strings.Contains(res.Error, "escapes destination") {
+		t.Errorf("malicious archive: error = %q", res.Error)
+	}
+	if _, err := os.Lstat(filepath.Join(parent, "escaped.txt")); err == nil {
+		t.Error("file was written outside dest_dir")
+	}
+}
@@ -0,0 +1,107 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/client"
+)
+
+// Where coverage: true keeps coverage.py's data and its JSON report; each run overwrites them.
+const (
+	coverageDataPath   = "/tmp/.adde-coverage"
+	coverageReportPath = "/tmp/.adde-coverage.json"
+)
+
+// coverageCommands returns the command that runs the Python file under coverage.py and the one that
+// writes its JSON report, plus the env both need. tool is coverage_tool: empty runs
+// "<interpreter> -m coverage" so the module of the same Python is used; otherwise it names the
+// coverage executable (e.g. "coverage" or "/opt/venv/bin/coverage").
+func coverageCommands(cmd []string, filename, tool string) (run, report, env []string, err error) {
+	if lang, _ := languageRunnerFor(filename); lang != "python" {
+		return nil, nil, nil, fmt.Errorf("coverage is supported for Python (.py) files, not %q", filename)
+	}
+	base := []string{cmd[0], "-m", "coverage"}
+	if tool != "" {
+		if !interpreterRe.MatchString(tool) || strings.Contains(tool, "..") {
+			return nil, nil, nil, fmt.Errorf("invalid coverage_tool %q (want a binary name like coverage or an absolute path)", tool)
+		}
+		base = []string{tool}
+	}
+	run = append(append([]string(nil), base...), "run", cmd[len(cmd)-1])
+	report = append(append([]string(nil), base...), "json", "-o", coverageReportPath)
+	// COVERAGE_FILE keeps the .coverage data file out of the working directory.
+	return run, report, []string{"COVERAGE_FILE=" + coverageDataPath}, nil
+}
+
+// collectCoverage writes the JSON report and copies it out of the container. Failures are reported
+// in the returned report's Error, never as the run's error.
+func collectCoverage(ctx context.Context, cli *client.Client, containerID string, report, env []string, workDir string) *CoverageReport {
+	_, stderr, exitCode, _, err := runExecWith(ctx, cli, containerID, report, 60, execOptions{workingDir: workDir, env: env})
+	if err != nil {
+		return &CoverageReport{Error: "coverage json: " + err.Error()}
+	}
+	if exitCode != 0 {
+		return &CoverageReport{Error: fmt.Sprintf("coverage json exited %d: %s", exitCode, strings.TrimSpace(stderr))}
+	}
+	rc, _, err := cli.CopyFromContainer(ctx, containerID, coverageReportPath)
+	if err != nil {
+		return &CoverageReport{Error: "copy coverage report: " + err.Error()}
+	}
+	defer rc.Close()
+	data, err := readSingleFileFromTar(rc)
+	if err != nil {
+		return &CoverageReport{Error: "copy coverage report: " + err.Error()}
+	}
+	res, err := parseCoverageJSON(data, workDir)
+	if err != nil {
+		return &CoverageReport{Error: err.Error()}
+	}
+	return res
+}
+
+// coverageSummary is the "summary" / "totals" object of coverage.py's JSON report.
+type coverageSummary struct {
+	CoveredLines   int     `json:"covered_lines"`
+	NumStatements  int     `json:"num_statements"`
+	PercentCovered float64 `json:"percent_covered"`
+}
+
+// parseCoverageJSON reduces coverage.py's JSON report to the totals and a per-file summary, sorted
+// by path. Paths under workDir are reported relative to it, as coverage.py itself does for cwd.
+func parseCoverageJSON(data []byte, workDir string) (*CoverageReport, error) {
+	var raw struct {
+		Files map[string]struct {
+			Summary      coverageSummary `json:"summary"`
+			MissingLines []int           `json:"missing_lines"`
+		} `json:"files"`
+		Totals coverageSummary `json:"totals"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse coverage report: %v", err)
+	}
+	res := &CoverageReport{
+		Percent:       raw.Totals.PercentCovered,
+		CoveredLines:  raw.Totals.CoveredLines,
+		NumStatements: raw.Totals.NumStatements,
+		Files:         []CoverageFile{},
+	}
+	for name, f := range raw.Files {
+		if rel := strings.TrimPrefix(name, workDir+"/"); path.IsAbs(name) && rel != name {
+			name = rel
+		}
+		res.Files = append(res.Files, CoverageFile{
+			Path:          name,
+			Percent:       f.Summary.PercentCovered,
+			CoveredLines:  f.Summary.CoveredLines,
+			NumStatements: f.Summary.NumStatements,
+			MissingLines:  f.MissingLines,
+		})
+	}
+	sort.Slice(res.Files, func(i, j int) bool { return res.Files[i].Path < res.Files[j].Path })
+	return res, nil
+}
@@ -0,0 +1,58 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCoverageCommands(t *testing.T) {
+	cmd, _ := commandForExecution("/workspace/test_app.py", "test_app.py", "python3.11", "")
+	run, report, env, err := coverageCommands(cmd, "test_app.py", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(run, " "); got != "python3.11 -m coverage run /workspace/test_app.py" {
+		t.Errorf("run = %q", got)
+	}
+	if got := strings.Join(report, " "); got != "python3.11 -m coverage json -o "+coverageReportPath {
+		t.Errorf("report = %q", got)
+	}
+	if len(env) != 1 || env[0] != "COVERAGE_FILE="+coverageDataPath {
+		t.Errorf("env = %q", env)
+	}
+
+	run, _, _, err = coverageCommands(cmd, "test_app.py", "/opt/venv/bin/coverage")
+	if err != nil || strings.Join(run, " ") != "/opt/venv/bin/coverage run /workspace/test_app.py" {
+		t.Errorf("coverage_tool: run = %q, err = %v", run, err)
+	}
+	if _, _, _, err := coverageCommands(cmd, "test_app.py", "coverage; id"); err == nil {
+		t.Error("expected error for an invalid coverage_tool")
+	}
+	jsCmd, _ := commandForExecution("/workspace/app.js", "app.js", "", "")
+	if _, _, _, err := coverageCommands(jsCmd, "app.js", ""); err == nil {
+		t.Error("expected error for a non-Python file")
+	}
+}
+
+func TestParseCoverageJSON(t *testing.T) {
+	data := []byte(`{"meta":{"version":"7.4.0"},"files":{
+		"/app/test_calc.py":{"executed_lines":[1,2,3],"summary":{"covered_lines":3,"num_statements":3,"percent_covered":100.0},"missing_lines":[]},
+		"calc.py":{"executed_lines":[1,2],"summary":{"covered_lines":2,"num_statements":4,"percent_covered":50.0},"missing_lines":[5,6]}},
+		"totals":{"covered_lines":5,"num_statements":7,"percent_covered":71.42857142857143}}`)
+	res, err := parseCoverageJSON(data, "/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Percent < 71.4 || res.Percent > 71.5 || res.CoveredLines != 5 || res.NumStatements != 7 {
+		t.Errorf("totals = %+v", res)
+	}
+	if len(res.Files) != 2 || res.Files[0].Path != "calc.py" || res.Files[1].Path != "test_calc.py" {
+		t.Fatalf("files = %+v", res.Files)
+	}
+	if f := res.Files[0]; f.Percent != 50 || len(f.MissingLines) != 2 || f.MissingLines[0] != 5 {
+		t.Errorf("calc.py = %+v", f)
+	}
+	if _, err := parseCoverageJSON([]byte("not json"), "/app"); err == nil {
+		t.Error("expected error for a malformed report")
+	}
+}
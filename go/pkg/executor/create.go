@@ -3,29 +3,107 @@ package executor
 import (
 	"context"
 	"fmt"
+	"net"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/blkiodev"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 	"github.com/docker/go-connections/nat"
 )
 
 // CreateRuntimeEnv provisions a container with workspace mount, resource limits, and optional network.
-// Returns the daemon error message on failure (per spec §4.2).
+// Returns the daemon error message on failure (per spec §4.2). If any step fails or ctx is cancelled
+// before a successful return, the container and workspace are rolled back so nothing is orphaned.
 func CreateRuntimeEnv(ctx context.Context, cli *client.Client, p CreateRuntimeEnvParams) CreateRuntimeEnvResult {
-	workspaceDir, err := os.MkdirTemp("", "adde-workspace-")
+	return createRuntimeEnv(ctx, cli, p, nil)
+}
+
+// createRuntimeEnv is CreateRuntimeEnv with extra labels for the container (e.g. get_or_create_env's fingerprint).
+func createRuntimeEnv(ctx context.Context, cli *client.Client, p CreateRuntimeEnvParams, extraLabels map[string]string) (res CreateRuntimeEnvResult) {
+	image, defaulted, err := resolveImage(p.Image)
+	if err != nil {
+		return CreateRuntimeEnvResult{Error: err.Error()}
+	}
+	policy, err := normalizePullPolicy(p.PullPolicy)
+	if err != nil {
+		return CreateRuntimeEnvResult{Error: err.Error()}
+	}
+	if p.IdempotencyKey != "" {
+		existing, ok, err := findIdempotentContainer(ctx, cli, p.IdempotencyKey)
+		if err != nil {
+			return CreateRuntimeEnvResult{Error: err.Error()}
+		}
+		if ok {
+			return existing
+		}
+	}
+	workspaceDir, err := os.MkdirTemp(TempRoot(), workspaceDirPrefix)
 	if err != nil {
 		return CreateRuntimeEnvResult{Error: fmt.Sprintf("failed to create workspace dir: %v", err)}
 	}
 	absWorkspace, _ := filepath.Abs(workspaceDir)
 
-	envSlice := make([]string, 0, len(p.EnvVars)+1)
-	for k, v := range p.EnvVars {
-		envSlice = append(envSlice, k+"="+v)
+	var containerID string
+	defer func() {
+		if res.Error == "" && res.ContainerID != "" {
+			return
+		}
+		if containerID != "" {
+			forceRemoveContainer(cli, containerID)
+		}
+		os.RemoveAll(absWorkspace)
+	}()
+
+	resources, err := containerResources(p)
+	if err != nil {
+		return CreateRuntimeEnvResult{Error: err.Error()}
+	}
+	if p.CACerts != "" {
+		if p.JobMode {
+			// The job's CMD starts before we could install the CA; bake it into the image instead.
+			return CreateRuntimeEnvResult{Error: "ca_certs is not supported with job_mode"}
+		}
+		if err := validateCACerts(p.CACerts); err != nil {
+			return CreateRuntimeEnvResult{Error: err.Error()}
+		}
+	}
+
+	if p.InitScript != "" {
+		if p.JobMode {
+			// The job's CMD may already have finished before the script could run.
+			return CreateRuntimeEnvResult{Error: "init_script is not supported with job_mode"}
+		}
+		if _, err := initScriptCommand(p.InitScriptShell, p.InitScript); err != nil {
+			return CreateRuntimeEnvResult{Error: err.Error()}
+		}
+	}
+
+	if p.VerifyIsolation && p.JobMode {
+		// The probe execs into the container, which a finished job no longer allows.
+		return CreateRuntimeEnvResult{Error: "verify_isolation is not supported with job_mode"}
+	}
+	if len(p.Dependencies) > 0 && p.JobMode {
+		// The job's CMD may already have finished before the install could exec into the container.
+		return CreateRuntimeEnvResult{Error: "dependencies is not supported with job_mode"}
+	}
+
+	vars, err := localeEnv(p)
+	if err != nil {
+		return CreateRuntimeEnvResult{Error: err.Error()}
+	}
+	envSlice, err := envList(vars)
+	if err != nil {
+		return CreateRuntimeEnvResult{Error: err.Error()}
 	}
 
 	networkMode := container.NetworkMode("none")
@@ -33,13 +111,63 @@ func CreateRuntimeEnv(ctx context.Context, cli *client.Client, p CreateRuntimeEn
 		networkMode = container.NetworkMode("default")
 	}
 
+	labels, err := maxExecutionsLabels(p.MaxExecutions)
+	if err != nil {
+		return CreateRuntimeEnvResult{Error: err.Error()}
+	}
+	if err := validateUserLabels(p.Labels); err != nil {
+		return CreateRuntimeEnvResult{Error: err.Error()}
+	}
+	labels = containerLabels(labels, p.Labels, absWorkspace, time.Now())
+	if err := validateContainerName(p.Name); err != nil {
+		return CreateRuntimeEnvResult{Error: err.Error()}
+	}
+	containerName := p.Name
+	if p.IdempotencyKey != "" {
+		if p.Name != "" {
+			return CreateRuntimeEnvResult{Error: "name and idempotency_key cannot be combined (the key determines the name)"}
+		}
+		labels[LabelIdempotencyKey] = p.IdempotencyKey
+		containerName = idempotentContainerName(p.IdempotencyKey)
+	}
+	for k, v := range extraLabels {
+		labels[k] = v
+	}
+	if err := validateHostname(p.Hostname, p.DomainName); err != nil {
+		return CreateRuntimeEnvResult{Error: err.Error()}
+	}
+
+	// Every parameter is valid; only now may the policy cost a pull.
+	pulled, err := ensureImage(ctx, image, policy, imagePresent(cli), imagePuller(cli))
+	if err != nil {
+		return CreateRuntimeEnvResult{Error: err.Error()}
+	}
+
+	// Docker already merges image labels into the container's, but not every engine does;
+	// copy provenance explicitly so build→run linkage survives on all of them.
+	var warnings []string
+	var imgConfig *container.Config
+	if img, _, err := cli.ImageInspectWithRaw(ctx, image); err == nil {
+		if img.Config != nil {
+			labels = withProvenanceLabels(labels, img.Config.Labels)
+			imgConfig = img.Config
+		}
+		if w := checkImageArch(ctx, cli, image, img); w != "" {
+			warnings = append(warnings, w)
+		}
+	}
+
 	cfg := &container.Config{
-		Image: p.Image,
-		Env:   envSlice,
+		Image:      image,
+		Env:        envSlice,
+		Labels:     labels,
+		Hostname:   p.Hostname,
+		Domainname: p.DomainName,
 	}
-	if p.UseImageCmd {
+	if p.UseImageCmd || p.JobMode {
 		// Run the image's default CMD (e.g. node server.js); use image's working dir so server starts correctly
-		// Port bindings and /workspace mount still apply; agent can exec into /workspace later if needed
+		// Port bindings and /workspace mount still apply; agent can exec into /workspace later if needed.
+		// In job mode the CMD runs to completion and the stopped container is kept for wait_container/logs.
 	} else {
 		// Default: keep alive with sleep so agent runs code via exec
 		cfg.Cmd = []string{"sleep", "86400"}
@@ -48,67 +176,312 @@ func CreateRuntimeEnv(ctx context.Context, cli *client.Client, p CreateRuntimeEn
 	hostCfg := &container.HostConfig{
 		Binds:       []string{absWorkspace + ":" + WorkspacePathInsideContainer},
 		NetworkMode: networkMode,
-		Resources: container.Resources{
-			Memory:   DefaultMemoryLimitBytes,
-			NanoCPUs: DefaultNanoCPUs,
-		},
-		AutoRemove: false,
-	}
-
-	// Port bindings: container_port -> host_port (e.g. "3000" -> "8080"); bind to 127.0.0.1
-	if len(p.PortBindings) > 0 {
-		exposed := make(map[nat.Port]struct{})
-		portMap := make(map[nat.Port][]nat.PortBinding)
-		for cPort, hPort := range p.PortBindings {
-			cPort = strings.TrimSpace(cPort)
-			hPort = strings.TrimSpace(hPort)
-			if cPort == "" || hPort == "" {
-				continue
-			}
-			if _, err := strconv.Atoi(hPort); err != nil {
-				continue
-			}
-			portKey := nat.Port(cPort)
-			if !strings.Contains(cPort, "/") {
-				portKey = nat.Port(cPort + "/tcp")
-			}
-			exposed[portKey] = struct{}{}
-			portMap[portKey] = []nat.PortBinding{{HostIP: "127.0.0.1", HostPort: hPort}}
-		}
+		Resources:   resources,
+		AutoRemove:  false,
+		Init:        initProcess(p),
+	}
+	if p.VerifyIsolation {
+		hostCfg.CgroupnsMode = "private"
+	}
+
+	var imageExposed nat.PortSet
+	if p.AutoPublishExposed && imgConfig != nil {
+		imageExposed = imgConfig.ExposedPorts
+	}
+	if exposed, portMap := portBindings(p.PortBindings, imageExposed); len(portMap) > 0 {
 		cfg.ExposedPorts = exposed
 		hostCfg.PortBindings = portMap
 	}
 
-	resp, err := cli.ContainerCreate(ctx, cfg, hostCfg, nil, nil, "")
+	debugContainerConfig(cfg, hostCfg)
+	resp, err := cli.ContainerCreate(ctx, cfg, hostCfg, nil, nil, containerName)
 	if err != nil {
+		if p.IdempotencyKey != "" && errdefs.IsConflict(err) {
+			// A concurrent request with the same key won the name; hand back its container.
+			if existing, ok, ierr := existingEnvResult(ctx, cli, containerName); ierr == nil && ok {
+				os.RemoveAll(absWorkspace)
+				return existing
+			}
+		}
+		if p.Name != "" && errdefs.IsConflict(err) {
+			return CreateRuntimeEnvResult{Error: fmt.Sprintf("container name %q is already in use: choose a different name, or clean up or reuse the existing container", p.Name)}
+		}
 		return CreateRuntimeEnvResult{Error: err.Error()}
 	}
+	containerID = resp.ID
 
 	if err := cli.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
-		_ = cli.ContainerRemove(ctx, resp.ID, types.ContainerRemoveOptions{Force: true})
 		return CreateRuntimeEnvResult{Error: err.Error()}
 	}
 
+	// Trust the custom CA before dependency installs, which may go through a TLS-intercepting proxy.
+	if p.CACerts != "" {
+		if err := installCACerts(ctx, cli, resp.ID, p.CACerts); err != nil {
+			return CreateRuntimeEnvResult{Error: err.Error()}
+		}
+	}
+
 	// Install dependencies if requested (e.g. pip install / npm install)
 	if len(p.Dependencies) > 0 {
-		installErr := runDependencyInstall(ctx, cli, resp.ID, p.Image, p.Dependencies)
-		if installErr != nil {
-			_ = cli.ContainerRemove(ctx, resp.ID, types.ContainerRemoveOptions{Force: true})
-			return CreateRuntimeEnvResult{Error: installErr.Error()}
+		if installLog, err := runDependencyInstall(ctx, cli, resp.ID, image, p.Dependencies); err != nil {
+			return CreateRuntimeEnvResult{InstallLog: installLog, Error: err.Error()}
+		}
+	}
+
+	if p.InitScript != "" {
+		if initLog, err := runInitScript(ctx, cli, resp.ID, p.InitScriptShell, p.InitScript); err != nil {
+			return CreateRuntimeEnvResult{InitLog: initLog, Error: err.Error()}
+		}
+	}
+
+	// Verify last, so the check sees what dependencies and the init script left behind.
+	if p.VerifyIsolation {
+		warnings = append(warnings, verifyIsolation(ctx, cli, resp.ID, p.Network)...)
+	}
+
+	// Ephemeral host ports are only known once the container runs.
+	var published map[string]string
+	if p.AutoPublishExposed {
+		inspect, err := cli.ContainerInspect(ctx, resp.ID)
+		if err != nil {
+			return CreateRuntimeEnvResult{Error: err.Error()}
+		}
+		if inspect.NetworkSettings != nil {
+			published = publishedPorts(inspect.NetworkSettings.Ports)
 		}
 	}
 
+	// A cancellation that raced the last Docker call must not hand back a container we are about to abandon.
+	if err := ctx.Err(); err != nil {
+		return CreateRuntimeEnvResult{Error: err.Error()}
+	}
+
 	return CreateRuntimeEnvResult{
-		ContainerID: resp.ID,
-		Workspace:   absWorkspace,
+		ContainerID:    resp.ID,
+		Image:          image,
+		DefaultImage:   defaulted,
+		Pulled:         pulled,
+		Workspace:      absWorkspace,
+		Warnings:       warnings,
+		PublishedPorts: published,
+	}
+}
+
+// portBindings maps port_bindings (container_port -> host_port, e.g. "3000" -> "8080") onto 127.0.0.1.
+// Each port in imageExposed that port_bindings does not cover gets an ephemeral host port (HostPort "").
+func portBindings(bindings map[string]string, imageExposed nat.PortSet) (nat.PortSet, nat.PortMap) {
+	exposed := make(nat.PortSet)
+	portMap := make(nat.PortMap)
+	for cPort, hPort := range bindings {
+		cPort = strings.TrimSpace(cPort)
+		hPort = strings.TrimSpace(hPort)
+		if cPort == "" || hPort == "" {
+			continue
+		}
+		if _, err := strconv.Atoi(hPort); err != nil {
+			continue
+		}
+		portKey := nat.Port(cPort)
+		if !strings.Contains(cPort, "/") {
+			portKey = nat.Port(cPort + "/tcp")
+		}
+		exposed[portKey] = struct{}{}
+		portMap[portKey] = []nat.PortBinding{{HostIP: "127.0.0.1", HostPort: hPort}}
+	}
+	for portKey := range imageExposed {
+		if _, ok := portMap[portKey]; ok {
+			continue
+		}
+		exposed[portKey] = struct{}{}
+		portMap[portKey] = []nat.PortBinding{{HostIP: "127.0.0.1"}}
+	}
+	return exposed, portMap
+}
+
+// publishedPorts flattens the running container's port map to container port -> "host_ip:host_port".
+func publishedPorts(ports nat.PortMap) map[string]string {
+	out := make(map[string]string, len(ports))
+	for portKey, bindings := range ports {
+		if len(bindings) == 0 {
+			continue
+		}
+		out[string(portKey)] = net.JoinHostPort(bindings[0].HostIP, bindings[0].HostPort)
+	}
+	return out
+}
+
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// envList turns env_vars into KEY=value entries. Names must be shell identifiers and values must not
+// contain NUL, which would truncate or split the entry in the container's environment.
+func envList(vars map[string]string) ([]string, error) {
+	out := make([]string, 0, len(vars))
+	for k, v := range vars {
+		if !envNameRe.MatchString(k) {
+			return nil, fmt.Errorf("env_vars: invalid name %q (must match [A-Za-z_][A-Za-z0-9_]*)", k)
+		}
+		if strings.ContainsRune(v, 0) {
+			return nil, fmt.Errorf("env_vars: value of %s contains a NUL byte", k)
+		}
+		out = append(out, k+"="+v)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// initProcess decides HostConfig.Init. Exec-mode containers default to an init (tini) as PID 1 because
+// "sleep" does not reap the zombies that exec'd programs leave behind; image-CMD modes keep the image's PID 1.
+func initProcess(p CreateRuntimeEnvParams) *bool {
+	if p.Init != nil {
+		v := *p.Init
+		return &v
+	}
+	v := !p.UseImageCmd && !p.JobMode
+	return &v
+}
+
+var hostLabelRe = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?$`)
+
+// containerNameRe is Docker's rule for container names.
+var containerNameRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+
+// validateContainerName checks name against Docker's rules; empty means a random name.
+func validateContainerName(name string) error {
+	if name != "" && !containerNameRe.MatchString(name) {
+		return fmt.Errorf("invalid name %q: use at least 2 characters from [a-zA-Z0-9_.-], starting with a letter or digit", name)
+	}
+	return nil
+}
+
+// validateHostname checks hostname (a single RFC 1123 label) and domainname (dot-separated labels); empty means Docker's default.
+func validateHostname(hostname, domainname string) error {
+	if hostname != "" && !hostLabelRe.MatchString(hostname) {
+		return fmt.Errorf("invalid hostname %q (letters, digits and '-', at most 63 chars, no leading/trailing '-')", hostname)
+	}
+	if domainname == "" {
+		return nil
+	}
+	if len(domainname) > 253 {
+		return fmt.Errorf("invalid domainname: longer than 253 characters")
+	}
+	for _, label := range strings.Split(domainname, ".") {
+		if !hostLabelRe.MatchString(label) {
+			return fmt.Errorf("invalid domainname %q (label %q)", domainname, label)
+		}
+	}
+	return nil
+}
+
+// containerResources maps the resource fields of p onto HostConfig.Resources; memory_mb and cpus
+// override DefaultMemoryLimitBytes and DefaultNanoCPUs. Swap defaults to the memory limit (no swap) so the sandbox cannot spill past its cap.
+func containerResources(p CreateRuntimeEnvParams) (container.Resources, error) {
+	r := container.Resources{
+		Memory:    DefaultMemoryLimitBytes,
+		NanoCPUs:  DefaultNanoCPUs,
+		CPUShares: p.CPUShares,
+	}
+	if p.MemoryMB != 0 {
+		if p.MemoryMB < MinMemoryMB {
+			return r, fmt.Errorf("memory_mb must be at least %d (Docker's minimum), got %d", MinMemoryMB, p.MemoryMB)
+		}
+		r.Memory = int64(p.MemoryMB) * 1024 * 1024
+	}
+	if p.CPUs != 0 {
+		if p.CPUs < 0 {
+			return r, fmt.Errorf("cpus must be positive, got %g", p.CPUs)
+		}
+		r.NanoCPUs = int64(p.CPUs * 1e9)
+	}
+	if p.CPUShares < 0 {
+		return r, fmt.Errorf("cpu_shares must not be negative")
+	}
+	if p.CpusetCPUs != "" {
+		if err := validateCpuset(p.CpusetCPUs); err != nil {
+			return r, err
+		}
+		r.CpusetCpus = p.CpusetCPUs
+	}
+	if p.BlkioWeight != 0 {
+		if p.BlkioWeight < 10 || p.BlkioWeight > 1000 {
+			return r, fmt.Errorf("blkio_weight must be between 10 and 1000 (0 = daemon default), got %d", p.BlkioWeight)
+		}
+		r.BlkioWeight = p.BlkioWeight
+	}
+	var err error
+	if r.BlkioDeviceReadIOps, err = throttleDevices("device_read_iops", p.DeviceReadIOps); err != nil {
+		return r, err
+	}
+	if r.BlkioDeviceWriteIOps, err = throttleDevices("device_write_iops", p.DeviceWriteIOps); err != nil {
+		return r, err
+	}
+	if r.BlkioDeviceReadBps, err = throttleDevices("device_read_bps", p.DeviceReadBps); err != nil {
+		return r, err
+	}
+	if r.BlkioDeviceWriteBps, err = throttleDevices("device_write_bps", p.DeviceWriteBps); err != nil {
+		return r, err
+	}
+	switch {
+	case p.MemorySwapMB == 0:
+		r.MemorySwap = r.Memory
+	case p.MemorySwapMB == -1:
+		r.MemorySwap = -1
+	case p.MemorySwapMB < 0:
+		return r, fmt.Errorf("memory_swap_mb must be -1 (unlimited), 0 (no swap) or a positive size")
+	default:
+		r.MemorySwap = int64(p.MemorySwapMB) * 1024 * 1024
+		if r.MemorySwap < r.Memory {
+			return r, fmt.Errorf("memory_swap_mb (%d) must be at least the memory limit (%d MB)", p.MemorySwapMB, r.Memory/(1024*1024))
+		}
 	}
+	return r, nil
 }
 
-func runDependencyInstall(ctx context.Context, cli *client.Client, containerID, image string, deps []string) error {
+var cpusetPattern = regexp.MustCompile(`^\d+(-\d+)?(,\d+(-\d+)?)*$`)
+
+// validateCpuset checks cpuset syntax ("0-1", "0,2", "0-3,6") and that every range is ascending.
+func validateCpuset(s string) error {
+	if !cpusetPattern.MatchString(s) {
+		return fmt.Errorf("cpuset_cpus %q is invalid (want e.g. \"0-1\" or \"0,2\")", s)
+	}
+	for _, part := range strings.Split(s, ",") {
+		if lo, hi, ok := strings.Cut(part, "-"); ok {
+			l, _ := strconv.Atoi(lo)
+			h, _ := strconv.Atoi(hi)
+			if l > h {
+				return fmt.Errorf("cpuset_cpus %q: range %s is descending", s, part)
+			}
+		}
+	}
+	return nil
+}
+
+// throttleDevices turns a device path -> rate map (e.g. {"/dev/sda": 100}) into the daemon's list,
+// sorted by path. The daemon rejects devices that do not exist on the host; this only checks the shape.
+func throttleDevices(param string, rates map[string]uint64) ([]*blkiodev.ThrottleDevice, error) {
+	if len(rates) == 0 {
+		return nil, nil
+	}
+	out := make([]*blkiodev.ThrottleDevice, 0, len(rates))
+	for dev, rate := range rates {
+		if !strings.HasPrefix(dev, "/dev/") || path.Clean(dev) != dev {
+			return nil, fmt.Errorf("%s: device %q must be a path under /dev, e.g. /dev/sda", param, dev)
+		}
+		if rate == 0 {
+			return nil, fmt.Errorf("%s: rate for %s must be positive", param, dev)
+		}
+		out = append(out, &blkiodev.ThrottleDevice{Path: dev, Rate: rate})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out, nil
+}
+
+// runDependencyInstall installs deps in the container. When the installer exits non-zero, its output
+// is returned as a LogEntry (same shape as execute_code_block) alongside the error.
+func runDependencyInstall(ctx context.Context, cli *client.Client, containerID, image string, deps []string) (*LogEntry, error) {
 	var cmd []string
 	switch {
 	case len(deps) == 0:
-		return nil
+		return nil, nil
 	case isPythonImage(image):
 		cmd = append([]string{"pip", "install", "--no-cache-dir", "-q"}, deps...)
 	case isNodeImage(image):
@@ -116,8 +489,25 @@ func runDependencyInstall(ctx context.Context, cli *client.Client, containerID,
 	default:
 		cmd = []string{"sh", "-c", "command -v pip >/dev/null 2>&1 && pip install --no-cache-dir -q " + strings.Join(deps, " ") + " || true"}
 	}
-	_, _, _, _, err := runExec(ctx, cli, containerID, cmd, 120)
-	return err
+	var installLog *LogEntry
+	err := withInstallSlot(ctx, func() error {
+		stdout, stderr, code, dur, err := runExec(ctx, cli, containerID, cmd, 120)
+		if err != nil {
+			return err
+		}
+		installLog, err = dependencyInstallFailure(stdout, stderr, code, dur)
+		return err
+	})
+	return installLog, err
+}
+
+// dependencyInstallFailure reports a non-zero install exit as a LogEntry and error; success yields nil, nil.
+func dependencyInstallFailure(stdout, stderr string, exitCode int, dur time.Duration) (*LogEntry, error) {
+	if exitCode == 0 {
+		return nil, nil
+	}
+	log := &LogEntry{ExitCode: exitCode, Stdout: stdout, Stderr: stderr, ExecutionTime: formatDuration(dur)}
+	return log, fmt.Errorf("dependency install failed with exit code %d (see install_log)", exitCode)
 }
 
 func isPythonImage(s string) bool {
@@ -127,4 +517,3 @@ func isPythonImage(s string) bool {
 func isNodeImage(s string) bool {
 	return strings.Contains(strings.ToLower(s), "node")
 }
-
@@ -0,0 +1,397 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+)
+
+func TestContainerResourcesSwap(t *testing.T) {
+	r, err := containerResources(CreateRuntimeEnvParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.MemorySwap != r.Memory || r.Memory != DefaultMemoryLimitBytes {
+		t.Errorf("default: Memory=%d MemorySwap=%d, want swap == memory", r.Memory, r.MemorySwap)
+	}
+
+	r, err = containerResources(CreateRuntimeEnvParams{MemorySwapMB: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.MemorySwap != 1024*1024*1024 {
+		t.Errorf("1024 MB: MemorySwap=%d", r.MemorySwap)
+	}
+
+	r, err = containerResources(CreateRuntimeEnvParams{MemorySwapMB: -1})
+	if err != nil || r.MemorySwap != -1 {
+		t.Errorf("unlimited: MemorySwap=%d err=%v", r.MemorySwap, err)
+	}
+
+	for _, bad := range []int{-2, 128} {
+		if _, err := containerResources(CreateRuntimeEnvParams{MemorySwapMB: bad}); err == nil {
+			t.Errorf("%d: expected error", bad)
+		}
+	}
+}
+
+func TestContainerResourcesLimits(t *testing.T) {
+	r, err := containerResources(CreateRuntimeEnvParams{MemoryMB: 1024, CPUs: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Memory != 1024*1024*1024 || r.NanoCPUs != 2e9 {
+		t.Errorf("Memory=%d NanoCPUs=%d, want 1 GiB and 2 CPUs", r.Memory, r.NanoCPUs)
+	}
+	if r.MemorySwap != r.Memory {
+		t.Errorf("MemorySwap=%d, want it to follow memory_mb", r.MemorySwap)
+	}
+	if r, err := containerResources(CreateRuntimeEnvParams{CPUs: 0.25}); err != nil || r.NanoCPUs != 250000000 {
+		t.Errorf("0.25 cpus: NanoCPUs=%d err=%v", r.NanoCPUs, err)
+	}
+	if _, err := containerResources(CreateRuntimeEnvParams{MemoryMB: 5}); err == nil || !strings.Contains(err.Error(), "at least 6") {
+		t.Errorf("memory_mb 5: err=%v", err)
+	}
+	if _, err := containerResources(CreateRuntimeEnvParams{MemoryMB: 256, MemorySwapMB: 128}); err == nil {
+		t.Error("swap below memory_mb: expected error")
+	}
+	if _, err := containerResources(CreateRuntimeEnvParams{CPUs: -1}); err == nil {
+		t.Error("negative cpus: expected error")
+	}
+}
+
+func TestContainerResourcesCPU(t *testing.T) {
+	r, err := containerResources(CreateRuntimeEnvParams{CPUShares: 512, CpusetCPUs: "0-1,3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.CPUShares != 512 || r.CpusetCpus != "0-1,3" {
+		t.Errorf("CPUShares=%d CpusetCpus=%q", r.CPUShares, r.CpusetCpus)
+	}
+	if r.NanoCPUs != DefaultNanoCPUs {
+		t.Errorf("NanoCPUs default lost: %d", r.NanoCPUs)
+	}
+	for _, bad := range []string{"a", "0-", "1-0", "0,,1", " 0"} {
+		if _, err := containerResources(CreateRuntimeEnvParams{CpusetCPUs: bad}); err == nil {
+			t.Errorf("cpuset %q: expected error", bad)
+		}
+	}
+	if _, err := containerResources(CreateRuntimeEnvParams{CPUShares: -1}); err == nil {
+		t.Error("negative cpu_shares: expected error")
+	}
+}
+
+func TestContainerResourcesBlkio(t *testing.T) {
+	r, err := containerResources(CreateRuntimeEnvParams{
+		BlkioWeight:     300,
+		DeviceReadIOps:  map[string]uint64{"/dev/sdb": 200, "/dev/sda": 100},
+		DeviceWriteBps:  map[string]uint64{"/dev/nvme0n1": 10 * 1024 * 1024},
+		DeviceWriteIOps: map[string]uint64{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.BlkioWeight != 300 {
+		t.Errorf("BlkioWeight = %d", r.BlkioWeight)
+	}
+	if len(r.BlkioDeviceReadIOps) != 2 || r.BlkioDeviceReadIOps[0].Path != "/dev/sda" || r.BlkioDeviceReadIOps[0].Rate != 100 || r.BlkioDeviceReadIOps[1].Rate != 200 {
+		t.Errorf("BlkioDeviceReadIOps = %v", r.BlkioDeviceReadIOps)
+	}
+	if len(r.BlkioDeviceWriteBps) != 1 || r.BlkioDeviceWriteBps[0].Rate != 10*1024*1024 {
+		t.Errorf("BlkioDeviceWriteBps = %v", r.BlkioDeviceWriteBps)
+	}
+	if r.BlkioDeviceWriteIOps != nil || r.BlkioDeviceReadBps != nil {
+		t.Errorf("unset limits not nil: %v %v", r.BlkioDeviceWriteIOps, r.BlkioDeviceReadBps)
+	}
+
+	for _, bad := range []CreateRuntimeEnvParams{
+		{BlkioWeight: 9},
+		{BlkioWeight: 1001},
+		{DeviceReadIOps: map[string]uint64{"sda": 100}},
+		{DeviceReadBps: map[string]uint64{"/dev/../etc/passwd": 100}},
+		{DeviceWriteIOps: map[string]uint64{"/dev/sda": 0}},
+	} {
+		if _, err := containerResources(bad); err == nil {
+			t.Errorf("%+v: expected error", bad)
+		}
+	}
+}
+
+func TestCreateRuntimeEnvBlkioHostConfig(t *testing.T) {
+	if err := SetTempRoot(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetTempRoot("") })
+	var created struct{ HostConfig container.HostConfig }
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1.43/images/docker.io/library/busybox:latest/json":
+			w.Write([]byte(`{"Id":"sha256:abc"}`))
+		case "/v1.43/containers/create":
+			json.NewDecoder(r.Body).Decode(&created)
+			w.Write([]byte(`{"Id":"c1"}`))
+		case "/v1.43/containers/c1/start":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+		}
+	}))
+
+	res := CreateRuntimeEnv(context.Background(), cli, CreateRuntimeEnvParams{
+		Image:           "busybox",
+		BlkioWeight:     100,
+		DeviceWriteIOps: map[string]uint64{"/dev/sda": 50},
+		DeviceReadBps:   map[string]uint64{"/dev/sda": 1 << 20},
+	})
+	if res.Error != "" {
+		t.Fatalf("unexpected error: %s", res.Error)
+	}
+	hc := created.HostConfig
+	if hc.BlkioWeight != 100 {
+		t.Errorf("BlkioWeight = %d", hc.BlkioWeight)
+	}
+	if len(hc.BlkioDeviceWriteIOps) != 1 || hc.BlkioDeviceWriteIOps[0].Path != "/dev/sda" || hc.BlkioDeviceWriteIOps[0].Rate != 50 {
+		t.Errorf("BlkioDeviceWriteIOps = %v", hc.BlkioDeviceWriteIOps)
+	}
+	if len(hc.BlkioDeviceReadBps) != 1 || hc.BlkioDeviceReadBps[0].Rate != 1<<20 {
+		t.Errorf("BlkioDeviceReadBps = %v", hc.BlkioDeviceReadBps)
+	}
+	if hc.Memory != DefaultMemoryLimitBytes {
+		t.Errorf("memory limit lost: %d", hc.Memory)
+	}
+}
+
+func TestCreateRuntimeEnvLabels(t *testing.T) {
+	if err := SetTempRoot(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetTempRoot("") })
+	var created struct{ Labels map[string]string }
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1.43/images/docker.io/library/busybox:latest/json":
+			w.Write([]byte(`{"Id":"sha256:abc"}`))
+		case "/v1.43/containers/create":
+			json.NewDecoder(r.Body).Decode(&created)
+			w.Write([]byte(`{"Id":"c1"}`))
+		case "/v1.43/containers/c1/start":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+		}
+	}))
+
+	res := CreateRuntimeEnv(context.Background(), cli, CreateRuntimeEnvParams{Image: "busybox", Labels: map[string]string{"team": "ml"}})
+	if res.Error != "" {
+		t.Fatalf("unexpected error: %s", res.Error)
+	}
+	l := created.Labels
+	if l["team"] != "ml" || l[LabelManaged] != "true" || l[LabelWorkspace] != res.Workspace {
+		t.Errorf("labels = %v (workspace %s)", l, res.Workspace)
+	}
+	if _, err := time.Parse(time.RFC3339, l[LabelCreatedAt]); err != nil {
+		t.Errorf("%s = %q: %v", LabelCreatedAt, l[LabelCreatedAt], err)
+	}
+
+	res = CreateRuntimeEnv(context.Background(), cli, CreateRuntimeEnvParams{Image: "busybox", Labels: map[string]string{LabelManaged: "false"}})
+	if !strings.Contains(res.Error, "reserved") {
+		t.Errorf("reserved label: error = %q", res.Error)
+	}
+}
+
+func TestCreateRuntimeEnvRejectsDependenciesWithJobMode(t *testing.T) {
+	res := CreateRuntimeEnv(context.Background(), nil, CreateRuntimeEnvParams{Image: "busybox", JobMode: true, Dependencies: []string{"requests"}})
+	if res.Error != "dependencies is not supported with job_mode" {
+		t.Errorf("error = %q", res.Error)
+	}
+}
+
+func TestEnvList(t *testing.T) {
+	got, err := envList(map[string]string{"B": "multi\nline", "_A1": "x=y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != "B=multi\nline" || got[1] != "_A1=x=y" {
+		t.Errorf("unexpected env: %q", got)
+	}
+
+	for name, vars := range map[string]map[string]string{
+		"empty name":    {"": "x"},
+		"leading digit": {"1ABC": "x"},
+		"equals":        {"A=B": "x"},
+		"dash":          {"MY-VAR": "x"},
+		"newline name":  {"A\nB": "x"},
+		"NUL value":     {"TOKEN": "abc\x00def"},
+	} {
+		if _, err := envList(vars); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestValidateHostname(t *testing.T) {
+	valid := []struct{ host, domain string }{
+		{"", ""},
+		{"worker-1", ""},
+		{"db0", "cluster.local"},
+		{strings.Repeat("a", 63), ""},
+	}
+	for _, v := range valid {
+		if err := validateHostname(v.host, v.domain); err != nil {
+			t.Errorf("%q/%q: %v", v.host, v.domain, err)
+		}
+	}
+	invalid := []struct{ host, domain string }{
+		{"-worker", ""},
+		{"worker-", ""},
+		{"my_host", ""},
+		{"host.example.com", ""}, // dots belong in domainname
+		{strings.Repeat("a", 64), ""},
+		{"ok", "bad..domain"},
+		{"ok", ".local"},
+		{"ok", "under_score.local"},
+	}
+	for _, v := range invalid {
+		if err := validateHostname(v.host, v.domain); err == nil {
+			t.Errorf("%q/%q: expected error", v.host, v.domain)
+		}
+	}
+}
+
+func TestInitProcess(t *testing.T) {
+	yes, no := true, false
+	cases := []struct {
+		name string
+		p    CreateRuntimeEnvParams
+		want bool
+	}{
+		{"exec mode default", CreateRuntimeEnvParams{}, true},
+		{"use_image_cmd default", CreateRuntimeEnvParams{UseImageCmd: true}, false},
+		{"job_mode default", CreateRuntimeEnvParams{JobMode: true}, false},
+		{"exec mode opt out", CreateRuntimeEnvParams{Init: &no}, false},
+		{"job_mode opt in", CreateRuntimeEnvParams{JobMode: true, Init: &yes}, true},
+	}
+	for _, tc := range cases {
+		got := initProcess(tc.p)
+		if got == nil || *got != tc.want {
+			t.Errorf("%s: Init = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestDependencyInstallFailure(t *testing.T) {
+	if log, err := dependencyInstallFailure("ok", "", 0, time.Second); log != nil || err != nil {
+		t.Errorf("success: log=%v err=%v", log, err)
+	}
+	log, err := dependencyInstallFailure("", "ERROR: No matching distribution found for nopkg", 1, 1500*time.Millisecond)
+	if err == nil || log == nil {
+		t.Fatalf("failure: log=%v err=%v", log, err)
+	}
+	if log.ExitCode != 1 || !strings.Contains(log.Stderr, "No matching distribution") || log.ExecutionTime == "" {
+		t.Errorf("install log = %+v", log)
+	}
+}
+
+func TestValidateContainerName(t *testing.T) {
+	for _, name := range []string{"", "agent-1", "job_2.run", "A1"} {
+		if err := validateContainerName(name); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+	for _, name := range []string{"a", "-agent", ".hidden", "has space", "slash/name", "/leading"} {
+		if err := validateContainerName(name); err == nil {
+			t.Errorf("%q: expected error", name)
+		}
+	}
+}
+
+func TestCreateRuntimeEnvAutoPublishExposed(t *testing.T) {
+	if err := SetTempRoot(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetTempRoot("") })
+	var created struct {
+		ExposedPorts map[string]struct{}
+		HostConfig   container.HostConfig
+	}
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v1.43/images/docker.io/library/node:20-alpine/json":
+			w.Write([]byte(`{"Id":"sha256:abc","Config":{"ExposedPorts":{"3000/tcp":{},"9229/tcp":{}}}}`))
+		case r.URL.Path == "/v1.43/containers/create":
+			json.NewDecoder(r.Body).Decode(&created)
+			w.Write([]byte(`{"Id":"c1"}`))
+		case r.URL.Path == "/v1.43/containers/c1/start":
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/v1.43/containers/c1/json":
+			w.Write([]byte(`{"Id":"c1","NetworkSettings":{"Ports":{` +
+				`"3000/tcp":[{"HostIp":"127.0.0.1","HostPort":"49153"}],"9229/tcp":[{"HostIp":"127.0.0.1","HostPort":"49154"}]}}}`))
+		default:
+			http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+		}
+	}))
+
+	res := CreateRuntimeEnv(context.Background(), cli, CreateRuntimeEnvParams{Image: "node:20-alpine", UseImageCmd: true, Network: true, AutoPublishExposed: true})
+	if res.Error != "" {
+		t.Fatalf("unexpected error: %s", res.Error)
+	}
+	for _, port := range []nat.Port{"3000/tcp", "9229/tcp"} {
+		if _, ok := created.ExposedPorts[string(port)]; !ok {
+			t.Errorf("%s not exposed: %v", port, created.ExposedPorts)
+		}
+		b := created.HostConfig.PortBindings[port]
+		if len(b) != 1 || b[0].HostIP != "127.0.0.1" || b[0].HostPort != "" {
+			t.Errorf("%s binding = %+v, want ephemeral port on 127.0.0.1", port, b)
+		}
+	}
+	want := map[string]string{"3000/tcp": "127.0.0.1:49153", "9229/tcp": "127.0.0.1:49154"}
+	if len(res.PublishedPorts) != len(want) || res.PublishedPorts["3000/tcp"] != want["3000/tcp"] || res.PublishedPorts["9229/tcp"] != want["9229/tcp"] {
+		t.Errorf("published_ports = %v, want %v", res.PublishedPorts, want)
+	}
+}
+
+func TestPortBindingsExplicitWinsOverExposed(t *testing.T) {
+	exposed, portMap := portBindings(map[string]string{"3000": "8080", "bad": "x"}, nat.PortSet{"3000/tcp": {}, "53/udp": {}})
+	if len(exposed) != 2 || len(portMap) != 2 {
+		t.Fatalf("exposed = %v, bindings = %v", exposed, portMap)
+	}
+	if b := portMap["3000/tcp"]; b[0].HostPort != "8080" {
+		t.Errorf("explicit binding overridden: %+v", b)
+	}
+	if b := portMap["53/udp"]; b[0].HostIP != "127.0.0.1" || b[0].HostPort != "" {
+		t.Errorf("exposed port binding = %+v", b)
+	}
+}
+
+func TestResolveImageDefault(t *testing.T) {
+	defer SetDefaultImage("")
+	if _, _, err := resolveImage(""); err == nil || !strings.Contains(err.Error(), "ADDE_DEFAULT_IMAGE") {
+		t.Errorf("no image, no default: err = %v", err)
+	}
+	if err := SetDefaultImage("python:3.11-slim"); err != nil {
+		t.Fatal(err)
+	}
+	ref, defaulted, err := resolveImage(" ")
+	if err != nil || !defaulted || ref != "docker.io/library/python:3.11-slim" {
+		t.Errorf("omitted: ref=%q defaulted=%v err=%v", ref, defaulted, err)
+	}
+	ref, defaulted, err = resolveImage("node:20-alpine")
+	if err != nil || defaulted || ref != "docker.io/library/node:20-alpine" {
+		t.Errorf("given: ref=%q defaulted=%v err=%v", ref, defaulted, err)
+	}
+	if err := SetDefaultImage("Not A Ref"); err == nil {
+		t.Error("invalid default: expected error")
+	}
+	if DefaultImage() != "python:3.11-slim" {
+		t.Errorf("invalid default replaced the previous one: %q", DefaultImage())
+	}
+}
@@ -0,0 +1,78 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// redactedValue replaces secret values in debug output.
+const redactedValue = "[REDACTED]"
+
+// secretKeyRe matches env var names whose values are treated as secrets (FOO_TOKEN, DB_PASSWORD, API_KEY, ...).
+var secretKeyRe = regexp.MustCompile(`(?i)(^|_)(TOKEN|PASSWORD|SECRET|KEY)$`)
+
+var (
+	debugMu    sync.Mutex
+	debugOut   io.Writer
+	debugAllow map[string]bool
+)
+
+// SetDebugLog enables debug logging to w (nil disables it). Env var values whose names look secret are
+// redacted, except for the names in allow.
+func SetDebugLog(w io.Writer, allow []string) {
+	debugMu.Lock()
+	defer debugMu.Unlock()
+	debugOut = w
+	debugAllow = make(map[string]bool, len(allow))
+	for _, k := range allow {
+		debugAllow[strings.TrimSpace(k)] = true
+	}
+}
+
+// debugf writes one line to the debug log, if enabled. Callers must redact arguments themselves.
+func debugf(format string, args ...interface{}) {
+	debugMu.Lock()
+	defer debugMu.Unlock()
+	if debugOut == nil {
+		return
+	}
+	fmt.Fprintf(debugOut, "adde: debug: "+format+"\n", args...)
+}
+
+// debugEnabled reports whether debug logging is on, so callers can skip building expensive messages.
+func debugEnabled() bool {
+	debugMu.Lock()
+	defer debugMu.Unlock()
+	return debugOut != nil
+}
+
+// redactEnv returns a copy of a KEY=VALUE list with secret-looking values masked.
+func redactEnv(env []string) []string {
+	debugMu.Lock()
+	allow := debugAllow
+	debugMu.Unlock()
+	out := make([]string, len(env))
+	for i, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		if secretKeyRe.MatchString(name) && !allow[name] {
+			kv = name + "=" + redactedValue
+		}
+		out[i] = kv
+	}
+	return out
+}
+
+// debugContainerConfig logs the settings of a container about to be created. Registry credentials
+// never reach this point, and env values pass through redactEnv.
+func debugContainerConfig(cfg *container.Config, hostCfg *container.HostConfig) {
+	if !debugEnabled() {
+		return
+	}
+	debugf("create container: image=%s cmd=%q env=%q labels=%v network=%s binds=%q memory=%d nano_cpus=%d",
+		cfg.Image, cfg.Cmd, redactEnv(cfg.Env), cfg.Labels, hostCfg.NetworkMode, hostCfg.Binds, hostCfg.Memory, hostCfg.NanoCPUs)
+}
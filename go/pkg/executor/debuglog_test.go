@@ -0,0 +1,46 @@
+package executor
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestDebugContainerConfigRedactsSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	SetDebugLog(&buf, []string{"PUBLIC_KEY"})
+	defer SetDebugLog(nil, nil)
+
+	env, err := envList(map[string]string{
+		"GITHUB_TOKEN": "ghp_supersecret",
+		"DB_PASSWORD":  "hunter2",
+		"api_secret":   "s3cr3t",
+		"PUBLIC_KEY":   "ssh-ed25519 AAAA",
+		"LOG_LEVEL":    "info",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	debugContainerConfig(&container.Config{Image: "busybox", Env: env}, &container.HostConfig{})
+
+	out := buf.String()
+	for _, secret := range []string{"ghp_supersecret", "hunter2", "s3cr3t"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("debug output leaks %q: %s", secret, out)
+		}
+	}
+	for _, want := range []string{"GITHUB_TOKEN=" + redactedValue, "PUBLIC_KEY=ssh-ed25519 AAAA", "LOG_LEVEL=info"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("debug output missing %q: %s", want, out)
+		}
+	}
+}
+
+func TestDebugLogDisabledByDefault(t *testing.T) {
+	if debugEnabled() {
+		t.Fatal("debug log enabled without SetDebugLog")
+	}
+	debugf("should not panic: %d", 1)
+}
@@ -0,0 +1,49 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// defaultImage is what create_runtime_env runs when the call names no image; empty means none.
+var (
+	defaultImageMu sync.Mutex
+	defaultImage   string
+)
+
+// SetDefaultImage makes ref (e.g. from ADDE_DEFAULT_IMAGE) the image create_runtime_env uses when
+// its image is omitted. ref must be a valid reference or image ID; "" removes the default.
+func SetDefaultImage(ref string) error {
+	ref = strings.TrimSpace(ref)
+	if ref != "" {
+		if err := validateImageRefOrID(ref); err != nil {
+			return err
+		}
+	}
+	defaultImageMu.Lock()
+	defaultImage = ref
+	defaultImageMu.Unlock()
+	return nil
+}
+
+// DefaultImage returns the image used when create_runtime_env names none, or "".
+func DefaultImage() string {
+	defaultImageMu.Lock()
+	defer defaultImageMu.Unlock()
+	return defaultImage
+}
+
+// resolveImage returns the image a create should run: image if given, else the default.
+// defaulted reports that the default was used.
+func resolveImage(image string) (ref string, defaulted bool, err error) {
+	if strings.TrimSpace(image) == "" {
+		image = DefaultImage()
+		if image == "" {
+			return "", false, fmt.Errorf("%s: image is required (or set ADDE_DEFAULT_IMAGE)", ErrCodeInvalidImageRef)
+		}
+		defaulted = true
+	}
+	ref, err = normalizeImageRef(image)
+	return ref, defaulted, err
+}
@@ -0,0 +1,108 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+)
+
+// detachedExecDir holds the output of start_detached_exec jobs: <n>.log is a job's stdout and stderr,
+// and <exec_id> names the .log of the exec, since the output path is fixed before Docker assigns the ID.
+const detachedExecDir = "/var/adde/detached"
+
+// MaxDetachedOutputBytes caps the output poll_exec returns; the end is kept, where the latest progress is.
+const MaxDetachedOutputBytes = 1024 * 1024
+
+// Detached exec statuses reported by poll_exec.
+const (
+	ExecStatusRunning  = "running"
+	ExecStatusFinished = "finished"
+)
+
+// StartDetachedExec starts command in the container without waiting for it, for jobs that outlive
+// the exec timeout. Output goes to a file in the container; collect it with PollExec.
+func StartDetachedExec(ctx context.Context, cli *client.Client, p StartDetachedExecParams) StartDetachedExecResult {
+	if p.ContainerID == "" {
+		return StartDetachedExecResult{Error: "container_id is required"}
+	}
+	if len(p.Command) == 0 {
+		return StartDetachedExecResult{Error: "command is required"}
+	}
+	outputPath := path.Join(detachedExecDir, fmt.Sprintf("%d.log", time.Now().UnixNano()))
+	cmd := append([]string{"sh", "-c", `exec "$@" >"$0" 2>&1`, outputPath}, p.Command...)
+	createResp, err := cli.ContainerExecCreate(ctx, p.ContainerID, types.ExecConfig{Cmd: cmd, WorkingDir: WorkspacePathInsideContainer})
+	if err != nil {
+		return StartDetachedExecResult{Error: err.Error()}
+	}
+	// Record which log belongs to the exec before it starts, so a poll can never miss it. The archive
+	// also creates detachedExecDir for the shell's redirect.
+	tarBuf, err := buildTarStream(strings.TrimPrefix(path.Join(detachedExecDir, createResp.ID), "/"), outputPath)
+	if err != nil {
+		return StartDetachedExecResult{Error: err.Error()}
+	}
+	if err := cli.CopyToContainer(ctx, p.ContainerID, "/", tarBuf, types.CopyToContainerOptions{}); err != nil {
+		return StartDetachedExecResult{Error: err.Error()}
+	}
+	if err := cli.ContainerExecStart(ctx, createResp.ID, types.ExecStartCheck{Detach: true}); err != nil {
+		return StartDetachedExecResult{Error: err.Error()}
+	}
+	return StartDetachedExecResult{ExecID: createResp.ID, OutputPath: outputPath}
+}
+
+// PollExec reports whether a start_detached_exec job is still running, its exit code once it has
+// finished, and the output it has written so far.
+func PollExec(ctx context.Context, cli *client.Client, p PollExecParams) PollExecResult {
+	if p.ContainerID == "" || p.ExecID == "" {
+		return PollExecResult{Error: "container_id and exec_id are required"}
+	}
+	inspect, err := cli.ContainerExecInspect(ctx, p.ExecID)
+	if err != nil {
+		return PollExecResult{Error: err.Error()}
+	}
+	res := PollExecResult{Status: ExecStatusRunning}
+	if !inspect.Running {
+		exitCode := inspect.ExitCode
+		res.Status, res.ExitCode = ExecStatusFinished, &exitCode
+	}
+
+	outputPath, err := readContainerFile(ctx, cli, p.ContainerID, path.Join(detachedExecDir, p.ExecID))
+	if errdefs.IsNotFound(err) {
+		res.Error = fmt.Sprintf("exec %s was not started by start_detached_exec in container %s", p.ExecID, p.ContainerID)
+		return res
+	}
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.OutputPath = string(outputPath)
+	output, err := readContainerFile(ctx, cli, p.ContainerID, res.OutputPath)
+	if errdefs.IsNotFound(err) {
+		return res // started, but the shell has not created the file yet
+	}
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if len(output) > MaxDetachedOutputBytes {
+		output = output[len(output)-MaxDetachedOutputBytes:]
+		res.OutputTruncated = true
+	}
+	res.Output = string(output)
+	return res
+}
+
+// readContainerFile returns the content of one regular file in the container.
+func readContainerFile(ctx context.Context, cli *client.Client, containerID, file string) ([]byte, error) {
+	rc, _, err := cli.CopyFromContainer(ctx, containerID, file)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return readSingleFileFromTar(rc)
+}
@@ -0,0 +1,123 @@
+package executor
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+// TestDetachedExecSleepThenEcho starts `sleep; echo done` detached and polls it to completion against
+// a fake daemon that plays the job: running for two inspects, then finished with its output written.
+func TestDetachedExecSleepThenEcho(t *testing.T) {
+	var mu sync.Mutex
+	files := map[string]string{} // in-container path -> content
+	var execCfg types.ExecConfig
+	var detached bool
+	inspects := 0
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/containers/c1/exec"):
+			json.NewDecoder(r.Body).Decode(&execCfg)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"Id":"e1"}`))
+		case r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/containers/c1/archive"):
+			tr := tar.NewReader(r.Body)
+			hdr, err := tr.Next()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			data, _ := io.ReadAll(tr)
+			files[path.Join(r.URL.Query().Get("path"), hdr.Name)] = string(data)
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/containers/c1/archive"):
+			p := r.URL.Query().Get("path")
+			content, ok := files[p]
+			if !ok {
+				http.Error(w, `{"message":"Could not find the file"}`, http.StatusNotFound)
+				return
+			}
+			tarBuf, _ := buildTarStream(path.Base(p), content)
+			w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString([]byte(`{"name":"`+path.Base(p)+`"}`)))
+			w.Write(tarBuf.Bytes())
+		case strings.HasSuffix(r.URL.Path, "/exec/e1/start"):
+			var check types.ExecStartCheck
+			json.NewDecoder(r.Body).Decode(&check)
+			detached = check.Detach
+			w.WriteHeader(http.StatusOK)
+		case strings.HasSuffix(r.URL.Path, "/exec/e1/json"):
+			inspects++
+			running := inspects <= 2
+			if !running {
+				// The shell redirected the job's output to the path it was given as $0.
+				files[execCfg.Cmd[3]] = "done\n"
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"ID": "e1", "ContainerID": "c1full", "Running": running, "ExitCode": 0})
+		default:
+			http.Error(w, `{"message":"not implemented"}`, http.StatusNotImplemented)
+		}
+	}))
+	ctx := context.Background()
+
+	start := StartDetachedExec(ctx, cli, StartDetachedExecParams{ContainerID: "c1", Command: []string{"sh", "-c", "sleep 1; echo done"}})
+	if start.Error != "" {
+		t.Fatalf("start: %s", start.Error)
+	}
+	if start.ExecID != "e1" || !strings.HasPrefix(start.OutputPath, detachedExecDir+"/") {
+		t.Errorf("handle = %+v", start)
+	}
+	mu.Lock()
+	if !detached {
+		t.Error("exec was not started detached")
+	}
+	if got := strings.Join(execCfg.Cmd[4:], " "); got != "sh -c sleep 1; echo done" || execCfg.Cmd[3] != start.OutputPath {
+		t.Errorf("exec cmd = %q", execCfg.Cmd)
+	}
+	mu.Unlock()
+
+	var res PollExecResult
+	for i := 0; i < 5; i++ {
+		res = PollExec(ctx, cli, PollExecParams{ContainerID: "c1", ExecID: start.ExecID})
+		if res.Error != "" {
+			t.Fatalf("poll %d: %s", i, res.Error)
+		}
+		if res.Status == ExecStatusFinished {
+			break
+		}
+		if res.ExitCode != nil || res.Output != "" {
+			t.Errorf("poll %d while running: %+v", i, res)
+		}
+	}
+	if res.Status != ExecStatusFinished || res.ExitCode == nil || *res.ExitCode != 0 || res.Output != "done\n" {
+		t.Errorf("final poll = %+v", res)
+	}
+
+	// An exec adde did not start has no mapping file, so there is no output to collect.
+	mu.Lock()
+	delete(files, detachedExecDir+"/e1")
+	mu.Unlock()
+	if res := PollExec(ctx, cli, PollExecParams{ContainerID: "c1", ExecID: "e1"}); !strings.Contains(res.Error, "not started by start_detached_exec") {
+		t.Errorf("unknown exec: error = %q", res.Error)
+	}
+}
+
+func TestDetachedExecValidation(t *testing.T) {
+	if res := StartDetachedExec(context.Background(), nil, StartDetachedExecParams{ContainerID: "c1"}); res.Error != "command is required" {
+		t.Errorf("no command: %q", res.Error)
+	}
+	if res := PollExec(context.Background(), nil, PollExecParams{ContainerID: "c1"}); res.Error == "" {
+		t.Error("no exec_id: expected error")
+	}
+}
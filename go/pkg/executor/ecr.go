@@ -0,0 +1,75 @@
+package executor
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ecrHostRe matches ECR registry hosts in every partition:
+//
+//	<account>.dkr.ecr.<region>.amazonaws.com          commercial and GovCloud (us-gov-west-1)
+//	<account>.dkr.ecr-fips.<region>.amazonaws.com     FIPS endpoints
+//	<account>.dkr.ecr.<region>.amazonaws.com.cn       China (cn-north-1, cn-northwest-1)
+var ecrHostRe = regexp.MustCompile(`^(\d{12})\.dkr\.(ecr|ecr-fips)\.([a-z]{2}(?:-[a-z]+)+-\d+)\.amazonaws\.com(\.cn)?$`)
+
+// ECR Public serves every public repository from one host; its login tokens are only issued in us-east-1.
+const (
+	ecrPublicHost   = "public.ecr.aws"
+	ecrPublicRegion = "us-east-1"
+)
+
+// ecrRegistry describes an ECR host; tokens are per account and region.
+type ecrRegistry struct {
+	Account   string
+	Region    string
+	Partition string // aws | aws-us-gov | aws-cn
+	FIPS      bool
+}
+
+// parseECRHost reports whether host (optionally with a port) is an ECR registry and, if so, its
+// account, region and partition. The region always comes from the host, never from AWS_REGION,
+// so cross-region pulls resolve to the registry's own region.
+func parseECRHost(host string) (ecrRegistry, bool) {
+	host = strings.ToLower(registryHost(host))
+	if i := strings.LastIndexByte(host, ':'); i >= 0 {
+		host = host[:i]
+	}
+	m := ecrHostRe.FindStringSubmatch(host)
+	if m == nil {
+		return ecrRegistry{}, false
+	}
+	r := ecrRegistry{Account: m[1], Region: m[3], Partition: "aws", FIPS: m[2] == "ecr-fips"}
+	switch {
+	case m[4] == ".cn":
+		if !strings.HasPrefix(r.Region, "cn-") {
+			return ecrRegistry{}, false
+		}
+		r.Partition = "aws-cn"
+	case strings.HasPrefix(r.Region, "cn-"):
+		return ecrRegistry{}, false // China regions only exist under amazonaws.com.cn
+	case strings.HasPrefix(r.Region, "us-gov-"):
+		r.Partition = "aws-us-gov"
+	}
+	return r, true
+}
+
+// isECRPublicHost reports whether host (optionally with scheme or port) is ECR Public.
+func isECRPublicHost(host string) bool {
+	host = strings.ToLower(registryHost(host))
+	if i := strings.LastIndexByte(host, ':'); i >= 0 {
+		host = host[:i]
+	}
+	return host == ecrPublicHost
+}
+
+// ecrLoginCommand returns the AWS CLI command that prints a registry password for host (user "AWS"):
+// ecr-public in us-east-1 for ECR Public, ecr in the host's own region for private registries.
+func ecrLoginCommand(host string) ([]string, bool) {
+	if isECRPublicHost(host) {
+		return []string{"aws", "ecr-public", "get-login-password", "--region", ecrPublicRegion}, true
+	}
+	if r, ok := parseECRHost(host); ok {
+		return []string{"aws", "ecr", "get-login-password", "--region", r.Region}, true
+	}
+	return nil, false
+}
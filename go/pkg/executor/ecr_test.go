@@ -0,0 +1,68 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseECRHost(t *testing.T) {
+	cases := []struct {
+		host string
+		want ecrRegistry
+		ok   bool
+	}{
+		{"123456789012.dkr.ecr.us-east-1.amazonaws.com", ecrRegistry{"123456789012", "us-east-1", "aws", false}, true},
+		{"123456789012.dkr.ecr.eu-central-1.amazonaws.com", ecrRegistry{"123456789012", "eu-central-1", "aws", false}, true},
+		{"123456789012.dkr.ecr.ap-southeast-3.amazonaws.com:443", ecrRegistry{"123456789012", "ap-southeast-3", "aws", false}, true},
+		{"123456789012.dkr.ecr-fips.us-east-2.amazonaws.com", ecrRegistry{"123456789012", "us-east-2", "aws", true}, true},
+		{"123456789012.dkr.ecr.us-gov-west-1.amazonaws.com", ecrRegistry{"123456789012", "us-gov-west-1", "aws-us-gov", false}, true},
+		{"123456789012.dkr.ecr-fips.us-gov-east-1.amazonaws.com", ecrRegistry{"123456789012", "us-gov-east-1", "aws-us-gov", true}, true},
+		{"123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn", ecrRegistry{"123456789012", "cn-north-1", "aws-cn", false}, true},
+		{"123456789012.dkr.ecr.cn-northwest-1.amazonaws.com.cn", ecrRegistry{"123456789012", "cn-northwest-1", "aws-cn", false}, true},
+		{"https://123456789012.dkr.ecr.us-west-2.amazonaws.com/v2/", ecrRegistry{"123456789012", "us-west-2", "aws", false}, true},
+		{"123456789012.dkr.ecr.cn-north-1.amazonaws.com", ecrRegistry{}, false},
+		{"123456789012.dkr.ecr.us-east-1.amazonaws.com.cn", ecrRegistry{}, false},
+		{"public.ecr.aws", ecrRegistry{}, false},
+		{"12345.dkr.ecr.us-east-1.amazonaws.com", ecrRegistry{}, false},
+		{"registry.example.com", ecrRegistry{}, false},
+	}
+	for _, tc := range cases {
+		got, ok := parseECRHost(tc.host)
+		if ok != tc.ok || got != tc.want {
+			t.Errorf("%s: got %+v, %v; want %+v, %v", tc.host, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestECRLoginCommand(t *testing.T) {
+	cases := []struct {
+		host, want string
+	}{
+		{"public.ecr.aws", "aws ecr-public get-login-password --region us-east-1"},
+		{"PUBLIC.ECR.AWS:443", "aws ecr-public get-login-password --region us-east-1"},
+		{"https://public.ecr.aws/v2/", "aws ecr-public get-login-password --region us-east-1"},
+		{"123456789012.dkr.ecr.eu-west-1.amazonaws.com", "aws ecr get-login-password --region eu-west-1"},
+		{"123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn", "aws ecr get-login-password --region cn-north-1"},
+		{"gallery.ecr.aws", ""},
+		{"public.ecr.aws.example.com", ""},
+		{"docker.io", ""},
+	}
+	for _, tc := range cases {
+		cmd, ok := ecrLoginCommand(tc.host)
+		if got := strings.Join(cmd, " "); got != tc.want || ok != (tc.want != "") {
+			t.Errorf("%s: got %q (%v), want %q", tc.host, got, ok, tc.want)
+		}
+	}
+}
+
+func TestCheckRegistryAuthECRPublicAnonymous(t *testing.T) {
+	env := map[string]string{"DOCKER_CONFIG": t.TempDir()}
+	res := checkRegistryAuth(context.Background(), nil, CheckRegistryAuthParams{Image: "public.ecr.aws/docker/library/python:3.11-slim"}, func(k string) string { return env[k] })
+	if res.Source != AuthSourceNone || res.Error != "" || !strings.Contains(res.Status, "anonymously") {
+		t.Errorf("result = %+v", res)
+	}
+	if res.LoginCommand != "aws ecr-public get-login-password --region us-east-1 | docker login --username AWS --password-stdin public.ecr.aws" {
+		t.Errorf("login_command = %q", res.LoginCommand)
+	}
+}
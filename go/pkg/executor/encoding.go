@@ -0,0 +1,67 @@
+package executor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// Content encodings accepted for file payloads passed through the JSON interface.
+const (
+	ContentEncodingPlain      = ""
+	ContentEncodingGzipBase64 = "gzip+base64"
+)
+
+// decodeContent turns a JSON file payload into raw bytes according to encoding.
+// "gzip+base64" lets callers ship large data files without the base64-of-plaintext bloat.
+func decodeContent(content, encoding string) ([]byte, error) {
+	switch encoding {
+	case ContentEncodingPlain, "plain":
+		return []byte(content), nil
+	case ContentEncodingGzipBase64:
+		raw, err := base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return nil, fmt.Errorf("content is not valid base64: %v", err)
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("content is not a valid gzip stream: %v", err)
+		}
+		defer zr.Close()
+		out, err := io.ReadAll(zr)
+		if err != nil {
+			return nil, fmt.Errorf("content gzip stream is corrupt: %v", err)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unknown content_encoding %q (want %q or %q)", encoding, "plain", ContentEncodingGzipBase64)
+}
+
+// Output encodings for execute_code_block's stdout/stderr.
+const (
+	OutputEncodingUTF8   = "utf8"
+	OutputEncodingBase64 = "base64"
+)
+
+// validateOutputEncoding normalizes stdout_encoding; empty means utf8.
+func validateOutputEncoding(enc string) (string, error) {
+	switch enc {
+	case "", OutputEncodingUTF8:
+		return OutputEncodingUTF8, nil
+	case OutputEncodingBase64:
+		return OutputEncodingBase64, nil
+	}
+	return "", fmt.Errorf("unknown stdout_encoding %q (want %q or %q)", enc, OutputEncodingUTF8, OutputEncodingBase64)
+}
+
+// encodeLogOutput base64-encodes the raw stdout/stderr bytes in base64 mode so binary output survives JSON.
+func encodeLogOutput(log *LogEntry, enc string) {
+	if enc != OutputEncodingBase64 {
+		return
+	}
+	log.Stdout = base64.StdEncoding.EncodeToString([]byte(log.Stdout))
+	log.Stderr = base64.StdEncoding.EncodeToString([]byte(log.Stderr))
+	log.Encoding = OutputEncodingBase64
+}
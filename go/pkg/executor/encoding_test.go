@@ -0,0 +1,41 @@
+package executor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+)
+
+func TestEncodeLogOutputBase64RoundTrip(t *testing.T) {
+	raw := "\x89PNG\r\n\x1a\n\xff\xfe\x00binary"
+	log := &LogEntry{Stdout: raw, Stderr: "warn\xff"}
+	encodeLogOutput(log, OutputEncodingBase64)
+	if log.Encoding != OutputEncodingBase64 {
+		t.Fatalf("encoding = %q", log.Encoding)
+	}
+	// Survives a JSON round trip, unlike invalid UTF-8 in a plain string.
+	data, err := json.Marshal(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back LogEntry
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	out, err := base64.StdEncoding.DecodeString(back.Stdout)
+	if err != nil || string(out) != raw {
+		t.Errorf("stdout round trip = %q, %v", out, err)
+	}
+	if errOut, _ := base64.StdEncoding.DecodeString(back.Stderr); string(errOut) != "warn\xff" {
+		t.Errorf("stderr round trip = %q", errOut)
+	}
+
+	plain := &LogEntry{Stdout: "hi"}
+	encodeLogOutput(plain, OutputEncodingUTF8)
+	if plain.Stdout != "hi" || plain.Encoding != "" {
+		t.Errorf("utf8 mode changed the log: %+v", plain)
+	}
+	if _, err := validateOutputEncoding("hex"); err == nil {
+		t.Error("hex: expected error")
+	}
+}
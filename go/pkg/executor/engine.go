@@ -0,0 +1,60 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// Container engines the toolkit knows how to talk to. Podman is reached through its
+// Docker-compatible socket, so the same client is used; only divergent calls branch.
+const (
+	EngineDocker = "docker"
+	EnginePodman = "podman"
+)
+
+var bareImageID = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ResolveEngine returns the engine to target: the explicit override if set (validated),
+// otherwise whatever the daemon reports via ServerVersion. Falls back to docker if detection fails.
+func ResolveEngine(ctx context.Context, cli *client.Client, override string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(override)) {
+	case EngineDocker:
+		return EngineDocker, nil
+	case EnginePodman:
+		return EnginePodman, nil
+	case "":
+	default:
+		return "", fmt.Errorf("unknown engine %q (want %q or %q)", override, EngineDocker, EnginePodman)
+	}
+	v, err := cli.ServerVersion(ctx)
+	if err != nil {
+		return EngineDocker, nil
+	}
+	return engineFromVersion(v), nil
+}
+
+// engineFromVersion classifies a daemon from its version report; Podman names itself in Components.
+func engineFromVersion(v types.Version) string {
+	if strings.Contains(strings.ToLower(v.Platform.Name), "podman") {
+		return EnginePodman
+	}
+	for _, c := range v.Components {
+		if strings.Contains(strings.ToLower(c.Name), "podman") {
+			return EnginePodman
+		}
+	}
+	return EngineDocker
+}
+
+// normalizeImageID adds the "sha256:" prefix Podman omits so IDs compare equal across engines.
+func normalizeImageID(id string) string {
+	if bareImageID.MatchString(id) {
+		return "sha256:" + id
+	}
+	return id
+}
@@ -0,0 +1,46 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestEngineFromVersion(t *testing.T) {
+	podman := types.Version{Components: []types.ComponentVersion{{Name: "Podman Engine", Version: "4.9.3"}}}
+	if got := engineFromVersion(podman); got != EnginePodman {
+		t.Errorf("podman components: got %q", got)
+	}
+	docker := types.Version{Platform: struct{ Name string }{"Docker Engine - Community"}, Components: []types.ComponentVersion{{Name: "Engine"}}}
+	if got := engineFromVersion(docker); got != EngineDocker {
+		t.Errorf("docker: got %q", got)
+	}
+}
+
+func TestPruneFiltersByEngine(t *testing.T) {
+	docker := pruneFilters(EngineDocker, 0)
+	if docker.Len() != 0 {
+		t.Errorf("docker, no age: expected no filters, got %v", docker.Keys())
+	}
+	podman := pruneFilters(EnginePodman, 0)
+	if !podman.ExactMatch("dangling", "true") {
+		t.Error("podman: expected dangling=true filter")
+	}
+	aged := pruneFilters(EnginePodman, 24)
+	if !aged.ExactMatch("until", "24h0m0s") || !aged.Contains("dangling") {
+		t.Errorf("podman, 24h: unexpected filters %v", aged.Keys())
+	}
+	if pruneFilters(EngineDocker, 24).Contains("dangling") {
+		t.Error("docker: dangling filter must not be set for build cache prune")
+	}
+}
+
+func TestNormalizeImageID(t *testing.T) {
+	bare := "a3ed95caeb02ffe68cdd9fd84406680ae93d633cb16422d00e8a7c22955b46d4"
+	if got := normalizeImageID(bare); got != "sha256:"+bare {
+		t.Errorf("bare id: got %q", got)
+	}
+	if got := normalizeImageID("sha256:" + bare); got != "sha256:"+bare {
+		t.Errorf("prefixed id: got %q", got)
+	}
+}
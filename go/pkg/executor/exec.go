@@ -3,6 +3,7 @@ package executor
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"time"
 
@@ -11,20 +12,51 @@ import (
 	"github.com/docker/docker/pkg/stdcopy"
 )
 
+// execOptions are the optional knobs for runExecWith; the zero value matches runExec.
+type execOptions struct {
+	tty            bool // allocate a pseudo-TTY; stdout and stderr arrive merged on stdout
+	maxWorkspaceMB int  // > 0: kill the program if /workspace grows past this size
+	killOnCancel   bool // kill the program when ctx ends (SIGINT, timeout) instead of only dropping the stream
+	// stdin, when set, is streamed to the program's stdin, which is then closed (EOF).
+	stdin io.Reader
+	// workingDir is the exec's working directory; empty means /workspace.
+	workingDir string
+	// env is added to the container's env for this exec only (KEY=VALUE).
+	env []string
+}
+
 // runExec runs cmd in the container and returns stdout, stderr, exitCode, duration.
 // Used by create (deps) and execute_code_block.
 func runExec(ctx context.Context, cli *client.Client, containerID string, cmd []string, timeoutSec int) (stdout, stderr string, exitCode int, dur time.Duration, err error) {
+	return runExecWith(ctx, cli, containerID, cmd, timeoutSec, execOptions{})
+}
+
+// runExecWith is runExec with extra exec options.
+func runExecWith(ctx context.Context, cli *client.Client, containerID string, cmd []string, timeoutSec int, opts execOptions) (stdout, stderr string, exitCode int, dur time.Duration, err error) {
 	if timeoutSec <= 0 {
 		timeoutSec = 30
 	}
 	runCtx, cancel := context.WithTimeout(ctx, time.Duration(timeoutSec)*time.Second)
 	defer cancel()
 
+	var pidFile string
+	if opts.maxWorkspaceMB > 0 || opts.killOnCancel {
+		pidFile = fmt.Sprintf("/tmp/.adde-exec-%d.pid", time.Now().UnixNano())
+		cmd = pidFileCommand(cmd, pidFile)
+	}
+
+	workingDir := opts.workingDir
+	if workingDir == "" {
+		workingDir = WorkspacePathInsideContainer
+	}
 	cfg := types.ExecConfig{
 		Cmd:          cmd,
+		AttachStdin:  opts.stdin != nil,
 		AttachStdout: true,
 		AttachStderr: true,
-		WorkingDir:   WorkspacePathInsideContainer,
+		Tty:          opts.tty,
+		WorkingDir:   workingDir,
+		Env:          opts.env,
 	}
 	start := time.Now()
 	createResp, err := cli.ContainerExecCreate(runCtx, containerID, cfg)
@@ -34,20 +66,60 @@ func runExec(ctx context.Context, cli *client.Client, containerID string, cmd []
 
 	// Attach before Start so we have the stream when the process runs; otherwise we can
 	// read "exec command has already run" instead of real stdout/stderr.
-	resp, err := cli.ContainerExecAttach(runCtx, createResp.ID, types.ExecStartCheck{})
+	resp, err := cli.ContainerExecAttach(runCtx, createResp.ID, types.ExecStartCheck{Tty: opts.tty})
 	if err != nil {
 		return "", "", -1, 0, err
 	}
 	defer resp.Close()
 
-	err = cli.ContainerExecStart(runCtx, createResp.ID, types.ExecStartCheck{})
+	// Armed before Start, so a cancel that lands while Start is in flight still kills the program.
+	var disarm func()
+	if opts.killOnCancel {
+		disarm = killOnDone(runCtx, cli, containerID, pidFile, resp.Close)
+	}
+	err = cli.ContainerExecStart(runCtx, createResp.ID, types.ExecStartCheck{Tty: opts.tty})
 	if err != nil {
+		if disarm != nil {
+			disarm()
+			removePIDFile(cli, containerID, pidFile)
+		}
 		return "", "", -1, 0, err
 	}
 
+	if opts.stdin != nil {
+		go func() {
+			// A program that exits without reading all input breaks the pipe; that is not our error.
+			_, _ = io.Copy(resp.Conn, opts.stdin)
+			_ = resp.CloseWrite()
+		}()
+	}
+
+	var stopGuard func() *workspaceLimitError
+	if opts.maxWorkspaceMB > 0 {
+		stopGuard = workspaceGuard(runCtx, cli, containerID, pidFile, opts.maxWorkspaceMB)
+	}
+
 	var outBuf, errBuf bytes.Buffer
-	_, err = stdcopy.StdCopy(&outBuf, &errBuf, resp.Reader)
+	if opts.tty {
+		// A TTY stream is raw (not multiplexed), so everything lands in stdout.
+		_, err = io.Copy(&outBuf, resp.Reader)
+	} else {
+		_, err = stdcopy.StdCopy(&outBuf, &errBuf, resp.Reader)
+	}
+	if disarm != nil {
+		disarm()
+	}
+	var limitErr *workspaceLimitError
+	if stopGuard != nil {
+		limitErr = stopGuard()
+	} else if pidFile != "" {
+		removePIDFile(cli, containerID, pidFile)
+	}
 	if err != nil && err != io.EOF {
+		if runCtx.Err() != nil {
+			// The stream was closed under us by killOnDone; report why rather than the read error.
+			err = runCtx.Err()
+		}
 		return "", "", -1, 0, err
 	}
 	dur = time.Since(start)
@@ -56,5 +128,8 @@ func runExec(ctx context.Context, cli *client.Client, containerID string, cmd []
 	if err != nil {
 		return outBuf.String(), errBuf.String(), -1, dur, err
 	}
+	if limitErr != nil {
+		return outBuf.String(), errBuf.String(), inspect.ExitCode, dur, limitErr
+	}
 	return outBuf.String(), errBuf.String(), inspect.ExitCode, dur, nil
 }
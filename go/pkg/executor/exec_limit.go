@@ -0,0 +1,67 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/client"
+)
+
+// LabelMaxExecutions records create_runtime_env's max_executions on the container.
+const LabelMaxExecutions = "adde.max_executions"
+
+// execCountPath holds the number of execute_code_block runs so far. Labels are immutable
+// after create, so the running count lives in the workspace next to the last-run log.
+const execCountPath = ".adde_exec_count"
+
+// maxExecutionsLabels returns the labels to set on a new container for max_executions (nil when unlimited).
+func maxExecutionsLabels(max int) (map[string]string, error) {
+	if max < 0 {
+		return nil, fmt.Errorf("max_executions must not be negative")
+	}
+	if max == 0 {
+		return nil, nil
+	}
+	return map[string]string{LabelMaxExecutions: strconv.Itoa(max)}, nil
+}
+
+// maxExecutionsFromLabels parses LabelMaxExecutions; 0 means unlimited.
+func maxExecutionsFromLabels(labels map[string]string) int {
+	n, err := strconv.Atoi(labels[LabelMaxExecutions])
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// countExecution increments the container's run counter and reports whether it has reached the
+// max_executions limit. Containers created without a limit are left untouched.
+func countExecution(ctx context.Context, cli *client.Client, containerID string) (limitReached bool, err error) {
+	inspect, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return false, err
+	}
+	if inspect.Config == nil {
+		return false, nil
+	}
+	max := maxExecutionsFromLabels(inspect.Config.Labels)
+	if max == 0 {
+		return false, nil
+	}
+	script := fmt.Sprintf(`f=%s/%s; n=$(cat "$f" 2>/dev/null || echo 0); n=$((n+1)); echo $n > "$f"; echo $n`,
+		WorkspacePathInsideContainer, execCountPath)
+	stdout, stderr, exitCode, _, err := runExec(ctx, cli, containerID, []string{"sh", "-c", script}, 10)
+	if err != nil {
+		return false, err
+	}
+	if exitCode != 0 {
+		return false, fmt.Errorf("update execution count: %s", strings.TrimSpace(stderr))
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(stdout))
+	if err != nil {
+		return false, fmt.Errorf("update execution count: unexpected output %q", stdout)
+	}
+	return count >= max, nil
+}
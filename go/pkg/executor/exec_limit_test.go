@@ -0,0 +1,38 @@
+package executor
+
+import "testing"
+
+func TestMaxExecutionsLabels(t *testing.T) {
+	labels, err := maxExecutionsLabels(0)
+	if err != nil || labels != nil {
+		t.Errorf("0: labels=%v err=%v, want nil", labels, err)
+	}
+	if _, err := maxExecutionsLabels(-1); err == nil {
+		t.Error("-1: expected error")
+	}
+	labels, err = maxExecutionsLabels(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := maxExecutionsFromLabels(labels); got != 3 {
+		t.Errorf("round trip = %d, want 3", got)
+	}
+}
+
+func TestMaxExecutionsFromLabels(t *testing.T) {
+	cases := []struct {
+		labels map[string]string
+		want   int
+	}{
+		{nil, 0},
+		{map[string]string{"other": "1"}, 0},
+		{map[string]string{LabelMaxExecutions: "junk"}, 0},
+		{map[string]string{LabelMaxExecutions: "-4"}, 0},
+		{map[string]string{LabelMaxExecutions: "5"}, 5},
+	}
+	for _, tc := range cases {
+		if got := maxExecutionsFromLabels(tc.labels); got != tc.want {
+			t.Errorf("%v: got %d, want %d", tc.labels, got, tc.want)
+		}
+	}
+}
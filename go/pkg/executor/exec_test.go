@@ -0,0 +1,216 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// catDaemon fakes an exec of cat: the hijacked attach stream echoes stdin back as multiplexed stdout,
+// chunk by chunk, as the real program would; with no stdin attached it exits at once. gotStdin
+// reports whether the exec was created with AttachStdin.
+func catDaemon(t *testing.T, gotStdin *bool) *client.Client {
+	t.Helper()
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/c1/exec"):
+			body, _ := io.ReadAll(r.Body)
+			*gotStdin = strings.Contains(string(body), `"AttachStdin":true`)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"Id":"e1"}`))
+		case strings.HasSuffix(r.URL.Path, "/exec/e1/start") && r.Header.Get("Upgrade") != "":
+			io.Copy(io.Discard, r.Body) // the ExecStartCheck JSON; it must not be echoed
+			conn, rw, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			go func() {
+				defer conn.Close()
+				rw.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.multiplexed-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+				rw.Flush()
+				if !*gotStdin {
+					return // nothing attached to read: cat sees EOF at once
+				}
+				buf := make([]byte, 32*1024)
+				for {
+					n, err := rw.Read(buf)
+					if n > 0 {
+						hdr := make([]byte, 8)
+						hdr[0] = 1 // stdout
+						binary.BigEndian.PutUint32(hdr[4:], uint32(n))
+						if _, werr := conn.Write(append(hdr, buf[:n]...)); werr != nil {
+							return
+						}
+					}
+					if err != nil {
+						return
+					}
+				}
+			}()
+		case strings.HasSuffix(r.URL.Path, "/exec/e1/start"):
+			w.WriteHeader(http.StatusOK)
+		case strings.HasSuffix(r.URL.Path, "/exec/e1/json"):
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"ExitCode":0}`))
+		default:
+			http.Error(w, `{"message":"not implemented"}`, http.StatusNotImplemented)
+		}
+	}))
+	return cli
+}
+
+func TestRunExecStdinLargePayload(t *testing.T) {
+	// 1MB in each direction is far more than the socket buffers hold, so this deadlocks unless stdin
+	// is written while the output is being read.
+	var attached bool
+	cli := catDaemon(t, &attached)
+	input := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	stdout, _, exitCode, _, err := runExecWith(context.Background(), cli, "c1", []string{"cat"}, 10, execOptions{stdin: bytes.NewReader(input)})
+	if err != nil {
+		t.Fatalf("runExecWith: %v", err)
+	}
+	if !attached {
+		t.Error("exec was created without AttachStdin")
+	}
+	if exitCode != 0 || len(stdout) != len(input) || stdout != string(input) {
+		t.Errorf("exit %d, echoed %d bytes, want %d", exitCode, len(stdout), len(input))
+	}
+}
+
+func TestRunExecNoStdinDoesNotAttach(t *testing.T) {
+	attached := true
+	cli := catDaemon(t, &attached)
+	if _, _, _, _, err := runExecWith(context.Background(), cli, "c1", []string{"cat"}, 10, execOptions{}); err != nil {
+		t.Fatalf("runExecWith: %v", err)
+	}
+	if attached {
+		t.Error("exec attached stdin although none was given")
+	}
+}
+
+func TestRunExecRemovesPIDFile(t *testing.T) {
+	var mu sync.Mutex
+	var cmds [][]string
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/c1/exec"):
+			var cfg types.ExecConfig
+			json.NewDecoder(r.Body).Decode(&cfg)
+			mu.Lock()
+			cmds = append(cmds, cfg.Cmd)
+			mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"Id":"e1"}`))
+		case strings.HasSuffix(r.URL.Path, "/exec/e1/start") && r.Header.Get("Upgrade") != "":
+			io.Copy(io.Discard, r.Body)
+			conn, rw, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			rw.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.multiplexed-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+			rw.Flush()
+			conn.Close()
+		case strings.HasSuffix(r.URL.Path, "/exec/e1/start"):
+			w.WriteHeader(http.StatusOK)
+		case strings.HasSuffix(r.URL.Path, "/exec/e1/json"):
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"ExitCode":0}`))
+		default:
+			http.Error(w, `{"message":"not implemented"}`, http.StatusNotImplemented)
+		}
+	}))
+	if _, _, _, _, err := runExecWith(context.Background(), cli, "c1", []string{"true"}, 10, execOptions{maxWorkspaceMB: 100}); err != nil {
+		t.Fatalf("runExecWith: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(cmds) < 2 {
+		t.Fatalf("execs = %q, want the program then the cleanup", cmds)
+	}
+	pidFile := cmds[0][3]
+	last := cmds[len(cmds)-1]
+	if strings.Join(last, " ") != "rm -f "+pidFile {
+		t.Errorf("last exec = %q, want rm -f %s", last, pidFile)
+	}
+}
+
+func TestRunExecKillsProgramOnCancel(t *testing.T) {
+	var mu sync.Mutex
+	var cmds [][]string
+	running := make(chan struct{})
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/c1/exec"):
+			var cfg types.ExecConfig
+			json.NewDecoder(r.Body).Decode(&cfg)
+			mu.Lock()
+			cmds = append(cmds, cfg.Cmd)
+			id := fmt.Sprintf("e%d", len(cmds))
+			mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"Id":"` + id + `"}`))
+		case strings.HasSuffix(r.URL.Path, "/start") && r.Header.Get("Upgrade") != "":
+			io.Copy(io.Discard, r.Body)
+			conn, rw, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			rw.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.multiplexed-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+			rw.Flush()
+			if strings.HasSuffix(r.URL.Path, "/exec/e1/start") {
+				// The program never exits on its own: the stream stays open until the client closes it.
+				io.Copy(io.Discard, conn)
+			}
+			conn.Close()
+		case strings.HasSuffix(r.URL.Path, "/start"):
+			w.WriteHeader(http.StatusOK)
+			if strings.HasSuffix(r.URL.Path, "/exec/e1/start") {
+				close(running)
+			}
+		case strings.HasSuffix(r.URL.Path, "/json"):
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"ExitCode":0}`))
+		default:
+			http.Error(w, `{"message":"not implemented"}`, http.StatusNotImplemented)
+		}
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-running
+		cancel() // as on SIGINT
+	}()
+	_, _, _, _, err := runExecWith(ctx, cli, "c1", []string{"sleep", "120"}, 60, execOptions{killOnCancel: true})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(cmds) < 3 {
+		t.Fatalf("execs = %q, want the program, the kill and the cleanup", cmds)
+	}
+	pidFile := cmds[0][3]
+	if kill := cmds[1]; kill[0] != "sh" || !strings.Contains(kill[2], "kill -9") || kill[3] != pidFile {
+		t.Errorf("exec after cancel = %q, want a kill of the PID in %s", kill, pidFile)
+	}
+	if last := strings.Join(cmds[len(cmds)-1], " "); last != "rm -f "+pidFile {
+		t.Errorf("last exec = %q, want rm -f %s", last, pidFile)
+	}
+}
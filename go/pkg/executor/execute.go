@@ -5,8 +5,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"path"
+	"regexp"
 	"strings"
 	"time"
 
@@ -19,28 +23,87 @@ const lastRunPath = ".adde_last_run.json"
 // ExecuteCodeBlock writes code into the container via put_archive and runs it with a timeout.
 // Returns the structured log (stdout/stderr/exit_code/execution_time) for the refiner agent.
 func ExecuteCodeBlock(ctx context.Context, cli *client.Client, p ExecuteCodeBlockParams) ExecuteCodeBlockResult {
-	timeout := 30
+	timeout := int(DefaultExecutionTimeout.Seconds())
 	if p.TimeoutSec > 0 {
 		timeout = p.TimeoutSec
 	}
 
-	// Safe file transfer: build tar with only the file content (no shell interpolation)
-	tarBuf, err := buildTarStream(p.Filename, p.CodeContent)
+	code, err := decodeContent(p.CodeContent, p.ContentEncoding)
+	if err != nil {
+		return ExecuteCodeBlockResult{Error: "code_content: " + err.Error()}
+	}
+
+	outputEncoding, err := validateOutputEncoding(p.StdoutEncoding)
+	if err != nil {
+		return ExecuteCodeBlockResult{Error: err.Error()}
+	}
+
+	workDir, err := execWorkingDir(p.WorkingDir)
+	if err != nil {
+		return ExecuteCodeBlockResult{Error: err.Error()}
+	}
+
+	// Run based on extension; path in container is <working_dir>/<filename>
+	cmd, err := commandForExecution(path.Join(workDir, p.Filename), p.Filename, p.Interpreter, p.FallbackShell)
+	if err != nil {
+		return ExecuteCodeBlockResult{Error: err.Error()}
+	}
+	if p.Interpreter == "" && usesFallback(p.Filename) {
+		if err := requireShell(ctx, cli, p.ContainerID, cmd[0]); err != nil {
+			return ExecuteCodeBlockResult{Error: err.Error()}
+		}
+	}
+	var profileCmd, coverageReportCmd, coverageEnv []string
+	if p.Profile && p.Coverage {
+		return ExecuteCodeBlockResult{Error: "profile and coverage cannot be combined"}
+	}
+	if p.Profile {
+		if cmd, profileCmd, err = profiledCommand(cmd, p.Filename); err != nil {
+			return ExecuteCodeBlockResult{Error: err.Error()}
+		}
+	}
+	if p.Coverage {
+		if cmd, coverageReportCmd, coverageEnv, err = coverageCommands(cmd, p.Filename, p.CoverageTool); err != nil {
+			return ExecuteCodeBlockResult{Error: err.Error()}
+		}
+	}
+	resultFormat, err := normalizeResultFormat(p.ResultFormat)
 	if err != nil {
 		return ExecuteCodeBlockResult{Error: err.Error()}
 	}
+	var testRunner string
+	if resultFormat == ResultFormatJUnit {
+		if p.Profile || p.Coverage {
+			return ExecuteCodeBlockResult{Error: "result_format cannot be combined with profile or coverage"}
+		}
+		if cmd, testRunner, err = junitCommand(cmd, p.Filename); err != nil {
+			return ExecuteCodeBlockResult{Error: err.Error()}
+		}
+	}
 
-	err = cli.CopyToContainer(ctx, p.ContainerID, WorkspacePathInsideContainer, tarBuf, types.CopyToContainerOptions{})
+	stdin, closeStdin, err := openStdin(p)
 	if err != nil {
 		return ExecuteCodeBlockResult{Error: err.Error()}
 	}
+	defer closeStdin()
 
-	// Run based on extension; path in container is /workspace/<filename>
-	fp := path.Join(WorkspacePathInsideContainer, p.Filename)
-	cmd := runCommandForFile(fp, p.Filename)
+	if err := prepareCodeFile(ctx, cli, p.ContainerID, workDir, path.Join(workDir, p.Filename), p.CleanWorkspace, p.CleanArtifacts); err != nil {
+		return ExecuteCodeBlockResult{Error: err.Error()}
+	}
+	if err := copyCodeFile(ctx, cli, p.ContainerID, workDir, p.Filename, code, p.CreateWorkspace); err != nil {
+		return ExecuteCodeBlockResult{Error: err.Error()}
+	}
+
+	restorePids, err := applyExecPidsLimit(ctx, cli, p.ContainerID, p.PidsLimit)
+	if err != nil {
+		return ExecuteCodeBlockResult{Error: err.Error()}
+	}
+	defer restorePids()
 
-	stdout, stderr, exitCode, dur, execErr := runExec(ctx, cli, p.ContainerID, cmd, timeout)
-	if execErr != nil {
+	opts := execOptions{tty: p.Tty, maxWorkspaceMB: p.MaxWorkspaceMB, killOnCancel: true, stdin: stdin, workingDir: workDir, env: coverageEnv}
+	stdout, stderr, exitCode, dur, execErr := runExecWith(ctx, cli, p.ContainerID, cmd, timeout, opts)
+	var limitErr *workspaceLimitError
+	if execErr != nil && !errors.As(execErr, &limitErr) {
 		return ExecuteCodeBlockResult{Error: execErr.Error()}
 	}
 
@@ -50,11 +113,113 @@ func ExecuteCodeBlock(ctx context.Context, cli *client.Client, p ExecuteCodeBloc
 		Stderr:        stderr,
 		ExecutionTime: formatDuration(dur),
 	}
+	if p.Transcript {
+		// Before encoding, so the transcript stays readable; a failed append never masks the run's result.
+		_ = appendTranscript(ctx, cli, p.ContainerID, p.Filename, logEntry, time.Now())
+	}
+	if profileCmd != nil {
+		logEntry.Profile = profileSummary(ctx, cli, p.ContainerID, profileCmd, workDir)
+	}
+	encodeLogOutput(logEntry, outputEncoding)
+	if limitErr != nil {
+		// Keep the partial output so the agent can see what the program was doing when it was killed.
+		_ = persistLastRun(ctx, cli, p.ContainerID, logEntry)
+		res := ExecuteCodeBlockResult{Log: logEntry, Error: limitErr.Error()}
+		res.Recycled = recycleIfExhausted(ctx, cli, p.ContainerID)
+		return res
+	}
 
 	// Persist last run so get_container_logs can read it
 	_ = persistLastRun(ctx, cli, p.ContainerID, logEntry)
 
-	return ExecuteCodeBlockResult{Log: logEntry}
+	res := ExecuteCodeBlockResult{Log: logEntry}
+	if coverageReportCmd != nil {
+		res.Coverage = collectCoverage(ctx, cli, p.ContainerID, coverageReportCmd, coverageEnv, workDir)
+	}
+	if testRunner != "" {
+		res.TestReport = collectJUnitReport(ctx, cli, p.ContainerID, testRunner)
+	}
+	if len(p.CaptureArtifacts) > 0 {
+		artifacts, err := listArtifacts(ctx, cli, p.ContainerID, p.CaptureArtifacts)
+		if err != nil {
+			res.Error = "capture_artifacts: " + err.Error()
+		}
+		res.Artifacts = artifacts
+	}
+	res.Recycled = recycleIfExhausted(ctx, cli, p.ContainerID)
+	return res
+}
+
+// execWorkingDir validates working_dir: an absolute path without "..", cleaned; empty means /workspace.
+func execWorkingDir(dir string) (string, error) {
+	if dir == "" {
+		return WorkspacePathInsideContainer, nil
+	}
+	if !path.IsAbs(dir) || strings.Contains(dir, "..") || strings.ContainsRune(dir, 0) {
+		return "", fmt.Errorf("invalid working_dir %q (want an absolute path like /app)", dir)
+	}
+	return path.Clean(dir), nil
+}
+
+// copyCodeFile writes code to workDir/filename in the container. Safe file transfer: the tar holds
+// only the file content (no shell interpolation). With createWorkspace, a missing /workspace is
+// created too, for containers that create_runtime_env did not make.
+func copyCodeFile(ctx context.Context, cli *client.Client, containerID, workDir, filename string, code []byte, createWorkspace bool) error {
+	copyDir, tarName := codeFilePlacement(workDir, filename, createWorkspace)
+	tarBuf, err := buildTarStream(tarName, string(code))
+	if err != nil {
+		return err
+	}
+	err = cli.CopyToContainer(ctx, containerID, copyDir, tarBuf, types.CopyToContainerOptions{})
+	if client.IsErrNotFound(err) && copyDir == WorkspacePathInsideContainer {
+		return fmt.Errorf("%v (if the container has no %s, set create_workspace: true)", err, WorkspacePathInsideContainer)
+	}
+	return err
+}
+
+// codeFilePlacement returns the CopyToContainer destination and tar entry name for filename in workDir.
+// /workspace exists in containers from create_runtime_env (it is the bind mount); any other dir, or
+// /workspace with createWorkspace, is reached from / so the archive creates it when missing.
+func codeFilePlacement(workDir, filename string, createWorkspace bool) (copyDir, tarName string) {
+	if workDir == WorkspacePathInsideContainer && !createWorkspace {
+		return WorkspacePathInsideContainer, filename
+	}
+	return "/", strings.TrimPrefix(path.Join(workDir, filename), "/")
+}
+
+// recycleIfExhausted counts this run against max_executions and removes the container once the
+// limit is reached. Counting failures are ignored so they never mask the run's own result.
+func recycleIfExhausted(ctx context.Context, cli *client.Client, containerID string) bool {
+	reached, err := countExecution(ctx, cli, containerID)
+	if err != nil || !reached {
+		return false
+	}
+	return CleanupEnv(ctx, cli, CleanupEnvParams{ContainerID: containerID}).OK
+}
+
+// openStdin returns the reader for stdin / stdin_file (nil when neither is set) and its closer.
+func openStdin(p ExecuteCodeBlockParams) (io.Reader, func(), error) {
+	noop := func() {}
+	switch {
+	case p.Stdin != "" && p.StdinFile != "":
+		return nil, noop, fmt.Errorf("stdin and stdin_file are mutually exclusive")
+	case p.StdinFile != "":
+		info, err := os.Stat(p.StdinFile)
+		if err != nil {
+			return nil, noop, fmt.Errorf("stdin_file: %w", err)
+		}
+		if !info.Mode().IsRegular() {
+			return nil, noop, fmt.Errorf("stdin_file: %s is not a regular file", p.StdinFile)
+		}
+		f, err := os.Open(p.StdinFile)
+		if err != nil {
+			return nil, noop, fmt.Errorf("stdin_file: %w", err)
+		}
+		return f, func() { f.Close() }, nil
+	case p.Stdin != "":
+		return strings.NewReader(p.Stdin), noop, nil
+	}
+	return nil, noop, nil
 }
 
 func buildTarStream(filename, content string) (*bytes.Buffer, error) {
@@ -77,20 +242,85 @@ func buildTarStream(filename, content string) (*bytes.Buffer, error) {
 	return &buf, nil
 }
 
+// languageRunners maps file extensions to the command that runs them; list_languages reports this table.
+var languageRunners = []struct {
+	lang    string
+	exts    []string
+	command []string // the file path is appended
+}{
+	{"python", []string{".py"}, []string{"python"}},
+	{"javascript", []string{".js", ".mjs"}, []string{"node"}},
+	{"typescript", []string{".ts"}, []string{"npx", "--yes", "ts-node"}},
+	{"shell", []string{".sh"}, []string{"sh"}},
+}
+
+// interpreterRe accepts a bare binary name or an absolute path made of the same characters.
+var interpreterRe = regexp.MustCompile(`^/?[A-Za-z0-9_][A-Za-z0-9._+-]*(/[A-Za-z0-9_][A-Za-z0-9._+-]*)*$`)
+
+// fallbackRunner runs files with any other extension as a script of the fallback shell (default sh).
+// The file is written without the execute bit, so it is passed to the shell rather than run directly.
+var fallbackRunner = []string{"sh"}
+
+// commandForExecution is runCommandForFile with an optional interpreter override. The extension-based
+// arguments are kept; only the binary changes (python -> python3.11, sh -> bash). fallbackShell
+// replaces sh for files no extension matches; interpreter wins over it.
+func commandForExecution(fullPath, filename, interpreter, fallbackShell string) ([]string, error) {
+	cmd := runCommandForFile(fullPath, filename)
+	if fallbackShell != "" && usesFallback(filename) {
+		if !interpreterRe.MatchString(fallbackShell) || strings.Contains(fallbackShell, "..") {
+			return nil, fmt.Errorf("invalid fallback_shell %q (want a shell name like bash or an absolute path)", fallbackShell)
+		}
+		cmd[0] = fallbackShell
+	}
+	if interpreter == "" {
+		return cmd, nil
+	}
+	if !interpreterRe.MatchString(interpreter) || strings.Contains(interpreter, "..") {
+		return nil, fmt.Errorf("invalid interpreter %q (want a binary name like python3.11 or an absolute path)", interpreter)
+	}
+	cmd[0] = interpreter
+	return cmd, nil
+}
+
+// usesFallback reports whether filename has no entry in languageRunners.
+func usesFallback(filename string) bool {
+	_, command := languageRunnerFor(filename)
+	return command == nil
+}
+
+// languageRunnerFor returns the language and command for filename's extension, or "" and nil.
+func languageRunnerFor(filename string) (string, []string) {
+	ext := strings.ToLower(path.Ext(filename))
+	for _, r := range languageRunners {
+		for _, e := range r.exts {
+			if e == ext {
+				return r.lang, r.command
+			}
+		}
+	}
+	return "", nil
+}
+
+// requireShell checks that shell can be started in the container, so a missing shell is reported
+// as such instead of as an opaque exec failure of the code file.
+func requireShell(ctx context.Context, cli *client.Client, containerID, shell string) error {
+	_, _, exitCode, _, err := runExec(ctx, cli, containerID, []string{shell, "-c", "exit 0"}, 10)
+	if err != nil || shellMissing(exitCode) {
+		return fmt.Errorf("fallback shell %q not found in the container (set fallback_shell, e.g. bash, or interpreter)", shell)
+	}
+	return nil
+}
+
+// shellMissing is true for the exit codes the runtime uses when the binary cannot be found or run.
+func shellMissing(exitCode int) bool {
+	return exitCode == 126 || exitCode == 127
+}
+
 func runCommandForFile(fullPath, filename string) []string {
-	base := strings.ToLower(path.Ext(filename))
-	switch base {
-	case ".py":
-		return []string{"python", fullPath}
-	case ".js", ".mjs":
-		return []string{"node", fullPath}
-	case ".ts":
-		return []string{"npx", "--yes", "ts-node", fullPath}
-	case ".sh":
-		return []string{"sh", fullPath}
-	default:
-		return []string{"sh", "-c", fullPath}
+	if _, command := languageRunnerFor(filename); command != nil {
+		return append(append([]string(nil), command...), fullPath)
 	}
+	return append(append([]string(nil), fallbackRunner...), fullPath)
 }
 
 func formatDuration(d time.Duration) string {
@@ -0,0 +1,244 @@
+package executor
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestOpenStdin(t *testing.T) {
+	r, closeFn, err := openStdin(ExecuteCodeBlockParams{})
+	if err != nil || r != nil {
+		t.Errorf("neither set: r=%v err=%v", r, err)
+	}
+	closeFn()
+
+	r, closeFn, err = openStdin(ExecuteCodeBlockParams{Stdin: "a\nb\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(r); string(data) != "a\nb\n" {
+		t.Errorf("stdin = %q", data)
+	}
+	closeFn()
+
+	path := filepath.Join(t.TempDir(), "input.txt")
+	if err := os.WriteFile(path, []byte("from file\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, closeFn, err = openStdin(ExecuteCodeBlockParams{StdinFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(r); string(data) != "from file\n" {
+		t.Errorf("stdin_file = %q", data)
+	}
+	closeFn()
+
+	for name, p := range map[string]ExecuteCodeBlockParams{
+		"both":      {Stdin: "x", StdinFile: path},
+		"missing":   {StdinFile: filepath.Join(t.TempDir(), "nope.txt")},
+		"directory": {StdinFile: t.TempDir()},
+	} {
+		if _, _, err := openStdin(p); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestCommandForExecutionInterpreter(t *testing.T) {
+	cases := []struct {
+		filename, interpreter, want string
+	}{
+		{"main.py", "", "python /workspace/main.py"},
+		{"main.py", "python3.11", "python3.11 /workspace/main.py"},
+		{"app.js", "/usr/local/n/versions/node/20.10.0/bin/node", "/usr/local/n/versions/node/20.10.0/bin/node /workspace/app.js"},
+		{"run", "bash", "bash /workspace/run"},
+	}
+	for _, tc := range cases {
+		cmd, err := commandForExecution("/workspace/"+tc.filename, tc.filename, tc.interpreter, "")
+		if err != nil {
+			t.Errorf("%s/%s: %v", tc.filename, tc.interpreter, err)
+			continue
+		}
+		if got := strings.Join(cmd, " "); got != tc.want {
+			t.Errorf("%s/%s: got %q, want %q", tc.filename, tc.interpreter, got, tc.want)
+		}
+	}
+
+	for _, bad := range []string{"python; rm -rf /", "python 3", "-c", "../../bin/sh", "/usr/bin/", "$(id)"} {
+		if _, err := commandForExecution("/workspace/main.py", "main.py", bad, ""); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestCommandForExecutionFallbackShell(t *testing.T) {
+	cases := []struct {
+		filename, interpreter, shell, want string
+	}{
+		{"run", "", "", "sh /workspace/run"},
+		{"run", "", "bash", "bash /workspace/run"},
+		{"setup.env", "", "/bin/ash", "/bin/ash /workspace/setup.env"},
+		{"main.py", "", "bash", "python /workspace/main.py"}, // only the fallback uses the shell
+		{"run", "zsh", "bash", "zsh /workspace/run"},         // interpreter wins
+	}
+	for _, tc := range cases {
+		cmd, err := commandForExecution("/workspace/"+tc.filename, tc.filename, tc.interpreter, tc.shell)
+		if err != nil {
+			t.Errorf("%s/%s: %v", tc.filename, tc.shell, err)
+			continue
+		}
+		if got := strings.Join(cmd, " "); got != tc.want {
+			t.Errorf("%s/%s: got %q, want %q", tc.filename, tc.shell, got, tc.want)
+		}
+	}
+	if _, err := commandForExecution("/workspace/run", "run", "", "bash -x"); err == nil {
+		t.Error("expected error for an invalid fallback_shell")
+	}
+}
+
+func TestExecuteCodeBlockMissingFallbackShell(t *testing.T) {
+	var mu sync.Mutex
+	var probes [][]string
+	copied := false
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/containers/c1/archive"):
+			copied = true
+		case strings.HasSuffix(r.URL.Path, "/containers/c1/exec"):
+			var cfg types.ExecConfig
+			json.NewDecoder(r.Body).Decode(&cfg)
+			probes = append(probes, cfg.Cmd)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"Id":"e1"}`))
+		default:
+			// Starting the exec fails, as it does when the binary is not in the image.
+			http.Error(w, `{"message":"exec: \"bash\": executable file not found in $PATH"}`, http.StatusBadRequest)
+		}
+	}))
+
+	res := ExecuteCodeBlock(context.Background(), cli, ExecuteCodeBlockParams{ContainerID: "c1", Filename: "run", CodeContent: "echo hi", FallbackShell: "bash"})
+	if !strings.Contains(res.Error, `fallback shell "bash" not found`) {
+		t.Errorf("error = %q", res.Error)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(probes) != 1 || probes[0][0] != "bash" {
+		t.Errorf("probes = %q, want one bash probe", probes)
+	}
+	if copied {
+		t.Error("code was copied although the shell is missing")
+	}
+}
+
+func TestExecuteCodeBlockWorkingDirPlacement(t *testing.T) {
+	var mu sync.Mutex
+	var copyPath, tarName string
+	var execCfg types.ExecConfig
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/containers/c1/archive"):
+			copyPath = r.URL.Query().Get("path")
+			if hdr, err := tar.NewReader(r.Body).Next(); err == nil {
+				tarName = hdr.Name
+			}
+		case strings.HasSuffix(r.URL.Path, "/containers/c1/exec"):
+			json.NewDecoder(r.Body).Decode(&execCfg)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"Id":"e1"}`))
+		default:
+			http.Error(w, `{"message":"not implemented"}`, http.StatusNotImplemented)
+		}
+	}))
+
+	// The script opens its sibling by a relative path, so it must be placed in and run from /app.
+	code := "print(open('config.json').read())"
+	ExecuteCodeBlock(context.Background(), cli, ExecuteCodeBlockParams{ContainerID: "c1", Filename: "main.py", CodeContent: code, WorkingDir: "/app/"})
+	mu.Lock()
+	defer mu.Unlock()
+	if copyPath != "/" || tarName != "app/main.py" {
+		t.Errorf("copied %q into %q, want app/main.py into /", tarName, copyPath)
+	}
+	if execCfg.WorkingDir != "/app" || strings.Join(programCommand(execCfg.Cmd), " ") != "python /app/main.py" {
+		t.Errorf("exec working dir %q, cmd %q", execCfg.WorkingDir, execCfg.Cmd)
+	}
+	if sibling := path.Join(execCfg.WorkingDir, "config.json"); sibling != "/app/config.json" {
+		t.Errorf("relative path resolves to %s", sibling)
+	}
+}
+
+func TestExecuteCodeBlockCreateWorkspace(t *testing.T) {
+	// An external container (e.g. from docker compose) without /workspace: copying into it is a 404.
+	var mu sync.Mutex
+	var copyPath, tarName string
+	var execCfg types.ExecConfig
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/containers/c1/archive"):
+			copyPath = r.URL.Query().Get("path")
+			if copyPath == "/workspace" {
+				http.Error(w, `{"message":"Could not find the file /workspace in container c1"}`, http.StatusNotFound)
+				return
+			}
+			if hdr, err := tar.NewReader(r.Body).Next(); err == nil {
+				tarName = hdr.Name
+			}
+		case strings.HasSuffix(r.URL.Path, "/containers/c1/exec"):
+			json.NewDecoder(r.Body).Decode(&execCfg)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"Id":"e1"}`))
+		default:
+			http.Error(w, `{"message":"not implemented"}`, http.StatusNotImplemented)
+		}
+	}))
+
+	p := ExecuteCodeBlockParams{ContainerID: "c1", Filename: "main.py", CodeContent: "print(1)"}
+	if res := ExecuteCodeBlock(context.Background(), cli, p); !strings.Contains(res.Error, "create_workspace: true") {
+		t.Errorf("without create_workspace: error = %q, want a create_workspace hint", res.Error)
+	}
+
+	p.CreateWorkspace = true
+	ExecuteCodeBlock(context.Background(), cli, p)
+	mu.Lock()
+	defer mu.Unlock()
+	if copyPath != "/" || tarName != "workspace/main.py" {
+		t.Errorf("copied %q into %q, want workspace/main.py into /", tarName, copyPath)
+	}
+	if execCfg.WorkingDir != "/workspace" || strings.Join(programCommand(execCfg.Cmd), " ") != "python /workspace/main.py" {
+		t.Errorf("exec working dir %q, cmd %q", execCfg.WorkingDir, execCfg.Cmd)
+	}
+}
+
+func TestExecWorkingDir(t *testing.T) {
+	if dir, err := execWorkingDir(""); err != nil || dir != WorkspacePathInsideContainer {
+		t.Errorf("default: %q, %v", dir, err)
+	}
+	if dir, tarName := codeFilePlacement(WorkspacePathInsideContainer, "pkg/a.py", false); dir != "/workspace" || tarName != "pkg/a.py" {
+		t.Errorf("workspace placement: %q %q", dir, tarName)
+	}
+	if dir, tarName := codeFilePlacement(WorkspacePathInsideContainer, "pkg/a.py", true); dir != "/" || tarName != "workspace/pkg/a.py" {
+		t.Errorf("create_workspace placement: %q %q", dir, tarName)
+	}
+	for _, bad := range []string{"app", "/app/../etc", "/a\x00b"} {
+		if _, err := execWorkingDir(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package executor
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// GCWorkspaces removes adde-workspace-* and adde-build-* dirs in the system temp dir that were left
+// behind by crashed or failed runs. Dirs newer than OlderThanHrs (default 24) or still bind-mounted
+// by any container (running or stopped) are kept.
+func GCWorkspaces(ctx context.Context, cli *client.Client, p GCWorkspacesParams) GCWorkspacesResult {
+	hrs := p.OlderThanHrs
+	if hrs <= 0 {
+		hrs = 24
+	}
+	// Without the container list we cannot tell which dirs are live, so never guess.
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{All: true})
+	if err != nil {
+		return GCWorkspacesResult{DryRun: p.DryRun, Error: err.Error()}
+	}
+	inUse := make(map[string]bool)
+	for _, c := range containers {
+		for _, m := range c.Mounts {
+			inUse[filepath.Clean(m.Source)] = true
+		}
+	}
+
+	stale, err := selectStaleTempDirs(TempRoot(), time.Now().Add(-time.Duration(hrs)*time.Hour), inUse)
+	if err != nil {
+		return GCWorkspacesResult{DryRun: p.DryRun, Error: err.Error()}
+	}
+	res := GCWorkspacesResult{DryRun: p.DryRun, Removed: []string{}}
+	var reclaimed int64
+	for _, dir := range stale {
+		size := dirSize(dir)
+		if !p.DryRun {
+			if err := os.RemoveAll(dir); err != nil {
+				continue
+			}
+		}
+		res.Removed = append(res.Removed, dir)
+		reclaimed += size
+	}
+	res.Count = len(res.Removed)
+	res.ReclaimedMB = float64(reclaimed) / (1024 * 1024)
+	return res
+}
+
+// selectStaleTempDirs returns toolkit temp dirs under tmpDir last modified before cutoff and not in inUse.
+func selectStaleTempDirs(tmpDir string, cutoff time.Time, inUse map[string]bool) ([]string, error) {
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() || !(strings.HasPrefix(name, workspaceDirPrefix) || strings.HasPrefix(name, buildDirPrefix)) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		dir := filepath.Join(tmpDir, name)
+		if abs, err := filepath.Abs(dir); err == nil && inUse[filepath.Clean(abs)] {
+			continue
+		}
+		out = append(out, dir)
+	}
+	return out, nil
+}
+
+// dirSize sums regular file sizes under dir; unreadable entries are skipped.
+func dirSize(dir string) int64 {
+	var total int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
@@ -0,0 +1,46 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestSelectStaleTempDirs(t *testing.T) {
+	tmp := t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+	mk := func(name string, mtime time.Time) string {
+		dir := filepath.Join(tmp, name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "f.txt"), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(dir, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+	staleWS := mk(workspaceDirPrefix+"111", old)
+	staleBuild := mk(buildDirPrefix+"222", old)
+	live := mk(workspaceDirPrefix+"333", old)
+	mk(workspaceDirPrefix+"444", time.Now()) // recent
+	mk("other-dir", old)                     // not ours
+
+	got, err := selectStaleTempDirs(tmp, time.Now().Add(-24*time.Hour), map[string]bool{live: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	want := []string{staleBuild, staleWS}
+	sort.Strings(want)
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if size := dirSize(staleWS); size != 4 {
+		t.Errorf("dirSize = %d, want 4", size)
+	}
+}
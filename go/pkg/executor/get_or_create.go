@@ -0,0 +1,80 @@
+package executor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+// LabelEnvFingerprint records get_or_create_env's fingerprint on the containers it creates.
+const LabelEnvFingerprint = "adde.env_fingerprint"
+
+// GetOrCreateEnv returns a running container created earlier by get_or_create_env with the same
+// image, dependencies, env_vars and network, or creates one via create_runtime_env and labels it
+// for reuse. Other settings (ports, limits, ...) are applied only when a container is created.
+func GetOrCreateEnv(ctx context.Context, cli *client.Client, p GetOrCreateEnvParams) GetOrCreateEnvResult {
+	if p.IdempotencyKey != "" || p.Name != "" {
+		return GetOrCreateEnvResult{CreateRuntimeEnvResult: CreateRuntimeEnvResult{
+			Error: "name and idempotency_key cannot be used with get_or_create_env (the fingerprint identifies the container)",
+		}}
+	}
+	fingerprint, err := envFingerprint(p.CreateRuntimeEnvParams)
+	if err != nil {
+		return GetOrCreateEnvResult{CreateRuntimeEnvResult: CreateRuntimeEnvResult{Error: err.Error()}}
+	}
+	list, err := cli.ContainerList(ctx, types.ContainerListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("label", LabelManaged+"=true"),
+			filters.Arg("label", LabelEnvFingerprint+"="+fingerprint),
+			filters.Arg("status", "running"),
+		),
+	})
+	if err != nil {
+		return GetOrCreateEnvResult{CreateRuntimeEnvResult: CreateRuntimeEnvResult{Error: err.Error()}, Fingerprint: fingerprint}
+	}
+	if len(list) > 0 {
+		res, _, err := existingEnvResult(ctx, cli, list[0].ID)
+		if err != nil {
+			res = CreateRuntimeEnvResult{Error: err.Error()}
+		}
+		return GetOrCreateEnvResult{CreateRuntimeEnvResult: res, Fingerprint: fingerprint}
+	}
+	res := createRuntimeEnv(ctx, cli, p.CreateRuntimeEnvParams, map[string]string{
+		LabelManaged:        "true",
+		LabelEnvFingerprint: fingerprint,
+	})
+	return GetOrCreateEnvResult{CreateRuntimeEnvResult: res, Fingerprint: fingerprint}
+}
+
+// envFingerprint hashes the settings that make two environments interchangeable: the canonical
+// image ref, the sorted dependencies, the env (envList sorts it; timezone and locale included) and the network flag.
+func envFingerprint(p CreateRuntimeEnvParams) (string, error) {
+	image, _, err := resolveImage(p.Image)
+	if err != nil {
+		return "", err
+	}
+	vars, err := localeEnv(p)
+	if err != nil {
+		return "", err
+	}
+	env, err := envList(vars)
+	if err != nil {
+		return "", err
+	}
+	deps := append([]string(nil), p.Dependencies...)
+	sort.Strings(deps)
+	data, _ := json.Marshal(struct {
+		Image        string   `json:"image"`
+		Dependencies []string `json:"dependencies"`
+		Env          []string `json:"env"`
+		Network      bool     `json:"network"`
+	}{image, deps, env, p.Network})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
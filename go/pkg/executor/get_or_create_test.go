@@ -0,0 +1,111 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestEnvFingerprint(t *testing.T) {
+	base := CreateRuntimeEnvParams{Image: "python:3.11-slim", Dependencies: []string{"requests", "numpy"}, EnvVars: map[string]string{"A": "1", "B": "2"}}
+	a, err := envFingerprint(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	same := CreateRuntimeEnvParams{Image: "docker.io/library/python:3.11-slim", Dependencies: []string{"numpy", "requests"}, EnvVars: map[string]string{"B": "2", "A": "1"}, MemorySwapMB: 1024}
+	if b, _ := envFingerprint(same); b != a {
+		t.Error("equivalent params (canonical image, reordered deps and env, other settings) changed the fingerprint")
+	}
+	for name, p := range map[string]CreateRuntimeEnvParams{
+		"image":   {Image: "python:3.12-slim", Dependencies: base.Dependencies, EnvVars: base.EnvVars},
+		"deps":    {Image: base.Image, Dependencies: []string{"requests"}, EnvVars: base.EnvVars},
+		"env":     {Image: base.Image, Dependencies: base.Dependencies, EnvVars: map[string]string{"A": "1", "B": "3"}},
+		"network": {Image: base.Image, Dependencies: base.Dependencies, EnvVars: base.EnvVars, Network: true},
+	} {
+		if b, _ := envFingerprint(p); b == a {
+			t.Errorf("%s: fingerprint unchanged", name)
+		}
+	}
+}
+
+func TestGetOrCreateEnvReusesRunningContainer(t *testing.T) {
+	if err := SetTempRoot(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetTempRoot("") })
+
+	var mu sync.Mutex
+	labels := map[string]map[string]string{} // container id -> labels
+	creates := 0
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v1.43/images/docker.io/library/busybox:latest/json":
+			w.Write([]byte(`{"Id":"sha256:abc","Config":{}}`))
+		case r.URL.Path == "/v1.43/containers/json":
+			var f struct {
+				Label map[string]bool `json:"label"`
+			}
+			json.Unmarshal([]byte(r.URL.Query().Get("filters")), &f)
+			var out []map[string]string
+			for id, l := range labels {
+				match := true
+				for kv := range f.Label {
+					k, v, _ := strings.Cut(kv, "=")
+					match = match && l[k] == v
+				}
+				if match {
+					out = append(out, map[string]string{"Id": id})
+				}
+			}
+			json.NewEncoder(w).Encode(out)
+		case r.URL.Path == "/v1.43/containers/create":
+			var cfg container.Config
+			json.NewDecoder(r.Body).Decode(&cfg)
+			creates++
+			id := fmt.Sprintf("c%d", creates)
+			labels[id] = cfg.Labels
+			fmt.Fprintf(w, `{"Id":%q}`, id)
+		case strings.HasSuffix(r.URL.Path, "/start"):
+			w.WriteHeader(http.StatusNoContent)
+		case strings.HasPrefix(r.URL.Path, "/v1.43/containers/c") && strings.HasSuffix(r.URL.Path, "/json"):
+			id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1.43/containers/"), "/json")
+			fmt.Fprintf(w, `{"Id":%q,"Config":{"Image":"docker.io/library/busybox:latest"},"Mounts":[{"Source":"/tmp/ws-%s","Destination":"/workspace"}]}`, id, id)
+		default:
+			http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+		}
+	}))
+
+	p := GetOrCreateEnvParams{CreateRuntimeEnvParams{Image: "busybox", EnvVars: map[string]string{"MODE": "test"}}}
+	first := GetOrCreateEnv(context.Background(), cli, p)
+	if first.Error != "" || first.ContainerID != "c1" || first.Reused {
+		t.Fatalf("first call = %+v, want a new container c1", first)
+	}
+	mu.Lock()
+	if l := labels["c1"]; l[LabelEnvFingerprint] != first.Fingerprint || l[LabelManaged] != "true" {
+		t.Errorf("labels = %v", l)
+	}
+	mu.Unlock()
+
+	second := GetOrCreateEnv(context.Background(), cli, p)
+	if second.Error != "" || second.ContainerID != "c1" || !second.Reused || second.Workspace != "/tmp/ws-c1" {
+		t.Errorf("second call = %+v, want c1 reused", second)
+	}
+
+	other := GetOrCreateEnv(context.Background(), cli, GetOrCreateEnvParams{CreateRuntimeEnvParams{Image: "busybox", Network: true}})
+	if other.ContainerID != "c2" || other.Reused {
+		t.Errorf("different params = %+v, want a new container c2", other)
+	}
+
+	if res := GetOrCreateEnv(context.Background(), cli, GetOrCreateEnvParams{CreateRuntimeEnvParams{Image: "busybox", Name: "x1"}}); res.Error == "" {
+		t.Error("expected error with name")
+	}
+}
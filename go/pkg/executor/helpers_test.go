@@ -0,0 +1,55 @@
+package executor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/client"
+)
+
+// newFakeClient serves handler as a Docker daemon and returns a client pointed at it; both are
+// closed when the test ends.
+func newFakeClient(t *testing.T, handler http.Handler) *client.Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	cli, err := client.NewClientWithOpts(client.WithHost("tcp://"+strings.TrimPrefix(srv.URL, "http://")), client.WithVersion("1.43"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cli.Close() })
+	return cli
+}
+
+// fakeDaemon records the API requests it receives and answers each with status.
+func fakeDaemon(t *testing.T, status int) (*client.Client, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var calls []string
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, r.Method+" "+r.URL.RequestURI())
+		mu.Unlock()
+		if status >= 400 {
+			http.Error(w, `{"message":"Container abc is not running"}`, status)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	return cli, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), calls...)
+	}
+}
+
+// programCommand strips the pidFileCommand wrapper from an exec's Cmd, leaving the program it runs.
+func programCommand(cmd []string) []string {
+	if len(cmd) > 4 && cmd[0] == "sh" && strings.HasPrefix(cmd[3], "/tmp/.adde-exec-") {
+		return cmd[4:]
+	}
+	return cmd
+}
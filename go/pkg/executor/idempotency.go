@@ -0,0 +1,55 @@
+package executor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+// LabelIdempotencyKey records create_runtime_env's idempotency_key on the container.
+const LabelIdempotencyKey = "adde.idempotency_key"
+
+// idempotentContainerName derives a stable container name from key. Docker rejects duplicate
+// names, so two racing creates with the same key cannot both succeed.
+func idempotentContainerName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "adde-idem-" + hex.EncodeToString(sum[:])[:24]
+}
+
+// findIdempotentContainer returns the container previously created with key, or ok=false if none exists.
+func findIdempotentContainer(ctx context.Context, cli *client.Client, key string) (res CreateRuntimeEnvResult, ok bool, err error) {
+	list, err := cli.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", LabelIdempotencyKey+"="+key)),
+	})
+	if err != nil {
+		return CreateRuntimeEnvResult{}, false, err
+	}
+	if len(list) == 0 {
+		return CreateRuntimeEnvResult{}, false, nil
+	}
+	return existingEnvResult(ctx, cli, list[0].ID)
+}
+
+// existingEnvResult describes an already-created container the way CreateRuntimeEnv would have.
+func existingEnvResult(ctx context.Context, cli *client.Client, id string) (CreateRuntimeEnvResult, bool, error) {
+	inspect, err := cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return CreateRuntimeEnvResult{}, false, err
+	}
+	if inspect.Config == nil {
+		return CreateRuntimeEnvResult{}, false, fmt.Errorf("container %s has no config", id)
+	}
+	res := CreateRuntimeEnvResult{ContainerID: inspect.ID, Image: inspect.Config.Image, Reused: true}
+	for _, m := range inspect.Mounts {
+		if m.Destination == WorkspacePathInsideContainer {
+			res.Workspace = m.Source
+		}
+	}
+	return res, true, nil
+}
@@ -0,0 +1,20 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIdempotentContainerName(t *testing.T) {
+	a := idempotentContainerName("task-42/attempt")
+	if a != idempotentContainerName("task-42/attempt") {
+		t.Error("same key produced different names")
+	}
+	if a == idempotentContainerName("task-43/attempt") {
+		t.Error("different keys produced the same name")
+	}
+	// Docker names allow [a-zA-Z0-9][a-zA-Z0-9_.-]+; keys may contain anything.
+	if !strings.HasPrefix(a, "adde-idem-") || strings.ContainsAny(a, "/ :") {
+		t.Errorf("unexpected name %q", a)
+	}
+}
@@ -0,0 +1,42 @@
+package executor
+
+import (
+	"context"
+	"sort"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// ImageConfig returns the defaults an image declares (entrypoint, cmd, exposed ports, env, ...), so an
+// agent can decide on use_image_cmd and port_bindings before create_runtime_env. The image must be local.
+func ImageConfig(ctx context.Context, cli *client.Client, p ImageConfigParams) ImageConfigResult {
+	ref, err := normalizeImageRef(p.Image)
+	if err != nil {
+		return ImageConfigResult{Error: err.Error()}
+	}
+	inspect, _, err := cli.ImageInspectWithRaw(ctx, ref)
+	if err != nil {
+		return ImageConfigResult{Error: err.Error()}
+	}
+	return imageConfigResult(ref, inspect)
+}
+
+// imageConfigResult extracts the declared config from an image inspect.
+func imageConfigResult(ref string, inspect types.ImageInspect) ImageConfigResult {
+	res := ImageConfigResult{Image: ref, ImageID: inspect.ID, ExposedPorts: []string{}}
+	cfg := inspect.Config
+	if cfg == nil {
+		return res
+	}
+	res.Entrypoint = cfg.Entrypoint
+	res.Cmd = cfg.Cmd
+	res.Env = cfg.Env
+	res.WorkingDir = cfg.WorkingDir
+	res.User = cfg.User
+	for port := range cfg.ExposedPorts {
+		res.ExposedPorts = append(res.ExposedPorts, string(port))
+	}
+	sort.Strings(res.ExposedPorts)
+	return res
+}
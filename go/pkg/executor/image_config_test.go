@@ -0,0 +1,44 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestImageConfigSurfacesDeclaredConfig(t *testing.T) {
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1.43/images/docker.io/library/node:20-alpine/json" {
+			http.Error(w, `{"message":"No such image"}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"Id":"sha256:abc","Config":{"Entrypoint":["docker-entrypoint.sh"],"Cmd":["node"],` +
+			`"ExposedPorts":{"8080/tcp":{},"3000/tcp":{}},"Env":["NODE_VERSION=20.11.0"],"WorkingDir":"/app","User":"node"}}`))
+	}))
+
+	res := ImageConfig(context.Background(), cli, ImageConfigParams{Image: "node:20-alpine"})
+	if res.Error != "" {
+		t.Fatalf("unexpected error: %s", res.Error)
+	}
+	if res.Image != "docker.io/library/node:20-alpine" || res.ImageID != "sha256:abc" {
+		t.Errorf("image = %q, id = %q", res.Image, res.ImageID)
+	}
+	if len(res.Entrypoint) != 1 || res.Entrypoint[0] != "docker-entrypoint.sh" || len(res.Cmd) != 1 || res.Cmd[0] != "node" {
+		t.Errorf("entrypoint = %q, cmd = %q", res.Entrypoint, res.Cmd)
+	}
+	if len(res.ExposedPorts) != 2 || res.ExposedPorts[0] != "3000/tcp" || res.ExposedPorts[1] != "8080/tcp" {
+		t.Errorf("exposed ports = %q, want sorted [3000/tcp 8080/tcp]", res.ExposedPorts)
+	}
+	if len(res.Env) != 1 || res.WorkingDir != "/app" || res.User != "node" {
+		t.Errorf("env = %q, working_dir = %q, user = %q", res.Env, res.WorkingDir, res.User)
+	}
+
+	if res := ImageConfig(context.Background(), cli, ImageConfigParams{Image: "missing:1"}); !strings.Contains(res.Error, "No such image") {
+		t.Errorf("missing image error not surfaced: %+v", res)
+	}
+	if res := ImageConfig(context.Background(), cli, ImageConfigParams{}); res.Error == "" {
+		t.Error("empty image should error")
+	}
+}
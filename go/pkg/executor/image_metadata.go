@@ -0,0 +1,90 @@
+package executor
+
+import (
+	"context"
+	"sort"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// baseImageLabels are the labels that may name an image's base, most specific first. The first is the
+// OCI pre-defined annotation that BuildKit and most publishers set.
+var baseImageLabels = []string{"org.opencontainers.image.base.name", "org.label-schema.base-image"}
+
+// packageManagerProbes maps each package manager image_metadata detects to the binary that gives it away.
+// Order matters: RHEL-family images with dnf often carry a yum shim as well.
+var packageManagerProbes = []struct{ name, path string }{
+	{"apk", "/sbin/apk"},
+	{"apt", "/usr/bin/apt-get"},
+	{"dnf", "/usr/bin/dnf"},
+	{"microdnf", "/usr/bin/microdnf"},
+	{"yum", "/usr/bin/yum"},
+}
+
+// ImageMetadata returns the facts an external vulnerability scanner wants about a local image: platform,
+// base image (from labels), package manager, exposed ports and layer count. The package manager is found
+// by statting binaries in a created-but-never-started container, so nothing in the image runs.
+func ImageMetadata(ctx context.Context, cli *client.Client, p ImageMetadataParams) ImageMetadataResult {
+	ref, err := normalizeImageRef(p.Image)
+	if err != nil {
+		return ImageMetadataResult{Error: err.Error()}
+	}
+	inspect, _, err := cli.ImageInspectWithRaw(ctx, ref)
+	if err != nil {
+		return ImageMetadataResult{Error: err.Error()}
+	}
+	res := imageMetadataResult(ref, inspect)
+	if res.PackageManager, err = probePackageManager(ctx, cli, ref); err != nil {
+		res.Error = "package manager probe failed: " + err.Error()
+	}
+	return res
+}
+
+// imageMetadataResult extracts everything image_metadata reports except the package manager.
+func imageMetadataResult(ref string, inspect types.ImageInspect) ImageMetadataResult {
+	res := ImageMetadataResult{
+		Image:        ref,
+		ImageID:      inspect.ID,
+		OS:           inspect.Os,
+		Architecture: inspect.Architecture,
+		Variant:      inspect.Variant,
+		LayerCount:   len(inspect.RootFS.Layers),
+		ExposedPorts: []string{},
+	}
+	if cfg := inspect.Config; cfg != nil {
+		for _, key := range baseImageLabels {
+			if v := cfg.Labels[key]; v != "" {
+				res.BaseImage = v
+				break
+			}
+		}
+		for port := range cfg.ExposedPorts {
+			res.ExposedPorts = append(res.ExposedPorts, string(port))
+		}
+		sort.Strings(res.ExposedPorts)
+	}
+	return res
+}
+
+// probePackageManager creates a throwaway container from ref and reports the first package manager
+// binary present, or "" when there is none (distroless, scratch).
+func probePackageManager(ctx context.Context, cli *client.Client, ref string) (string, error) {
+	// Entrypoint and Cmd are set so images without either (scratch-based) can still be created.
+	cfg := &container.Config{Image: ref, Entrypoint: []string{"/adde-probe"}, Cmd: []string{}}
+	hostCfg := &container.HostConfig{NetworkMode: "none"}
+	resp, err := cli.ContainerCreate(ctx, cfg, hostCfg, nil, nil, "")
+	if err != nil {
+		return "", err
+	}
+	defer cli.ContainerRemove(context.Background(), resp.ID, types.ContainerRemoveOptions{Force: true})
+	for _, pm := range packageManagerProbes {
+		if _, err := cli.ContainerStatPath(ctx, resp.ID, pm.path); err == nil {
+			return pm.name, nil
+		} else if !client.IsErrNotFound(err) {
+			return "", err
+		}
+	}
+	return "", nil
+}
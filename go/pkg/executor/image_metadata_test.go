@@ -0,0 +1,92 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/client"
+)
+
+// imageMetadataDaemon fakes inspect for python:3.12-slim plus the probe container, in which only the
+// paths in present exist. removed is set once the probe container is deleted.
+func imageMetadataDaemon(t *testing.T, present map[string]bool, removed *bool) *client.Client {
+	t.Helper()
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1.43/images/docker.io/library/python:3.12-slim/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"Id":"sha256:abc","Os":"linux","Architecture":"arm64","Variant":"v8",` +
+				`"RootFS":{"Type":"layers","Layers":["sha256:1","sha256:2","sha256:3"]},` +
+				`"Config":{"ExposedPorts":{"8000/tcp":{}},"Labels":{"org.opencontainers.image.base.name":"docker.io/library/debian:bookworm-slim"}}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/v1.43/containers/create":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"Id":"probe1"}`))
+		case r.Method == http.MethodHead && r.URL.Path == "/v1.43/containers/probe1/archive":
+			if !present[r.URL.Query().Get("path")] {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("X-Docker-Container-Path-Stat", "eyJuYW1lIjoiYXB0LWdldCJ9") // {"name":"apt-get"}
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1.43/containers/probe1":
+			*removed = true
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, `{"message":"No such image"}`, http.StatusNotFound)
+		}
+	}))
+	return cli
+}
+
+func TestImageMetadata(t *testing.T) {
+	var removed bool
+	cli := imageMetadataDaemon(t, map[string]bool{"/usr/bin/apt-get": true}, &removed)
+
+	res := ImageMetadata(context.Background(), cli, ImageMetadataParams{Image: "python:3.12-slim"})
+	if res.Error != "" {
+		t.Fatalf("unexpected error: %s", res.Error)
+	}
+	if res.Image != "docker.io/library/python:3.12-slim" || res.ImageID != "sha256:abc" {
+		t.Errorf("image = %q, id = %q", res.Image, res.ImageID)
+	}
+	if res.OS != "linux" || res.Architecture != "arm64" || res.Variant != "v8" {
+		t.Errorf("platform = %s/%s/%s, want linux/arm64/v8", res.OS, res.Architecture, res.Variant)
+	}
+	if res.BaseImage != "docker.io/library/debian:bookworm-slim" {
+		t.Errorf("base_image = %q", res.BaseImage)
+	}
+	if res.PackageManager != "apt" {
+		t.Errorf("package_manager = %q, want apt", res.PackageManager)
+	}
+	if res.LayerCount != 3 {
+		t.Errorf("layer_count = %d, want 3", res.LayerCount)
+	}
+	if len(res.ExposedPorts) != 1 || res.ExposedPorts[0] != "8000/tcp" {
+		t.Errorf("exposed_ports = %q", res.ExposedPorts)
+	}
+	if !removed {
+		t.Error("probe container was not removed")
+	}
+
+	if res := ImageMetadata(context.Background(), cli, ImageMetadataParams{Image: "missing:1"}); !strings.Contains(res.Error, "No such image") {
+		t.Errorf("missing image error not surfaced: %+v", res)
+	}
+	if res := ImageMetadata(context.Background(), cli, ImageMetadataParams{}); res.Error == "" {
+		t.Error("empty image should error")
+	}
+}
+
+func TestImageMetadataNoPackageManager(t *testing.T) {
+	var removed bool
+	cli := imageMetadataDaemon(t, nil, &removed)
+	res := ImageMetadata(context.Background(), cli, ImageMetadataParams{Image: "python:3.12-slim"})
+	if res.Error != "" || res.PackageManager != "" {
+		t.Errorf("distroless-style image: package_manager = %q, error = %q", res.PackageManager, res.Error)
+	}
+	if !removed {
+		t.Error("probe container was not removed")
+	}
+}
@@ -13,13 +13,22 @@ import (
 const AgentImageTagPrefix = "agent-env:"
 
 // ListAgentImages returns images tagged with the agent-env convention (optionally filtered by filter_tag).
+// With match_label it also returns the images adde built (see ownBuilds) whatever their tags, so they
+// are found after a retag.
 func ListAgentImages(ctx context.Context, cli *client.Client, p ListAgentImagesParams) ListAgentImagesResult {
+	if p.MatchLabel && p.FilterTag != "" {
+		return ListAgentImagesResult{Error: "filter_tag and match_label cannot be combined (filter_tag narrows by tag prefix)"}
+	}
 	listOpts := types.ImageListOptions{}
 	list, err := cli.ImageList(ctx, listOpts)
 	if err != nil {
 		return ListAgentImagesResult{Error: err.Error()}
 	}
+	return ListAgentImagesResult{Images: agentImages(list, p)}
+}
 
+// agentImages picks the images list_agent_images reports out of the daemon's image list.
+func agentImages(list []types.ImageSummary, p ListAgentImagesParams) []AgentImageEntry {
 	filterPrefix := AgentImageTagPrefix
 	if p.FilterTag != "" {
 		filterPrefix = strings.TrimSpace(p.FilterTag)
@@ -28,6 +37,10 @@ func ListAgentImages(ctx context.Context, cli *client.Client, p ListAgentImagesP
 		}
 	}
 
+	var own map[string]bool
+	if p.MatchLabel {
+		own = ownBuilds(list)
+	}
 	var out []AgentImageEntry
 	for _, im := range list {
 		var matchingTags []string
@@ -36,7 +49,15 @@ func ListAgentImages(ctx context.Context, cli *client.Client, p ListAgentImagesP
 				matchingTags = append(matchingTags, tag)
 			}
 		}
-		if len(matchingTags) == 0 {
+		if own[im.ID] {
+			// Owned by adde: report every tag it has now, none if it was untagged.
+			matchingTags = []string{}
+			for _, tag := range im.RepoTags {
+				if tag != "<none>:<none>" {
+					matchingTags = append(matchingTags, tag)
+				}
+			}
+		} else if len(matchingTags) == 0 {
 			continue
 		}
 		sizeMB := float64(im.Size) / (1024 * 1024)
@@ -45,13 +66,34 @@ func ListAgentImages(ctx context.Context, cli *client.Client, p ListAgentImagesP
 			created = time.Unix(im.Created, 0).Format(time.RFC3339)
 		}
 		out = append(out, AgentImageEntry{
-			ID:      im.ID,
+			ID:      normalizeImageID(im.ID),
 			Tags:    matchingTags,
 			SizeMB:  sizeMB,
 			Created: created,
 		})
 	}
-	return ListAgentImagesResult{Images: out}
+	return out
+}
+
+// ownBuilds returns the IDs of the images adde built: for each LabelBuildID value, the oldest image
+// carrying it. Images built FROM an adde image inherit the label but are always newer. If the image
+// adde built was deleted, the oldest derived one is taken for it, so this is only used for listing.
+func ownBuilds(list []types.ImageSummary) map[string]bool {
+	oldest := make(map[string]types.ImageSummary)
+	for _, im := range list {
+		id := im.Labels[LabelBuildID]
+		if id == "" {
+			continue
+		}
+		if cur, ok := oldest[id]; !ok || im.Created < cur.Created {
+			oldest[id] = im
+		}
+	}
+	own := make(map[string]bool, len(oldest))
+	for _, im := range oldest {
+		own[im.ID] = true
+	}
+	return own
 }
 
 // DeleteImage removes a Docker image by tag or ID. When AgentEnvOnly is true, only tags with prefix "agent-env:" are allowed.
@@ -63,6 +105,9 @@ func DeleteImage(ctx context.Context, cli *client.Client, p DeleteImageParams) D
 	if p.AgentEnvOnly && !strings.HasPrefix(img, AgentImageTagPrefix) {
 		return DeleteImageResult{Error: "only agent-created images can be deleted (image must start with \"agent-env:\"); use list_agent_images to see allowed tags"}
 	}
+	if err := validateImageRefOrID(img); err != nil {
+		return DeleteImageResult{Error: err.Error()}
+	}
 	opts := types.ImageRemoveOptions{Force: p.Force, PruneChildren: false}
 	deleted, err := cli.ImageRemove(ctx, img, opts)
 	if err != nil {
@@ -0,0 +1,43 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestAgentImagesMatchLabel(t *testing.T) {
+	list := []types.ImageSummary{
+		{ID: "sha256:aaa", RepoTags: []string{"agent-env:task-1"}},
+		{ID: "sha256:bbb", RepoTags: []string{"myrepo/app:v2"}, Created: 100, Labels: map[string]string{LabelBuildID: "b1"}},
+		{ID: "sha256:ccc", RepoTags: []string{"<none>:<none>"}, Created: 100, Labels: map[string]string{LabelBuildID: "b2"}},
+		{ID: "sha256:ddd", RepoTags: []string{"python:3.11-slim"}},
+		// Built FROM sha256:bbb, so it inherited b1; being newer gives it away.
+		{ID: "sha256:eee", RepoTags: []string{"myrepo/derived:v1"}, Created: 200, Labels: map[string]string{LabelBuildID: "b1"}},
+	}
+
+	got := agentImages(list, ListAgentImagesParams{})
+	if len(got) != 1 || got[0].Tags[0] != "agent-env:task-1" {
+		t.Errorf("by tag: %+v", got)
+	}
+
+	got = agentImages(list, ListAgentImagesParams{MatchLabel: true})
+	if len(got) != 3 {
+		t.Fatalf("by label: got %d images, want 3: %+v", len(got), got)
+	}
+	if len(got[1].Tags) != 1 || got[1].Tags[0] != "myrepo/app:v2" {
+		t.Errorf("retagged image tags = %q", got[1].Tags)
+	}
+	if got[2].Tags == nil || len(got[2].Tags) != 0 {
+		t.Errorf("untagged image tags = %#v, want empty", got[2].Tags)
+	}
+}
+
+func TestListAgentImagesMatchLabelWithFilterTag(t *testing.T) {
+	res := ListAgentImages(context.Background(), nil, ListAgentImagesParams{FilterTag: "task", MatchLabel: true})
+	if !strings.Contains(res.Error, "cannot be combined") {
+		t.Errorf("error = %q", res.Error)
+	}
+}
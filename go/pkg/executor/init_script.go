@@ -0,0 +1,48 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/client"
+)
+
+// initScriptTimeoutSec bounds init_script; it may fetch datasets, so it gets more than an exec.
+const initScriptTimeoutSec = 300
+
+// initScriptCommand returns the exec command for init_script: <shell> -c <script>. Shell defaults to sh.
+func initScriptCommand(shell, script string) ([]string, error) {
+	shell = strings.TrimSpace(shell)
+	if shell == "" {
+		shell = "sh"
+	}
+	if strings.ContainsAny(shell, " \t\n\x00") {
+		return nil, fmt.Errorf("invalid init_script_shell %q (a single program, e.g. \"bash\")", shell)
+	}
+	return []string{shell, "-c", script}, nil
+}
+
+// runInitScript runs init_script in the container. When it exits non-zero, its output is returned
+// as a LogEntry (same shape as execute_code_block) alongside the error.
+func runInitScript(ctx context.Context, cli *client.Client, containerID, shell, script string) (*LogEntry, error) {
+	cmd, err := initScriptCommand(shell, script)
+	if err != nil {
+		return nil, err
+	}
+	stdout, stderr, code, dur, err := runExec(ctx, cli, containerID, cmd, initScriptTimeoutSec)
+	if err != nil {
+		return nil, err
+	}
+	return initScriptFailure(stdout, stderr, code, dur)
+}
+
+// initScriptFailure reports a non-zero init_script exit as a LogEntry and error; success yields nil, nil.
+func initScriptFailure(stdout, stderr string, exitCode int, dur time.Duration) (*LogEntry, error) {
+	if exitCode == 0 {
+		return nil, nil
+	}
+	log := &LogEntry{ExitCode: exitCode, Stdout: stdout, Stderr: stderr, ExecutionTime: formatDuration(dur)}
+	return log, fmt.Errorf("init_script failed with exit code %d (see init_log)", exitCode)
+}
@@ -0,0 +1,47 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInitScriptCommand(t *testing.T) {
+	script := "mkdir -p /workspace/data && touch /workspace/data/ready"
+	cmd, err := initScriptCommand("", script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cmd) != 3 || cmd[0] != "sh" || cmd[1] != "-c" || cmd[2] != script {
+		t.Errorf("default shell: %q", cmd)
+	}
+	if cmd, _ := initScriptCommand(" /bin/bash ", "echo hi"); cmd[0] != "/bin/bash" {
+		t.Errorf("custom shell: %q", cmd)
+	}
+	for _, bad := range []string{"bash -e", "s\th", "a\x00b"} {
+		if _, err := initScriptCommand(bad, "true"); err == nil {
+			t.Errorf("shell %q: expected error", bad)
+		}
+	}
+}
+
+func TestInitScriptFailure(t *testing.T) {
+	if log, err := initScriptFailure("created /workspace/data/ready\n", "", 0, time.Second); log != nil || err != nil {
+		t.Errorf("success: log=%v err=%v", log, err)
+	}
+	log, err := initScriptFailure("", "fatal: unable to access 'https://example.com/repo.git/'", 128, 2*time.Second)
+	if err == nil || log == nil || !strings.Contains(err.Error(), "init_log") {
+		t.Fatalf("failure: log=%v err=%v", log, err)
+	}
+	if log.ExitCode != 128 || !strings.Contains(log.Stderr, "unable to access") || log.ExecutionTime == "" {
+		t.Errorf("init log = %+v", log)
+	}
+}
+
+func TestCreateRuntimeEnvRejectsInitScriptWithJobMode(t *testing.T) {
+	res := CreateRuntimeEnv(context.Background(), nil, CreateRuntimeEnvParams{Image: "busybox", JobMode: true, InitScript: "true"})
+	if !strings.Contains(res.Error, "job_mode") {
+		t.Errorf("error = %q", res.Error)
+	}
+}
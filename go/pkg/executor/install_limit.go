@@ -0,0 +1,50 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// DefaultMaxConcurrentInstalls bounds simultaneous dependency installs (pip/npm) across containers.
+const DefaultMaxConcurrentInstalls = 4
+
+// installSlots is a counting semaphore shared by every create_runtime_env in this process, so a burst
+// of creates in server mode queues its installs instead of hammering the package index and disk.
+var (
+	installSlotsMu sync.Mutex
+	installSlots   = make(chan struct{}, DefaultMaxConcurrentInstalls)
+)
+
+// SetMaxConcurrentInstalls changes the install limit (e.g. from ADDE_MAX_INSTALLS). Installs already
+// running keep their slot in the previous semaphore; new installs use the new limit.
+func SetMaxConcurrentInstalls(n int) error {
+	if n < 1 {
+		return fmt.Errorf("max concurrent installs must be at least 1, got %d", n)
+	}
+	installSlotsMu.Lock()
+	installSlots = make(chan struct{}, n)
+	installSlotsMu.Unlock()
+	return nil
+}
+
+// MaxConcurrentInstalls reports the current install limit.
+func MaxConcurrentInstalls() int {
+	installSlotsMu.Lock()
+	defer installSlotsMu.Unlock()
+	return cap(installSlots)
+}
+
+// withInstallSlot runs install once a slot is free, or returns ctx's error if it is cancelled while queued.
+func withInstallSlot(ctx context.Context, install func() error) error {
+	installSlotsMu.Lock()
+	slots := installSlots
+	installSlotsMu.Unlock()
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-slots }()
+	return install()
+}
@@ -0,0 +1,78 @@
+package executor
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithInstallSlotSerializesBeyondLimit(t *testing.T) {
+	defer SetMaxConcurrentInstalls(DefaultMaxConcurrentInstalls)
+	if err := SetMaxConcurrentInstalls(2); err != nil {
+		t.Fatal(err)
+	}
+
+	var running, peak int32
+	install := func() error {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := withInstallSlot(context.Background(), install); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if peak != 2 {
+		t.Errorf("peak concurrent installs = %d, want 2", peak)
+	}
+}
+
+func TestWithInstallSlotCancelledWhileQueued(t *testing.T) {
+	defer SetMaxConcurrentInstalls(DefaultMaxConcurrentInstalls)
+	if err := SetMaxConcurrentInstalls(1); err != nil {
+		t.Fatal(err)
+	}
+	hold := make(chan struct{})
+	started := make(chan struct{})
+	go withInstallSlot(context.Background(), func() error {
+		close(started)
+		<-hold
+		return nil
+	})
+	<-started
+	defer close(hold)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	ran := false
+	err := withInstallSlot(ctx, func() error { ran = true; return nil })
+	if err == nil || ran {
+		t.Errorf("queued install: err=%v ran=%v, want deadline error without running", err, ran)
+	}
+}
+
+func TestSetMaxConcurrentInstallsRejectsZero(t *testing.T) {
+	if err := SetMaxConcurrentInstalls(0); err == nil {
+		t.Error("expected error for 0")
+	}
+	if got := MaxConcurrentInstalls(); got != DefaultMaxConcurrentInstalls {
+		t.Errorf("limit changed to %d after rejected value", got)
+	}
+}
@@ -0,0 +1,76 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// Warning codes for create_runtime_env's verify_isolation check.
+const (
+	WeakIsolationCode       = "WEAK_ISOLATION"
+	IsolationUnverifiedCode = "ISOLATION_UNVERIFIED"
+)
+
+// isolationProbe runs inside the new container and prints one finding per line:
+// "docker_socket <path>" for a reachable daemon socket, "default_route <iface>" for a route off the box.
+var isolationProbe = []string{"sh", "-c", `for s in /var/run/docker.sock /run/docker.sock; do [ -e "$s" ] && echo "docker_socket $s"; done
+if [ -r /proc/net/route ]; then while read -r iface dest rest; do [ "$dest" = 00000000 ] && echo "default_route $iface"; done < /proc/net/route; fi
+exit 0`}
+
+// verifyIsolation checks a started container for ways out of the sandbox: a Docker socket,
+// a default route, or the host's cgroup namespace. The check itself failing is reported too,
+// since an unverified container must not pass as isolated.
+func verifyIsolation(ctx context.Context, cli *client.Client, containerID string, network bool) []string {
+	var warnings []string
+	inspect, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: inspect: %v", IsolationUnverifiedCode, err)}
+	}
+	if inspect.HostConfig != nil {
+		warnings = append(warnings, hostConfigIsolationWarnings(inspect.HostConfig)...)
+	}
+	stdout, stderr, code, _, err := runExec(ctx, cli, containerID, isolationProbe, 10)
+	if err != nil || code != 0 {
+		return append(warnings, fmt.Sprintf("%s: %v", IsolationUnverifiedCode, execFailure("probe", stderr, code, err)))
+	}
+	return append(warnings, isolationProbeWarnings(stdout, network)...)
+}
+
+// hostConfigIsolationWarnings flags settings that share host namespaces or devices with the container.
+func hostConfigIsolationWarnings(hc *container.HostConfig) []string {
+	var warnings []string
+	if hc.Privileged {
+		warnings = append(warnings, WeakIsolationCode+": the container is privileged")
+	}
+	if hc.NetworkMode.IsHost() {
+		warnings = append(warnings, WeakIsolationCode+": the container uses the host network")
+	}
+	if hc.CgroupnsMode.IsHost() {
+		warnings = append(warnings, WeakIsolationCode+": the container shares the host cgroup namespace (the daemon ignored cgroupns=private)")
+	}
+	return warnings
+}
+
+// isolationProbeWarnings turns isolationProbe output into warnings. With network off any default
+// route is a misconfiguration; with network on it is the expected, but still reported, exposure.
+func isolationProbeWarnings(out string, network bool) []string {
+	var warnings []string
+	for _, line := range splitNonEmptyLines(out) {
+		kind, arg, _ := strings.Cut(line, " ")
+		switch kind {
+		case "docker_socket":
+			warnings = append(warnings, fmt.Sprintf("%s: Docker socket %s is reachable; code in the container can control the daemon", WeakIsolationCode, arg))
+		case "default_route":
+			if network {
+				warnings = append(warnings, fmt.Sprintf("%s: network is enabled; code can reach the host and other networks via %s", WeakIsolationCode, arg))
+			} else {
+				warnings = append(warnings, fmt.Sprintf("%s: default route via %s although network is false", WeakIsolationCode, arg))
+			}
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,64 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestIsolationProbeWarnings(t *testing.T) {
+	// network: false gives the container only a loopback, so /proc/net/route has no default entry.
+	if w := isolationProbeWarnings("", false); len(w) != 0 {
+		t.Errorf("network-disabled container: warnings = %q, want none", w)
+	}
+
+	w := isolationProbeWarnings("default_route eth0\n", true)
+	if len(w) != 1 || !strings.HasPrefix(w[0], WeakIsolationCode+": network is enabled") || !strings.Contains(w[0], "eth0") {
+		t.Errorf("network-enabled container: warnings = %q", w)
+	}
+
+	w = isolationProbeWarnings("docker_socket /var/run/docker.sock\ndefault_route eth0\n", false)
+	if len(w) != 2 || !strings.Contains(w[0], "/var/run/docker.sock") || !strings.Contains(w[1], "although network is false") {
+		t.Errorf("misconfigured container: warnings = %q", w)
+	}
+}
+
+func TestHostConfigIsolationWarnings(t *testing.T) {
+	if w := hostConfigIsolationWarnings(&container.HostConfig{NetworkMode: "none", CgroupnsMode: "private"}); len(w) != 0 {
+		t.Errorf("isolated: warnings = %q", w)
+	}
+	w := hostConfigIsolationWarnings(&container.HostConfig{Privileged: true, NetworkMode: "host", CgroupnsMode: "host"})
+	if len(w) != 3 {
+		t.Errorf("host namespaces: warnings = %q, want 3", w)
+	}
+}
+
+func TestVerifyIsolationUnverified(t *testing.T) {
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/c1/json"):
+			w.Write([]byte(`{"Id":"c1","HostConfig":{"NetworkMode":"none","CgroupnsMode":"private"}}`))
+		case strings.HasSuffix(r.URL.Path, "/containers/c1/exec"):
+			w.Write([]byte(`{"Id":"e1"}`))
+		default:
+			// A distroless image has no sh for the probe.
+			http.Error(w, `{"message":"exec: \"sh\": executable file not found in $PATH"}`, http.StatusBadRequest)
+		}
+	}))
+
+	w := verifyIsolation(context.Background(), cli, "c1", false)
+	if len(w) != 1 || !strings.HasPrefix(w[0], IsolationUnverifiedCode+": probe") {
+		t.Errorf("warnings = %q", w)
+	}
+}
+
+func TestCreateRuntimeEnvRejectsVerifyIsolationWithJobMode(t *testing.T) {
+	res := CreateRuntimeEnv(context.Background(), nil, CreateRuntimeEnvParams{Image: "busybox", JobMode: true, VerifyIsolation: true})
+	if !strings.Contains(res.Error, "verify_isolation") {
+		t.Errorf("error = %q", res.Error)
+	}
+}
@@ -0,0 +1,121 @@
+package executor
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/client"
+)
+
+// ResultFormatJUnit is the result_format that runs a test file under its test runner and returns
+// the runner's JUnit XML report, parsed, as test_report.
+const ResultFormatJUnit = "junit"
+
+// junitReportPath is where the runner writes its report; it is removed once read, so a run that
+// fails before writing one is never reported with the previous run's results.
+const junitReportPath = "/tmp/.adde-junit.xml"
+
+// MaxJUnitXMLBytes caps test_report.xml; the counts are always computed from the whole report.
+const MaxJUnitXMLBytes = 256 * 1024
+
+// junitCommand returns the command that runs the test file under the runner for its language,
+// with a JUnit XML report at junitReportPath, and the runner's name. Python runs
+// "<interpreter> -m pytest" (pytest must be installed); JavaScript runs the jest binary with the
+// jest-junit reporter (install both as dependencies, which puts them in npm's global root).
+func junitCommand(cmd []string, filename string) (run []string, runner string, err error) {
+	interpreter, fullPath := cmd[0], cmd[len(cmd)-1]
+	switch lang, _ := languageRunnerFor(filename); lang {
+	case "python":
+		// No cache provider: the run must not leave .pytest_cache in the working directory.
+		return []string{interpreter, "-m", "pytest", "-p", "no:cacheprovider", "--junitxml=" + junitReportPath, fullPath}, "pytest", nil
+	case "javascript":
+		// jest resolves reporters from the project, not npm's global root, so name it by path.
+		script := `export JEST_JUNIT_OUTPUT_FILE="$1"; exec jest --ci --reporters=default --reporters="$(npm root -g)/jest-junit" "$0"`
+		return []string{"sh", "-c", script, fullPath, junitReportPath}, "jest", nil
+	}
+	return nil, "", fmt.Errorf("result_format %q is supported for Python (.py, pytest) and JavaScript (.js, jest) files, not %q", ResultFormatJUnit, filename)
+}
+
+// collectJUnitReport copies the runner's report out of the container and parses it. Failures are
+// reported in the returned report's Error, never as the run's error.
+func collectJUnitReport(ctx context.Context, cli *client.Client, containerID, runner string) *TestReport {
+	rc, _, err := cli.CopyFromContainer(ctx, containerID, junitReportPath)
+	if err != nil {
+		return &TestReport{Runner: runner, Error: "no JUnit report (did the runner start?): " + err.Error()}
+	}
+	data, err := readSingleFileFromTar(rc)
+	rc.Close()
+	_, _, _, _, _ = runExec(ctx, cli, containerID, []string{"rm", "-f", junitReportPath}, 10)
+	if err != nil {
+		return &TestReport{Runner: runner, Error: "copy JUnit report: " + err.Error()}
+	}
+	res, err := parseJUnitXML(data)
+	if err != nil {
+		return &TestReport{Runner: runner, Error: err.Error()}
+	}
+	res.Runner = runner
+	return res
+}
+
+// junitSuite is a <testsuites> or <testsuite> element; suites may nest.
+type junitSuite struct {
+	Suites []junitSuite `xml:"testsuite"`
+	Cases  []struct {
+		Name      string    `xml:"name,attr"`
+		Classname string    `xml:"classname,attr"`
+		Failure   *struct{} `xml:"failure"`
+		Error     *struct{} `xml:"error"`
+		Skipped   *struct{} `xml:"skipped"`
+	} `xml:"testcase"`
+}
+
+// parseJUnitXML counts the test cases of a JUnit XML report by outcome. The counts come from the
+// <testcase> elements rather than the suites' attributes, which runners fill in inconsistently.
+func parseJUnitXML(data []byte) (*TestReport, error) {
+	var root junitSuite
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parse JUnit report: %v", err)
+	}
+	res := &TestReport{XML: string(data)}
+	if len(res.XML) > MaxJUnitXMLBytes {
+		res.XML = res.XML[:MaxJUnitXMLBytes] + "\n<!-- report truncated -->\n"
+	}
+	var walk func(s junitSuite)
+	walk = func(s junitSuite) {
+		for _, c := range s.Cases {
+			res.Tests++
+			name := c.Name
+			if c.Classname != "" {
+				name = c.Classname + "::" + c.Name
+			}
+			switch {
+			case c.Error != nil:
+				res.Errors++
+				res.FailedTests = append(res.FailedTests, name)
+			case c.Failure != nil:
+				res.Failed++
+				res.FailedTests = append(res.FailedTests, name)
+			case c.Skipped != nil:
+				res.Skipped++
+			default:
+				res.Passed++
+			}
+		}
+		for _, sub := range s.Suites {
+			walk(sub)
+		}
+	}
+	walk(root)
+	return res, nil
+}
+
+// normalizeResultFormat validates result_format: "" (plain run) or "junit".
+func normalizeResultFormat(s string) (string, error) {
+	switch f := strings.ToLower(strings.TrimSpace(s)); f {
+	case "", ResultFormatJUnit:
+		return f, nil
+	}
+	return "", fmt.Errorf("invalid result_format %q (want %q)", s, ResultFormatJUnit)
+}
@@ -0,0 +1,88 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+)
+
+// pytestReport is what pytest --junitxml writes for a file with one passing, one failing, one
+// erroring (fixture) and one skipped test.
+const pytestReport = `<?xml version="1.0" encoding="utf-8"?><testsuites><testsuite name="pytest" errors="1" failures="1" skipped="1" tests="4" time="0.031" timestamp="2026-10-15T10:00:00.000000" hostname="c1"><testcase classname="test_math" name="test_add" time="0.001" /><testcase classname="test_math" name="test_sub" time="0.001"><failure message="assert 1 == 2">def test_sub():
+&gt;       assert 2 - 1 == 2
+E       assert 1 == 2
+
+test_math.py:6: AssertionError</failure></testcase><testcase classname="test_math" name="test_db" time="0.001"><error message="failed on setup with &quot;fixture 'db' not found&quot;">fixture 'db' not found</error></testcase><testcase classname="test_math" name="test_later" time="0.000"><skipped type="pytest.skip" message="not yet">test_math.py:12: not yet</skipped></testcase></testsuite></testsuites>`
+
+// jestReport is what jest-junit writes: a <testsuites> root with one suite per test file.
+const jestReport = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="jest tests" tests="2" failures="0" errors="0" time="0.412">
+  <testsuite name="math.test.js" errors="0" failures="0" skipped="0" timestamp="2026-10-15T10:00:00" time="0.2" tests="2">
+    <testcase classname="math adds" name="math adds" time="0.002">
+    </testcase>
+    <testcase classname="math subtracts" name="math subtracts" time="0.001">
+    </testcase>
+  </testsuite>
+</testsuites>`
+
+func TestParseJUnitXMLPytest(t *testing.T) {
+	res, err := parseJUnitXML([]byte(pytestReport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Tests != 4 || res.Passed != 1 || res.Failed != 1 || res.Errors != 1 || res.Skipped != 1 {
+		t.Errorf("counts = %+v", res)
+	}
+	if strings.Join(res.FailedTests, ",") != "test_math::test_sub,test_math::test_db" {
+		t.Errorf("failed_tests = %q", res.FailedTests)
+	}
+	if res.XML != pytestReport {
+		t.Error("raw XML not returned as is")
+	}
+}
+
+func TestParseJUnitXMLJest(t *testing.T) {
+	res, err := parseJUnitXML([]byte(jestReport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Tests != 2 || res.Passed != 2 || res.Failed != 0 || len(res.FailedTests) != 0 {
+		t.Errorf("counts = %+v", res)
+	}
+}
+
+func TestParseJUnitXMLTruncatesRawReport(t *testing.T) {
+	big := `<testsuite>` + strings.Repeat(`<testcase classname="c" name="t"/>`, MaxJUnitXMLBytes/30) + `</testsuite>`
+	res, err := parseJUnitXML([]byte(big))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Tests != MaxJUnitXMLBytes/30 || !strings.HasSuffix(res.XML, "<!-- report truncated -->\n") {
+		t.Errorf("tests = %d, xml suffix = %q", res.Tests, res.XML[len(res.XML)-40:])
+	}
+	if _, err := parseJUnitXML([]byte("FAILED test_math.py")); err == nil {
+		t.Error("expected an error for a non-XML report")
+	}
+}
+
+func TestJUnitCommand(t *testing.T) {
+	run, runner, err := junitCommand([]string{"python3", "/workspace/test_math.py"}, "test_math.py")
+	if err != nil || runner != "pytest" {
+		t.Fatalf("python: %q, %v", runner, err)
+	}
+	if strings.Join(run, " ") != "python3 -m pytest -p no:cacheprovider --junitxml="+junitReportPath+" /workspace/test_math.py" {
+		t.Errorf("python run = %q", run)
+	}
+	run, runner, err = junitCommand([]string{"node", "/workspace/math.test.js"}, "math.test.js")
+	if err != nil || runner != "jest" || run[0] != "sh" || run[3] != "/workspace/math.test.js" || run[4] != junitReportPath {
+		t.Errorf("jest run = %q, %q, %v", run, runner, err)
+	}
+	if _, _, err := junitCommand([]string{"sh", "/workspace/t.sh"}, "t.sh"); err == nil {
+		t.Error("expected an error for a shell file")
+	}
+	if _, err := normalizeResultFormat("xml"); err == nil {
+		t.Error("expected an error for an unknown result_format")
+	}
+	if f, err := normalizeResultFormat(" JUnit "); err != nil || f != ResultFormatJUnit {
+		t.Errorf("normalizeResultFormat = %q, %v", f, err)
+	}
+}
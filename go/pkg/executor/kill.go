@@ -0,0 +1,59 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/client"
+)
+
+// DefaultKillSignal is sent by kill_container when no signal is given.
+const DefaultKillSignal = "SIGKILL"
+
+// linuxSignals are the signal names the daemon accepts for a Linux container.
+var linuxSignals = map[string]bool{
+	"SIGABRT": true, "SIGALRM": true, "SIGBUS": true, "SIGCHLD": true, "SIGCONT": true, "SIGFPE": true,
+	"SIGHUP": true, "SIGILL": true, "SIGINT": true, "SIGIO": true, "SIGKILL": true, "SIGPIPE": true,
+	"SIGPROF": true, "SIGPWR": true, "SIGQUIT": true, "SIGSEGV": true, "SIGSTOP": true, "SIGSYS": true,
+	"SIGTERM": true, "SIGTRAP": true, "SIGTSTP": true, "SIGTTIN": true, "SIGTTOU": true, "SIGURG": true,
+	"SIGUSR1": true, "SIGUSR2": true, "SIGVTALRM": true, "SIGWINCH": true, "SIGXCPU": true, "SIGXFSZ": true,
+}
+
+// normalizeSignal accepts "SIGUSR1", "usr1" or a number (1-64) and returns the form sent to the daemon.
+func normalizeSignal(s string) (string, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return DefaultKillSignal, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 1 || n > 64 {
+			return "", fmt.Errorf("signal %d out of range (1-64)", n)
+		}
+		return s, nil
+	}
+	if !strings.HasPrefix(s, "SIG") {
+		s = "SIG" + s
+	}
+	if !linuxSignals[s] && !strings.HasPrefix(s, "SIGRTMIN") && !strings.HasPrefix(s, "SIGRTMAX") {
+		return "", fmt.Errorf("unknown signal %q", s)
+	}
+	return s, nil
+}
+
+// KillContainer sends a signal to the container's main process without removing the container.
+// SIGKILL (the default) stops it immediately; other signals (e.g. SIGUSR1) are delivered as is.
+func KillContainer(ctx context.Context, cli *client.Client, p KillContainerParams) KillContainerResult {
+	if p.ContainerID == "" {
+		return KillContainerResult{Error: "container_id is required"}
+	}
+	sig, err := normalizeSignal(p.Signal)
+	if err != nil {
+		return KillContainerResult{Error: err.Error()}
+	}
+	if err := cli.ContainerKill(ctx, p.ContainerID, sig); err != nil {
+		return KillContainerResult{Signal: sig, Error: err.Error()}
+	}
+	return KillContainerResult{OK: true, Signal: sig}
+}
@@ -0,0 +1,38 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/client"
+)
+
+// RunningExec describes a streamed execute_code_block run while it is in progress. adde serve
+// keeps one per run so operators can list and kill execs, which Docker cannot enumerate.
+type RunningExec struct {
+	ExecID      string    `json:"exec_id"`
+	ContainerID string    `json:"container_id"`
+	Filename    string    `json:"filename"`
+	StartedAt   time.Time `json:"started_at"`
+	// Host is the daemon the run was started on when the request named one; empty = the server's.
+	Host string `json:"host,omitempty"`
+	// PIDFile is where the exec's wrapper recorded its in-container PID (pidFileCommand).
+	PIDFile string `json:"-"`
+}
+
+// KillExec sends signal (default SIGKILL) to the process of a running exec, found through the PID
+// its wrapper recorded. Returns the signal that was sent.
+func KillExec(ctx context.Context, cli *client.Client, e RunningExec, signal string) (string, error) {
+	sig, err := normalizeSignal(signal)
+	if err != nil {
+		return "", err
+	}
+	cmd := []string{"sh", "-c", `pid="$(cat "$1" 2>/dev/null)" || { echo "no PID recorded yet" >&2; exit 1; }; kill -s "$0" "$pid"`,
+		strings.TrimPrefix(sig, "SIG"), e.PIDFile}
+	_, stderr, code, _, err := runExec(ctx, cli, e.ContainerID, cmd, 10)
+	if err != nil || code != 0 {
+		return sig, execFailure("kill_exec", stderr, code, err)
+	}
+	return sig, nil
+}
@@ -0,0 +1,47 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeSignal(t *testing.T) {
+	cases := map[string]string{"": "SIGKILL", "SIGUSR1": "SIGUSR1", "term": "SIGTERM", " hup ": "SIGHUP", "9": "9", "SIGRTMIN+3": "SIGRTMIN+3"}
+	for in, want := range cases {
+		got, err := normalizeSignal(in)
+		if err != nil || got != want {
+			t.Errorf("%q: got %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"SIGFOO", "0", "65", "-1", "KILL ME"} {
+		if _, err := normalizeSignal(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestKillContainerForwardsSignal(t *testing.T) {
+	cli, calls := fakeDaemon(t, http.StatusNoContent)
+	res := KillContainer(context.Background(), cli, KillContainerParams{ContainerID: "abc", Signal: "usr1"})
+	if !res.OK || res.Signal != "SIGUSR1" {
+		t.Errorf("kill: %+v", res)
+	}
+	KillContainer(context.Background(), cli, KillContainerParams{ContainerID: "abc"})
+	want := []string{"POST /v1.43/containers/abc/kill?signal=SIGUSR1", "POST /v1.43/containers/abc/kill?signal=SIGKILL"}
+	got := calls()
+	if len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("daemon calls = %q, want %q", got, want)
+	}
+
+	if res := KillContainer(context.Background(), cli, KillContainerParams{ContainerID: "abc", Signal: "SIGFOO"}); res.Error == "" || len(calls()) != 2 {
+		t.Errorf("invalid signal reached the daemon: %+v", res)
+	}
+}
+
+func TestKillExecRejectsUnknownSignal(t *testing.T) {
+	if _, err := KillExec(context.Background(), nil, RunningExec{ContainerID: "c1", PIDFile: "/tmp/x.pid"}, "SIGNOPE"); err == nil || !strings.Contains(err.Error(), "unknown signal") {
+		t.Errorf("err = %v", err)
+	}
+}
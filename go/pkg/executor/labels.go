@@ -0,0 +1,45 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Ownership labels create_runtime_env sets on every container (with LabelManaged=true), so tooling can
+// find what the toolkit created without relying on container names or image tags.
+const (
+	LabelCreatedAt = "adde.created_at" // RFC 3339, UTC
+	LabelWorkspace = "adde.workspace"  // host directory mounted at /workspace
+)
+
+// reservedLabelPrefix is the namespace of the toolkit's own labels; callers' labels may not use it,
+// since labels such as adde.max_executions and adde.idempotency_key change how adde treats a container.
+const reservedLabelPrefix = "adde."
+
+// validateUserLabels rejects empty keys and keys in the toolkit's reserved namespace.
+func validateUserLabels(labels map[string]string) error {
+	for k := range labels {
+		if k == "" {
+			return fmt.Errorf("labels: empty label key")
+		}
+		if strings.HasPrefix(k, reservedLabelPrefix) {
+			return fmt.Errorf("labels: %q is reserved (keys starting with %q are set by adde)", k, reservedLabelPrefix)
+		}
+	}
+	return nil
+}
+
+// containerLabels adds the caller's labels and the ownership labels to labels, allocating it if needed.
+func containerLabels(labels, user map[string]string, workspace string, now time.Time) map[string]string {
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	for k, v := range user {
+		labels[k] = v
+	}
+	labels[LabelManaged] = "true"
+	labels[LabelCreatedAt] = now.UTC().Format(time.RFC3339)
+	labels[LabelWorkspace] = workspace
+	return labels
+}
@@ -0,0 +1,39 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateUserLabels(t *testing.T) {
+	if err := validateUserLabels(map[string]string{"team": "ml", "com.example.run": "7"}); err != nil {
+		t.Errorf("valid labels: %v", err)
+	}
+	if err := validateUserLabels(map[string]string{LabelManaged: "false"}); err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Errorf("adde. key: err = %v", err)
+	}
+	if err := validateUserLabels(map[string]string{"": "x"}); err == nil {
+		t.Error("empty key: expected error")
+	}
+}
+
+func TestContainerLabels(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	got := containerLabels(map[string]string{LabelMaxExecutions: "3"}, map[string]string{"team": "ml"}, "/tmp/adde-ws-1", now)
+	want := map[string]string{
+		LabelMaxExecutions: "3",
+		"team":             "ml",
+		LabelManaged:       "true",
+		LabelCreatedAt:     "2026-10-16T10:30:00Z",
+		LabelWorkspace:     "/tmp/adde-ws-1",
+	}
+	if len(got) != len(want) {
+		t.Errorf("labels = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package executor
+
+import "strings"
+
+// ListLanguages reports which file extensions execute_code_block dispatches to which command.
+// It is generated from languageRunners, so it always matches what execution does.
+func ListLanguages() ListLanguagesResult {
+	var res ListLanguagesResult
+	for _, r := range languageRunners {
+		for _, ext := range r.exts {
+			res.Languages = append(res.Languages, LanguageInfo{
+				Extension: ext,
+				Language:  r.lang,
+				Command:   strings.Join(r.command, " "),
+			})
+		}
+	}
+	res.Fallback = strings.Join(fallbackRunner, " ")
+	return res
+}
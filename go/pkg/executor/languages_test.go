@@ -0,0 +1,33 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestListLanguages(t *testing.T) {
+	res := ListLanguages()
+	byExt := make(map[string]LanguageInfo)
+	for _, l := range res.Languages {
+		byExt[l.Extension] = l
+	}
+	for ext, want := range map[string]string{".py": "python", ".js": "node", ".sh": "sh"} {
+		if got := byExt[ext].Command; got != want {
+			t.Errorf("%s: command %q, want %q", ext, got, want)
+		}
+	}
+	if res.Fallback != "sh" {
+		t.Errorf("fallback = %q", res.Fallback)
+	}
+
+	// Every listed entry must be what runCommandForFile actually runs.
+	for _, l := range res.Languages {
+		cmd := runCommandForFile("/workspace/f"+l.Extension, "f"+l.Extension)
+		if got := strings.Join(cmd[:len(cmd)-1], " "); got != l.Command {
+			t.Errorf("%s: listed %q but runs %q", l.Extension, l.Command, got)
+		}
+	}
+	if cmd := runCommandForFile("/workspace/run", "run"); strings.Join(cmd, " ") != "sh /workspace/run" {
+		t.Errorf("fallback runs %q", cmd)
+	}
+}
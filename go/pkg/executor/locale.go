@@ -0,0 +1,52 @@
+package executor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// timezoneRe matches IANA zone names (e.g. "Europe/Berlin", "Etc/GMT+3", "UTC").
+	timezoneRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_+-]*(/[A-Za-z0-9_+-]+)*$`)
+	// localeRe matches locale names (e.g. "C.UTF-8", "de_DE.UTF-8", "sr_RS@latin").
+	localeRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.@-]*$`)
+)
+
+// localeEnv returns env_vars with TZ set from timezone and LANG/LC_ALL from locale. Unset fields
+// leave the image defaults. Setting a variable both ways is rejected rather than silently resolved.
+func localeEnv(p CreateRuntimeEnvParams) (map[string]string, error) {
+	if p.Timezone == "" && p.Locale == "" {
+		return p.EnvVars, nil
+	}
+	vars := make(map[string]string, len(p.EnvVars)+3)
+	for k, v := range p.EnvVars {
+		vars[k] = v
+	}
+	set := func(field, value string, names ...string) error {
+		for _, name := range names {
+			if _, ok := p.EnvVars[name]; ok {
+				return fmt.Errorf("%s conflicts with env_vars %s; set one or the other", field, name)
+			}
+			vars[name] = value
+		}
+		return nil
+	}
+	if p.Timezone != "" {
+		if !timezoneRe.MatchString(p.Timezone) || strings.Contains(p.Timezone, "..") {
+			return nil, fmt.Errorf("timezone: invalid zone name %q (want an IANA name such as \"Europe/Berlin\")", p.Timezone)
+		}
+		if err := set("timezone", p.Timezone, "TZ"); err != nil {
+			return nil, err
+		}
+	}
+	if p.Locale != "" {
+		if !localeRe.MatchString(p.Locale) {
+			return nil, fmt.Errorf("locale: invalid locale name %q (e.g. \"C.UTF-8\", \"en_US.UTF-8\")", p.Locale)
+		}
+		if err := set("locale", p.Locale, "LANG", "LC_ALL"); err != nil {
+			return nil, err
+		}
+	}
+	return vars, nil
+}
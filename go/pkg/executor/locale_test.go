@@ -0,0 +1,65 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestLocaleEnv(t *testing.T) {
+	vars, err := localeEnv(CreateRuntimeEnvParams{EnvVars: map[string]string{"A": "1"}, Timezone: "America/Argentina/Buenos_Aires", Locale: "de_DE.UTF-8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vars["TZ"] != "America/Argentina/Buenos_Aires" || vars["LANG"] != "de_DE.UTF-8" || vars["LC_ALL"] != "de_DE.UTF-8" || vars["A"] != "1" {
+		t.Errorf("vars = %v", vars)
+	}
+	if vars, _ := localeEnv(CreateRuntimeEnvParams{EnvVars: map[string]string{"A": "1"}}); len(vars) != 1 {
+		t.Errorf("unset timezone/locale changed env: %v", vars)
+	}
+	for _, bad := range []CreateRuntimeEnvParams{
+		{Timezone: "../../etc/passwd"},
+		{Timezone: "/etc/localtime"},
+		{Timezone: "Europe/Ber lin"},
+		{Locale: "en_US.UTF-8; rm -rf /"},
+		{Timezone: "UTC", EnvVars: map[string]string{"TZ": "UTC"}},
+		{Locale: "C.UTF-8", EnvVars: map[string]string{"LC_ALL": "C"}},
+	} {
+		if _, err := localeEnv(bad); err == nil {
+			t.Errorf("%+v: expected an error", bad)
+		}
+	}
+}
+
+func TestCreateRuntimeEnvTimezoneAndLocale(t *testing.T) {
+	if err := SetTempRoot(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetTempRoot("") })
+	var created struct{ Env []string }
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1.43/images/docker.io/library/python:3.11-slim/json":
+			w.Write([]byte(`{"Id":"sha256:abc"}`))
+		case "/v1.43/containers/create":
+			json.NewDecoder(r.Body).Decode(&created)
+			w.Write([]byte(`{"Id":"c1"}`))
+		case "/v1.43/containers/c1/start":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+		}
+	}))
+
+	res := CreateRuntimeEnv(context.Background(), cli, CreateRuntimeEnvParams{Image: "python:3.11-slim", Timezone: "Asia/Tokyo", Locale: "C.UTF-8"})
+	if res.Error != "" {
+		t.Fatalf("unexpected error: %s", res.Error)
+	}
+	want := []string{"LANG=C.UTF-8", "LC_ALL=C.UTF-8", "TZ=Asia/Tokyo"}
+	if strings.Join(created.Env, " ") != strings.Join(want, " ") {
+		t.Errorf("container env = %q, want %q", created.Env, want)
+	}
+}
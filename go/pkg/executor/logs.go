@@ -1,26 +1,58 @@
 package executor
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"path"
 	"strings"
+	"time"
 
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/stdcopy"
 )
 
+// Log sources for get_container_logs.
+const (
+	LogSourceLastRun   = "last_run"
+	LogSourceContainer = "container"
+)
+
+const noLastRunError = "no previous execution log found (run execute_code_block first)"
+
 // GetContainerLogs returns the last execution's structured log (exit_code, stdout, stderr, execution_time).
 // Reads from /workspace/.adde_last_run.json written by ExecuteCodeBlock. tail_lines trims stdout/stderr to last N lines.
+// With source "container" it instead returns the main process output (docker logs), e.g. for job_mode containers.
 func GetContainerLogs(ctx context.Context, cli *client.Client, p GetContainerLogsParams) GetContainerLogsResult {
-	stdout, _, _, _, err := runExec(ctx, cli, p.ContainerID, []string{"cat", ".adde_last_run.json"}, 10)
+	switch p.Source {
+	case "", LogSourceLastRun:
+	case LogSourceContainer:
+		return containerProcessLogs(ctx, cli, p)
+	default:
+		return GetContainerLogsResult{Error: fmt.Sprintf("unknown source %q (want %q or %q)", p.Source, LogSourceLastRun, LogSourceContainer)}
+	}
+	// Copy the file out as a tar rather than cat-ing it through exec: no stream demuxing, no size limit.
+	rc, _, err := cli.CopyFromContainer(ctx, p.ContainerID, path.Join(WorkspacePathInsideContainer, lastRunPath))
+	if errdefs.IsNotFound(err) {
+		return GetContainerLogsResult{Error: noLastRunError}
+	}
 	if err != nil {
 		return GetContainerLogsResult{Error: err.Error()}
 	}
-	raw := strings.TrimSpace(stdout)
-	if raw == "" {
-		return GetContainerLogsResult{Error: "no previous execution log found (run execute_code_block first)"}
+	defer rc.Close()
+	data, err := readSingleFileFromTar(rc)
+	if err != nil {
+		return GetContainerLogsResult{Error: "read last run: " + err.Error()}
+	}
+	raw := bytes.TrimSpace(data)
+	if len(raw) == 0 {
+		return GetContainerLogsResult{Error: noLastRunError}
 	}
 	var log LogEntry
-	if err := json.Unmarshal([]byte(raw), &log); err != nil {
+	if err := json.Unmarshal(raw, &log); err != nil {
 		return GetContainerLogsResult{Error: "invalid last run data: " + err.Error()}
 	}
 	if p.TailLines > 0 {
@@ -30,6 +62,46 @@ func GetContainerLogs(ctx context.Context, cli *client.Client, p GetContainerLog
 	return GetContainerLogsResult{Log: &log}
 }
 
+// containerProcessLogs reads the main process stdout/stderr and exit state of the container.
+func containerProcessLogs(ctx context.Context, cli *client.Client, p GetContainerLogsParams) GetContainerLogsResult {
+	inspect, err := cli.ContainerInspect(ctx, p.ContainerID)
+	if err != nil {
+		return GetContainerLogsResult{Error: err.Error()}
+	}
+	opts := types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true}
+	if p.TailLines > 0 {
+		opts.Tail = fmt.Sprintf("%d", p.TailLines)
+	}
+	rc, err := cli.ContainerLogs(ctx, p.ContainerID, opts)
+	if err != nil {
+		return GetContainerLogsResult{Error: err.Error()}
+	}
+	defer rc.Close()
+
+	var outBuf, errBuf bytes.Buffer
+	if inspect.Config != nil && inspect.Config.Tty {
+		_, err = outBuf.ReadFrom(rc)
+	} else {
+		_, err = stdcopy.StdCopy(&outBuf, &errBuf, rc)
+	}
+	if err != nil {
+		return GetContainerLogsResult{Error: err.Error()}
+	}
+
+	log := &LogEntry{ExitCode: -1, Stdout: outBuf.String(), Stderr: errBuf.String()}
+	if st := inspect.State; st != nil {
+		if !st.Running {
+			log.ExitCode = st.ExitCode
+		}
+		started, errS := time.Parse(time.RFC3339Nano, st.StartedAt)
+		finished, errF := time.Parse(time.RFC3339Nano, st.FinishedAt)
+		if errS == nil && errF == nil && finished.After(started) {
+			log.ExecutionTime = formatDuration(finished.Sub(started))
+		}
+	}
+	return GetContainerLogsResult{Log: log}
+}
+
 func tailLines(s string, n int) string {
 	lines := strings.Split(s, "\n")
 	if len(lines) <= n {
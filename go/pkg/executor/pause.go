@@ -0,0 +1,30 @@
+package executor
+
+import (
+	"context"
+
+	"github.com/docker/docker/client"
+)
+
+// PauseContainer freezes every process in the container (cgroup freezer) without stopping it,
+// so in-memory state survives until UnpauseContainer. Exec into a paused container fails.
+func PauseContainer(ctx context.Context, cli *client.Client, p PauseContainerParams) PauseContainerResult {
+	if p.ContainerID == "" {
+		return PauseContainerResult{Error: "container_id is required"}
+	}
+	if err := cli.ContainerPause(ctx, p.ContainerID); err != nil {
+		return PauseContainerResult{Error: err.Error()}
+	}
+	return PauseContainerResult{OK: true}
+}
+
+// UnpauseContainer resumes a container frozen by PauseContainer.
+func UnpauseContainer(ctx context.Context, cli *client.Client, p UnpauseContainerParams) UnpauseContainerResult {
+	if p.ContainerID == "" {
+		return UnpauseContainerResult{Error: "container_id is required"}
+	}
+	if err := cli.ContainerUnpause(ctx, p.ContainerID); err != nil {
+		return UnpauseContainerResult{Error: err.Error()}
+	}
+	return UnpauseContainerResult{OK: true}
+}
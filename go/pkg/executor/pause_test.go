@@ -0,0 +1,34 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPauseUnpauseContainer(t *testing.T) {
+	cli, calls := fakeDaemon(t, http.StatusNoContent)
+	if res := PauseContainer(context.Background(), cli, PauseContainerParams{ContainerID: "abc"}); !res.OK || res.Error != "" {
+		t.Errorf("pause: %+v", res)
+	}
+	if res := UnpauseContainer(context.Background(), cli, UnpauseContainerParams{ContainerID: "abc"}); !res.OK || res.Error != "" {
+		t.Errorf("unpause: %+v", res)
+	}
+	want := []string{"POST /v1.43/containers/abc/pause", "POST /v1.43/containers/abc/unpause"}
+	got := calls()
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("daemon calls = %q, want %q", got, want)
+	}
+}
+
+func TestPauseContainerErrors(t *testing.T) {
+	cli, calls := fakeDaemon(t, http.StatusConflict)
+	if res := PauseContainer(context.Background(), cli, PauseContainerParams{}); res.Error == "" || len(calls()) != 0 {
+		t.Errorf("missing id: %+v, calls=%q", res, calls())
+	}
+	res := PauseContainer(context.Background(), cli, PauseContainerParams{ContainerID: "abc"})
+	if res.OK || !strings.Contains(res.Error, "not running") {
+		t.Errorf("daemon error not surfaced: %+v", res)
+	}
+}
@@ -0,0 +1,63 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// unlimitedPids is the pids limit value the daemon treats as "no limit".
+const unlimitedPids = -1
+
+// execPidsLimit decides whether a per-exec pids limit must be applied. It is skipped when the
+// container is already limited to requested processes or fewer.
+func execPidsLimit(current *int64, requested int64) (apply bool, err error) {
+	if requested < 0 {
+		return false, fmt.Errorf("pids_limit must not be negative")
+	}
+	if requested == 0 {
+		return false, nil
+	}
+	if current != nil && *current > 0 && *current <= requested {
+		return false, nil
+	}
+	return true, nil
+}
+
+// applyExecPidsLimit lowers the container's pids limit to requested for the duration of one exec
+// and returns a func restoring the previous limit. The limit is cgroup-wide: the container's idle
+// processes (sleep, init) count against it too.
+func applyExecPidsLimit(ctx context.Context, cli *client.Client, containerID string, requested int64) (restore func(), err error) {
+	noop := func() {}
+	if requested == 0 {
+		return noop, nil // no per-exec limit requested; skip the inspect round trip
+	}
+	inspect, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return noop, err
+	}
+	var current *int64
+	if inspect.HostConfig != nil {
+		current = inspect.HostConfig.PidsLimit
+	}
+	apply, err := execPidsLimit(current, requested)
+	if err != nil || !apply {
+		return noop, err
+	}
+	if _, err := cli.ContainerUpdate(ctx, containerID, container.UpdateConfig{Resources: container.Resources{PidsLimit: &requested}}); err != nil {
+		return noop, fmt.Errorf("pids_limit: %v", err)
+	}
+	previous := int64(unlimitedPids)
+	if current != nil && *current > 0 {
+		previous = *current
+	}
+	return func() {
+		// Fresh context: the limit must be restored even if the caller's context was cancelled.
+		rctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_, _ = cli.ContainerUpdate(rctx, containerID, container.UpdateConfig{Resources: container.Resources{PidsLimit: &previous}})
+	}, nil
+}
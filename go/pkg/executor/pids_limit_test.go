@@ -0,0 +1,29 @@
+package executor
+
+import "testing"
+
+func TestExecPidsLimit(t *testing.T) {
+	n := func(v int64) *int64 { return &v }
+	cases := []struct {
+		name      string
+		current   *int64
+		requested int64
+		apply     bool
+	}{
+		{"not requested", nil, 0, false},
+		{"unlimited container", nil, 20, true},
+		{"unlimited (-1)", n(-1), 20, true},
+		{"looser limit", n(1000), 20, true},
+		{"already tight", n(10), 20, false},
+		{"equal", n(20), 20, false},
+	}
+	for _, tc := range cases {
+		apply, err := execPidsLimit(tc.current, tc.requested)
+		if err != nil || apply != tc.apply {
+			t.Errorf("%s: apply=%v err=%v, want %v", tc.name, apply, err, tc.apply)
+		}
+	}
+	if _, err := execPidsLimit(nil, -5); err == nil {
+		t.Error("negative: expected error")
+	}
+}
@@ -0,0 +1,65 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/client"
+)
+
+// Where profile: true leaves the raw profile; each run overwrites the previous one.
+const (
+	pythonProfilePath = "/tmp/.adde-profile.pstats"
+	nodeProfilePath   = "/tmp/.adde-profile.v8.log"
+)
+
+// MaxProfileBytes caps log.profile; node --prof-process output in particular can be long.
+const MaxProfileBytes = 32 * 1024
+
+// profileTopFunctions is how many functions the Python summary lists, by cumulative time.
+const profileTopFunctions = 30
+
+// pstatsScript prints the cProfile dump named by argv[1], hottest (cumulative) functions first.
+var pstatsScript = fmt.Sprintf("import pstats, sys; pstats.Stats(sys.argv[1], stream=sys.stdout).sort_stats('cumulative').print_stats(%d)", profileTopFunctions)
+
+// profiledCommand wraps cmd (from commandForExecution) in the language's profiler and returns it
+// with the command that prints the summary afterwards. The interpreter chosen for cmd also runs
+// the summary, so an interpreter override applies to both. Python needs the cProfile and pstats
+// modules (standard library, but missing from some stripped images); Node needs a V8 build with
+// --prof and --prof-process (any official node binary).
+func profiledCommand(cmd []string, filename string) (run, summary []string, err error) {
+	interpreter, fullPath := cmd[0], cmd[len(cmd)-1]
+	switch lang, _ := languageRunnerFor(filename); lang {
+	case "python":
+		run = []string{interpreter, "-m", "cProfile", "-o", pythonProfilePath, fullPath}
+		summary = []string{interpreter, "-c", pstatsScript, pythonProfilePath}
+	case "javascript":
+		run = []string{interpreter, "--prof", "--no-logfile-per-isolate", "--logfile=" + nodeProfilePath, fullPath}
+		summary = []string{interpreter, "--prof-process", nodeProfilePath}
+	default:
+		return nil, nil, fmt.Errorf("profile is supported for Python (.py) and Node (.js, .mjs) files, not %q", filename)
+	}
+	return run, summary, nil
+}
+
+// profileSummary runs the summary command and returns its output, capped at MaxProfileBytes. A
+// failure is described in the summary itself; it never fails the run that was profiled.
+func profileSummary(ctx context.Context, cli *client.Client, containerID string, summary []string, workDir string) string {
+	stdout, stderr, exitCode, _, err := runExecWith(ctx, cli, containerID, summary, 60, execOptions{workingDir: workDir})
+	switch {
+	case err != nil:
+		return "profile unavailable: " + err.Error()
+	case exitCode != 0:
+		return fmt.Sprintf("profile unavailable (exit code %d): %s", exitCode, strings.TrimSpace(stderr))
+	}
+	return truncateProfile(stdout)
+}
+
+// truncateProfile keeps the first MaxProfileBytes of s, where the hottest entries are.
+func truncateProfile(s string) string {
+	if len(s) <= MaxProfileBytes {
+		return s
+	}
+	return s[:MaxProfileBytes] + "\n... (profile truncated)\n"
+}
@@ -0,0 +1,50 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProfiledCommand(t *testing.T) {
+	cases := []struct {
+		filename, interpreter, wantRun, wantSummaryPrefix string
+	}{
+		{"main.py", "", "python -m cProfile -o " + pythonProfilePath + " /workspace/main.py", "python -c import pstats"},
+		{"main.py", "python3.11", "python3.11 -m cProfile -o " + pythonProfilePath + " /workspace/main.py", "python3.11 -c import pstats"},
+		{"app.mjs", "", "node --prof --no-logfile-per-isolate --logfile=" + nodeProfilePath + " /workspace/app.mjs", "node --prof-process " + nodeProfilePath},
+	}
+	for _, tc := range cases {
+		cmd, err := commandForExecution("/workspace/"+tc.filename, tc.filename, tc.interpreter, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		run, summary, err := profiledCommand(cmd, tc.filename)
+		if err != nil {
+			t.Errorf("%s: %v", tc.filename, err)
+			continue
+		}
+		if got := strings.Join(run, " "); got != tc.wantRun {
+			t.Errorf("%s: run %q, want %q", tc.filename, got, tc.wantRun)
+		}
+		if got := strings.Join(summary, " "); !strings.HasPrefix(got, tc.wantSummaryPrefix) {
+			t.Errorf("%s: summary %q, want prefix %q", tc.filename, got, tc.wantSummaryPrefix)
+		}
+	}
+
+	for _, filename := range []string{"run.sh", "main.ts", "run"} {
+		cmd, _ := commandForExecution("/workspace/"+filename, filename, "", "")
+		if _, _, err := profiledCommand(cmd, filename); err == nil {
+			t.Errorf("%s: expected error", filename)
+		}
+	}
+}
+
+func TestTruncateProfile(t *testing.T) {
+	if got := truncateProfile("short"); got != "short" {
+		t.Errorf("got %q", got)
+	}
+	got := truncateProfile(strings.Repeat("x", MaxProfileBytes+10))
+	if !strings.HasSuffix(got, "(profile truncated)\n") || len(got) > MaxProfileBytes+64 {
+		t.Errorf("len %d, suffix %q", len(got), got[len(got)-30:])
+	}
+}
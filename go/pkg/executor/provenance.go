@@ -0,0 +1,65 @@
+package executor
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Provenance labels stamped on images by the build tools and carried onto containers by
+// create_runtime_env, so a running environment can be traced back to the build that produced it.
+const (
+	LabelContextID = "adde.context_id"
+	LabelTaskID    = "adde.task_id"
+)
+
+// LabelBuildID carries a value unique to one adde build. Image labels are inherited through FROM, so
+// an image built from an adde image carries the same value; it is always newer than the image adde
+// built, which is how ownBuilds tells them apart. Images never get LabelManaged for the same reason:
+// derived images and containers other tools start from them would pass as adde's.
+const LabelBuildID = "adde.build_id"
+
+// provenanceLabelKeys lists the image labels create_runtime_env copies onto the container.
+var provenanceLabelKeys = []string{LabelContextID, LabelTaskID}
+
+// buildLabels returns the labels for a new image: the caller's, the provenance labels that are set,
+// and a fresh LabelBuildID, so adde's images can be found even after they are retagged.
+func buildLabels(contextID, taskID string, user map[string]string) map[string]string {
+	labels := make(map[string]string, len(user)+3)
+	for k, v := range user {
+		labels[k] = v
+	}
+	if contextID != "" {
+		labels[LabelContextID] = contextID
+	}
+	if taskID != "" {
+		labels[LabelTaskID] = taskID
+	}
+	labels[LabelBuildID] = newBuildID()
+	return labels
+}
+
+// newBuildID returns a random value for LabelBuildID.
+func newBuildID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// withProvenanceLabels copies the provenance labels present in imageLabels into labels,
+// allocating it if needed. Labels already set on the container are left alone.
+func withProvenanceLabels(labels, imageLabels map[string]string) map[string]string {
+	for _, k := range provenanceLabelKeys {
+		v, ok := imageLabels[k]
+		if !ok {
+			continue
+		}
+		if _, set := labels[k]; set {
+			continue
+		}
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[k] = v
+	}
+	return labels
+}
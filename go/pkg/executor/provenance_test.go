@@ -0,0 +1,38 @@
+package executor
+
+import "testing"
+
+func TestWithProvenanceLabels(t *testing.T) {
+	image := map[string]string{LabelContextID: "/tmp/ctx", LabelTaskID: "task-1", "maintainer": "someone"}
+	got := withProvenanceLabels(nil, image)
+	if len(got) != 2 || got[LabelContextID] != "/tmp/ctx" || got[LabelTaskID] != "task-1" {
+		t.Errorf("copied labels = %v", got)
+	}
+
+	own := map[string]string{LabelTaskID: "override", LabelMaxExecutions: "3"}
+	got = withProvenanceLabels(own, image)
+	if got[LabelTaskID] != "override" || got[LabelContextID] != "/tmp/ctx" || got[LabelMaxExecutions] != "3" {
+		t.Errorf("merged labels = %v", got)
+	}
+
+	if got := withProvenanceLabels(nil, map[string]string{"maintainer": "someone"}); got != nil {
+		t.Errorf("no provenance: got %v, want nil", got)
+	}
+}
+
+func TestBuildLabels(t *testing.T) {
+	empty := buildLabels("", "", nil)
+	if len(empty) != 1 || len(empty[LabelBuildID]) != 16 {
+		t.Errorf("empty: got %v, want only %s", empty, LabelBuildID)
+	}
+	got := buildLabels("/tmp/ctx", "task-1", map[string]string{"team": "ml"})
+	if got[LabelContextID] != "/tmp/ctx" || got[LabelTaskID] != "task-1" || got["team"] != "ml" {
+		t.Errorf("labels = %v", got)
+	}
+	if _, ok := got[LabelManaged]; ok {
+		t.Errorf("image labelled %s; derived images and their containers would inherit it", LabelManaged)
+	}
+	if got[LabelBuildID] == empty[LabelBuildID] {
+		t.Errorf("two builds share %s %q", LabelBuildID, got[LabelBuildID])
+	}
+}
@@ -11,14 +11,23 @@ import (
 
 // PruneBuildCache cleans up intermediate build stages and unused build cache.
 // older_than_hrs: if > 0, only prune cache older than that many hours; 0 = prune all unused.
+// On Podman, whose compat API has no BuildKit cache, dangling images (the leftover build layers) are pruned instead.
 func PruneBuildCache(ctx context.Context, cli *client.Client, p PruneBuildCacheParams) PruneBuildCacheResult {
+	engine, err := ResolveEngine(ctx, cli, p.Engine)
+	if err != nil {
+		return PruneBuildCacheResult{Error: err.Error()}
+	}
+	if engine == EnginePodman {
+		report, err := cli.ImagesPrune(ctx, pruneFilters(engine, p.OlderThanHrs))
+		if err != nil {
+			return PruneBuildCacheResult{Error: err.Error()}
+		}
+		return PruneBuildCacheResult{SpaceReclaimedMB: float64(report.SpaceReclaimed) / (1024 * 1024)}
+	}
+
 	opts := types.BuildCachePruneOptions{
 		KeepStorage: 0,
-	}
-	if p.OlderThanHrs > 0 {
-		opts.Filters = filters.NewArgs(
-			filters.Arg("until", (time.Duration(p.OlderThanHrs)*time.Hour).String()),
-		)
+		Filters:     pruneFilters(engine, p.OlderThanHrs),
 	}
 	report, err := cli.BuildCachePrune(ctx, opts)
 	if err != nil {
@@ -27,3 +36,16 @@ func PruneBuildCache(ctx context.Context, cli *client.Client, p PruneBuildCacheP
 	spaceReclaimedMB := float64(report.SpaceReclaimed) / (1024 * 1024)
 	return PruneBuildCacheResult{SpaceReclaimedMB: spaceReclaimedMB}
 }
+
+// pruneFilters builds the prune filter set for the engine: build cache filters for Docker,
+// dangling-image filters for Podman.
+func pruneFilters(engine string, olderThanHrs int) filters.Args {
+	args := filters.NewArgs()
+	if engine == EnginePodman {
+		args.Add("dangling", "true")
+	}
+	if olderThanHrs > 0 {
+		args.Add("until", (time.Duration(olderThanHrs) * time.Hour).String())
+	}
+	return args
+}
@@ -0,0 +1,230 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// workspaceDirPrefix is the temp-dir prefix CreateRuntimeEnv uses for workspace bind mounts;
+// a container whose /workspace comes from such a dir was created by this toolkit (before containers
+// carried LabelManaged).
+const workspaceDirPrefix = "adde-workspace-"
+
+// PruneAll reclaims stopped containers, unused images, and build cache in one call.
+// By default only toolkit-created containers and agent-env: images are candidates; IncludeUnmanaged
+// opts in to every stopped container and dangling image on the host, and to the build cache, which the
+// daemon shares across all builds and cannot be narrowed to adde's. DryRun reports without removing.
+func PruneAll(ctx context.Context, cli *client.Client, p PruneAllParams) PruneAllResult {
+	var cutoff time.Time
+	if p.OlderThanHrs > 0 {
+		cutoff = time.Now().Add(-time.Duration(p.OlderThanHrs) * time.Hour)
+	}
+	res := PruneAllResult{DryRun: p.DryRun}
+	managedOnly := !p.IncludeUnmanaged
+
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{All: true, Size: true})
+	if err != nil {
+		return PruneAllResult{Error: err.Error()}
+	}
+	var reclaimed int64
+	removed := make(map[string]bool)
+	for _, c := range selectPruneContainers(containers, managedOnly, cutoff) {
+		if !p.DryRun {
+			if err := cli.ContainerRemove(ctx, c.ID, types.ContainerRemoveOptions{}); err != nil {
+				res.Failed = append(res.Failed, fmt.Sprintf("container %s: %v", c.ID, err))
+				continue
+			}
+		}
+		removed[c.ID] = true
+		res.Containers = append(res.Containers, c.ID)
+		reclaimed += c.SizeRw
+	}
+	res.ContainersReclaimedMB = float64(reclaimed) / (1024 * 1024)
+
+	// Images still referenced by a container that survives the prune stay.
+	inUse := make(map[string]bool)
+	for _, c := range containers {
+		if !removed[c.ID] {
+			inUse[c.ImageID] = true
+		}
+	}
+	images, err := cli.ImageList(ctx, types.ImageListOptions{})
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	reclaimed = 0
+	for _, im := range selectPruneImages(images, inUse, managedOnly, cutoff) {
+		deleted, untagged, err := removePruneImage(ctx, cli, im, managedOnly, p.DryRun)
+		if err != nil {
+			res.Failed = append(res.Failed, fmt.Sprintf("image %s: %v", normalizeImageID(im.ID), err))
+		}
+		if !deleted {
+			res.Untagged = append(res.Untagged, untagged...)
+			continue
+		}
+		res.Images = append(res.Images, normalizeImageID(im.ID))
+		reclaimed += im.Size
+	}
+	res.ImagesReclaimedMB = float64(reclaimed) / (1024 * 1024)
+
+	switch {
+	case managedOnly:
+		// The build cache is host-wide; leave it to include_unmanaged (or prune_build_cache).
+	case p.DryRun:
+		res.BuildCacheReclaimedMB = reclaimableBuildCacheMB(ctx, cli, cutoff)
+	default:
+		cache := PruneBuildCache(ctx, cli, PruneBuildCacheParams{OlderThanHrs: p.OlderThanHrs, Engine: p.Engine})
+		if cache.Error != "" {
+			res.Failed = append(res.Failed, "build cache: "+cache.Error)
+		}
+		res.BuildCacheReclaimedMB = cache.SpaceReclaimedMB
+	}
+	res.TotalReclaimedMB = res.ContainersReclaimedMB + res.ImagesReclaimedMB + res.BuildCacheReclaimedMB
+	if len(res.Failed) > 0 {
+		res.Error = fmt.Sprintf("%d removal(s) failed; see failed", len(res.Failed))
+	}
+	return res
+}
+
+// removePruneImage removes a prune candidate (with dryRun, only works out what would happen). A dangling
+// image is removed by ID. An adde image is removed by its agent-env: tags, without Force: while the
+// image has other tags (e.g. a user's myorg/app:prod) the daemon only untags it, and it deletes the
+// image along with its last tag. Reports whether the image was deleted and the tags removed.
+func removePruneImage(ctx context.Context, cli *client.Client, im types.ImageSummary, managedOnly, dryRun bool) (deleted bool, untagged []string, err error) {
+	if !managedOnly {
+		if !dryRun {
+			if _, err := cli.ImageRemove(ctx, im.ID, types.ImageRemoveOptions{PruneChildren: true}); err != nil {
+				return false, nil, err
+			}
+		}
+		return true, nil, nil
+	}
+	var agent, other int
+	for _, tag := range im.RepoTags {
+		switch {
+		case strings.HasPrefix(tag, AgentImageTagPrefix):
+			agent++
+		case tag != "<none>:<none>":
+			other++
+		}
+	}
+	for _, tag := range im.RepoTags {
+		if !strings.HasPrefix(tag, AgentImageTagPrefix) {
+			continue
+		}
+		if dryRun {
+			untagged = append(untagged, tag)
+			continue
+		}
+		items, err := cli.ImageRemove(ctx, tag, types.ImageRemoveOptions{PruneChildren: true})
+		if err != nil {
+			return deleted, untagged, err
+		}
+		untagged = append(untagged, tag)
+		for _, it := range items {
+			if it.Deleted != "" {
+				deleted = true
+			}
+		}
+	}
+	if dryRun {
+		deleted = agent > 0 && other == 0
+	}
+	return deleted, untagged, nil
+}
+
+// selectPruneContainers returns stopped containers eligible for removal.
+func selectPruneContainers(list []types.Container, managedOnly bool, cutoff time.Time) []types.Container {
+	var out []types.Container
+	for _, c := range list {
+		if c.State == "running" || c.State == "paused" || c.State == "restarting" {
+			continue
+		}
+		if !cutoff.IsZero() && time.Unix(c.Created, 0).After(cutoff) {
+			continue
+		}
+		if managedOnly && !isManagedContainer(c) {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// selectPruneImages returns unused images eligible for removal: adde's images (an agent-env: tag) when
+// managedOnly, else dangling ones. Image labels are inherited through FROM, so a label cannot tell
+// adde's images from ones built on top of them; tags are not inherited.
+func selectPruneImages(list []types.ImageSummary, inUse map[string]bool, managedOnly bool, cutoff time.Time) []types.ImageSummary {
+	var out []types.ImageSummary
+	for _, im := range list {
+		if inUse[im.ID] {
+			continue
+		}
+		if !cutoff.IsZero() && time.Unix(im.Created, 0).After(cutoff) {
+			continue
+		}
+		if managedOnly {
+			if !hasAgentTag(im.RepoTags) {
+				continue
+			}
+		} else if !isDangling(im.RepoTags) {
+			continue
+		}
+		out = append(out, im)
+	}
+	return out
+}
+
+// isManagedContainer reports whether adde created c: it carries LabelManaged=true with LabelWorkspace,
+// or (for containers created before the labels) its /workspace is an adde workspace dir. LabelManaged
+// alone is not enough: images built by older versions carried it, and containers inherit image labels,
+// whereas LabelWorkspace is only ever set by create_runtime_env.
+func isManagedContainer(c types.Container) bool {
+	if c.Labels[LabelManaged] == "true" && c.Labels[LabelWorkspace] != "" {
+		return true
+	}
+	for _, m := range c.Mounts {
+		if m.Destination == WorkspacePathInsideContainer && strings.Contains(m.Source, workspaceDirPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAgentTag(tags []string) bool {
+	for _, t := range tags {
+		if strings.HasPrefix(t, AgentImageTagPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isDangling(tags []string) bool {
+	return len(tags) == 0 || (len(tags) == 1 && tags[0] == "<none>:<none>")
+}
+
+// reclaimableBuildCacheMB sums unused, unshared build cache records (what a prune would free).
+func reclaimableBuildCacheMB(ctx context.Context, cli *client.Client, cutoff time.Time) float64 {
+	du, err := cli.DiskUsage(ctx, types.DiskUsageOptions{Types: []types.DiskUsageObject{types.BuildCacheObject}})
+	if err != nil {
+		return 0
+	}
+	var total int64
+	for _, bc := range du.BuildCache {
+		if bc.InUse || bc.Shared {
+			continue
+		}
+		if !cutoff.IsZero() && bc.CreatedAt.After(cutoff) {
+			continue
+		}
+		total += bc.Size
+	}
+	return float64(total) / (1024 * 1024)
+}
@@ -0,0 +1,164 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestSelectPruneContainers(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour).Unix()
+	managed := []types.MountPoint{{Source: "/tmp/adde-workspace-123", Destination: WorkspacePathInsideContainer}}
+	list := []types.Container{
+		{ID: "running", State: "running", Created: old, Mounts: managed},
+		{ID: "managed-exited", State: "exited", Created: old, Mounts: managed},
+		{ID: "foreign-exited", State: "exited", Created: old},
+		{ID: "labelled-exited", State: "exited", Created: old, Labels: map[string]string{LabelManaged: "true", LabelWorkspace: "/data/ws"}},
+		// Started by another tool from an image that carried adde.managed; it inherited only that label.
+		{ID: "inherited-exited", State: "exited", Created: old, Labels: map[string]string{LabelManaged: "true"}},
+		{ID: "managed-fresh", State: "exited", Created: time.Now().Unix(), Mounts: managed},
+	}
+	ids := func(cs []types.Container) map[string]bool {
+		m := make(map[string]bool)
+		for _, c := range cs {
+			m[c.ID] = true
+		}
+		return m
+	}
+
+	all := ids(selectPruneContainers(list, false, time.Time{}))
+	if all["running"] || !all["managed-exited"] || !all["foreign-exited"] || !all["managed-fresh"] || !all["labelled-exited"] {
+		t.Errorf("unfiltered: %v", all)
+	}
+	managedOnly := ids(selectPruneContainers(list, true, time.Now().Add(-24*time.Hour)))
+	if len(managedOnly) != 2 || !managedOnly["managed-exited"] || !managedOnly["labelled-exited"] || managedOnly["inherited-exited"] {
+		t.Errorf("managed, older than 24h: %v", managedOnly)
+	}
+}
+
+func TestSelectPruneImages(t *testing.T) {
+	list := []types.ImageSummary{
+		{ID: "dangling", RepoTags: []string{"<none>:<none>"}},
+		{ID: "agent", RepoTags: []string{"agent-env:task-1"}},
+		{ID: "agent-used", RepoTags: []string{"agent-env:task-2"}},
+		{ID: "base", RepoTags: []string{"python:3.11-slim"}},
+		// Built FROM an adde image: it inherits the image's labels, so it must not count as adde's.
+		{ID: "derived", RepoTags: []string{"myrepo/app:v2"}, Labels: map[string]string{LabelManaged: "true", LabelBuildID: "b1"}},
+	}
+	inUse := map[string]bool{"agent-used": true}
+
+	got := selectPruneImages(list, inUse, false, time.Time{})
+	if len(got) != 1 || got[0].ID != "dangling" {
+		t.Errorf("default: %v", got)
+	}
+	got = selectPruneImages(list, inUse, true, time.Time{})
+	if len(got) != 1 || got[0].ID != "agent" {
+		t.Errorf("managed only: %v", got)
+	}
+}
+
+func TestPruneAllBuildCacheOnlyWithIncludeUnmanaged(t *testing.T) {
+	var cachePrunes int
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/json"), strings.HasSuffix(r.URL.Path, "/images/json"):
+			w.Write([]byte(`[]`))
+		case strings.HasSuffix(r.URL.Path, "/build/prune"):
+			cachePrunes++
+			w.Write([]byte(`{"SpaceReclaimed":1048576}`))
+		default:
+			http.Error(w, `{"message":"not implemented"}`, http.StatusNotImplemented)
+		}
+	}))
+
+	res := PruneAll(context.Background(), cli, PruneAllParams{Engine: EngineDocker})
+	if res.Error != "" || cachePrunes != 0 || res.BuildCacheReclaimedMB != 0 {
+		t.Errorf("managed only: %d cache prunes, result %+v", cachePrunes, res)
+	}
+	res = PruneAll(context.Background(), cli, PruneAllParams{Engine: EngineDocker, IncludeUnmanaged: true})
+	if res.Error != "" || cachePrunes != 1 || res.BuildCacheReclaimedMB != 1 {
+		t.Errorf("include_unmanaged: %d cache prunes, result %+v", cachePrunes, res)
+	}
+}
+
+func TestPruneAllReportsFailedRemovals(t *testing.T) {
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			w.Write([]byte(`[{"Id":"c1","State":"exited","Mounts":[{"Source":"/tmp/adde-workspace-1","Destination":"/workspace"}]},` +
+				`{"Id":"c2","State":"exited","Mounts":[{"Source":"/tmp/adde-workspace-2","Destination":"/workspace"}]}]`))
+		case r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/containers/c1"):
+			http.Error(w, `{"message":"removal of container c1 is already in progress"}`, http.StatusConflict)
+		case r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/containers/c2"):
+			w.WriteHeader(http.StatusNoContent)
+		case strings.HasSuffix(r.URL.Path, "/images/json"):
+			w.Write([]byte(`[]`))
+		default:
+			http.Error(w, `{"message":"not implemented"}`, http.StatusNotImplemented)
+		}
+	}))
+
+	res := PruneAll(context.Background(), cli, PruneAllParams{})
+	if len(res.Containers) != 1 || res.Containers[0] != "c2" {
+		t.Errorf("containers = %v, want only c2", res.Containers)
+	}
+	if len(res.Failed) != 1 || !strings.HasPrefix(res.Failed[0], "container c1: ") || !strings.Contains(res.Failed[0], "already in progress") {
+		t.Errorf("failed = %q", res.Failed)
+	}
+	if res.Error == "" {
+		t.Error("error not set for a failed removal")
+	}
+}
+
+func TestPruneAllUntagsMultiTaggedImages(t *testing.T) {
+	var mu sync.Mutex
+	var deletes []string
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			w.Write([]byte(`[]`))
+		case strings.HasSuffix(r.URL.Path, "/images/json"):
+			w.Write([]byte(`[{"Id":"sha256:multi","RepoTags":["agent-env:t1","myorg/app:prod"],"Size":4194304},` +
+				`{"Id":"sha256:solo","RepoTags":["agent-env:t2"],"Size":1048576}]`))
+		case r.Method == http.MethodDelete:
+			ref := strings.TrimPrefix(r.URL.Path, "/v1.43/images/")
+			mu.Lock()
+			deletes = append(deletes, ref+"?force="+r.URL.Query().Get("force"))
+			mu.Unlock()
+			switch ref {
+			case "agent-env:t1":
+				w.Write([]byte(`[{"Untagged":"agent-env:t1"}]`))
+			case "agent-env:t2":
+				w.Write([]byte(`[{"Untagged":"agent-env:t2"},{"Deleted":"sha256:solo"}]`))
+			default:
+				http.Error(w, `{"message":"unexpected image removal"}`, http.StatusConflict)
+			}
+		default:
+			http.Error(w, `{"message":"not implemented"}`, http.StatusNotImplemented)
+		}
+	}))
+
+	for _, dryRun := range []bool{true, false} {
+		res := PruneAll(context.Background(), cli, PruneAllParams{DryRun: dryRun})
+		if res.Error != "" {
+			t.Fatalf("dry_run=%v: %s", dryRun, res.Error)
+		}
+		// The image keeps myorg/app:prod: only its agent-env: tag goes and no space is reclaimed.
+		if len(res.Images) != 1 || res.Images[0] != "sha256:solo" || len(res.Untagged) != 1 || res.Untagged[0] != "agent-env:t1" || res.ImagesReclaimedMB != 1 {
+			t.Errorf("dry_run=%v: result %+v", dryRun, res)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(deletes, " ") != "agent-env:t1?force= agent-env:t2?force=" {
+		t.Errorf("removals = %q, want the two agent-env: tags without force", deletes)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
@@ -16,11 +17,17 @@ func PullImage(ctx context.Context, cli *client.Client, p PullImageParams) PullI
 	if ref == "" {
 		return PullImageResult{Error: "image name is required"}
 	}
-	rc, err := cli.ImagePull(ctx, ref, types.ImagePullOptions{})
+	ref, err := normalizeImageRef(ref)
 	if err != nil {
 		return PullImageResult{Error: err.Error()}
 	}
+	start := time.Now()
+	rc, err := cli.ImagePull(ctx, ref, types.ImagePullOptions{})
+	if err != nil {
+		return PullImageResult{Error: err.Error(), DurationMillis: time.Since(start).Milliseconds()}
+	}
 	defer rc.Close()
+	// The pull runs as the progress stream is consumed, so the timing includes draining it.
 	_, _ = io.Copy(io.Discard, rc)
-	return PullImageResult{OK: true}
+	return PullImageResult{OK: true, Image: ref, DurationMillis: time.Since(start).Milliseconds()}
 }
@@ -0,0 +1,48 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Pull policies for create_runtime_env, with Kubernetes' imagePullPolicy semantics.
+const (
+	PullIfNotPresent = "IfNotPresent" // pull only when the image is not local (default)
+	PullAlways       = "Always"       // pull every time, so a moved tag (e.g. :latest) is picked up
+	PullNever        = "Never"        // use the local image only; fail fast when it is absent
+)
+
+// normalizePullPolicy validates pull_policy (case-insensitive) and returns its canonical spelling.
+func normalizePullPolicy(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", strings.ToLower(PullIfNotPresent):
+		return PullIfNotPresent, nil
+	case strings.ToLower(PullAlways):
+		return PullAlways, nil
+	case strings.ToLower(PullNever):
+		return PullNever, nil
+	}
+	return "", fmt.Errorf("unknown pull_policy %q (want %s, %s or %s)", s, PullIfNotPresent, PullAlways, PullNever)
+}
+
+// ensureImage applies policy to ref and reports whether it pulled. present and pull are imagePresent
+// and imagePuller in production.
+func ensureImage(ctx context.Context, ref, policy string, present func(context.Context, string) bool, pull func(context.Context, string) error) (bool, error) {
+	switch policy {
+	case PullAlways:
+	case PullNever:
+		if !present(ctx, ref) {
+			return false, fmt.Errorf("image %s is not present locally and pull_policy is %s", ref, PullNever)
+		}
+		return false, nil
+	default:
+		if present(ctx, ref) {
+			return false, nil
+		}
+	}
+	if err := pull(ctx, ref); err != nil {
+		return false, fmt.Errorf("pull %s (pull_policy %s): %v", ref, policy, err)
+	}
+	return true, nil
+}
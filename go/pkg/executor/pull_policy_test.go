@@ -0,0 +1,57 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalizePullPolicy(t *testing.T) {
+	for in, want := range map[string]string{"": PullIfNotPresent, "ifnotpresent": PullIfNotPresent, "Always": PullAlways, " never ": PullNever} {
+		if got, err := normalizePullPolicy(in); err != nil || got != want {
+			t.Errorf("normalizePullPolicy(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := normalizePullPolicy("sometimes"); err == nil {
+		t.Error("expected error for an unknown policy")
+	}
+}
+
+func TestEnsureImage(t *testing.T) {
+	cases := []struct {
+		policy     string
+		present    bool
+		wantPulled bool
+		wantErr    string
+	}{
+		{PullIfNotPresent, true, false, ""},
+		{PullIfNotPresent, false, true, ""},
+		{PullAlways, true, true, ""},
+		{PullAlways, false, true, ""},
+		{PullNever, true, false, ""},
+		{PullNever, false, false, "not present locally"},
+	}
+	for _, tc := range cases {
+		var pulls []string
+		present := func(context.Context, string) bool { return tc.present }
+		pull := func(_ context.Context, ref string) error { pulls = append(pulls, ref); return nil }
+		pulled, err := ensureImage(context.Background(), "docker.io/library/busybox:latest", tc.policy, present, pull)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("%s/present=%v: err = %v, want %q", tc.policy, tc.present, err, tc.wantErr)
+			}
+		} else if err != nil {
+			t.Errorf("%s/present=%v: %v", tc.policy, tc.present, err)
+		}
+		if pulled != tc.wantPulled || len(pulls) != map[bool]int{true: 1, false: 0}[tc.wantPulled] {
+			t.Errorf("%s/present=%v: pulled = %v after %d pulls, want %v", tc.policy, tc.present, pulled, len(pulls), tc.wantPulled)
+		}
+	}
+
+	failing := func(context.Context, string) error { return errors.New("manifest unknown") }
+	absent := func(context.Context, string) bool { return false }
+	if _, err := ensureImage(context.Background(), "ghcr.io/acme/app:1", PullIfNotPresent, absent, failing); err == nil || !strings.Contains(err.Error(), "manifest unknown") {
+		t.Errorf("pull error not surfaced: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package executor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+)
+
+// ErrCodeInvalidImageRef prefixes errors for malformed image references so callers can match on it.
+const ErrCodeInvalidImageRef = "INVALID_IMAGE_REF"
+
+var imageIDPattern = regexp.MustCompile(`^(sha256:)?[0-9a-f]{12,64}$`)
+
+// validateImageRef parses ref with the distribution reference grammar (the same one the daemon uses)
+// so malformed names fail fast with a readable message instead of deep inside a daemon call.
+func validateImageRef(ref string) (reference.Named, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil, fmt.Errorf("%s: image reference is empty", ErrCodeInvalidImageRef)
+	}
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %q is not a valid image reference (%v); expected e.g. \"python:3.11-slim\" or \"registry.example.com/team/app:1.0\"", ErrCodeInvalidImageRef, ref, err)
+	}
+	return named, nil
+}
+
+// validateImageRefOrID accepts either an image ID (full or short, with or without "sha256:") or a reference.
+func validateImageRefOrID(ref string) error {
+	if imageIDPattern.MatchString(strings.TrimSpace(ref)) {
+		return nil
+	}
+	_, err := validateImageRef(ref)
+	return err
+}
+
+// normalizeImageRef validates ref and returns its canonical form with registry and explicit tag,
+// e.g. "busybox" -> "docker.io/library/busybox:latest". Image IDs and digest refs are returned unchanged.
+func normalizeImageRef(ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if imageIDPattern.MatchString(ref) {
+		return ref, nil
+	}
+	named, err := validateImageRef(ref)
+	if err != nil {
+		return "", err
+	}
+	return reference.TagNameOnly(named).String(), nil
+}
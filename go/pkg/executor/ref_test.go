@@ -0,0 +1,71 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateImageRef(t *testing.T) {
+	valid := []string{
+		"busybox",
+		"python:3.11-slim",
+		"agent-env:task-123-1706457600",
+		"registry.example.com:5000/team/app:1.0",
+		"busybox@sha256:a3ed95caeb02ffe68cdd9fd84406680ae93d633cb16422d00e8a7c22955b46d4",
+	}
+	for _, ref := range valid {
+		if _, err := validateImageRef(ref); err != nil {
+			t.Errorf("%q: unexpected error %v", ref, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"Busybox",
+		"library//busybox",
+		"busybox:bad tag",
+		"busybox:-leadingdash",
+		"busybox@sha256:short",
+	}
+	for _, ref := range invalid {
+		_, err := validateImageRef(ref)
+		if err == nil {
+			t.Errorf("%q: expected error", ref)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), ErrCodeInvalidImageRef) {
+			t.Errorf("%q: error should start with %s, got %v", ref, ErrCodeInvalidImageRef, err)
+		}
+	}
+}
+
+func TestValidateImageRefOrIDAcceptsIDs(t *testing.T) {
+	for _, id := range []string{"sha256:a3ed95caeb02ffe68cdd9fd84406680ae93d633cb16422d00e8a7c22955b46d4", "a3ed95caeb02"} {
+		if err := validateImageRefOrID(id); err != nil {
+			t.Errorf("%q: unexpected error %v", id, err)
+		}
+	}
+	if err := validateImageRefOrID("UPPER:case"); err == nil {
+		t.Error("expected error for invalid ref")
+	}
+}
+
+func TestNormalizeImageRef(t *testing.T) {
+	cases := map[string]string{
+		"busybox":                      "docker.io/library/busybox:latest",
+		"python:3.11-slim":             "docker.io/library/python:3.11-slim",
+		"team/app":                     "docker.io/team/app:latest",
+		"registry.example.com/app:1.0": "registry.example.com/app:1.0",
+		"a3ed95caeb02":                 "a3ed95caeb02",
+	}
+	for in, want := range cases {
+		got, err := normalizeImageRef(in)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%q: got %q, want %q", in, got, want)
+		}
+	}
+}
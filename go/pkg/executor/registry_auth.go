@@ -0,0 +1,208 @@
+package executor
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/client"
+)
+
+// Credential sources reported by check_registry_auth.
+const (
+	AuthSourceRegistries  = "ADDE_REGISTRIES_JSON" // host -> {username, password} for several registries
+	AuthSourceRegistry    = "ADDE_REGISTRY_URL"    // one registry via ADDE_REGISTRY_URL/_USERNAME/_PASSWORD
+	AuthSourceEnv         = "DOCKER_AUTH_CONFIG"   // JSON config in the env var, as CI systems inject it
+	AuthSourceAuths       = "auths"                // inline credentials in the Docker client config file
+	AuthSourceCredsStore  = "credsStore"           // external credential store (not readable by adde)
+	AuthSourceCredHelpers = "credHelpers"          // per-registry credential helper (not readable by adde)
+	AuthSourceNone        = "none"
+)
+
+// dockerHubAuthKey is the key the Docker CLI stores Docker Hub credentials under.
+const dockerHubAuthKey = "https://index.docker.io/v1/"
+
+// dockerClientConfig is the part of the Docker client config (config.json / DOCKER_AUTH_CONFIG) adde reads.
+type dockerClientConfig struct {
+	Auths       map[string]registry.AuthConfig `json:"auths"`
+	CredsStore  string                         `json:"credsStore"`
+	CredHelpers map[string]string              `json:"credHelpers"`
+}
+
+// DockerConfigPath is $DOCKER_CONFIG/config.json, else ~/.docker/config.json ("" when there is no home).
+func DockerConfigPath(getenv func(string) string) string {
+	if dir := getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".docker", "config.json")
+}
+
+// RegistryAuthForImage finds the credentials for image's registry, in order: ADDE_REGISTRIES_JSON,
+// ADDE_REGISTRY_URL (with _USERNAME/_PASSWORD), DOCKER_AUTH_CONFIG, then the Docker client config file. source is one of the AuthSource* constants; for credsStore/credHelpers the
+// returned config is empty because adde does not run credential helpers.
+func RegistryAuthForImage(image string, getenv func(string) string) (auth registry.AuthConfig, source string, err error) {
+	named, err := validateImageRef(image)
+	if err != nil {
+		return auth, "", err
+	}
+	reg := reference.Domain(named)
+	if v := getenv("ADDE_REGISTRIES_JSON"); v != "" {
+		var regs map[string]registry.AuthConfig
+		if err := json.Unmarshal([]byte(v), &regs); err != nil {
+			return auth, "", fmt.Errorf("ADDE_REGISTRIES_JSON is not valid JSON (want {\"host\": {\"username\": ..., \"password\": ...}}): %v", err)
+		}
+		if a, ok := lookupAuth(regs, reg); ok {
+			return a, AuthSourceRegistries, nil
+		}
+	}
+	if url := getenv("ADDE_REGISTRY_URL"); url != "" && registryHost(url) == registryHost(reg) {
+		return registry.AuthConfig{Username: getenv("ADDE_REGISTRY_USERNAME"), Password: getenv("ADDE_REGISTRY_PASSWORD")}, AuthSourceRegistry, nil
+	}
+	if v := getenv("DOCKER_AUTH_CONFIG"); v != "" {
+		var cfg dockerClientConfig
+		if err := json.Unmarshal([]byte(v), &cfg); err != nil {
+			return auth, "", fmt.Errorf("DOCKER_AUTH_CONFIG is not valid JSON: %v", err)
+		}
+		if a, ok := lookupAuth(cfg.Auths, reg); ok {
+			a, err := withCredentials(a)
+			return a, AuthSourceEnv, err
+		}
+	}
+	path := DockerConfigPath(getenv)
+	if path == "" {
+		return auth, AuthSourceNone, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return auth, AuthSourceNone, nil
+	}
+	if err != nil {
+		return auth, "", err
+	}
+	var cfg dockerClientConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return auth, "", fmt.Errorf("%s: %v", path, err)
+	}
+	for key := range cfg.CredHelpers {
+		if registryHost(key) == registryHost(reg) {
+			return auth, AuthSourceCredHelpers, nil
+		}
+	}
+	a, ok := lookupAuth(cfg.Auths, reg)
+	switch {
+	case ok && (a.Auth != "" || a.Username != "" || a.IdentityToken != ""):
+		a, err := withCredentials(a)
+		return a, AuthSourceAuths, err
+	case cfg.CredsStore != "":
+		return auth, AuthSourceCredsStore, nil
+	}
+	return auth, AuthSourceNone, nil
+}
+
+// CheckRegistryAuth verifies the credentials RegistryAuthForImage finds for image by logging in to its
+// registry through the daemon; nothing is pulled and no credential is returned.
+func CheckRegistryAuth(ctx context.Context, cli *client.Client, p CheckRegistryAuthParams) CheckRegistryAuthResult {
+	return checkRegistryAuth(ctx, cli, p, os.Getenv)
+}
+
+func checkRegistryAuth(ctx context.Context, cli *client.Client, p CheckRegistryAuthParams, getenv func(string) string) CheckRegistryAuthResult {
+	auth, source, err := RegistryAuthForImage(p.Image, getenv)
+	if err != nil {
+		return CheckRegistryAuthResult{Source: source, Error: err.Error()}
+	}
+	named, _ := validateImageRef(p.Image)
+	res := CheckRegistryAuthResult{Registry: reference.Domain(named), Source: source}
+	if ecr, ok := parseECRHost(res.Registry); ok {
+		res.ECRRegion = ecr.Region
+	}
+	switch source {
+	case AuthSourceNone:
+		res.Status = "no credentials configured for this registry"
+		if isECRPublicHost(res.Registry) {
+			res.Status = "no credentials configured; ECR Public serves public images anonymously, as pull_image pulls them"
+		}
+		res.LoginCommand = ecrLoginHint(res.Registry)
+		return res
+	case AuthSourceCredsStore, AuthSourceCredHelpers:
+		res.Status = "credentials are held by a credential helper, which adde does not run; verify with docker login"
+		res.LoginCommand = ecrLoginHint(res.Registry)
+		return res
+	}
+	auth.ServerAddress = authServerAddress(res.Registry)
+	ok, err := cli.RegistryLogin(ctx, auth)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Authenticated = true
+	res.Status = ok.Status
+	return res
+}
+
+// ecrLoginHint is the shell pipeline that logs the Docker CLI in to an ECR or ECR Public host, or "".
+func ecrLoginHint(reg string) string {
+	cmd, ok := ecrLoginCommand(reg)
+	if !ok {
+		return ""
+	}
+	return strings.Join(cmd, " ") + " | docker login --username AWS --password-stdin " + reg
+}
+
+// lookupAuth finds reg in auths, whose keys may carry a scheme or path (https://index.docker.io/v1/).
+func lookupAuth(auths map[string]registry.AuthConfig, reg string) (registry.AuthConfig, bool) {
+	want := registryHost(reg)
+	for key, a := range auths {
+		if registryHost(key) == want {
+			return a, true
+		}
+	}
+	return registry.AuthConfig{}, false
+}
+
+// registryHost reduces an auths key or reference domain to a host, folding Docker Hub's aliases.
+func registryHost(s string) string {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "https://"), "http://")
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		s = s[:i]
+	}
+	switch s {
+	case "docker.io", "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	}
+	return s
+}
+
+// authServerAddress is the login address for a registry domain.
+func authServerAddress(reg string) string {
+	if registryHost(reg) == "docker.io" {
+		return dockerHubAuthKey
+	}
+	return reg
+}
+
+// withCredentials decodes the base64 "user:password" auth field into Username/Password when they are unset.
+func withCredentials(a registry.AuthConfig) (registry.AuthConfig, error) {
+	if a.Auth == "" || a.Username != "" {
+		return a, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(a.Auth)
+	if err != nil {
+		return a, fmt.Errorf("invalid auth field: %v", err)
+	}
+	user, pass, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return a, fmt.Errorf("invalid auth field: want base64 of user:password")
+	}
+	a.Username, a.Password, a.Auth = user, pass, ""
+	return a, nil
+}
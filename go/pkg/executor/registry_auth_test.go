@@ -0,0 +1,144 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/registry"
+)
+
+func TestRegistryAuthForImageSources(t *testing.T) {
+	dir := t.TempDir()
+	config := `{
+		"auths": {
+			"https://index.docker.io/v1/": {"auth": "aHViLXVzZXI6aHViLXB3"},
+			"ghcr.io": {},
+			"registry.example.com": {"username": "file-user", "password": "file-pw"}
+		},
+		"credsStore": "desktop",
+		"credHelpers": {"123456789.dkr.ecr.us-east-1.amazonaws.com": "ecr-login"}
+	}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"DOCKER_CONFIG":      dir,
+		"DOCKER_AUTH_CONFIG": `{"auths":{"registry.example.com":{"username":"ci-user","password":"ci-pw"}}}`,
+	}
+	getenv := func(k string) string { return env[k] }
+
+	cases := []struct {
+		image, source, user, pass string
+	}{
+		{"registry.example.com/team/app:1.0", AuthSourceEnv, "ci-user", "ci-pw"},
+		{"python:3.11-slim", AuthSourceAuths, "hub-user", "hub-pw"},
+		{"123456789.dkr.ecr.us-east-1.amazonaws.com/app", AuthSourceCredHelpers, "", ""},
+		{"ghcr.io/org/tool", AuthSourceCredsStore, "", ""},
+		{"quay.io/org/tool", AuthSourceCredsStore, "", ""},
+	}
+	for _, tc := range cases {
+		auth, source, err := RegistryAuthForImage(tc.image, getenv)
+		if err != nil {
+			t.Errorf("%s: %v", tc.image, err)
+			continue
+		}
+		if source != tc.source || auth.Username != tc.user || auth.Password != tc.pass {
+			t.Errorf("%s: source=%q user=%q pass=%q, want %q %q %q", tc.image, source, auth.Username, auth.Password, tc.source, tc.user, tc.pass)
+		}
+	}
+
+	delete(env, "DOCKER_AUTH_CONFIG")
+	if auth, source, _ := RegistryAuthForImage("registry.example.com/team/app:1.0", getenv); source != AuthSourceAuths || auth.Username != "file-user" {
+		t.Errorf("without env: source=%q user=%q", source, auth.Username)
+	}
+	env["DOCKER_CONFIG"] = filepath.Join(dir, "missing")
+	if _, source, err := RegistryAuthForImage("python:3.11-slim", getenv); err != nil || source != AuthSourceNone {
+		t.Errorf("no config: source=%q err=%v", source, err)
+	}
+	env["DOCKER_AUTH_CONFIG"] = "{not json"
+	if _, _, err := RegistryAuthForImage("python:3.11-slim", getenv); err == nil {
+		t.Error("invalid DOCKER_AUTH_CONFIG: expected error")
+	}
+}
+
+func TestCheckRegistryAuthLogsInWithoutExposingSecret(t *testing.T) {
+	var sent registry.AuthConfig
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1.43/auth" {
+			http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&sent)
+		w.Header().Set("Content-Type", "application/json")
+		if sent.Password != "ci-pw" {
+			http.Error(w, `{"message":"unauthorized: incorrect username or password"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"Status":"Login Succeeded"}`))
+	}))
+
+	env := map[string]string{
+		"DOCKER_CONFIG":      t.TempDir(),
+		"DOCKER_AUTH_CONFIG": `{"auths":{"registry.example.com":{"username":"ci-user","password":"ci-pw"}}}`,
+	}
+	getenv := func(k string) string { return env[k] }
+	res := checkRegistryAuth(context.Background(), cli, CheckRegistryAuthParams{Image: "registry.example.com/team/app:1.0"}, getenv)
+	if !res.Authenticated || res.Source != AuthSourceEnv || res.Registry != "registry.example.com" || res.Status != "Login Succeeded" {
+		t.Errorf("result = %+v", res)
+	}
+	if sent.ServerAddress != "registry.example.com" || sent.Username != "ci-user" {
+		t.Errorf("login request = %+v", sent)
+	}
+	if out, _ := json.Marshal(res); strings.Contains(string(out), "ci-pw") {
+		t.Errorf("result exposes the password: %s", out)
+	}
+
+	env["DOCKER_AUTH_CONFIG"] = `{"auths":{"registry.example.com":{"username":"ci-user","password":"wrong"}}}`
+	res = checkRegistryAuth(context.Background(), cli, CheckRegistryAuthParams{Image: "registry.example.com/team/app:1.0"}, getenv)
+	if res.Authenticated || !strings.Contains(res.Error, "incorrect username or password") {
+		t.Errorf("bad password: %+v", res)
+	}
+
+	res = checkRegistryAuth(context.Background(), cli, CheckRegistryAuthParams{Image: "quay.io/org/tool"}, getenv)
+	if res.Authenticated || res.Source != AuthSourceNone || res.Error != "" {
+		t.Errorf("no credentials: %+v", res)
+	}
+}
+
+func TestRegistryAuthForImageSeveralRegistries(t *testing.T) {
+	env := map[string]string{
+		"DOCKER_CONFIG":          t.TempDir(),
+		"ADDE_REGISTRIES_JSON":   `{"registry.example.com": {"username": "team-a", "password": "pw-a"}, "https://harbor.internal:8443": {"username": "team-b", "password": "pw-b"}}`,
+		"ADDE_REGISTRY_URL":      "legacy.example.com",
+		"ADDE_REGISTRY_USERNAME": "legacy-user",
+		"ADDE_REGISTRY_PASSWORD": "legacy-pw",
+	}
+	getenv := func(k string) string { return env[k] }
+	cases := []struct {
+		image, source, user, pass string
+	}{
+		{"registry.example.com/team/app:1.0", AuthSourceRegistries, "team-a", "pw-a"},
+		{"harbor.internal:8443/proj/svc@sha256:" + strings.Repeat("a", 64), AuthSourceRegistries, "team-b", "pw-b"},
+		{"legacy.example.com/old/app", AuthSourceRegistry, "legacy-user", "legacy-pw"},
+		{"quay.io/org/tool", AuthSourceNone, "", ""},
+	}
+	for _, tc := range cases {
+		auth, source, err := RegistryAuthForImage(tc.image, getenv)
+		if err != nil {
+			t.Errorf("%s: %v", tc.image, err)
+			continue
+		}
+		if source != tc.source || auth.Username != tc.user || auth.Password != tc.pass {
+			t.Errorf("%s: source=%q user=%q pass=%q, want %q %q %q", tc.image, source, auth.Username, auth.Password, tc.source, tc.user, tc.pass)
+		}
+	}
+	env["ADDE_REGISTRIES_JSON"] = `["registry.example.com"]`
+	if _, _, err := RegistryAuthForImage("registry.example.com/team/app", getenv); err == nil || !strings.Contains(err.Error(), "ADDE_REGISTRIES_JSON") {
+		t.Errorf("invalid ADDE_REGISTRIES_JSON: err = %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// RunFromDockerfile builds an image from an inline Dockerfile, runs one code block in a container from it,
+// and removes the container, image, workspace and build context before returning, whether or not any step failed.
+func RunFromDockerfile(ctx context.Context, cli *client.Client, p RunFromDockerfileParams) RunFromDockerfileResult {
+	files, err := runFromDockerfileFiles(p.Dockerfile, p.Files)
+	if err != nil {
+		return RunFromDockerfileResult{Error: err.Error()}
+	}
+	tag := p.Tag
+	if tag == "" {
+		tag = fmt.Sprintf("%srun-%d", AgentImageTagPrefix, time.Now().UnixNano())
+	}
+
+	prep := PrepareBuildContext(PrepareBuildContextParams{Files: files})
+	if prep.Error != "" {
+		return RunFromDockerfileResult{Error: "prepare: " + prep.Error}
+	}
+	defer os.RemoveAll(prep.ContextID)
+
+	build := BuildImageFromContext(ctx, cli, BuildImageFromContextParams{ContextID: prep.ContextID, Tag: tag})
+	res := RunFromDockerfileResult{Build: &build}
+	if build.Error != "" {
+		res.Error = "build: " + build.Error
+		return res
+	}
+	defer removeImageQuietly(cli, tag)
+
+	env := CreateRuntimeEnv(ctx, cli, CreateRuntimeEnvParams{Image: tag})
+	if env.Error != "" {
+		res.Error = "create: " + env.Error
+		return res
+	}
+	defer func() {
+		forceRemoveContainer(cli, env.ContainerID)
+		os.RemoveAll(env.Workspace)
+	}()
+
+	run := ExecuteCodeBlock(ctx, cli, ExecuteCodeBlockParams{
+		ContainerID: env.ContainerID,
+		Filename:    p.Filename,
+		CodeContent: p.CodeContent,
+		TimeoutSec:  p.TimeoutSec,
+	})
+	res.Log = run.Log
+	if run.Error != "" {
+		res.Error = "execute: " + run.Error
+	}
+	return res
+}
+
+// runFromDockerfileFiles merges the inline Dockerfile into the context files.
+func runFromDockerfileFiles(dockerfile string, files map[string]string) (map[string]string, error) {
+	if dockerfile == "" {
+		return nil, fmt.Errorf("dockerfile is required")
+	}
+	out := make(map[string]string, len(files)+1)
+	for name, content := range files {
+		if filepath.Clean(name) == "Dockerfile" {
+			return nil, fmt.Errorf("files must not contain a Dockerfile; pass it as dockerfile")
+		}
+		out[name] = content
+	}
+	out["Dockerfile"] = dockerfile
+	return out, nil
+}
+
+// removeImageQuietly deletes a throwaway image with a fresh context so cleanup still runs after cancellation.
+func removeImageQuietly(cli *client.Client, ref string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, _ = cli.ImageRemove(ctx, ref, types.ImageRemoveOptions{Force: true, PruneChildren: true})
+}
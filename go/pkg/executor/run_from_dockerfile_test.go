@@ -0,0 +1,20 @@
+package executor
+
+import "testing"
+
+func TestRunFromDockerfileFiles(t *testing.T) {
+	files, err := runFromDockerfileFiles("FROM busybox\n", map[string]string{"data/in.txt": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files["Dockerfile"] != "FROM busybox\n" || files["data/in.txt"] != "1" || len(files) != 2 {
+		t.Errorf("unexpected files: %v", files)
+	}
+
+	if _, err := runFromDockerfileFiles("", nil); err == nil {
+		t.Error("empty dockerfile: expected error")
+	}
+	if _, err := runFromDockerfileFiles("FROM busybox\n", map[string]string{"./Dockerfile": "FROM alpine\n"}); err == nil {
+		t.Error("duplicate Dockerfile: expected error")
+	}
+}
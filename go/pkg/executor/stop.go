@@ -0,0 +1,34 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+)
+
+// DefaultStopTimeoutSec is the grace period stop_container gives the main process after SIGTERM
+// before the daemon sends SIGKILL.
+const DefaultStopTimeoutSec = 5
+
+// StopContainer stops the container (SIGTERM, then SIGKILL after the grace period) but keeps it, so
+// its logs and filesystem stay available, unlike cleanup_env. Stopping a stopped container is OK.
+func StopContainer(ctx context.Context, cli *client.Client, p StopContainerParams) StopContainerResult {
+	if p.ContainerID == "" {
+		return StopContainerResult{Error: "container_id is required"}
+	}
+	timeout := p.TimeoutSec
+	if timeout <= 0 {
+		timeout = DefaultStopTimeoutSec
+	}
+	// The daemon answers 304 Not Modified for a container that is not running; the client treats it as success.
+	if err := cli.ContainerStop(ctx, p.ContainerID, container.StopOptions{Timeout: &timeout}); err != nil {
+		if errdefs.IsNotFound(err) {
+			return StopContainerResult{Error: fmt.Sprintf("container %q not found", p.ContainerID)}
+		}
+		return StopContainerResult{Error: err.Error()}
+	}
+	return StopContainerResult{OK: true}
+}
@@ -0,0 +1,40 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestStopContainer(t *testing.T) {
+	cli, calls := fakeDaemon(t, http.StatusNoContent)
+	if res := StopContainer(context.Background(), cli, StopContainerParams{ContainerID: "abc"}); !res.OK || res.Error != "" {
+		t.Errorf("stop: %+v", res)
+	}
+	if res := StopContainer(context.Background(), cli, StopContainerParams{ContainerID: "abc", TimeoutSec: 30}); !res.OK {
+		t.Errorf("stop with timeout: %+v", res)
+	}
+	want := []string{"POST /v1.43/containers/abc/stop?t=5", "POST /v1.43/containers/abc/stop?t=30"}
+	if got := calls(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("daemon calls = %q, want %q", got, want)
+	}
+}
+
+func TestStopContainerAlreadyStopped(t *testing.T) {
+	// The daemon answers 304 Not Modified when the container is not running.
+	cli, _ := fakeDaemon(t, http.StatusNotModified)
+	if res := StopContainer(context.Background(), cli, StopContainerParams{ContainerID: "abc"}); !res.OK || res.Error != "" {
+		t.Errorf("already stopped: %+v", res)
+	}
+}
+
+func TestStopContainerErrors(t *testing.T) {
+	cli, calls := fakeDaemon(t, http.StatusNotFound)
+	if res := StopContainer(context.Background(), cli, StopContainerParams{}); res.Error == "" || len(calls()) != 0 {
+		t.Errorf("missing id: %+v, calls=%q", res, calls())
+	}
+	if res := StopContainer(context.Background(), cli, StopContainerParams{ContainerID: "nope"}); res.OK || res.Error != `container "nope" not found` {
+		t.Errorf("unknown container: %+v", res)
+	}
+}
@@ -0,0 +1,97 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// Stream names passed to LineEmitter.
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// LineEmitter receives one line of output (without the trailing newline) tagged with its stream.
+// Returning an error stops the stream, e.g. when an HTTP client has disconnected.
+type LineEmitter func(stream, line string) error
+
+// StreamContainerLogs follows the container's main process output (docker logs -f) and emits it
+// line by line until the container stops, ctx is cancelled, or emit fails.
+func StreamContainerLogs(ctx context.Context, cli *client.Client, containerID string, emit LineEmitter) error {
+	inspect, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return err
+	}
+	rc, err := cli.ContainerLogs(ctx, containerID, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true, Follow: true})
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return demuxLines(rc, inspect.Config != nil && inspect.Config.Tty, emit)
+}
+
+// demuxLines splits a Docker output stream (multiplexed, or raw when tty) into lines as frames arrive.
+func demuxLines(r io.Reader, tty bool, emit LineEmitter) error {
+	var mu sync.Mutex
+	out := &lineWriter{stream: StreamStdout, emit: emit, mu: &mu}
+	errw := &lineWriter{stream: StreamStderr, emit: emit, mu: &mu}
+	var err error
+	if tty {
+		_, err = io.Copy(out, r)
+	} else {
+		_, err = stdcopy.StdCopy(out, errw, r)
+	}
+	if ferr := out.flush(); err == nil {
+		err = ferr
+	}
+	if ferr := errw.flush(); err == nil {
+		err = ferr
+	}
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// lineWriter buffers partial writes and emits each complete line.
+type lineWriter struct {
+	stream string
+	emit   LineEmitter
+	mu     *sync.Mutex
+	buf    bytes.Buffer
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := strings.TrimSuffix(string(w.buf.Next(i + 1)[:i]), "\r")
+		w.mu.Lock()
+		err := w.emit(w.stream, line)
+		w.mu.Unlock()
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (w *lineWriter) flush() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	line := w.buf.String()
+	w.buf.Reset()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.emit(w.stream, line)
+}
@@ -0,0 +1,117 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// StreamExecuteCodeBlock is execute_code_block for adde serve: the file is placed and run the same
+// way, but output is emitted line by line as the exec produces it instead of being buffered. The
+// returned entry carries the exit code and execution time; its stdout and stderr are empty, the
+// lines having been emitted. Options that need the whole output or extra execs are rejected.
+// The program runs under a wrapper that records its PID (so KillExec can signal it); started,
+// if non-nil, is called once the exec is running.
+func StreamExecuteCodeBlock(ctx context.Context, cli *client.Client, p ExecuteCodeBlockParams, emit LineEmitter, started func(RunningExec)) (*LogEntry, error) {
+	if opt := streamUnsupportedOption(p); opt != "" {
+		return nil, fmt.Errorf("%s is not supported when streaming; use execute_code_block", opt)
+	}
+	timeout := int(DefaultExecutionTimeout.Seconds())
+	if p.TimeoutSec > 0 {
+		timeout = p.TimeoutSec
+	}
+	code, err := decodeContent(p.CodeContent, p.ContentEncoding)
+	if err != nil {
+		return nil, fmt.Errorf("code_content: %v", err)
+	}
+	workDir, err := execWorkingDir(p.WorkingDir)
+	if err != nil {
+		return nil, err
+	}
+	cmd, err := commandForExecution(path.Join(workDir, p.Filename), p.Filename, p.Interpreter, p.FallbackShell)
+	if err != nil {
+		return nil, err
+	}
+	if p.Interpreter == "" && usesFallback(p.Filename) {
+		if err := requireShell(ctx, cli, p.ContainerID, cmd[0]); err != nil {
+			return nil, err
+		}
+	}
+	if err := prepareCodeFile(ctx, cli, p.ContainerID, workDir, path.Join(workDir, p.Filename), p.CleanWorkspace, p.CleanArtifacts); err != nil {
+		return nil, err
+	}
+	if err := copyCodeFile(ctx, cli, p.ContainerID, workDir, p.Filename, code, p.CreateWorkspace); err != nil {
+		return nil, err
+	}
+
+	pidFile := fmt.Sprintf("/tmp/.adde-exec-%d.pid", time.Now().UnixNano())
+	runCtx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+	start := time.Now()
+	createResp, err := cli.ContainerExecCreate(runCtx, p.ContainerID, types.ExecConfig{
+		Cmd:          pidFileCommand(cmd, pidFile),
+		AttachStdout: true,
+		AttachStderr: true,
+		Tty:          p.Tty,
+		WorkingDir:   workDir,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer removePIDFile(cli, p.ContainerID, pidFile)
+	resp, err := cli.ContainerExecAttach(runCtx, createResp.ID, types.ExecStartCheck{Tty: p.Tty})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+	if started != nil {
+		started(RunningExec{ExecID: createResp.ID, ContainerID: p.ContainerID, Filename: p.Filename, StartedAt: start, PIDFile: pidFile})
+	}
+	// On timeout or disconnect, kill the program and close the hijacked connection to unblock the read.
+	disarm := killOnDone(runCtx, cli, p.ContainerID, pidFile, resp.Close)
+	defer disarm()
+	if err := demuxLines(resp.Reader, p.Tty, emit); err != nil && runCtx.Err() == nil {
+		return nil, err
+	}
+	dur := time.Since(start)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if runCtx.Err() != nil {
+		return nil, fmt.Errorf("execution timed out after %ds", timeout)
+	}
+	inspect, err := cli.ContainerExecInspect(ctx, createResp.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &LogEntry{ExitCode: inspect.ExitCode, ExecutionTime: formatDuration(dur)}, nil
+}
+
+// streamUnsupportedOption names the first execute_code_block option set in p that streaming cannot honour.
+func streamUnsupportedOption(p ExecuteCodeBlockParams) string {
+	switch {
+	case p.Stdin != "" || p.StdinFile != "":
+		return "stdin"
+	case len(p.CaptureArtifacts) > 0:
+		return "capture_artifacts"
+	case p.StdoutEncoding != "":
+		return "stdout_encoding"
+	case p.MaxWorkspaceMB > 0:
+		return "max_workspace_mb"
+	case p.PidsLimit > 0:
+		return "pids_limit"
+	case p.Transcript:
+		return "transcript"
+	case p.Profile:
+		return "profile"
+	case p.Coverage:
+		return "coverage"
+	case p.ResultFormat != "":
+		return "result_format"
+	}
+	return ""
+}
@@ -0,0 +1,20 @@
+package executor
+
+import "testing"
+
+func TestStreamUnsupportedOption(t *testing.T) {
+	if opt := streamUnsupportedOption(ExecuteCodeBlockParams{Filename: "a.py", Tty: true, WorkingDir: "/app", Interpreter: "python3"}); opt != "" {
+		t.Errorf("supported options rejected as %q", opt)
+	}
+	for want, p := range map[string]ExecuteCodeBlockParams{
+		"stdin":             {StdinFile: "/tmp/in"},
+		"capture_artifacts": {CaptureArtifacts: []string{"*.png"}},
+		"max_workspace_mb":  {MaxWorkspaceMB: 10},
+		"transcript":        {Transcript: true},
+		"coverage":          {Coverage: true},
+	} {
+		if opt := streamUnsupportedOption(p); opt != want {
+			t.Errorf("got %q, want %q", opt, want)
+		}
+	}
+}
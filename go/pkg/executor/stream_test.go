@@ -0,0 +1,35 @@
+package executor
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+func TestDemuxLinesSplitsStreams(t *testing.T) {
+	var raw bytes.Buffer
+	stdout := stdcopy.NewStdWriter(&raw, stdcopy.Stdout)
+	stderr := stdcopy.NewStdWriter(&raw, stdcopy.Stderr)
+	stdout.Write([]byte("one\ntw"))
+	stderr.Write([]byte("warn\n"))
+	stdout.Write([]byte("o\nthree"))
+
+	var got []string
+	err := demuxLines(&raw, false, func(stream, line string) error {
+		got = append(got, stream+":"+line)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("demuxLines: %v", err)
+	}
+	want := []string{"stdout:one", "stderr:warn", "stdout:two", "stdout:three"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
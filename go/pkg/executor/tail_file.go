@@ -0,0 +1,88 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// Line counts for tail_file.
+const (
+	DefaultTailLines = 10
+	MaxTailLines     = 10000
+)
+
+// TailFile returns the last lines of a file inside the container (tail -n), e.g. the log file of a
+// use_image_cmd server that does not log to stdout. A missing file is reported with tail's stderr.
+func TailFile(ctx context.Context, cli *client.Client, p TailFileParams) TailFileResult {
+	if p.ContainerID == "" {
+		return TailFileResult{Error: "container_id is required"}
+	}
+	cmd, err := tailCommand(p.Path, p.Lines, false)
+	if err != nil {
+		return TailFileResult{Error: err.Error()}
+	}
+	stdout, stderr, exitCode, _, err := runExec(ctx, cli, p.ContainerID, cmd, 30)
+	if err != nil {
+		return TailFileResult{Error: err.Error()}
+	}
+	if exitCode != 0 {
+		return TailFileResult{Error: fmt.Sprintf("tail exited with code %d: %s", exitCode, strings.TrimSpace(stderr))}
+	}
+	return TailFileResult{Content: stdout}
+}
+
+// StreamFileTail follows a file inside the container (tail -f), emitting the last lines and then each
+// appended line until ctx is cancelled, emit fails, or the container stops.
+func StreamFileTail(ctx context.Context, cli *client.Client, containerID, path string, lines int, emit LineEmitter) error {
+	cmd, err := tailCommand(path, lines, true)
+	if err != nil {
+		return err
+	}
+	createResp, err := cli.ContainerExecCreate(ctx, containerID, types.ExecConfig{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+		WorkingDir:   WorkspacePathInsideContainer,
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := cli.ContainerExecAttach(ctx, createResp.ID, types.ExecStartCheck{})
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+	// Closing the hijacked connection on cancel unblocks the read; tail itself ends with the container.
+	stop := context.AfterFunc(ctx, func() { resp.Close() })
+	defer stop()
+	if err := demuxLines(resp.Reader, false, emit); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// tailCommand builds tail -n <lines> [-f] -- <path>; lines <= 0 means DefaultTailLines.
+func tailCommand(path string, lines int, follow bool) ([]string, error) {
+	if strings.TrimSpace(path) == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	if strings.ContainsRune(path, 0) {
+		return nil, fmt.Errorf("path contains a NUL byte")
+	}
+	if lines <= 0 {
+		lines = DefaultTailLines
+	}
+	if lines > MaxTailLines {
+		return nil, fmt.Errorf("lines must be at most %d", MaxTailLines)
+	}
+	cmd := []string{"tail", "-n", strconv.Itoa(lines)}
+	if follow {
+		cmd = append(cmd, "-f")
+	}
+	return append(cmd, "--", path), nil
+}
@@ -0,0 +1,50 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestTailCommand(t *testing.T) {
+	cmd, err := tailCommand("/var/log/app.log", 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(cmd, " "); got != "tail -n 10 -- /var/log/app.log" {
+		t.Errorf("default: %q", got)
+	}
+	cmd, err = tailCommand("-weird.log", 50, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(cmd, " "); got != "tail -n 50 -f -- -weird.log" {
+		t.Errorf("follow: %q", got)
+	}
+	for name, tc := range map[string]struct {
+		path  string
+		lines int
+	}{
+		"empty path": {"", 10},
+		"blank path": {"  ", 10},
+		"NUL path":   {"a\x00b", 10},
+		"too many":   {"app.log", MaxTailLines + 1},
+	} {
+		if _, err := tailCommand(tc.path, tc.lines, false); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestTailFileValidatesBeforeExec(t *testing.T) {
+	cli, calls := fakeDaemon(t, 200)
+	if res := TailFile(context.Background(), cli, TailFileParams{Path: "app.log"}); res.Error == "" {
+		t.Error("missing container_id: expected error")
+	}
+	if res := TailFile(context.Background(), cli, TailFileParams{ContainerID: "abc"}); res.Error == "" {
+		t.Error("missing path: expected error")
+	}
+	if got := calls(); len(got) != 0 {
+		t.Errorf("daemon called for invalid params: %q", got)
+	}
+}
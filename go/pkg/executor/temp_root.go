@@ -0,0 +1,54 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// tempRoot is the parent directory for adde-workspace-* and adde-build-* dirs; empty means os.TempDir().
+var (
+	tempRootMu sync.Mutex
+	tempRoot   string
+)
+
+// SetTempRoot makes dir (e.g. from ADDE_WORKSPACE_ROOT) the parent of new workspace and build
+// context dirs, so large workspaces can live on a big disk instead of a small tmpfs. dir must be
+// an existing, writable directory; "" restores the OS temp dir.
+func SetTempRoot(dir string) error {
+	if dir != "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", abs)
+		}
+		probe, err := os.CreateTemp(abs, ".adde-probe-")
+		if err != nil {
+			return fmt.Errorf("%s is not writable: %v", abs, err)
+		}
+		probe.Close()
+		os.Remove(probe.Name())
+		dir = abs
+	}
+	tempRootMu.Lock()
+	tempRoot = dir
+	tempRootMu.Unlock()
+	return nil
+}
+
+// TempRoot returns the directory new workspace and build context dirs are created in.
+func TempRoot() string {
+	tempRootMu.Lock()
+	defer tempRootMu.Unlock()
+	if tempRoot == "" {
+		return os.TempDir()
+	}
+	return tempRoot
+}
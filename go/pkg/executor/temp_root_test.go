@@ -0,0 +1,45 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSetTempRootPlacesBuildContexts(t *testing.T) {
+	root := t.TempDir()
+	if err := SetTempRoot(root); err != nil {
+		t.Fatal(err)
+	}
+	defer SetTempRoot("")
+
+	res := PrepareBuildContext(PrepareBuildContextParams{Files: map[string]string{"Dockerfile": "FROM busybox\n"}})
+	if res.Error != "" {
+		t.Fatal(res.Error)
+	}
+	defer os.RemoveAll(res.ContextID)
+	if filepath.Dir(res.ContextID) != root {
+		t.Errorf("context dir %s not under %s", res.ContextID, root)
+	}
+	stale, err := selectStaleTempDirs(TempRoot(), time.Now().Add(time.Hour), nil)
+	if err != nil || len(stale) != 1 {
+		t.Errorf("gc scan of the root: %v, %v", stale, err)
+	}
+}
+
+func TestSetTempRootValidates(t *testing.T) {
+	defer SetTempRoot("")
+	file := filepath.Join(t.TempDir(), "f")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{filepath.Join(t.TempDir(), "missing"), file} {
+		if err := SetTempRoot(bad); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+	if err := SetTempRoot(""); err != nil || TempRoot() != os.TempDir() {
+		t.Errorf("reset: %v, TempRoot=%s", err, TempRoot())
+	}
+}
@@ -0,0 +1,96 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+)
+
+// TranscriptPath is the in-container file execute_code_block appends to with transcript: true.
+// It lives outside /workspace so it never shows up among the agent's files or artifacts.
+const TranscriptPath = "/var/adde/transcript.log"
+
+const noTranscriptError = "no transcript found (run execute_code_block with transcript: true first)"
+
+// GetTranscript returns the chronological record of every execute_code_block run with transcript: true.
+// tail_lines keeps only the last N lines.
+func GetTranscript(ctx context.Context, cli *client.Client, p GetTranscriptParams) GetTranscriptResult {
+	if p.ContainerID == "" {
+		return GetTranscriptResult{Error: "container_id is required"}
+	}
+	data, found, err := readTranscript(ctx, cli, p.ContainerID)
+	if err != nil {
+		return GetTranscriptResult{Error: err.Error()}
+	}
+	if !found {
+		return GetTranscriptResult{Error: noTranscriptError}
+	}
+	transcript := string(data)
+	if p.TailLines > 0 {
+		transcript = tailLines(strings.TrimSuffix(transcript, "\n"), p.TailLines)
+	}
+	return GetTranscriptResult{Transcript: transcript}
+}
+
+// appendTranscript adds one run to the container's transcript. The file is copied out, extended and
+// copied back through the archive API, so the container needs no shell; concurrent runs in one
+// container may lose each other's entries.
+func appendTranscript(ctx context.Context, cli *client.Client, containerID, filename string, log *LogEntry, at time.Time) error {
+	data, _, err := readTranscript(ctx, cli, containerID)
+	if err != nil {
+		return err
+	}
+	content := string(data) + transcriptEntry(filename, log, at)
+	// Extracted at /, so the archive recreates /var/adde when it does not exist yet.
+	tarBuf, err := buildTarStream(strings.TrimPrefix(TranscriptPath, "/"), content)
+	if err != nil {
+		return err
+	}
+	return cli.CopyToContainer(ctx, containerID, "/", tarBuf, types.CopyToContainerOptions{})
+}
+
+// readTranscript returns the transcript file; found is false when no run has written it yet.
+func readTranscript(ctx context.Context, cli *client.Client, containerID string) (data []byte, found bool, err error) {
+	rc, _, err := cli.CopyFromContainer(ctx, containerID, TranscriptPath)
+	if errdefs.IsNotFound(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer rc.Close()
+	data, err = readSingleFileFromTar(rc)
+	if err != nil {
+		return nil, false, fmt.Errorf("read transcript: %v", err)
+	}
+	return data, true, nil
+}
+
+// transcriptEntry formats one run: a header with time, file, exit code and duration, then its
+// stdout and, when non-empty, its stderr.
+func transcriptEntry(filename string, log *LogEntry, at time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== %s %s exit_code=%d (%s) ===\n", at.UTC().Format(time.RFC3339), path.Base(filename), log.ExitCode, log.ExecutionTime)
+	writeTranscriptOutput(&b, log.Stdout)
+	if log.Stderr != "" {
+		b.WriteString("--- stderr ---\n")
+		writeTranscriptOutput(&b, log.Stderr)
+	}
+	return b.String()
+}
+
+func writeTranscriptOutput(b *strings.Builder, s string) {
+	if s == "" {
+		return
+	}
+	b.WriteString(s)
+	if !strings.HasSuffix(s, "\n") {
+		b.WriteString("\n")
+	}
+}
@@ -0,0 +1,96 @@
+package executor
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// fakeArchiveDaemon serves the container archive endpoints for one file, like a container filesystem would.
+func fakeArchiveDaemon(t *testing.T) *client.Client {
+	t.Helper()
+	var mu sync.Mutex
+	files := map[string][]byte{}
+	cli := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		dir := r.URL.Query().Get("path")
+		switch r.Method {
+		case http.MethodGet:
+			data, ok := files[dir]
+			if !ok {
+				http.Error(w, `{"message":"Could not find the file"}`, http.StatusNotFound)
+				return
+			}
+			name := dir[strings.LastIndex(dir, "/")+1:]
+			stat, _ := json.Marshal(types.ContainerPathStat{Name: name, Size: int64(len(data)), Mode: 0644})
+			w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString(stat))
+			buf, _ := buildTarStream(name, string(data))
+			w.Write(buf.Bytes())
+		case http.MethodPut:
+			tr := tar.NewReader(r.Body)
+			for {
+				hdr, err := tr.Next()
+				if err != nil {
+					break
+				}
+				data, _ := io.ReadAll(tr)
+				files[strings.TrimSuffix(dir, "/")+"/"+hdr.Name] = data
+			}
+		}
+	}))
+	return cli
+}
+
+func TestTranscriptAccumulatesRuns(t *testing.T) {
+	cli := fakeArchiveDaemon(t)
+	ctx := context.Background()
+	if res := GetTranscript(ctx, cli, GetTranscriptParams{ContainerID: "c1"}); res.Error != noTranscriptError {
+		t.Errorf("before any run: %+v", res)
+	}
+
+	at := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	runs := []struct {
+		file string
+		log  LogEntry
+	}{
+		{"step1.py", LogEntry{ExitCode: 0, Stdout: "loaded 3 rows\n", ExecutionTime: "0.10s"}},
+		{"step2.py", LogEntry{ExitCode: 1, Stdout: "partial", Stderr: "KeyError: 'id'\n", ExecutionTime: "0.20s"}},
+	}
+	for i, r := range runs {
+		if err := appendTranscript(ctx, cli, "c1", r.file, &r.log, at.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res := GetTranscript(ctx, cli, GetTranscriptParams{ContainerID: "c1"})
+	want := "=== 2026-10-15T12:00:00Z step1.py exit_code=0 (0.10s) ===\nloaded 3 rows\n" +
+		"=== 2026-10-15T12:00:01Z step2.py exit_code=1 (0.20s) ===\npartial\n--- stderr ---\nKeyError: 'id'\n"
+	if res.Error != "" || res.Transcript != want {
+		t.Errorf("transcript:\n%s\nwant:\n%s (error %q)", res.Transcript, want, res.Error)
+	}
+	tail := GetTranscript(ctx, cli, GetTranscriptParams{ContainerID: "c1", TailLines: 2})
+	if tail.Transcript != "--- stderr ---\nKeyError: 'id'" {
+		t.Errorf("tail_lines 2: %q", tail.Transcript)
+	}
+}
+
+func TestTranscriptEntryNoOutput(t *testing.T) {
+	got := transcriptEntry("/workspace/empty.sh", &LogEntry{ExitCode: 0, ExecutionTime: "0.01s"}, time.Unix(0, 0))
+	if got != "=== 1970-01-01T00:00:00Z empty.sh exit_code=0 (0.01s) ===\n" {
+		t.Errorf("entry = %q", got)
+	}
+	if strings.Contains(got, "stderr") {
+		t.Error("empty stderr should be omitted")
+	}
+}
@@ -2,47 +2,221 @@ package executor
 
 // CreateRuntimeEnvParams defines parameters for create_runtime_env.
 type CreateRuntimeEnvParams struct {
-	Image        string            `json:"image"`
+	Image        string            `json:"image"` // empty = the ADDE_DEFAULT_IMAGE default
 	Dependencies []string          `json:"dependencies"`
 	EnvVars      map[string]string `json:"env_vars"`
-	Network      bool              `json:"network,omitempty"`   // true = allow network; default false
-	PortBindings map[string]string `json:"port_bindings,omitempty"` // container_port -> host_port, e.g. {"3000": "8080"}
-	UseImageCmd  bool              `json:"use_image_cmd,omitempty"` // true = run image's default CMD (e.g. server); false = run "sleep 86400" for exec-based use
+	Network      bool              `json:"network,omitempty"`        // true = allow network; default false
+	PortBindings map[string]string `json:"port_bindings,omitempty"`  // container_port -> host_port, e.g. {"3000": "8080"}
+	UseImageCmd  bool              `json:"use_image_cmd,omitempty"`  // true = run image's default CMD (e.g. server); false = run "sleep 86400" for exec-based use
+	JobMode      bool              `json:"job_mode,omitempty"`       // true = run image's CMD once to completion; collect with wait_container + get_container_logs(source:"container")
+	MemoryMB     int               `json:"memory_mb,omitempty"`      // memory cap in MB (at least 6); 0 = 512
+	CPUs         float64           `json:"cpus,omitempty"`           // CPU cap, e.g. 2 or 0.25; 0 = 0.5
+	MemorySwapMB int               `json:"memory_swap_mb,omitempty"` // memory+swap cap in MB; 0 = same as memory (no swap), -1 = unlimited
+	CPUShares    int64             `json:"cpu_shares,omitempty"`     // relative CPU weight (default 1024); complements the NanoCPUs cap
+	CpusetCPUs   string            `json:"cpuset_cpus,omitempty"`    // pin to CPUs, e.g. "0-1" or "0,2"
+	// BlkioWeight is the relative block IO weight, 10-1000 (0 = daemon default, usually 500).
+	BlkioWeight uint16 `json:"blkio_weight,omitempty"`
+	// Per-device IO caps: device path -> IO operations (iops) or bytes (bps) per second, e.g. {"/dev/sda": 100}.
+	DeviceReadIOps  map[string]uint64 `json:"device_read_iops,omitempty"`
+	DeviceWriteIOps map[string]uint64 `json:"device_write_iops,omitempty"`
+	DeviceReadBps   map[string]uint64 `json:"device_read_bps,omitempty"`
+	DeviceWriteBps  map[string]uint64 `json:"device_write_bps,omitempty"`
+	// MaxExecutions caps execute_code_block runs; the run that reaches it removes the container. 0 = unlimited.
+	MaxExecutions int `json:"max_executions,omitempty"`
+	// CACerts is PEM data added to the system trust store (Debian/Ubuntu/Alpine/RHEL family images).
+	CACerts string `json:"ca_certs,omitempty"`
+	// IdempotencyKey makes retries safe: a repeat create with the same key returns the existing container.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	Hostname       string `json:"hostname,omitempty"`   // container hostname (single label, e.g. "worker-1"); default is the short container ID
+	DomainName     string `json:"domainname,omitempty"` // e.g. "cluster.local"
+	// Init runs an init (tini) as PID 1 to reap zombies. Unset = true in exec mode, false with use_image_cmd/job_mode.
+	Init *bool `json:"init,omitempty"`
+	// Name is the container name (Docker rules: [a-zA-Z0-9][a-zA-Z0-9_.-]+); empty = random. Not combinable with idempotency_key.
+	Name string `json:"name,omitempty"`
+	// AutoPublishExposed binds every port the image EXPOSEs to an ephemeral 127.0.0.1 port (docker run -P);
+	// port_bindings still wins for the ports it lists.
+	AutoPublishExposed bool `json:"auto_publish_exposed,omitempty"`
+	// InitScript runs after the dependency install (e.g. mkdir, git config); a non-zero exit fails the create.
+	InitScript      string `json:"init_script,omitempty"`
+	InitScriptShell string `json:"init_script_shell,omitempty"` // interpreter for init_script, e.g. "bash"; default "sh"
+	// PullPolicy is IfNotPresent (default: pull only a missing image), Always (re-pull to pick up tag
+	// updates) or Never (local image only; fail fast when absent), as in Kubernetes.
+	PullPolicy string `json:"pull_policy,omitempty"`
+	// Labels are added to the container's labels. Keys starting with "adde." are reserved: adde always sets
+	// adde.managed=true, adde.created_at and adde.workspace so tooling can find the containers it owns.
+	Labels map[string]string `json:"labels,omitempty"`
+	// VerifyIsolation runs the container in a private cgroup namespace and, once it is set up, checks it for
+	// a reachable Docker socket, a default route or host namespaces; findings become WEAK_ISOLATION warnings.
+	VerifyIsolation bool `json:"verify_isolation,omitempty"`
+	// Timezone sets TZ (IANA name, e.g. "Europe/Berlin") and Locale sets LANG and LC_ALL (e.g. "C.UTF-8");
+	// unset = the image default. The image must ship the zone data and locale for them to take effect.
+	Timezone string `json:"timezone,omitempty"`
+	Locale   string `json:"locale,omitempty"`
 }
 
 // CreateRuntimeEnvResult is the return value of create_runtime_env.
 type CreateRuntimeEnvResult struct {
-	ContainerID string `json:"container_id,omitempty"`
-	Workspace   string `json:"workspace,omitempty"`
-	Error       string `json:"error,omitempty"`
+	ContainerID  string    `json:"container_id,omitempty"`
+	Image        string    `json:"image,omitempty"`         // canonical ref, e.g. docker.io/library/busybox:latest
+	DefaultImage bool      `json:"default_image,omitempty"` // image was omitted and the ADDE_DEFAULT_IMAGE default was used
+	Workspace    string    `json:"workspace,omitempty"`
+	Reused       bool      `json:"reused,omitempty"`      // idempotency_key matched an existing container
+	Pulled       bool      `json:"pulled,omitempty"`      // the image was pulled under pull_policy
+	InstallLog   *LogEntry `json:"install_log,omitempty"` // set when the dependency install exited non-zero
+	InitLog      *LogEntry `json:"init_log,omitempty"`    // set when init_script exited non-zero
+	Warnings     []string  `json:"warnings,omitempty"`    // e.g. "ARCH_MISMATCH: ..." or verify_isolation's "WEAK_ISOLATION: ..."
+	// PublishedPorts maps container port to host address with auto_publish_exposed, e.g. {"3000/tcp": "127.0.0.1:49153"}.
+	PublishedPorts map[string]string `json:"published_ports,omitempty"`
+	Error          string            `json:"error,omitempty"`
+}
+
+// GetOrCreateEnvParams defines parameters for get_or_create_env: the create_runtime_env payload.
+// image, dependencies, env_vars (with timezone and locale) and network form the fingerprint; name and idempotency_key are rejected.
+type GetOrCreateEnvParams struct {
+	CreateRuntimeEnvParams
+}
+
+// GetOrCreateEnvResult is the return value of get_or_create_env; reused is true for an existing container.
+type GetOrCreateEnvResult struct {
+	CreateRuntimeEnvResult
+	Fingerprint string `json:"fingerprint,omitempty"` // sha256 hex, also the adde.env_fingerprint label
 }
 
 // ExecuteCodeBlockParams defines parameters for execute_code_block.
 type ExecuteCodeBlockParams struct {
 	ContainerID string `json:"container_id"`
-	Filename   string `json:"filename"`
+	Filename    string `json:"filename"`
 	CodeContent string `json:"code_content"`
-	TimeoutSec int    `json:"timeout_sec,omitempty"` // default 30
+	TimeoutSec  int    `json:"timeout_sec,omitempty"` // default 30
+	Tty         bool   `json:"tty,omitempty"`         // run under a pseudo-TTY; stdout and stderr are merged into log.stdout
+	// CaptureArtifacts lists glob patterns (e.g. "*.png", "out/**"); matching workspace files are returned as artifacts.
+	CaptureArtifacts []string `json:"capture_artifacts,omitempty"`
+	ContentEncoding  string   `json:"content_encoding,omitempty"` // "" / "plain" (default) or "gzip+base64" for code_content
+	Stdin            string   `json:"stdin,omitempty"`            // fed to the program's stdin
+	StdinFile        string   `json:"stdin_file,omitempty"`       // host file streamed to stdin (not loaded into memory); excludes stdin
+	Interpreter      string   `json:"interpreter,omitempty"`      // overrides the binary chosen by extension, e.g. "python3.11" or "/usr/bin/node20"
+	FallbackShell    string   `json:"fallback_shell,omitempty"`   // shell for files no extension matches (default sh), e.g. "bash"; checked before running
+	Profile          bool     `json:"profile,omitempty"`          // .py (cProfile) / .js, .mjs (node --prof): summary in log.profile
+	Coverage         bool     `json:"coverage,omitempty"`         // .py only: run under coverage.py and return the report as coverage
+	CoverageTool     string   `json:"coverage_tool,omitempty"`    // coverage executable, e.g. "/opt/venv/bin/coverage"; default "<interpreter> -m coverage"
+	ResultFormat     string   `json:"result_format,omitempty"`    // "junit": run as a test suite (.py pytest, .js jest) and return test_report
+	StdoutEncoding   string   `json:"stdout_encoding,omitempty"`  // "utf8" (default) or "base64" for binary-safe stdout/stderr
+	MaxWorkspaceMB   int      `json:"max_workspace_mb,omitempty"` // > 0: kill the program if /workspace grows past this (WORKSPACE_LIMIT_EXCEEDED)
+	// PidsLimit caps the container's process count while this exec runs (fork-bomb guard); restored afterwards.
+	PidsLimit int64 `json:"pids_limit,omitempty"`
+	// Transcript appends this run's output to /var/adde/transcript.log, read back with get_transcript.
+	Transcript bool `json:"transcript,omitempty"`
+	// WorkingDir is where the file is placed and run from, so relative paths in the code resolve
+	// against it; absolute, created if missing. Default /workspace.
+	WorkingDir string `json:"working_dir,omitempty"`
+	// CreateWorkspace creates /workspace when missing, for containers not made by create_runtime_env
+	// (e.g. started by docker compose).
+	CreateWorkspace bool `json:"create_workspace,omitempty"`
+	// CleanWorkspace removes the code files earlier runs copied in before this one is copied, so a stale
+	// helper module cannot be imported; files the programs created are kept unless CleanArtifacts is set.
+	CleanWorkspace bool `json:"clean_workspace,omitempty"`
+	// CleanArtifacts (with CleanWorkspace) also clears everything else in the working dir.
+	CleanArtifacts bool `json:"clean_artifacts,omitempty"`
 }
 
 // ExecuteCodeBlockResult is the return value of execute_code_block; includes log for refiner feedback loop.
 type ExecuteCodeBlockResult struct {
-	Log   *LogEntry `json:"log,omitempty"`
-	Error string    `json:"error,omitempty"`
+	Log       *LogEntry       `json:"log,omitempty"`
+	Artifacts []ArtifactInfo  `json:"artifacts,omitempty"` // files matching capture_artifacts after the run
+	Recycled  bool            `json:"recycled,omitempty"`  // max_executions reached; the container has been removed
+	Coverage  *CoverageReport `json:"coverage,omitempty"`  // with coverage: true
+	// TestReport is the parsed JUnit XML report with result_format: "junit".
+	TestReport *TestReport `json:"test_report,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// CoverageReport is the coverage.py summary of a run with coverage: true.
+type CoverageReport struct {
+	Percent       float64        `json:"percent"` // statements covered, 0-100
+	CoveredLines  int            `json:"covered_lines"`
+	NumStatements int            `json:"num_statements"`
+	Files         []CoverageFile `json:"files"`           // sorted by path
+	Error         string         `json:"error,omitempty"` // the report could not be produced; the run's log is still valid
+}
+
+// CoverageFile is one measured file of a CoverageReport.
+type CoverageFile struct {
+	Path          string  `json:"path"` // relative to working_dir when under it
+	Percent       float64 `json:"percent"`
+	CoveredLines  int     `json:"covered_lines"`
+	NumStatements int     `json:"num_statements"`
+	MissingLines  []int   `json:"missing_lines,omitempty"`
+}
+
+// TestReport is the outcome of a run with result_format: "junit", counted from its <testcase> elements.
+type TestReport struct {
+	Runner      string   `json:"runner,omitempty"` // "pytest" or "jest"
+	Tests       int      `json:"tests"`
+	Passed      int      `json:"passed"`
+	Failed      int      `json:"failed"`
+	Errors      int      `json:"errors"`
+	Skipped     int      `json:"skipped"`
+	FailedTests []string `json:"failed_tests,omitempty"` // "classname::name" of each failed or errored case
+	XML         string   `json:"xml,omitempty"`          // the raw report, capped at MaxJUnitXMLBytes
+	Error       string   `json:"error,omitempty"`        // the report could not be read; the run's log is still valid
+}
+
+// ExecuteCellsParams defines parameters for execute_cells.
+type ExecuteCellsParams struct {
+	ContainerID string `json:"container_id"`
+	Cells       []Cell `json:"cells"`
+	StopOnError bool   `json:"stop_on_error,omitempty"` // stop after the first cell with a non-zero exit code
+	Transcript  bool   `json:"transcript,omitempty"`    // append each cell's output to the transcript (see get_transcript)
+	WorkingDir  string `json:"working_dir,omitempty"`   // where cells are placed and run; default /workspace
+}
+
+// Cell is one code block of execute_cells.
+type Cell struct {
+	Filename    string `json:"filename"`
+	CodeContent string `json:"code_content"`
+	TimeoutSec  int    `json:"timeout_sec,omitempty"` // default 30
+}
+
+// ExecuteCellsResult is the return value of execute_cells.
+type ExecuteCellsResult struct {
+	Logs    []LogEntry `json:"logs"`              // one per cell that ran, in order
+	Stopped bool       `json:"stopped,omitempty"` // stop_on_error ended the run early
+	Error   string     `json:"error,omitempty"`
+}
+
+// ArtifactInfo describes a file the executed code left in the workspace.
+type ArtifactInfo struct {
+	Path      string `json:"path"` // relative to /workspace
+	SizeBytes int64  `json:"size_bytes"`
+}
+
+// GetTranscriptParams defines parameters for get_transcript.
+type GetTranscriptParams struct {
+	ContainerID string `json:"container_id"`
+	TailLines   int    `json:"tail_lines,omitempty"` // 0 = all
+}
+
+// GetTranscriptResult is the return value of get_transcript.
+type GetTranscriptResult struct {
+	Transcript string `json:"transcript"` // every transcript: true run, oldest first
+	Error      string `json:"error,omitempty"`
 }
 
 // GetContainerLogsParams defines parameters for get_container_logs.
 type GetContainerLogsParams struct {
 	ContainerID string `json:"container_id"`
 	TailLines   int    `json:"tail_lines,omitempty"` // 0 = all
+	Source      string `json:"source,omitempty"`     // "last_run" (default) = last execute_code_block; "container" = main process output (docker logs)
 }
 
 // LogEntry is the structured feedback for the refiner agent (per spec §3.B).
 type LogEntry struct {
-	ExitCode       int    `json:"exit_code"`
-	Stdout         string `json:"stdout"`
-	Stderr         string `json:"stderr"`
-	ExecutionTime  string `json:"execution_time"`
+	ExitCode      int    `json:"exit_code"`
+	Stdout        string `json:"stdout"`
+	Stderr        string `json:"stderr"`
+	ExecutionTime string `json:"execution_time"`
+	Encoding      string `json:"encoding,omitempty"` // "base64" when stdout/stderr are base64-encoded (stdout_encoding)
+	Profile       string `json:"profile,omitempty"`  // profiler summary with profile: true (cProfile stats / node --prof-process)
 }
 
 // GetContainerLogsResult wraps LogEntry or error.
@@ -51,6 +225,18 @@ type GetContainerLogsResult struct {
 	Error string   `json:"error,omitempty"`
 }
 
+// WaitContainerParams defines parameters for wait_container.
+type WaitContainerParams struct {
+	ContainerID string `json:"container_id"`
+	TimeoutSec  int    `json:"timeout_sec,omitempty"` // default 300
+}
+
+// WaitContainerResult is the return value of wait_container.
+type WaitContainerResult struct {
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+}
+
 // CleanupEnvParams defines parameters for cleanup_env.
 type CleanupEnvParams struct {
 	ContainerID string `json:"container_id"`
@@ -62,6 +248,145 @@ type CleanupEnvResult struct {
 	Error string `json:"error,omitempty"`
 }
 
+// ImageConfigParams defines parameters for image_config.
+type ImageConfigParams struct {
+	Image string `json:"image"` // local image, e.g. node:20-alpine or agent-env:app-1
+}
+
+// ImageConfigResult is the return value of image_config: the defaults the image declares.
+type ImageConfigResult struct {
+	Image        string   `json:"image,omitempty"` // canonical ref
+	ImageID      string   `json:"image_id,omitempty"`
+	Entrypoint   []string `json:"entrypoint,omitempty"`
+	Cmd          []string `json:"cmd,omitempty"` // what use_image_cmd / job_mode run
+	ExposedPorts []string `json:"exposed_ports"` // e.g. ["3000/tcp"]; candidates for port_bindings
+	Env          []string `json:"env,omitempty"` // KEY=VALUE as declared by ENV
+	WorkingDir   string   `json:"working_dir,omitempty"`
+	User         string   `json:"user,omitempty"` // empty = root
+	Error        string   `json:"error,omitempty"`
+}
+
+// ImageMetadataParams defines parameters for image_metadata.
+type ImageMetadataParams struct {
+	Image string `json:"image"` // local image, e.g. python:3.12-slim or agent-env:app-1
+}
+
+// ImageMetadataResult is the return value of image_metadata: inputs for an external vulnerability scanner.
+type ImageMetadataResult struct {
+	Image          string   `json:"image,omitempty"` // canonical ref
+	ImageID        string   `json:"image_id,omitempty"`
+	OS             string   `json:"os,omitempty"`
+	Architecture   string   `json:"architecture,omitempty"`
+	Variant        string   `json:"variant,omitempty"`         // e.g. v8 for arm64
+	BaseImage      string   `json:"base_image,omitempty"`      // from org.opencontainers.image.base.name; empty if unlabelled
+	PackageManager string   `json:"package_manager,omitempty"` // apk, apt, dnf, microdnf or yum; empty if none found
+	ExposedPorts   []string `json:"exposed_ports"`
+	LayerCount     int      `json:"layer_count"`
+	Error          string   `json:"error,omitempty"`
+}
+
+// StartDetachedExecParams defines parameters for start_detached_exec.
+type StartDetachedExecParams struct {
+	ContainerID string   `json:"container_id"`
+	Command     []string `json:"command"` // argv run in /workspace, e.g. ["python", "train.py"]; needs sh in the image
+}
+
+// StartDetachedExecResult is the return value of start_detached_exec.
+type StartDetachedExecResult struct {
+	ExecID     string `json:"exec_id,omitempty"`     // pass to poll_exec
+	OutputPath string `json:"output_path,omitempty"` // in-container file receiving stdout and stderr
+	Error      string `json:"error,omitempty"`
+}
+
+// PollExecParams defines parameters for poll_exec.
+type PollExecParams struct {
+	ContainerID string `json:"container_id"`
+	ExecID      string `json:"exec_id"` // from start_detached_exec
+}
+
+// PollExecResult is the return value of poll_exec.
+type PollExecResult struct {
+	Status          string `json:"status,omitempty"`    // "running" or "finished"
+	ExitCode        *int   `json:"exit_code,omitempty"` // set once finished
+	Output          string `json:"output"`              // stdout and stderr so far, interleaved
+	OutputTruncated bool   `json:"output_truncated,omitempty"`
+	OutputPath      string `json:"output_path,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// GetContainerEnvParams defines parameters for get_container_env.
+type GetContainerEnvParams struct {
+	ContainerID string `json:"container_id"`
+	Redact      bool   `json:"redact,omitempty"` // mask values of secret-looking names (*_TOKEN, *_PASSWORD, *_SECRET, *_KEY)
+}
+
+// GetContainerEnvResult is the return value of get_container_env.
+type GetContainerEnvResult struct {
+	Env      map[string]string `json:"env,omitempty"`      // name -> value as configured at create time
+	Redacted []string          `json:"redacted,omitempty"` // names whose values were masked, sorted
+	Error    string            `json:"error,omitempty"`
+}
+
+// ContainerDiffParams defines parameters for container_diff.
+type ContainerDiffParams struct {
+	ContainerID string `json:"container_id"`
+}
+
+// ContainerDiffResult is the return value of container_diff: writable-layer changes since create, sorted.
+type ContainerDiffResult struct {
+	Added   []string `json:"added"`
+	Changed []string `json:"changed"` // includes directories whose entries were added or deleted
+	Deleted []string `json:"deleted"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// PauseContainerParams defines parameters for pause_container.
+type PauseContainerParams struct {
+	ContainerID string `json:"container_id"`
+}
+
+// PauseContainerResult is the return value of pause_container.
+type PauseContainerResult struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// UnpauseContainerParams defines parameters for unpause_container.
+type UnpauseContainerParams struct {
+	ContainerID string `json:"container_id"`
+}
+
+// UnpauseContainerResult is the return value of unpause_container.
+type UnpauseContainerResult struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// StopContainerParams defines parameters for stop_container.
+type StopContainerParams struct {
+	ContainerID string `json:"container_id"`
+	TimeoutSec  int    `json:"timeout_sec,omitempty"` // grace period before SIGKILL; default 5
+}
+
+// StopContainerResult is the return value of stop_container.
+type StopContainerResult struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// KillContainerParams defines parameters for kill_container.
+type KillContainerParams struct {
+	ContainerID string `json:"container_id"`
+	Signal      string `json:"signal,omitempty"` // e.g. "SIGUSR1", "TERM" or "9"; default SIGKILL
+}
+
+// KillContainerResult is the return value of kill_container.
+type KillContainerResult struct {
+	OK     bool   `json:"ok"`
+	Signal string `json:"signal,omitempty"` // the signal sent
+	Error  string `json:"error,omitempty"`
+}
+
 // PullImageParams defines parameters for pull_image.
 type PullImageParams struct {
 	Image string `json:"image"` // e.g. "busybox", "python:3.11-slim"
@@ -70,21 +395,42 @@ type PullImageParams struct {
 // PullImageResult is the return value of pull_image.
 type PullImageResult struct {
 	OK    bool   `json:"ok"`
-	Error string `json:"error,omitempty"`
+	Image string `json:"image,omitempty"` // canonical ref that was pulled, e.g. docker.io/library/busybox:latest
+	// DurationMillis is the time spent in the daemon pull, including draining its progress stream.
+	DurationMillis int64  `json:"duration_ms"`
+	Error          string `json:"error,omitempty"`
 }
 
 // ---- Image Builder & Factory ----
 
 // PrepareBuildContextParams defines parameters for prepare_build_context.
 type PrepareBuildContextParams struct {
-	Files    map[string]string `json:"files"`     // path -> content
-	ContextID string           `json:"context_id"` // optional; if empty, a new ID is generated
+	Files             map[string]string `json:"files"`                        // path -> content
+	ContextID         string            `json:"context_id"`                   // optional; if empty, a new ID is generated
+	ContentEncoding   string            `json:"content_encoding,omitempty"`   // "" / "plain" (default) or "gzip+base64" for every file content
+	ExtraDockerignore []string          `json:"extra_dockerignore,omitempty"` // patterns appended to the default .dockerignore, or to the one in files
+	// Base images of the generated Dockerfile (ignored when files has a Dockerfile); empty = python:3-slim / node:20-alpine.
+	PythonBaseImage string `json:"python_base_image,omitempty"`
+	NodeBaseImage   string `json:"node_base_image,omitempty"`
 }
 
 // PrepareBuildContextResult is the return value of prepare_build_context.
 type PrepareBuildContextResult struct {
-	ContextID string `json:"context_id,omitempty"` // absolute path to build context dir
-	Error     string `json:"error,omitempty"`
+	ContextID   string `json:"context_id,omitempty"`   // absolute path to build context dir
+	ContentHash string `json:"content_hash,omitempty"` // sha256 over the paths and contents the build would see
+	Error       string `json:"error,omitempty"`
+}
+
+// HashBuildContextParams defines parameters for hash_build_context.
+type HashBuildContextParams struct {
+	ContextID string `json:"context_id"` // path from prepare_build_context (or any build directory)
+}
+
+// HashBuildContextResult is the return value of hash_build_context.
+type HashBuildContextResult struct {
+	ContentHash string `json:"content_hash,omitempty"` // "sha256:<hex>"; equal hashes mean the same build input
+	Files       int    `json:"files"`                  // regular files covered (after .dockerignore)
+	Error       string `json:"error,omitempty"`
 }
 
 // BuildImageFromContextParams defines parameters for build_image_from_context.
@@ -92,30 +438,89 @@ type BuildImageFromContextParams struct {
 	ContextID string            `json:"context_id"` // path from prepare_build_context
 	Tag       string            `json:"tag"`        // e.g. agent-env:task-123-1706457600
 	BuildArgs map[string]string `json:"build_args,omitempty"`
+	Compress  bool              `json:"compress,omitempty"` // gzip the context before sending; helps with remote daemons
+	// KeepFailedContainers keeps the container of a failed build step and returns it as failed_container_id;
+	// the other intermediate containers are removed, as are all of them after a successful build.
+	KeepFailedContainers bool `json:"keep_failed_containers,omitempty"`
+	// StrictArgs fails the build up front when the Dockerfile declares an ARG with no default that build_args does not set.
+	StrictArgs bool `json:"strict_args,omitempty"`
+	// TaskID is stamped on the image as the adde.task_id label (with adde.context_id) and inherited by its containers.
+	TaskID string `json:"task_id,omitempty"`
+	// CacheFrom lists images (e.g. a previously pushed build) whose layers may be reused as build cache.
+	CacheFrom []string `json:"cache_from,omitempty"`
+	// PullCacheFrom pulls cache_from images that are not present locally before building (the classic builder needs them local).
+	PullCacheFrom bool `json:"pull_cache_from,omitempty"`
+	// BuildID names the build in server mode (adde serve) so POST /cancel_build can abort it.
+	BuildID string `json:"build_id,omitempty"`
+	// HashTag tags the image agent-env:<content-hash prefix> when tag is empty, so identical contexts reuse one tag.
+	HashTag bool `json:"hash_tag,omitempty"`
+	// Stream writes build output lines to stderr as they arrive; the JSON result on stdout is unchanged.
+	Stream bool `json:"stream,omitempty"`
+	// Labels are added to the image's labels (keys starting with "adde." are reserved); the image is always labelled adde.managed=true.
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 // BuildImageFromPathParams defines parameters for build_image_from_path.
 // Use when the project already exists on disk (e.g. cloned repo) with a Dockerfile.
 type BuildImageFromPathParams struct {
-	Path      string            `json:"path"`       // absolute or relative path to directory containing Dockerfile
-	Tag       string            `json:"tag"`        // e.g. agent-env:myapp-1
+	Path      string            `json:"path"` // absolute or relative path to directory containing Dockerfile
+	Tag       string            `json:"tag"`  // e.g. agent-env:myapp-1
 	BuildArgs map[string]string `json:"build_args,omitempty"`
+	TaskID    string            `json:"task_id,omitempty"` // stamped on the image as the adde.task_id label
+	Labels    map[string]string `json:"labels,omitempty"`  // added to the image's labels, as in build_image_from_context
 }
 
 // BuildImageFromContextResult is the return value of build_image_from_context (handshake format).
 type BuildImageFromContextResult struct {
-	Status          string  `json:"status,omitempty"`           // "success" or "error"
-	ImageID         string  `json:"image_id,omitempty"`         // sha256:...
-	Tag             string  `json:"tag,omitempty"`
-	SizeMB          float64 `json:"size_mb,omitempty"`
-	BuildLogSummary string  `json:"build_log_summary,omitempty"`
-	FailedLayer     string  `json:"failed_layer,omitempty"`     // when status is error
-	Error           string  `json:"error,omitempty"`
+	Status            string   `json:"status,omitempty"`   // "success" or "error"
+	ImageID           string   `json:"image_id,omitempty"` // sha256:...
+	Tag               string   `json:"tag,omitempty"`
+	SizeMB            float64  `json:"size_mb,omitempty"`
+	BuildLogSummary   string   `json:"build_log_summary,omitempty"`
+	FailedLayer       string   `json:"failed_layer,omitempty"`        // when status is error
+	FailedContainerID string   `json:"failed_container_id,omitempty"` // with keep_failed_containers: intermediate container to inspect
+	DurationMillis    int64    `json:"duration_ms"`                   // time spent in the daemon build (0 if it never started)
+	ConsumedBuildArgs []string `json:"consumed_build_args,omitempty"` // build_args declared by an ARG (or predefined, e.g. HTTP_PROXY)
+	IgnoredBuildArgs  []string `json:"ignored_build_args,omitempty"`  // build_args no ARG declares; the daemon drops them
+	BuildArgWarnings  []string `json:"build_arg_warnings,omitempty"`  // required ARGs (no default) that were not passed
+	Error             string   `json:"error,omitempty"`
+}
+
+// RunFromDockerfileParams defines parameters for run_from_dockerfile.
+type RunFromDockerfileParams struct {
+	Dockerfile  string            `json:"dockerfile"`      // Dockerfile content
+	Files       map[string]string `json:"files,omitempty"` // extra build context files, path -> content
+	Tag         string            `json:"tag,omitempty"`   // default agent-env:run-<nanos>; the image is removed afterwards
+	Filename    string            `json:"filename"`        // code file written to /workspace
+	CodeContent string            `json:"code_content"`
+	TimeoutSec  int               `json:"timeout_sec,omitempty"` // default 30
+}
+
+// RunFromDockerfileResult is the return value of run_from_dockerfile.
+type RunFromDockerfileResult struct {
+	Build *BuildImageFromContextResult `json:"build,omitempty"`
+	Log   *LogEntry                    `json:"log,omitempty"`
+	Error string                       `json:"error,omitempty"`
+}
+
+// ListLanguagesResult is the return value of list_languages.
+type ListLanguagesResult struct {
+	Languages []LanguageInfo `json:"languages"`
+	Fallback  string         `json:"fallback"` // command for any other extension, e.g. "sh -c"
+}
+
+// LanguageInfo maps one file extension to the command execute_code_block runs it with.
+type LanguageInfo struct {
+	Extension string `json:"extension"` // e.g. ".py"
+	Language  string `json:"language"`  // e.g. "python"
+	Command   string `json:"command"`   // the file path is appended, e.g. "python"
 }
 
 // ListAgentImagesParams defines parameters for list_agent_images.
 type ListAgentImagesParams struct {
 	FilterTag string `json:"filter_tag,omitempty"` // optional prefix filter, e.g. "agent-env"
+	// MatchLabel also lists images labelled adde.managed=true (built by adde) under any tag, or none.
+	MatchLabel bool `json:"match_label,omitempty"`
 }
 
 // ListAgentImagesResult is the return value of list_agent_images.
@@ -134,7 +539,8 @@ type AgentImageEntry struct {
 
 // PruneBuildCacheParams defines parameters for prune_build_cache.
 type PruneBuildCacheParams struct {
-	OlderThanHrs int `json:"older_than_hrs,omitempty"` // 0 = prune all unused
+	OlderThanHrs int    `json:"older_than_hrs,omitempty"` // 0 = prune all unused
+	Engine       string `json:"engine,omitempty"`         // "docker" | "podman"; empty = detect from the daemon
 }
 
 // PruneBuildCacheResult is the return value of prune_build_cache.
@@ -143,6 +549,185 @@ type PruneBuildCacheResult struct {
 	Error            string  `json:"error,omitempty"`
 }
 
+// GCWorkspacesParams defines parameters for gc_workspaces.
+type GCWorkspacesParams struct {
+	OlderThanHrs int  `json:"older_than_hrs,omitempty"` // default 24
+	DryRun       bool `json:"dry_run,omitempty"`        // report what would be removed without deleting
+}
+
+// GCWorkspacesResult is the return value of gc_workspaces.
+type GCWorkspacesResult struct {
+	DryRun      bool     `json:"dry_run,omitempty"`
+	Removed     []string `json:"removed,omitempty"` // host paths of removed (or, with dry_run, removable) dirs
+	Count       int      `json:"count"`
+	ReclaimedMB float64  `json:"reclaimed_mb"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// VolumeInfo describes one toolkit-managed volume.
+type VolumeInfo struct {
+	Name       string            `json:"name"`
+	Driver     string            `json:"driver,omitempty"`
+	Mountpoint string            `json:"mountpoint,omitempty"`
+	CreatedAt  string            `json:"created_at,omitempty"`
+	SizeMB     float64           `json:"size_mb,omitempty"` // omitted when the daemon does not report usage
+	Labels     map[string]string `json:"labels,omitempty"`
+}
+
+// ListVolumesResult is the return value of list_volumes.
+type ListVolumesResult struct {
+	Volumes []VolumeInfo `json:"volumes"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// PruneVolumesParams defines parameters for prune_volumes.
+type PruneVolumesParams struct {
+	ManagedOnly bool `json:"managed_only,omitempty"` // only volumes labelled adde.managed=true
+	DryRun      bool `json:"dry_run,omitempty"`      // report removable volumes without removing them
+}
+
+// PruneVolumesResult is the return value of prune_volumes.
+type PruneVolumesResult struct {
+	DryRun           bool     `json:"dry_run,omitempty"`
+	Removed          []string `json:"removed"`          // removed (or, with dry_run, removable) volume names
+	InUse            []string `json:"in_use,omitempty"` // volumes kept because a container uses them
+	SpaceReclaimedMB float64  `json:"space_reclaimed_mb"`
+	Error            string   `json:"error,omitempty"`
+}
+
+// PruneAllParams defines parameters for prune_all.
+type PruneAllParams struct {
+	// IncludeUnmanaged also prunes stopped containers and dangling images adde did not create, and the
+	// host-wide build cache; by default only toolkit-created containers and agent-env: images are removed.
+	// It is the inverse of a managed_only flag, so that the safe mode needs no flag.
+	IncludeUnmanaged bool   `json:"include_unmanaged,omitempty"`
+	OlderThanHrs     int    `json:"older_than_hrs,omitempty"` // 0 = any age
+	DryRun           bool   `json:"dry_run,omitempty"`        // report candidates without removing them
+	Engine           string `json:"engine,omitempty"`         // passed through to the build cache prune
+}
+
+// PruneAllResult is the return value of prune_all.
+type PruneAllResult struct {
+	DryRun                bool     `json:"dry_run,omitempty"`
+	Containers            []string `json:"containers,omitempty"` // removed (or, with dry_run, removable) container IDs
+	Images                []string `json:"images,omitempty"`     // removed (or removable) image IDs
+	Untagged              []string `json:"untagged,omitempty"`   // agent-env: tags removed from images kept for their other tags
+	ContainersReclaimedMB float64  `json:"containers_reclaimed_mb"`
+	ImagesReclaimedMB     float64  `json:"images_reclaimed_mb"`
+	BuildCacheReclaimedMB float64  `json:"build_cache_reclaimed_mb"`
+	TotalReclaimedMB      float64  `json:"total_reclaimed_mb"`
+	Failed                []string `json:"failed,omitempty"` // removals that failed, e.g. "container <id>: <error>"; error is set too
+	Error                 string   `json:"error,omitempty"`
+}
+
+// VerifyWorkspaceParams defines parameters for verify_workspace.
+type VerifyWorkspaceParams struct {
+	ContainerID string `json:"container_id"`
+}
+
+// VerifyWorkspaceResult is the diagnostic returned by verify_workspace.
+type VerifyWorkspaceResult struct {
+	OK              bool     `json:"ok"`                  // true when no problems were found
+	MountPresent    bool     `json:"mount_present"`       // container has a /workspace mount
+	MountWritable   bool     `json:"mount_writable"`      // mounted read-write
+	HostPath        string   `json:"host_path,omitempty"` // bind source on the daemon host
+	HostDirExists   bool     `json:"host_dir_exists"`     // checked on the machine running adde
+	HostDirWritable bool     `json:"host_dir_writable"`
+	Files           []string `json:"files,omitempty"` // entries in /workspace as seen by the container
+	Problems        []string `json:"problems"`
+	Error           string   `json:"error,omitempty"`
+}
+
+// CheckPortParams defines parameters for check_port.
+type CheckPortParams struct {
+	ContainerID   string `json:"container_id"`
+	ContainerPort string `json:"container_port"`        // published TCP port, e.g. "3000" or "3000/tcp"
+	Path          string `json:"path,omitempty"`        // set: HTTP GET this path (e.g. "/health") instead of a TCP connect
+	TimeoutSec    int    `json:"timeout_sec,omitempty"` // default 5
+}
+
+// CheckPortResult is the return value of check_port.
+type CheckPortResult struct {
+	Reachable   bool   `json:"reachable"`
+	HostAddress string `json:"host_address,omitempty"` // the address that was checked, e.g. "127.0.0.1:8080"
+	StatusCode  int    `json:"status_code,omitempty"`  // HTTP status with path (redirects are not followed)
+	LatencyMS   int64  `json:"latency_ms,omitempty"`   // time to connect, or to the response with path
+	Reason      string `json:"reason,omitempty"`       // why the port is not reachable
+	Error       string `json:"error,omitempty"`
+}
+
+// CheckDependencyParams defines parameters for check_dependency.
+type CheckDependencyParams struct {
+	ContainerID string `json:"container_id"`
+	Language    string `json:"language"` // python (pip show) | node (npm ls -g) | system (which)
+	Package     string `json:"package"`
+}
+
+// CheckDependencyResult is the return value of check_dependency.
+type CheckDependencyResult struct {
+	Installed bool   `json:"installed"`
+	Version   string `json:"version,omitempty"` // empty for system binaries
+	Error     string `json:"error,omitempty"`
+}
+
+// CheckRegistryAuthParams defines parameters for check_registry_auth.
+type CheckRegistryAuthParams struct {
+	Image string `json:"image"` // the registry is taken from it, e.g. registry.example.com/team/app:1.0
+}
+
+// CheckRegistryAuthResult is the return value of check_registry_auth; credentials are never included.
+type CheckRegistryAuthResult struct {
+	Registry      string `json:"registry,omitempty"`
+	ECRRegion     string `json:"ecr_region,omitempty"`    // region parsed from an ECR host, e.g. us-gov-west-1 or cn-north-1
+	Source        string `json:"source,omitempty"`        // ADDE_REGISTRIES_JSON | ADDE_REGISTRY_URL | DOCKER_AUTH_CONFIG | auths | credsStore | credHelpers | none
+	Authenticated bool   `json:"authenticated"`           // the registry accepted the credentials
+	Status        string `json:"status,omitempty"`        // registry response, or why nothing was checked
+	LoginCommand  string `json:"login_command,omitempty"` // ECR / ECR Public without usable credentials: the aws CLI login pipeline
+	Error         string `json:"error,omitempty"`
+}
+
+// TailFileParams defines parameters for tail_file.
+type TailFileParams struct {
+	ContainerID string `json:"container_id"`
+	Path        string `json:"path"`            // file inside the container; relative paths resolve against /workspace
+	Lines       int    `json:"lines,omitempty"` // default 10, max 10000
+}
+
+// TailFileResult is the return value of tail_file.
+type TailFileResult struct {
+	Content string `json:"content"` // the last lines of the file
+	Error   string `json:"error,omitempty"`
+}
+
+// CopyFromContainerParams defines parameters for copy_from_container.
+type CopyFromContainerParams struct {
+	ContainerID string `json:"container_id"`
+	Path        string `json:"path"` // file or, with as_tar, directory inside the container; relative paths resolve against /workspace
+	// AsTar returns path as one tar archive (the only way to copy a directory) with its entries in manifest.
+	AsTar bool `json:"as_tar,omitempty"`
+	Gzip  bool `json:"gzip,omitempty"` // with as_tar, gzip the archive
+	// DestDir extracts path (file or directory) into this directory on the host running adde instead of returning it.
+	DestDir string `json:"dest_dir,omitempty"`
+}
+
+// CopyFromContainerResult is the return value of copy_from_container.
+type CopyFromContainerResult struct {
+	Content  string     `json:"content"`            // the file's bytes, or with as_tar the archive, base64-encoded
+	Size     int64      `json:"size"`               // in bytes, before encoding
+	Manifest []TarEntry `json:"manifest,omitempty"` // with as_tar, the archive's entries in order
+	// Extracted lists, with dest_dir, the paths written relative to it (also when extraction stopped on an error).
+	Extracted []string `json:"extracted,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// TarEntry describes one entry of an archive returned by copy_from_container with as_tar.
+type TarEntry struct {
+	Name       string `json:"name"` // slash-separated, rooted at the copied path's base name
+	Type       string `json:"type"` // file, dir, symlink, hardlink or other
+	Size       int64  `json:"size,omitempty"`
+	LinkTarget string `json:"link_target,omitempty"`
+}
+
 // DeleteImageParams defines parameters for delete_image.
 type DeleteImageParams struct {
 	Image        string `json:"image"`                   // tag (e.g. agent-env:task-1) or image ID
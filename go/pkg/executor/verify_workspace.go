@@ -0,0 +1,90 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// VerifyWorkspace diagnoses the container's /workspace mount: that it exists, that its host dir is present
+// and writable, and what the container currently sees in it. Problems are listed rather than returned as
+// an error so an agent gets the whole picture in one call; Error is only set when the container can't be inspected.
+func VerifyWorkspace(ctx context.Context, cli *client.Client, p VerifyWorkspaceParams) VerifyWorkspaceResult {
+	inspect, err := cli.ContainerInspect(ctx, p.ContainerID)
+	if err != nil {
+		return VerifyWorkspaceResult{Error: err.Error()}
+	}
+	res := VerifyWorkspaceResult{Problems: []string{}}
+	mount, ok := workspaceMount(inspect)
+	if !ok {
+		res.Problems = append(res.Problems, "no mount at "+WorkspacePathInsideContainer)
+		return res
+	}
+	res.MountPresent = true
+	res.HostPath = mount.Source
+	res.MountWritable = mount.RW
+	if !mount.RW {
+		res.Problems = append(res.Problems, WorkspacePathInsideContainer+" is mounted read-only")
+	}
+
+	res.HostDirExists, res.HostDirWritable = checkHostDir(mount.Source)
+	switch {
+	case !res.HostDirExists:
+		res.Problems = append(res.Problems, fmt.Sprintf("host dir %s does not exist on this machine (removed, or the daemon is remote)", mount.Source))
+	case !res.HostDirWritable:
+		res.Problems = append(res.Problems, fmt.Sprintf("host dir %s is not writable", mount.Source))
+	}
+
+	if inspect.State == nil || !inspect.State.Running {
+		res.Problems = append(res.Problems, "container is not running; cannot list workspace files")
+	} else {
+		stdout, stderr, code, _, err := runExec(ctx, cli, p.ContainerID, []string{"ls", "-1A", WorkspacePathInsideContainer}, 10)
+		if err != nil || code != 0 {
+			res.Problems = append(res.Problems, execFailure("list "+WorkspacePathInsideContainer, stderr, code, err).Error())
+		} else {
+			res.Files = splitNonEmptyLines(stdout)
+		}
+	}
+	res.OK = len(res.Problems) == 0
+	return res
+}
+
+// workspaceMount finds the /workspace mount in an inspect result.
+func workspaceMount(inspect types.ContainerJSON) (types.MountPoint, bool) {
+	for _, m := range inspect.Mounts {
+		if m.Destination == WorkspacePathInsideContainer {
+			return m, true
+		}
+	}
+	return types.MountPoint{}, false
+}
+
+// checkHostDir reports whether dir exists as a directory and accepts a new file.
+func checkHostDir(dir string) (exists, writable bool) {
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return false, false
+	}
+	f, err := os.CreateTemp(dir, ".adde-verify-")
+	if err != nil {
+		return true, false
+	}
+	name := f.Name()
+	f.Close()
+	os.Remove(name)
+	return true, true
+}
+
+func splitNonEmptyLines(s string) []string {
+	out := []string{}
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
@@ -0,0 +1,41 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestWorkspaceMountDetection(t *testing.T) {
+	withMount := types.ContainerJSON{Mounts: []types.MountPoint{
+		{Destination: "/data", Source: "/srv/data"},
+		{Destination: WorkspacePathInsideContainer, Source: "/tmp/adde-workspace-123", RW: true},
+	}}
+	m, ok := workspaceMount(withMount)
+	if !ok || m.Source != "/tmp/adde-workspace-123" || !m.RW {
+		t.Errorf("got %+v, %v", m, ok)
+	}
+
+	if _, ok := workspaceMount(types.ContainerJSON{Mounts: []types.MountPoint{{Destination: "/data"}}}); ok {
+		t.Error("container without /workspace reported a workspace mount")
+	}
+	if _, ok := workspaceMount(types.ContainerJSON{}); ok {
+		t.Error("container without mounts reported a workspace mount")
+	}
+}
+
+func TestCheckHostDir(t *testing.T) {
+	dir := t.TempDir()
+	if exists, writable := checkHostDir(dir); !exists || !writable {
+		t.Errorf("temp dir: exists=%v writable=%v", exists, writable)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("probe file left behind: %v", entries)
+	}
+	if exists, _ := checkHostDir(filepath.Join(dir, "missing")); exists {
+		t.Error("missing dir reported as existing")
+	}
+}
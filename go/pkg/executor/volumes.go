@@ -0,0 +1,144 @@
+package executor
+
+import (
+	"context"
+	"sort"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/versions"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+)
+
+// LabelManaged marks Docker objects (volumes) created by the toolkit; list_volumes shows only volumes
+// carrying LabelManaged=true, and prune_volumes limits itself to them with managed_only.
+const LabelManaged = "adde.managed"
+
+// ListVolumes returns the toolkit-managed volumes. Sizes come from the daemon's disk-usage
+// report, which can be slow on large hosts; they are omitted if it fails.
+func ListVolumes(ctx context.Context, cli *client.Client) ListVolumesResult {
+	vols, err := managedVolumeList(ctx, cli)
+	if err != nil {
+		return ListVolumesResult{Error: err.Error()}
+	}
+	return ListVolumesResult{Volumes: managedVolumes(vols, volumeUsage(ctx, cli))}
+}
+
+// PruneVolumes removes unused volumes via the daemon's volume prune: only toolkit-managed ones
+// (LabelManaged=true) with managed_only, else every unused volume. The daemon never removes a volume
+// a container uses; those are reported in in_use so callers can see why they remain. With dry_run
+// nothing is removed and removed lists the volumes a prune would delete.
+func PruneVolumes(ctx context.Context, cli *client.Client, p PruneVolumesParams) PruneVolumesResult {
+	usage := volumeUsage(ctx, cli)
+	vols, err := pruneCandidateList(ctx, cli, p.ManagedOnly)
+	if err != nil {
+		return PruneVolumesResult{DryRun: p.DryRun, Error: err.Error()}
+	}
+	res := PruneVolumesResult{DryRun: p.DryRun, Removed: []string{}}
+	var unusedBytes int64
+	for _, v := range vols {
+		if v == nil {
+			continue
+		}
+		if u, ok := usage[v.Name]; ok && u.RefCount > 0 {
+			res.InUse = append(res.InUse, v.Name)
+			continue
+		}
+		if p.DryRun {
+			res.Removed = append(res.Removed, v.Name)
+			if u, ok := usage[v.Name]; ok && u.Size > 0 {
+				unusedBytes += u.Size
+			}
+		}
+	}
+	sort.Strings(res.InUse)
+	if p.DryRun {
+		sort.Strings(res.Removed)
+		res.SpaceReclaimedMB = float64(unusedBytes) / (1024 * 1024)
+		return res
+	}
+
+	report, err := cli.VolumesPrune(ctx, volumePruneFilters(p.ManagedOnly, cli.ClientVersion()))
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	pruned := volumesPruneResult(report)
+	res.Removed, res.SpaceReclaimedMB = pruned.Removed, pruned.SpaceReclaimedMB
+	return res
+}
+
+// volumePruneFilters builds the volume prune filters. Since API 1.42 the daemon prunes only anonymous
+// volumes unless all=true, which would skip every named (managed) volume; older daemons reject "all".
+func volumePruneFilters(managedOnly bool, apiVersion string) filters.Args {
+	args := filters.NewArgs()
+	if managedOnly {
+		args.Add("label", LabelManaged+"=true")
+	}
+	if apiVersion == "" || versions.GreaterThanOrEqualTo(apiVersion, "1.42") {
+		args.Add("all", "true")
+	}
+	return args
+}
+
+// volumesPruneResult converts the daemon's prune report.
+func volumesPruneResult(report types.VolumesPruneReport) PruneVolumesResult {
+	removed := append([]string{}, report.VolumesDeleted...)
+	sort.Strings(removed)
+	return PruneVolumesResult{Removed: removed, SpaceReclaimedMB: float64(report.SpaceReclaimed) / (1024 * 1024)}
+}
+
+// pruneCandidateList lists the volumes a prune may consider: managed ones, or all of them.
+func pruneCandidateList(ctx context.Context, cli *client.Client, managedOnly bool) ([]*volume.Volume, error) {
+	if managedOnly {
+		return managedVolumeList(ctx, cli)
+	}
+	list, err := cli.VolumeList(ctx, volume.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return list.Volumes, nil
+}
+
+// managedVolumeList asks the daemon for volumes labelled LabelManaged=true.
+func managedVolumeList(ctx context.Context, cli *client.Client) ([]*volume.Volume, error) {
+	list, err := cli.VolumeList(ctx, volume.ListOptions{Filters: filters.NewArgs(filters.Arg("label", LabelManaged+"=true"))})
+	if err != nil {
+		return nil, err
+	}
+	return list.Volumes, nil
+}
+
+// volumeUsage returns per-volume usage data by name, or nil if the daemon cannot report it.
+func volumeUsage(ctx context.Context, cli *client.Client) map[string]*volume.UsageData {
+	du, err := cli.DiskUsage(ctx, types.DiskUsageOptions{Types: []types.DiskUsageObject{types.VolumeObject}})
+	if err != nil {
+		return nil
+	}
+	usage := make(map[string]*volume.UsageData, len(du.Volumes))
+	for _, v := range du.Volumes {
+		if v != nil && v.UsageData != nil {
+			usage[v.Name] = v.UsageData
+		}
+	}
+	return usage
+}
+
+// managedVolumes converts the managed volumes in vols to VolumeInfo, sorted by name. The label is
+// checked again so a daemon that ignores the filter cannot expose unrelated volumes.
+func managedVolumes(vols []*volume.Volume, usage map[string]*volume.UsageData) []VolumeInfo {
+	out := []VolumeInfo{}
+	for _, v := range vols {
+		if v == nil || v.Labels[LabelManaged] != "true" {
+			continue
+		}
+		info := VolumeInfo{Name: v.Name, Driver: v.Driver, Mountpoint: v.Mountpoint, CreatedAt: v.CreatedAt, Labels: v.Labels}
+		if u, ok := usage[v.Name]; ok && u.Size >= 0 {
+			info.SizeMB = float64(u.Size) / (1024 * 1024)
+		}
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
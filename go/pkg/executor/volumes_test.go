@@ -0,0 +1,61 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/volume"
+)
+
+func TestManagedVolumesFiltersByLabel(t *testing.T) {
+	vols := []*volume.Volume{
+		{Name: "pip-cache", Driver: "local", Mountpoint: "/var/lib/docker/volumes/pip-cache/_data", CreatedAt: "2026-10-01T10:00:00Z", Labels: map[string]string{LabelManaged: "true"}},
+		{Name: "postgres-data", Driver: "local", Labels: map[string]string{"com.example": "db"}},
+		{Name: "adde-old", Labels: map[string]string{LabelManaged: "false"}},
+		{Name: "npm-cache", Labels: map[string]string{LabelManaged: "true"}},
+		nil,
+	}
+	usage := map[string]*volume.UsageData{"pip-cache": {Size: 3 * 1024 * 1024, RefCount: 1}, "npm-cache": {Size: -1}}
+
+	got := managedVolumes(vols, usage)
+	if len(got) != 2 || got[0].Name != "npm-cache" || got[1].Name != "pip-cache" {
+		t.Fatalf("managed volumes = %+v", got)
+	}
+	if got[1].SizeMB != 3 || got[1].Mountpoint == "" || got[1].CreatedAt != "2026-10-01T10:00:00Z" {
+		t.Errorf("pip-cache = %+v", got[1])
+	}
+	if got[0].SizeMB != 0 {
+		t.Errorf("unknown size (-1) should be omitted, got %v", got[0].SizeMB)
+	}
+	if none := managedVolumes(nil, nil); none == nil || len(none) != 0 {
+		t.Errorf("no volumes: got %#v, want empty slice", none)
+	}
+}
+
+func TestVolumePruneFilters(t *testing.T) {
+	args := volumePruneFilters(true, "1.43")
+	if !args.ExactMatch("label", LabelManaged+"=true") || !args.ExactMatch("all", "true") {
+		t.Errorf("managed, API 1.43: %v", args)
+	}
+	args = volumePruneFilters(true, "1.41")
+	if !args.ExactMatch("label", LabelManaged+"=true") || args.Contains("all") {
+		t.Errorf("managed, API 1.41 must not send all: %v", args)
+	}
+	args = volumePruneFilters(false, "1.43")
+	if args.Contains("label") || !args.ExactMatch("all", "true") {
+		t.Errorf("unmanaged: %v", args)
+	}
+}
+
+func TestVolumesPruneResultConvertsToMB(t *testing.T) {
+	res := volumesPruneResult(types.VolumesPruneReport{VolumesDeleted: []string{"pip-cache", "npm-cache"}, SpaceReclaimed: 5 * 1024 * 1024})
+	if res.SpaceReclaimedMB != 5 {
+		t.Errorf("space_reclaimed_mb = %v, want 5", res.SpaceReclaimedMB)
+	}
+	if len(res.Removed) != 2 || res.Removed[0] != "npm-cache" {
+		t.Errorf("removed = %q", res.Removed)
+	}
+	if empty := volumesPruneResult(types.VolumesPruneReport{}); empty.Removed == nil {
+		t.Error("removed should be an empty list, not null")
+	}
+}
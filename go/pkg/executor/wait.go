@@ -0,0 +1,34 @@
+package executor
+
+import (
+	"context"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// WaitContainer blocks until the container's main process exits (e.g. a job_mode container)
+// and returns its exit code. Pair with get_container_logs source "container" to collect output.
+func WaitContainer(ctx context.Context, cli *client.Client, p WaitContainerParams) WaitContainerResult {
+	if p.ContainerID == "" {
+		return WaitContainerResult{ExitCode: -1, Error: "container_id is required"}
+	}
+	timeout := DefaultWaitTimeout
+	if p.TimeoutSec > 0 {
+		timeout = time.Duration(p.TimeoutSec) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	statusCh, errCh := cli.ContainerWait(ctx, p.ContainerID, container.WaitConditionNotRunning)
+	select {
+	case st := <-statusCh:
+		if st.Error != nil && st.Error.Message != "" {
+			return WaitContainerResult{ExitCode: int(st.StatusCode), Error: st.Error.Message}
+		}
+		return WaitContainerResult{ExitCode: int(st.StatusCode)}
+	case err := <-errCh:
+		return WaitContainerResult{ExitCode: -1, Error: err.Error()}
+	}
+}
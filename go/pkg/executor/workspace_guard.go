@@ -0,0 +1,117 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/docker/docker/client"
+)
+
+// ErrCodeWorkspaceLimitExceeded prefixes the error returned when max_workspace_mb trips.
+const ErrCodeWorkspaceLimitExceeded = "WORKSPACE_LIMIT_EXCEEDED"
+
+// workspaceGuardInterval is how often workspace usage is sampled while an exec runs.
+const workspaceGuardInterval = time.Second
+
+// workspaceLimitError reports that the guard killed the exec; ExecuteCodeBlock still returns the partial log.
+type workspaceLimitError struct {
+	limitMB int
+	usedKB  int64
+}
+
+func (e *workspaceLimitError) Error() string {
+	return fmt.Sprintf("%s: workspace grew to %d MB, over the %d MB limit; the program was killed", ErrCodeWorkspaceLimitExceeded, e.usedKB/1024, e.limitMB)
+}
+
+// pidFileCommand wraps cmd so its PID is written to pidFile before it execs; Docker has no
+// exec-kill API, so the guard needs the in-container PID to stop a runaway program.
+func pidFileCommand(cmd []string, pidFile string) []string {
+	return append([]string{"sh", "-c", `echo $$ > "$0"; exec "$@"`, pidFile}, cmd...)
+}
+
+// workspaceGuard samples /workspace usage until stop is closed and kills the PID in pidFile once
+// usage exceeds limitMB. Returns a func that stops the guard, removes pidFile and reports whether it tripped.
+func workspaceGuard(ctx context.Context, cli *client.Client, containerID, pidFile string, limitMB int) func() *workspaceLimitError {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	var tripped atomic.Pointer[workspaceLimitError]
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(workspaceGuardInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			usedKB, err := workspaceUsageKB(ctx, cli, containerID)
+			if err != nil || usedKB <= int64(limitMB)*1024 {
+				continue
+			}
+			tripped.Store(&workspaceLimitError{limitMB: limitMB, usedKB: usedKB})
+			killPIDFile(cli, containerID, pidFile)
+			return
+		}
+	}()
+	return func() *workspaceLimitError {
+		close(stop)
+		<-done
+		removePIDFile(cli, containerID, pidFile)
+		return tripped.Load()
+	}
+}
+
+// killPIDFile sends SIGKILL to the program recorded in pidFile and to its process group, so children
+// such as a script's sleep go too; an exec is normally a group leader, otherwise only the PID is killed.
+// Uses a fresh context since it runs once the run's context is done.
+func killPIDFile(cli *client.Client, containerID, pidFile string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	script := `pid="$(cat "$0" 2>/dev/null)" || exit 0; kill -9 -"$pid" 2>/dev/null || kill -9 "$pid"`
+	_, _, _, _, _ = runExec(ctx, cli, containerID, []string{"sh", "-c", script, pidFile}, 10)
+}
+
+// killOnDone kills the program recorded in pidFile and then calls closeStream once ctx is done:
+// closing the attach stream alone leaves the program running in the container. The returned func
+// disarms it; if the kill already started, it waits for it so the kill lands before pidFile is removed.
+func killOnDone(ctx context.Context, cli *client.Client, containerID, pidFile string, closeStream func()) func() {
+	killed := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(killed)
+		killPIDFile(cli, containerID, pidFile)
+		closeStream()
+	})
+	return func() {
+		if !stop() {
+			<-killed
+		}
+	}
+}
+
+// removePIDFile deletes pidFile once the program is done with it; the wrapper execs the program, so
+// nothing in the container cleans it up. Uses a fresh context since the run's may have expired.
+func removePIDFile(cli *client.Client, containerID, pidFile string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, _, _, _, _ = runExec(ctx, cli, containerID, []string{"rm", "-f", pidFile}, 10)
+}
+
+// workspaceUsageKB returns the size of /workspace in KiB as reported by du.
+func workspaceUsageKB(ctx context.Context, cli *client.Client, containerID string) (int64, error) {
+	stdout, _, _, _, err := runExec(ctx, cli, containerID, []string{"du", "-sk", WorkspacePathInsideContainer}, 10)
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(stdout)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("unexpected du output %q", stdout)
+	}
+	return strconv.ParseInt(fields[0], 10, 64)
+}
@@ -3,42 +3,126 @@ ADDE – Agent-Driven Docker Executor.
 
 Python client for the ADDE Go CLI. Use from agent code to:
 - pull_image: pull an image from the registry (call before create_runtime_env if needed)
+- check_registry_auth: verify registry credentials without pulling
+- image_config: read an image's declared entrypoint, cmd, exposed ports and env
+- image_metadata: report an image's platform, base image, package manager and layer count for scanners
 - create_runtime_env: provision a container with workspace mount and limits
+- get_or_create_env: reuse a running container with the same image/dependencies/env/network, or create one
 - execute_code_block: write code into the container and run it (returns structured log)
+- execute_cells: run several code blocks in sequence in one container (optional stop_on_error)
+- wait_container: wait for a job_mode container to finish and return its exit code
 - get_container_logs: fetch the last execution's stdout/stderr/exit_code/execution_time
+- get_container_env: read the container's configured env vars (optionally redacting secrets)
+- container_diff: list files added, changed and deleted in the container since it was created
+- get_transcript: fetch the output of every execution run with transcript=True, in order
+- start_detached_exec / poll_exec: start a long job in the background, then check on it and collect its output
+- verify_workspace: diagnose the /workspace mount (host dir present/writable, files)
+- check_dependency: check whether a package is installed in the container
+- check_port: check from the host that a published port answers (TCP or HTTP GET)
+- tail_file: read the last lines of a file in the container (e.g. a server's log file)
+- copy_from_container: fetch a file a program wrote in the container (base64 content)
+- pause_container / unpause_container: freeze and resume a container, keeping its memory state
+- stop_container: stop a container (graceful, then SIGKILL) but keep it for logs
+- kill_container: send a signal (default SIGKILL) to the container without removing it
 - cleanup_env: stop and remove the container
 - prepare_build_context: stage files into a temp dir for Docker build (optional Dockerfile)
+- hash_build_context: content hash of a build context, to skip rebuilding unchanged inputs
 - build_image_from_context: run docker build from context; returns image_id for create_runtime_env
 - build_image_from_path: build from an existing directory (e.g. cloned repo) that has a Dockerfile
+- run_from_dockerfile: build from an inline Dockerfile, run one code block, clean everything up
+- list_languages: file extensions execute_code_block runs automatically, and their commands
+- capabilities: the binary's version and the tools and params it supports, for feature detection
 - list_agent_images: list custom images (agent-env:...)
 - prune_build_cache: clean up build cache
+- prune_all: reclaim stopped containers, unused images and build cache (with dry_run)
+- list_volumes / prune_volumes: list and remove unused toolkit-managed volumes (adde.managed=true)
+- gc_workspaces: remove leaked workspace/build temp dirs left by crashed runs
+- batch: run many tool calls in one adde process (NDJSON over stdin), one result per call
 - delete_image: remove a Docker image by tag or ID
 """
 
 from .client import (
+    batch,
     build_image_from_context,
     build_image_from_path,
+    capabilities,
+    check_dependency,
+    check_port,
+    check_registry_auth,
     cleanup_env,
+    container_diff,
+    copy_from_container,
     create_runtime_env,
     delete_image,
+    execute_cells,
     execute_code_block,
+    gc_workspaces,
+    get_container_env,
     get_container_logs,
+    get_or_create_env,
+    get_transcript,
+    hash_build_context,
+    image_config,
+    image_metadata,
+    kill_container,
     list_agent_images,
+    list_languages,
+    list_volumes,
+    pause_container,
+    poll_exec,
     prepare_build_context,
+    prune_all,
+    prune_volumes,
     prune_build_cache,
     pull_image,
+    run_from_dockerfile,
+    start_detached_exec,
+    stop_container,
+    tail_file,
+    unpause_container,
+    verify_workspace,
+    wait_container,
 )
 
 __all__ = [
+    "batch",
     "build_image_from_context",
     "build_image_from_path",
+    "capabilities",
+    "check_dependency",
+    "check_port",
+    "check_registry_auth",
     "cleanup_env",
+    "container_diff",
+    "copy_from_container",
     "create_runtime_env",
     "delete_image",
+    "execute_cells",
     "execute_code_block",
+    "gc_workspaces",
+    "get_container_env",
     "get_container_logs",
+    "get_or_create_env",
+    "get_transcript",
+    "hash_build_context",
+    "image_config",
+    "image_metadata",
+    "kill_container",
     "list_agent_images",
+    "list_languages",
+    "list_volumes",
+    "pause_container",
+    "poll_exec",
     "prepare_build_context",
+    "prune_all",
+    "prune_volumes",
     "prune_build_cache",
     "pull_image",
+    "run_from_dockerfile",
+    "start_detached_exec",
+    "stop_container",
+    "tail_file",
+    "unpause_container",
+    "verify_workspace",
+    "wait_container",
 ]
@@ -38,6 +38,50 @@ def pull_image(
     return _call("pull_image", params, bin_path=bin_path)
 
 
+def check_registry_auth(
+    image: str,
+    bin_path: Optional[str] = None,
+) -> dict[str, Any]:
+    """
+    Verifies the credentials adde finds for the image's registry (ADDE_REGISTRIES_JSON,
+    ADDE_REGISTRY_URL, DOCKER_AUTH_CONFIG, then the Docker client config) by logging in
+    through the daemon, without pulling.
+
+    Returns dict with keys: registry, source (ADDE_REGISTRIES_JSON, ADDE_REGISTRY_URL,
+    DOCKER_AUTH_CONFIG, auths, credsStore, credHelpers or none), authenticated, status,
+    or error. Credentials are never returned.
+    """
+    return _call("check_registry_auth", {"image": image}, bin_path=bin_path)
+
+
+def image_config(
+    image: str,
+    bin_path: Optional[str] = None,
+) -> dict[str, Any]:
+    """
+    Returns the defaults a local image declares, to decide use_image_cmd and
+    port_bindings before create_runtime_env.
+
+    Returns dict with keys: image, image_id, entrypoint, cmd, exposed_ports, env,
+    working_dir, user, or error.
+    """
+    return _call("image_config", {"image": image}, bin_path=bin_path)
+
+
+def image_metadata(
+    image: str,
+    bin_path: Optional[str] = None,
+) -> dict[str, Any]:
+    """
+    Returns facts about a local image to feed an external vulnerability scanner. The package
+    manager is detected by checking for apk/apt-get/dnf/microdnf/yum in a never-started container.
+
+    Returns dict with keys: image, image_id, os, architecture, variant, base_image (from labels),
+    package_manager, exposed_ports, layer_count, or error.
+    """
+    return _call("image_metadata", {"image": image}, bin_path=bin_path)
+
+
 def _call(
     tool: str,
     params: dict,
@@ -58,29 +102,130 @@ def _call(
     return json.loads(out.stdout)
 
 
+def batch(
+    calls: list[dict[str, Any]],
+    bin_path: Optional[str] = None,
+    timeout: int = 3600,
+) -> list[dict[str, Any]]:
+    """
+    Runs several tool calls in one adde process (one Docker client), in order.
+
+    calls: [{"tool": "execute_code_block", "payload": {...}}, ...]
+    Returns one dict per call: {line, tool, result, failed} or {line, error} when the
+    call could not be dispatched. A failing call does not stop the batch.
+    """
+    bin_ = bin_path or _find_adde()
+    stdin = "".join(json.dumps(c) + "\n" for c in calls)
+    out = subprocess.run(
+        [bin_, "batch"],
+        input=stdin,
+        capture_output=True,
+        text=True,
+        timeout=timeout,
+    )
+    results = [json.loads(line) for line in out.stdout.splitlines() if line.strip()]
+    if len(results) != len(calls):
+        err = out.stderr.strip() or f"adde batch returned {len(results)} results for {len(calls)} calls"
+        raise RuntimeError(err)
+    return results
+
+
 def create_runtime_env(
-    image: str,
+    image: Optional[str] = None,
     dependencies: Optional[list[str]] = None,
     env_vars: Optional[dict[str, str]] = None,
     network: bool = False,
     port_bindings: Optional[dict[str, str]] = None,
     use_image_cmd: bool = False,
+    job_mode: bool = False,
+    max_executions: int = 0,
+    ca_certs: Optional[str] = None,
+    idempotency_key: Optional[str] = None,
+    hostname: Optional[str] = None,
+    domainname: Optional[str] = None,
+    init: Optional[bool] = None,
+    name: Optional[str] = None,
+    auto_publish_exposed: bool = False,
+    init_script: Optional[str] = None,
+    init_script_shell: Optional[str] = None,
+    pull_policy: Optional[str] = None,
+    verify_isolation: bool = False,
+    timezone: Optional[str] = None,
+    locale: Optional[str] = None,
+    labels: Optional[dict[str, str]] = None,
     bin_path: Optional[str] = None,
 ) -> dict[str, Any]:
     """
     Provisions a container with workspace at /workspace, 512MB / 0.5 CPU, network=none by default.
 
+    image: the image to run; when omitted, adde uses ADDE_DEFAULT_IMAGE (an error if unset)
+    and the result carries default_image=True.
+
     port_bindings: optional map container_port -> host_port (e.g. {"3000": "8080"}).
     Ports are bound to 127.0.0.1 on the host.
 
+    auto_publish_exposed: if True, every port the image EXPOSEs is bound to an ephemeral
+    127.0.0.1 port (like docker run -P); port_bindings still wins for the ports it lists.
+    The result's published_ports maps each, e.g. {"3000/tcp": "127.0.0.1:49153"}.
+
     use_image_cmd: if True, run the image's default CMD (e.g. node server.js) instead of
     sleep 86400. Use this when the image runs a long-lived server; use False (default) for
     exec-based workflows where you run code via execute_code_block.
 
-    Returns dict with keys: container_id, workspace, or error.
+    job_mode: if True, run the image's CMD once to completion; collect the result with
+    wait_container and get_container_logs(source="container"). Not combinable with
+    dependencies (the job may finish before an install could run); bake them into the image.
+
+    max_executions: if > 0, the execute_code_block run that reaches this count returns its
+    log and then removes the container (the result has recycled=True).
+
+    ca_certs: optional PEM bundle added to the container's system trust store before
+    dependencies are installed (Debian/Ubuntu/Alpine/RHEL-family images).
+
+    idempotency_key: if set, a repeat call with the same key (e.g. a retry after a timeout)
+    returns the existing container with reused=True instead of creating another one.
+
+    hostname / domainname: set the container's hostname (single label, e.g. "worker-1")
+    and domain (e.g. "cluster.local"); Docker's defaults are used when omitted.
+
+    init: run an init process (tini) as PID 1 to reap zombies. None = on for exec-based
+    containers, off with use_image_cmd/job_mode.
+
+    name: container name ([a-zA-Z0-9][a-zA-Z0-9_.-]+); random when omitted. A name
+    already in use is an error. Cannot be combined with idempotency_key.
+
+    init_script: optional shell script run after the dependency install (e.g. create
+    directories, fetch a dataset, configure git), with init_script_shell (default "sh").
+    If it exits non-zero, the container is removed and the error JSON carries init_log.
+    Not supported with job_mode.
+
+    pull_policy: "IfNotPresent" (default: pull only when the image is not local), "Always"
+    (re-pull to pick up tag updates) or "Never" (fail fast when the image is not local);
+    pulled is True in the result when a pull happened.
+
+    verify_isolation: if True, the container gets a private cgroup namespace and, once set
+    up, is checked for a reachable Docker socket, a default route (expected with
+    network=True, still reported) and host namespaces. Findings are "WEAK_ISOLATION: ..."
+    warnings; "ISOLATION_UNVERIFIED: ..." means the check could not run (e.g. no sh in the
+    image). Not supported with job_mode.
+
+    timezone / locale: set TZ (IANA name, e.g. "Europe/Berlin") and LANG/LC_ALL (e.g.
+    "C.UTF-8") in the container; the image defaults apply when omitted. The image must
+    ship the zone data and locale. Setting the same variable in env_vars is an error.
+
+    labels: extra container labels (keys starting with "adde." are reserved). Every
+    container is labelled adde.managed=true, adde.created_at and adde.workspace, so
+    tooling can find the ones adde created (e.g. docker ps --filter label=adde.managed=true).
+
+    Returns dict with keys: container_id, workspace, or error. If a dependency install
+    exits non-zero, the container is removed and the error JSON carries install_log
+    (exit_code, stdout, stderr, execution_time).
+
+    warnings: list of strings, e.g. "ARCH_MISMATCH: ..." when the image targets another
+    CPU architecture than the daemon (execs fail with "exec format error" without QEMU).
     """
     params: dict[str, Any] = {
-        "image": image,
+        "image": image or "",
         "dependencies": dependencies or [],
         "env_vars": env_vars or {},
         "network": network,
@@ -89,33 +234,237 @@ def create_runtime_env(
         params["port_bindings"] = port_bindings
     if use_image_cmd:
         params["use_image_cmd"] = True
+    if job_mode:
+        params["job_mode"] = True
+    if max_executions:
+        params["max_executions"] = max_executions
+    if ca_certs:
+        params["ca_certs"] = ca_certs
+    if idempotency_key:
+        params["idempotency_key"] = idempotency_key
+    if hostname:
+        params["hostname"] = hostname
+    if domainname:
+        params["domainname"] = domainname
+    if init is not None:
+        params["init"] = init
+    if name:
+        params["name"] = name
+    if auto_publish_exposed:
+        params["auto_publish_exposed"] = True
+    if init_script:
+        params["init_script"] = init_script
+    if init_script_shell:
+        params["init_script_shell"] = init_script_shell
+    if pull_policy:
+        params["pull_policy"] = pull_policy
+    if verify_isolation:
+        params["verify_isolation"] = True
+    if timezone:
+        params["timezone"] = timezone
+    if locale:
+        params["locale"] = locale
+    if labels:
+        params["labels"] = labels
     return _call("create_runtime_env", params, bin_path=bin_path)
 
 
+def get_or_create_env(
+    image: Optional[str] = None,
+    dependencies: Optional[list[str]] = None,
+    env_vars: Optional[dict[str, str]] = None,
+    network: bool = False,
+    options: Optional[dict[str, Any]] = None,
+    bin_path: Optional[str] = None,
+) -> dict[str, Any]:
+    """
+    Returns a running container previously created by get_or_create_env with the same image,
+    dependencies (any order), env_vars and network (reused=True), or creates one like
+    create_runtime_env and labels it with the fingerprint for the next call.
+
+    options: further create_runtime_env fields (e.g. {"port_bindings": {...}, "pull_policy":
+    "Always"}); they apply only when a container is created and are not part of the
+    fingerprint. name and idempotency_key are rejected.
+
+    Returns the create_runtime_env result plus fingerprint, or error.
+    """
+    params: dict[str, Any] = dict(options or {})
+    params.update(
+        {
+            "image": image or "",
+            "dependencies": dependencies or [],
+            "env_vars": env_vars or {},
+            "network": network,
+        }
+    )
+    return _call("get_or_create_env", params, bin_path=bin_path)
+
+
 def execute_code_block(
     container_id: str,
     filename: str,
     code_content: str,
     timeout_sec: int = 30,
+    tty: bool = False,
+    capture_artifacts: Optional[list[str]] = None,
+    stdin: Optional[str] = None,
+    stdin_file: Optional[str] = None,
+    interpreter: Optional[str] = None,
+    stdout_encoding: Optional[str] = None,
+    pids_limit: int = 0,
+    transcript: bool = False,
+    working_dir: Optional[str] = None,
+    create_workspace: bool = False,
+    clean_workspace: bool = False,
+    clean_artifacts: bool = False,
+    fallback_shell: Optional[str] = None,
+    profile: bool = False,
+    coverage: bool = False,
+    coverage_tool: Optional[str] = None,
+    result_format: Optional[str] = None,
     bin_path: Optional[str] = None,
 ) -> dict[str, Any]:
     """
     Writes code into the container and runs it. Uses put_archive (no shell on code_content).
 
+    tty: run under a pseudo-TTY for programs that need a terminal; stdout and stderr
+    are then merged into log["stdout"].
+
+    capture_artifacts: glob patterns (e.g. ["*.png", "out/**"]); matching workspace files
+    are returned as artifacts: [{path, size_bytes}].
+
+    stdin: text fed to the program's stdin. stdin_file: path to a host file streamed to
+    stdin instead (for large inputs); the two are mutually exclusive.
+
+    interpreter: override the binary picked from the extension (e.g. "python3.11" or an
+    absolute path such as "/usr/local/bin/node20"); arguments stay the same.
+
+    stdout_encoding: "base64" returns stdout/stderr base64-encoded (log["encoding"] ==
+    "base64") so binary output survives intact; default "utf8".
+
+    pids_limit: if > 0, cap the container's process count while this run executes (fork-bomb
+    guard); the previous limit is restored afterwards. Counts the container's idle processes too.
+
+    transcript: if True, also append this run's output to the container's transcript
+    (/var/adde/transcript.log), read back across runs with get_transcript.
+
+    working_dir: absolute directory (default /workspace, created if missing) the file is
+    placed in and run from, so relative paths in the code resolve against it.
+
+    create_workspace: create /workspace if it is missing, for containers not created by
+    create_runtime_env (e.g. started by docker compose).
+
+    clean_workspace: before copying the file, remove the code files earlier runs copied into
+    the container, so a stale helper module cannot be imported. Files the programs created are
+    kept unless clean_artifacts=True, which clears the rest of the working dir as well.
+
+    fallback_shell: shell that runs files with no known extension as a script (default "sh"),
+    e.g. "bash" for images without sh; a missing shell is reported as an error.
+
+    profile: run .py files under cProfile and .js/.mjs files under node --prof and return
+    the summary as log["profile"] (needs the cProfile/pstats modules or a node with
+    --prof-process; other file types are rejected).
+
+    coverage: run a .py file under coverage.py (5.0+ must be installed in the image, e.g.
+    dependencies=["coverage"]) and return coverage {percent, covered_lines, num_statements,
+    files}; coverage_tool names the coverage executable when it is not "python -m coverage".
+
+    result_format: "junit" runs the file as a test suite (.py with pytest, .js with jest and
+    jest-junit, which must be installed) and returns test_report {runner, tests, passed,
+    failed, errors, skipped, failed_tests, xml}; log["exit_code"] is the runner's.
+
     Returns dict with keys: log (exit_code, stdout, stderr, execution_time), or error.
     """
-    params = {
+    params: dict[str, Any] = {
         "container_id": container_id,
         "filename": filename,
         "code_content": code_content,
         "timeout_sec": timeout_sec,
     }
+    if tty:
+        params["tty"] = True
+    if capture_artifacts:
+        params["capture_artifacts"] = capture_artifacts
+    if stdin is not None:
+        params["stdin"] = stdin
+    if stdin_file:
+        params["stdin_file"] = stdin_file
+    if interpreter:
+        params["interpreter"] = interpreter
+    if stdout_encoding:
+        params["stdout_encoding"] = stdout_encoding
+    if pids_limit > 0:
+        params["pids_limit"] = pids_limit
+    if transcript:
+        params["transcript"] = True
+    if working_dir:
+        params["working_dir"] = working_dir
+    if create_workspace:
+        params["create_workspace"] = True
+    if clean_workspace:
+        params["clean_workspace"] = True
+    if clean_artifacts:
+        params["clean_artifacts"] = True
+    if fallback_shell:
+        params["fallback_shell"] = fallback_shell
+    if profile:
+        params["profile"] = True
+    if coverage:
+        params["coverage"] = True
+    if coverage_tool:
+        params["coverage_tool"] = coverage_tool
+    if result_format:
+        params["result_format"] = result_format
     return _call("execute_code_block", params, bin_path=bin_path)
 
 
+def execute_cells(
+    container_id: str,
+    cells: list[dict[str, Any]],
+    stop_on_error: bool = False,
+    transcript: bool = False,
+    working_dir: Optional[str] = None,
+    bin_path: Optional[str] = None,
+) -> dict[str, Any]:
+    """
+    Runs notebook-style cells in order in the same container.
+
+    cells: [{"filename": ..., "code_content": ..., "timeout_sec": ...}, ...]
+    stop_on_error: stop after the first cell that exits non-zero (stopped=True).
+    transcript: append each cell's output to the transcript (see get_transcript).
+    working_dir: where the cells are placed and run (default /workspace).
+
+    Returns dict with logs (one per cell that ran), or error.
+    """
+    params: dict[str, Any] = {"container_id": container_id, "cells": cells}
+    if stop_on_error:
+        params["stop_on_error"] = True
+    if transcript:
+        params["transcript"] = True
+    if working_dir:
+        params["working_dir"] = working_dir
+    timeout = 120 + sum(c.get("timeout_sec", 30) for c in cells)
+    return _call("execute_cells", params, bin_path=bin_path, timeout=timeout)
+
+
+def wait_container(
+    container_id: str,
+    timeout_sec: int = 300,
+    bin_path: Optional[str] = None,
+) -> dict[str, Any]:
+    """
+    Waits for the container's main process to exit (e.g. a job_mode container).
+
+    Returns dict with exit_code, or error.
+    """
+    params = {"container_id": container_id, "timeout_sec": timeout_sec}
+    return _call("wait_container", params, bin_path=bin_path, timeout=timeout_sec + 30)
+
+
 def get_container_logs(
     container_id: str,
     tail_lines: int = 0,
+    source: Optional[str] = None,
     bin_path: Optional[str] = None,
 ) -> dict[str, Any]:
     """
@@ -123,11 +472,217 @@ def get_container_logs(
 
     Keys: log (exit_code, stdout, stderr, execution_time), or error.
     tail_lines: 0 = all; otherwise last N lines of stdout/stderr.
+    source: "last_run" (default) or "container" for the main process output (docker logs).
     """
-    params = {"container_id": container_id, "tail_lines": tail_lines}
+    params: dict[str, Any] = {"container_id": container_id, "tail_lines": tail_lines}
+    if source is not None:
+        params["source"] = source
     return _call("get_container_logs", params, bin_path=bin_path)
 
 
+def get_container_env(
+    container_id: str,
+    redact: bool = False,
+    bin_path: Optional[str] = None,
+) -> dict[str, Any]:
+    """
+    Returns the env the container was created with (image ENV plus env_vars) as env {name: value}.
+
+    redact: mask values of secret-looking names (*_TOKEN, *_PASSWORD, *_SECRET, *_KEY) as
+    "[REDACTED]"; the masked names are listed in redacted.
+    """
+    params: dict[str, Any] = {"container_id": container_id}
+    if redact:
+        params["redact"] = True
+    return _call("get_container_env", params, bin_path=bin_path)
+
+
+def container_diff(container_id: str, bin_path: Optional[str] = None) -> dict[str, Any]:
+    """
+    Lists the paths added, changed and deleted in the container's filesystem since it was
+    created (docker diff), as added / changed / deleted, each sorted. /workspace is a bind
+    mount and is not included.
+    """
+    return _call("container_diff", {"container_id": container_id}, bin_path=bin_path)
+
+
+def get_transcript(
+    container_id: str,
+    tail_lines: int = 0,
+    bin_path: Optional[str] = None,
+) -> dict[str, Any]:
+    """
+    Returns the chronological output of every execute_code_block run with transcript=True.
+
+    Keys: transcript (each run under a "=== <time> <file> exit_code=N (<duration>) ===" header),
+    or error. tail_lines: 0 = all; otherwise the last N lines.
+    """
+    params: dict[str, Any] = {"container_id": container_id}
+    if tail_lines > 0:
+        params["tail_lines"] = tail_lines
+    return _call("get_transcript", params, bin_path=bin_path)
+
+
+def start_detached_exec(
+    container_id: str,
+    command: list[str],
+    bin_path: Optional[str] = None,
+) -> dict[str, Any]:
+    """
+    Starts command in the container without waiting for it, for jobs longer than the exec timeout.
+
+    Returns exec_id and output_path (the file stdout and stderr go to), or error.
+    Collect the result with poll_exec.
+    """
+    return _call("start_detached_exec", {"container_id": container_id, "command": command}, bin_path=bin_path)
+
+
+def poll_exec(container_id: str, exec_id: str, bin_path: Optional[str] = None) -> dict[str, Any]:
+    """
+    Checks on a start_detached_exec job.
+
+    Returns status ("running" or "finished"), exit_code once finished, output so far
+    (the last 1 MiB, with output_truncated), output_path, or error.
+    """
+    return _call("poll_exec", {"container_id": container_id, "exec_id": exec_id}, bin_path=bin_path)
+
+
+def verify_workspace(container_id: str, bin_path: Optional[str] = None) -> dict[str, Any]:
+    """
+    Diagnoses the container's /workspace mount before executing code.
+
+    Returns ok, mount_present, mount_writable, host_path, host_dir_exists,
+    host_dir_writable, files (as seen in the container) and problems[], or error.
+    """
+    return _call("verify_workspace", {"container_id": container_id}, bin_path=bin_path)
+
+
+def check_dependency(
+    container_id: str,
+    language: str,
+    package: str,
+    bin_path: Optional[str] = None,
+) -> dict[str, Any]:
+    """
+    Checks whether a package is present in the container, without installing it.
+
+    language: "python" (pip show), "node" (npm ls -g) or "system" (which, for binaries).
+    Returns dict with installed (bool) and version (when known), or error.
+    """
+    params = {"container_id": container_id, "language": language, "package": package}
+    return _call("check_dependency", params, bin_path=bin_path)
+
+
+def check_port(
+    container_id: str,
+    container_port: str,
+    path: Optional[str] = None,
+    timeout_sec: int = 0,
+    bin_path: Optional[str] = None,
+) -> dict[str, Any]:
+    """
+    Checks from the host that a published container port (e.g. "3000") accepts connections:
+    a TCP connect, or an HTTP GET of path (e.g. "/health") when given.
+
+    Returns dict with reachable, host_address (e.g. "127.0.0.1:8080"), status_code (with
+    path; redirects are not followed), latency_ms, and reason when not reachable. error is
+    set when the container or a published binding for the port cannot be found.
+    timeout_sec: connect / request timeout (default 5).
+    """
+    params: dict[str, Any] = {"container_id": container_id, "container_port": container_port}
+    if path:
+        params["path"] = path
+    if timeout_sec > 0:
+        params["timeout_sec"] = timeout_sec
+    return _call("check_port", params, bin_path=bin_path)
+
+
+def tail_file(
+    container_id: str,
+    path: str,
+    lines: int = 10,
+    bin_path: Optional[str] = None,
+) -> dict[str, Any]:
+    """
+    Returns the last lines of a file inside the container (tail -n), e.g. the log file of
+    a use_image_cmd server. Relative paths resolve against /workspace; lines is at most 10000.
+
+    Returns dict with content, or error. To follow the file, use adde serve's /tail_file.
+    """
+    params = {"container_id": container_id, "path": path, "lines": lines}
+    return _call("tail_file", params, bin_path=bin_path)
+
+
+def copy_from_container(
+    container_id: str,
+    path: str,
+    as_tar: bool = False,
+    gzip: bool = False,
+    dest_dir: Optional[str] = None,
+    bin_path: Optional[str] = None,
+) -> dict[str, Any]:
+    """
+    Returns one file from the container, e.g. a plot or CSV a program run by
+    execute_code_block wrote. Relative paths resolve against /workspace; at most 64 MiB.
+    as_tar: return the path (a directory too) as one tar archive, with its entries in manifest.
+    gzip: with as_tar, gzip the archive.
+    dest_dir: instead extract the path (a directory too) into this host directory; entries
+    that would land outside it are refused.
+
+    Returns dict with content (base64; decode with base64.b64decode) and size in bytes,
+    plus manifest with as_tar, or extracted (paths written) with dest_dir, or error (also
+    for a directory without as_tar or dest_dir, or a missing file).
+    """
+    params: dict[str, Any] = {"container_id": container_id, "path": path}
+    if as_tar:
+        params["as_tar"] = True
+    if gzip:
+        params["gzip"] = True
+    if dest_dir:
+        params["dest_dir"] = dest_dir
+    return _call("copy_from_container", params, bin_path=bin_path)
+
+
+def pause_container(container_id: str, bin_path: Optional[str] = None) -> dict[str, Any]:
+    """
+    Freezes all processes in the container without stopping it; memory state is kept.
+    Use to halt a runaway process before deciding whether to kill it. Returns {ok} or error.
+    """
+    return _call("pause_container", {"container_id": container_id}, bin_path=bin_path)
+
+
+def unpause_container(container_id: str, bin_path: Optional[str] = None) -> dict[str, Any]:
+    """Resumes a container frozen by pause_container. Returns {ok} or error."""
+    return _call("unpause_container", {"container_id": container_id}, bin_path=bin_path)
+
+
+def stop_container(container_id: str, timeout_sec: int = 0, bin_path: Optional[str] = None) -> dict[str, Any]:
+    """
+    Stops the container (SIGTERM, then SIGKILL after timeout_sec, default 5) without removing
+    it, so get_container_logs still works afterwards. Stopping a stopped container is not an
+    error. Returns {ok} or error.
+    """
+    params: dict[str, Any] = {"container_id": container_id}
+    if timeout_sec > 0:
+        params["timeout_sec"] = timeout_sec
+    return _call("stop_container", params, bin_path=bin_path)
+
+
+def kill_container(
+    container_id: str,
+    signal: Optional[str] = None,
+    bin_path: Optional[str] = None,
+) -> dict[str, Any]:
+    """
+    Sends a signal to the container's main process without removing the container.
+    signal: e.g. "SIGUSR1", "TERM" or "9"; default SIGKILL. Returns {ok, signal} or error.
+    """
+    params: dict[str, Any] = {"container_id": container_id}
+    if signal:
+        params["signal"] = signal
+    return _call("kill_container", params, bin_path=bin_path)
+
+
 def cleanup_env(
     container_id: str,
     bin_path: Optional[str] = None,
@@ -143,35 +698,96 @@ def cleanup_env(
 def prepare_build_context(
     files: dict[str, str],
     context_id: Optional[str] = None,
+    extra_dockerignore: Optional[list[str]] = None,
+    python_base_image: Optional[str] = None,
+    node_base_image: Optional[str] = None,
     bin_path: Optional[str] = None,
 ) -> dict[str, Any]:
     """
     Stages files (source code, configs, requirements) into a temporary directory for Docker build.
     Auto-generates .dockerignore if missing; injects a standard Dockerfile if requirements.txt
-    or package.json exists but no Dockerfile is provided.
+    or package.json exists but no Dockerfile is provided. extra_dockerignore patterns (e.g. "dist/")
+    are appended to the default .dockerignore, or to the one in files.
+
+    python_base_image / node_base_image: FROM image of the injected Dockerfile (default
+    python:3-slim, a glibc image where pip finds prebuilt wheels, and node:20-alpine).
 
-    Returns dict with context_id (absolute path to build context dir), or error.
+    Returns dict with context_id (absolute path to build context dir) and content_hash, or error.
     """
     params: dict[str, Any] = {"files": files}
     if context_id is not None:
         params["context_id"] = context_id
+    if extra_dockerignore:
+        params["extra_dockerignore"] = extra_dockerignore
+    if python_base_image:
+        params["python_base_image"] = python_base_image
+    if node_base_image:
+        params["node_base_image"] = node_base_image
     return _call("prepare_build_context", params, bin_path=bin_path)
 
 
+def hash_build_context(context_id: str, bin_path: Optional[str] = None) -> dict[str, Any]:
+    """
+    Hashes a build context directory: sha256 over the sorted file paths and contents, excluding
+    files matched by .dockerignore. Identical files give an identical hash, so a matching
+    agent-env: image can be reused instead of rebuilt.
+
+    Returns dict with content_hash ("sha256:...") and files (count), or error.
+    """
+    return _call("hash_build_context", {"context_id": context_id}, bin_path=bin_path)
+
+
 def build_image_from_context(
     context_id: str,
-    tag: str,
+    tag: str = "",
     build_args: Optional[dict[str, str]] = None,
+    compress: bool = False,
+    strict_args: bool = False,
+    task_id: Optional[str] = None,
+    cache_from: Optional[list[str]] = None,
+    pull_cache_from: bool = False,
+    hash_tag: bool = False,
+    labels: Optional[dict[str, str]] = None,
     bin_path: Optional[str] = None,
 ) -> dict[str, Any]:
     """
     Runs docker build from the context directory (path from prepare_build_context).
     Tag convention: agent-env:{task_id}-{timestamp}. Returns handshake:
     { status, image_id, tag, size_mb, build_log_summary } or error/failed_layer.
+
+    compress: gzip the build context before sending it (helps with a remote DOCKER_HOST).
+
+    The result lists consumed_build_args, ignored_build_args and build_arg_warnings
+    (ARGs without a default that were not passed); strict_args=True turns those
+    warnings into an error before the build starts.
+
+    The image is labelled adde.context_id (and adde.task_id when task_id is set), which
+    containers created from it by create_runtime_env inherit, and a per-build adde.build_id.
+    labels: extra image labels (keys starting with "adde." are reserved).
+
+    cache_from: images whose layers may be reused as build cache; pull_cache_from=True
+    pulls any that are not present locally first (the classic builder needs them local).
+
+    hash_tag: with an empty tag, tag the image agent-env:<content hash prefix> (build_args
+    included) instead of a timestamp, so identical contexts map to the same tag.
     """
     params: dict[str, Any] = {"context_id": context_id, "tag": tag}
     if build_args:
         params["build_args"] = build_args
+    if compress:
+        params["compress"] = True
+    if strict_args:
+        params["strict_args"] = True
+    if task_id:
+        params["task_id"] = task_id
+    if cache_from:
+        params["cache_from"] = cache_from
+    if pull_cache_from:
+        params["pull_cache_from"] = True
+    if hash_tag:
+        params["hash_tag"] = True
+    if labels:
+        params["labels"] = labels
     return _call(
         "build_image_from_context", params, bin_path=bin_path, timeout=600
     )
@@ -181,6 +797,8 @@ def build_image_from_path(
     path: str,
     tag: str,
     build_args: Optional[dict[str, str]] = None,
+    task_id: Optional[str] = None,
+    labels: Optional[dict[str, str]] = None,
     bin_path: Optional[str] = None,
 ) -> dict[str, Any]:
     """
@@ -192,28 +810,85 @@ def build_image_from_path(
 
     path: absolute or relative path to the project directory
     tag: e.g. agent-env:myapp-1 (agent-env: prefix is added if missing)
+    task_id: recorded as the adde.task_id image label, inherited by its containers
+    labels: extra image labels ("adde." keys are reserved); the image is always labelled adde.build_id
 
     Returns: { status, image_id, tag, size_mb, build_log_summary } or error.
     """
     params: dict[str, Any] = {"path": path, "tag": tag}
     if build_args:
         params["build_args"] = build_args
+    if task_id:
+        params["task_id"] = task_id
+    if labels:
+        params["labels"] = labels
     return _call(
         "build_image_from_path", params, bin_path=bin_path, timeout=600
     )
 
 
+def run_from_dockerfile(
+    dockerfile: str,
+    filename: str,
+    code_content: str,
+    files: Optional[dict[str, str]] = None,
+    tag: Optional[str] = None,
+    timeout_sec: int = 30,
+    bin_path: Optional[str] = None,
+) -> dict[str, Any]:
+    """
+    Builds an image from dockerfile (plus optional context files), runs code_content in a
+    container from it, then removes the container, image and build context.
+
+    Returns dict with build (build_image_from_context result) and log, or error.
+    """
+    params: dict[str, Any] = {
+        "dockerfile": dockerfile,
+        "filename": filename,
+        "code_content": code_content,
+        "timeout_sec": timeout_sec,
+    }
+    if files:
+        params["files"] = files
+    if tag:
+        params["tag"] = tag
+    return _call("run_from_dockerfile", params, bin_path=bin_path, timeout=timeout_sec + 600)
+
+
+def list_languages(bin_path: Optional[str] = None) -> dict[str, Any]:
+    """
+    Returns the file extensions execute_code_block dispatches automatically.
+
+    Keys: languages [{extension, language, command}], fallback (used for other extensions).
+    """
+    return _call("list_languages", {}, bin_path=bin_path)
+
+
+def capabilities(bin_path: Optional[str] = None) -> dict[str, Any]:
+    """
+    Returns what the installed adde binary supports, for feature detection across versions:
+    version, and tools [{name, params}] where params are the payload fields the tool reads
+    (any other field would be silently ignored).
+    """
+    return _call("capabilities", {}, bin_path=bin_path)
+
+
 def list_agent_images(
     filter_tag: Optional[str] = None,
+    match_label: bool = False,
     bin_path: Optional[str] = None,
 ) -> dict[str, Any]:
     """
     Returns a list of custom images created by the agent (tagged agent-env:...).
     filter_tag: optional prefix filter (e.g. 'agent-env' or 'agent-env:task-123').
+    match_label: also return the images adde built (the oldest image per adde.build_id
+    label) whatever their tags, e.g. after a retag; cannot be combined with filter_tag.
     """
     params: dict[str, Any] = {}
     if filter_tag is not None:
         params["filter_tag"] = filter_tag
+    if match_label:
+        params["match_label"] = True
     return _call("list_agent_images", params, bin_path=bin_path)
 
 
@@ -232,6 +907,74 @@ def prune_build_cache(
     return _call("prune_build_cache", params, bin_path=bin_path)
 
 
+def prune_all(
+    include_unmanaged: bool = False,
+    older_than_hrs: int = 0,
+    dry_run: bool = False,
+    bin_path: Optional[str] = None,
+) -> dict[str, Any]:
+    """
+    Reclaims stopped containers and unused images (and build cache with include_unmanaged) in one call.
+    include_unmanaged: also remove stopped containers and dangling images adde did not
+    create, and the host-wide build cache (default False: only containers and images adde
+    created: containers labelled adde.managed=true and adde.workspace, images tagged agent-env:).
+    dry_run: report what would be removed without removing anything.
+    Images that also carry a non-agent-env: tag keep it and only lose their agent-env: tags
+    (listed in untagged).
+    Returns containers, images, *_reclaimed_mb and total_reclaimed_mb; failed lists removals
+    the daemon refused (error is then set too).
+    """
+    params: dict[str, Any] = {}
+    if include_unmanaged:
+        params["include_unmanaged"] = True
+    if older_than_hrs > 0:
+        params["older_than_hrs"] = older_than_hrs
+    if dry_run:
+        params["dry_run"] = True
+    return _call("prune_all", params, bin_path=bin_path, timeout=600)
+
+
+def list_volumes(bin_path: Optional[str] = None) -> dict[str, Any]:
+    """
+    Lists toolkit-managed volumes (label adde.managed=true).
+    Returns volumes [{name, driver, mountpoint, created_at, size_mb, labels}], or error.
+    """
+    return _call("list_volumes", {}, bin_path=bin_path)
+
+
+def prune_volumes(
+    managed_only: bool = True,
+    dry_run: bool = False,
+    bin_path: Optional[str] = None,
+) -> dict[str, Any]:
+    """
+    Removes volumes no container uses (daemon volume prune).
+    managed_only: only volumes labelled adde.managed=true (default True); False prunes
+    every unused volume on the host. Volumes still mounted are kept and listed in in_use.
+    Returns removed, in_use and space_reclaimed_mb, or error.
+    """
+    params: dict[str, Any] = {"managed_only": managed_only}
+    if dry_run:
+        params["dry_run"] = True
+    return _call("prune_volumes", params, bin_path=bin_path, timeout=600)
+
+
+def gc_workspaces(
+    older_than_hrs: int = 24,
+    dry_run: bool = False,
+    bin_path: Optional[str] = None,
+) -> dict[str, Any]:
+    """
+    Removes leaked adde-workspace-* / adde-build-* temp dirs left by crashed runs.
+    Dirs newer than older_than_hrs or still mounted by a container are kept.
+    Returns removed (paths), count and reclaimed_mb, or error.
+    """
+    params: dict[str, Any] = {"older_than_hrs": older_than_hrs}
+    if dry_run:
+        params["dry_run"] = True
+    return _call("gc_workspaces", params, bin_path=bin_path, timeout=600)
+
+
 def delete_image(
     image: str,
     force: bool = False,
@@ -5,6 +5,7 @@ Unit tests mock subprocess; integration tests call the real adde binary when
 available (set ADDE_BIN or have go/adde.exe in repo).
 """
 
+import base64
 import json
 import os
 from pathlib import Path
@@ -15,17 +16,46 @@ import pytest
 from adde.client import (
     _call,
     _find_adde,
+    batch,
     build_image_from_context,
     build_image_from_path,
+    capabilities,
+    check_dependency,
+    check_port,
+    check_registry_auth,
     cleanup_env,
+    container_diff,
+    copy_from_container,
     create_runtime_env,
     delete_image,
+    execute_cells,
     execute_code_block,
+    gc_workspaces,
+    get_container_env,
     get_container_logs,
+    get_or_create_env,
+    get_transcript,
+    hash_build_context,
+    image_config,
+    image_metadata,
+    kill_container,
     list_agent_images,
+    list_languages,
+    list_volumes,
+    pause_container,
+    poll_exec,
     prepare_build_context,
+    prune_all,
+    prune_volumes,
     prune_build_cache,
     pull_image,
+    run_from_dockerfile,
+    start_detached_exec,
+    stop_container,
+    tail_file,
+    unpause_container,
+    verify_workspace,
+    wait_container,
 )
 
 
@@ -83,6 +113,61 @@ def test_pull_image_params(mock_subprocess_run):
     assert call_args == {"image": "busybox"}
 
 
+def test_check_registry_auth_params(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0, stdout='{"registry":"registry.example.com","source":"auths","authenticated":true}', stderr=""
+    )
+    out = check_registry_auth("registry.example.com/team/app:1.0", bin_path="/fake/adde")
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "check_registry_auth"
+    assert json.loads(args[2]) == {"image": "registry.example.com/team/app:1.0"}
+    assert out["authenticated"] is True
+
+
+def test_get_container_env_params(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0, stdout='{"env":{"API_KEY":"[REDACTED]","MODE":"dev"},"redacted":["API_KEY"]}', stderr=""
+    )
+    out = get_container_env("cid", redact=True, bin_path="/fake/adde")
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "get_container_env"
+    assert json.loads(args[2]) == {"container_id": "cid", "redact": True}
+    assert out["redacted"] == ["API_KEY"]
+
+
+def test_container_diff_params(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0, stdout='{"added":["/tmp/out.csv"],"changed":["/tmp"],"deleted":[]}', stderr=""
+    )
+    out = container_diff("cid", bin_path="/fake/adde")
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "container_diff"
+    assert json.loads(args[2]) == {"container_id": "cid"}
+    assert out["added"] == ["/tmp/out.csv"]
+
+
+def test_image_config_params(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0, stdout='{"image":"docker.io/library/node:20-alpine","cmd":["node"],"exposed_ports":["3000/tcp"]}', stderr=""
+    )
+    out = image_config("node:20-alpine", bin_path="/fake/adde")
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "image_config"
+    assert json.loads(args[2]) == {"image": "node:20-alpine"}
+    assert out["exposed_ports"] == ["3000/tcp"]
+
+
+def test_image_metadata_params(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0, stdout='{"image":"docker.io/library/python:3.12-slim","package_manager":"apt","layer_count":4}', stderr=""
+    )
+    out = image_metadata("python:3.12-slim", bin_path="/fake/adde")
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "image_metadata"
+    assert json.loads(args[2]) == {"image": "python:3.12-slim"}
+    assert out["package_manager"] == "apt"
+
+
 def test_create_runtime_env_params(mock_subprocess_run):
     mock_subprocess_run.return_value = MagicMock(
         returncode=0,
@@ -94,6 +179,7 @@ def test_create_runtime_env_params(mock_subprocess_run):
         dependencies=["requests"],
         env_vars={"X": "1"},
         network=True,
+        name="agent-task-1",
         bin_path="/fake/adde",
     )
     call_args = json.loads(mock_subprocess_run.call_args[0][0][2])
@@ -101,6 +187,89 @@ def test_create_runtime_env_params(mock_subprocess_run):
     assert call_args["dependencies"] == ["requests"]
     assert call_args["env_vars"] == {"X": "1"}
     assert call_args["network"] is True
+    assert call_args["name"] == "agent-task-1"
+
+
+def test_create_runtime_env_default_image(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0,
+        stdout='{"container_id":"abc","image":"docker.io/library/python:3.11-slim","default_image":true}',
+        stderr="",
+    )
+    out = create_runtime_env(bin_path="/fake/adde")
+    call_args = json.loads(mock_subprocess_run.call_args[0][0][2])
+    assert call_args["image"] == ""
+    assert out["default_image"] is True
+
+
+def test_create_runtime_env_init_script(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(returncode=0, stdout='{"container_id":"abc"}', stderr="")
+    out = create_runtime_env(
+        image="python:3.11-slim", init_script="mkdir /data", init_script_shell="bash", bin_path="/fake/adde"
+    )
+    call_args = json.loads(mock_subprocess_run.call_args[0][0][2])
+    assert call_args["init_script"] == "mkdir /data"
+    assert call_args["init_script_shell"] == "bash"
+    assert out["container_id"] == "abc"
+
+
+def test_create_runtime_env_pull_policy(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(returncode=0, stdout='{"container_id":"abc","pulled":true}', stderr="")
+    out = create_runtime_env(image="python:3.11-slim", pull_policy="Always", bin_path="/fake/adde")
+    assert json.loads(mock_subprocess_run.call_args[0][0][2])["pull_policy"] == "Always"
+    assert out["pulled"] is True
+    create_runtime_env(image="python:3.11-slim", bin_path="/fake/adde")
+    assert "pull_policy" not in json.loads(mock_subprocess_run.call_args[0][0][2])
+
+
+def test_create_runtime_env_verify_isolation(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0,
+        stdout='{"container_id":"abc","warnings":["WEAK_ISOLATION: network is enabled; code can reach the host and other networks via eth0"]}',
+        stderr="",
+    )
+    out = create_runtime_env(image="python:3.11-slim", network=True, verify_isolation=True, bin_path="/fake/adde")
+    assert json.loads(mock_subprocess_run.call_args[0][0][2])["verify_isolation"] is True
+    assert out["warnings"][0].startswith("WEAK_ISOLATION:")
+
+
+def test_create_runtime_env_timezone_locale(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(returncode=0, stdout='{"container_id":"abc"}', stderr="")
+    create_runtime_env(image="python:3.11-slim", timezone="Asia/Tokyo", locale="C.UTF-8", bin_path="/fake/adde")
+    params = json.loads(mock_subprocess_run.call_args[0][0][2])
+    assert params["timezone"] == "Asia/Tokyo"
+    assert params["locale"] == "C.UTF-8"
+
+
+def test_create_runtime_env_labels(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(returncode=0, stdout='{"container_id":"abc"}', stderr="")
+    create_runtime_env(image="python:3.11-slim", labels={"team": "ml"}, bin_path="/fake/adde")
+    params = json.loads(mock_subprocess_run.call_args[0][0][2])
+    assert params["labels"] == {"team": "ml"}
+    create_runtime_env(image="python:3.11-slim", bin_path="/fake/adde")
+    assert "labels" not in json.loads(mock_subprocess_run.call_args[0][0][2])
+
+
+def test_get_or_create_env_params(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0, stdout='{"container_id":"abc","reused":true,"fingerprint":"f00"}', stderr=""
+    )
+    out = get_or_create_env(
+        image="python:3.11-slim",
+        dependencies=["requests"],
+        options={"port_bindings": {"3000": "8080"}},
+        bin_path="/fake/adde",
+    )
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "get_or_create_env"
+    assert json.loads(args[2]) == {
+        "image": "python:3.11-slim",
+        "dependencies": ["requests"],
+        "env_vars": {},
+        "network": False,
+        "port_bindings": {"3000": "8080"},
+    }
+    assert out["reused"] is True
 
 
 def test_create_runtime_env_port_bindings(mock_subprocess_run):
@@ -118,6 +287,21 @@ def test_create_runtime_env_port_bindings(mock_subprocess_run):
     call_args = json.loads(mock_subprocess_run.call_args[0][0][2])
     assert call_args["port_bindings"] == {"3000": "8080"}
     assert call_args["network"] is True
+    assert "auto_publish_exposed" not in call_args
+
+
+def test_create_runtime_env_auto_publish_exposed(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0,
+        stdout='{"container_id":"abc","published_ports":{"3000/tcp":"127.0.0.1:49153"}}',
+        stderr="",
+    )
+    out = create_runtime_env(
+        image="node:20-alpine", use_image_cmd=True, network=True, auto_publish_exposed=True, bin_path="/fake/adde"
+    )
+    call_args = json.loads(mock_subprocess_run.call_args[0][0][2])
+    assert call_args["auto_publish_exposed"] is True
+    assert out["published_ports"] == {"3000/tcp": "127.0.0.1:49153"}
 
 
 def test_execute_code_block_params(mock_subprocess_run):
@@ -131,6 +315,7 @@ def test_execute_code_block_params(mock_subprocess_run):
         filename="t.py",
         code_content="print(42)",
         timeout_sec=15,
+        pids_limit=32,
         bin_path="/fake/adde",
     )
     call_args = json.loads(mock_subprocess_run.call_args[0][0][2])
@@ -138,6 +323,7 @@ def test_execute_code_block_params(mock_subprocess_run):
     assert call_args["filename"] == "t.py"
     assert call_args["code_content"] == "print(42)"
     assert call_args["timeout_sec"] == 15
+    assert call_args["pids_limit"] == 32
 
 
 def test_get_container_logs_params(mock_subprocess_run):
@@ -152,6 +338,263 @@ def test_get_container_logs_params(mock_subprocess_run):
     assert call_args["tail_lines"] == 10
 
 
+def test_execute_code_block_working_dir(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(returncode=0, stdout='{"log":{"exit_code":0}}', stderr="")
+    execute_code_block("cid", "main.py", "print(open('config.json').read())", working_dir="/app", bin_path="/fake/adde")
+    assert json.loads(mock_subprocess_run.call_args[0][0][2])["working_dir"] == "/app"
+    execute_code_block("cid", "main.py", "print(1)", bin_path="/fake/adde")
+    assert "working_dir" not in json.loads(mock_subprocess_run.call_args[0][0][2])
+
+
+def test_execute_code_block_create_workspace(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(returncode=0, stdout='{"log":{"exit_code":0}}', stderr="")
+    execute_code_block("compose-web-1", "main.py", "print(1)", create_workspace=True, bin_path="/fake/adde")
+    assert json.loads(mock_subprocess_run.call_args[0][0][2])["create_workspace"] is True
+
+
+def test_execute_code_block_clean_workspace(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(returncode=0, stdout='{"log":{"exit_code":0}}', stderr="")
+    execute_code_block("cid", "main.py", "print(1)", clean_workspace=True, bin_path="/fake/adde")
+    params = json.loads(mock_subprocess_run.call_args[0][0][2])
+    assert params["clean_workspace"] is True
+    assert "clean_artifacts" not in params
+
+
+def test_execute_code_block_fallback_shell(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(returncode=0, stdout='{"log":{"exit_code":0}}', stderr="")
+    execute_code_block("cid", "run", "echo hi", fallback_shell="bash", bin_path="/fake/adde")
+    assert json.loads(mock_subprocess_run.call_args[0][0][2])["fallback_shell"] == "bash"
+
+
+def test_execute_code_block_profile(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0, stdout='{"log":{"exit_code":0,"profile":"42 function calls"}}', stderr=""
+    )
+    out = execute_code_block("cid", "main.py", "print(1)", profile=True, bin_path="/fake/adde")
+    assert json.loads(mock_subprocess_run.call_args[0][0][2])["profile"] is True
+    assert out["log"]["profile"] == "42 function calls"
+
+
+def test_execute_code_block_coverage(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0, stdout='{"log":{"exit_code":0},"coverage":{"percent":75.0,"files":[]}}', stderr=""
+    )
+    out = execute_code_block(
+        "cid", "test_app.py", "assert True", coverage=True, coverage_tool="/opt/venv/bin/coverage", bin_path="/fake/adde"
+    )
+    call_args = json.loads(mock_subprocess_run.call_args[0][0][2])
+    assert call_args["coverage"] is True
+    assert call_args["coverage_tool"] == "/opt/venv/bin/coverage"
+    assert out["coverage"]["percent"] == 75.0
+
+
+def test_execute_code_block_result_format(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0,
+        stdout='{"log":{"exit_code":1},"test_report":{"runner":"pytest","tests":2,"passed":1,"failed":1,"errors":0,"skipped":0}}',
+        stderr="",
+    )
+    out = execute_code_block("cid", "test_app.py", "def test_a(): pass", result_format="junit", bin_path="/fake/adde")
+    assert json.loads(mock_subprocess_run.call_args[0][0][2])["result_format"] == "junit"
+    assert out["test_report"]["failed"] == 1
+
+
+def test_transcript_params(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(returncode=0, stdout='{"log":{"exit_code":0}}', stderr="")
+    execute_code_block("cid", "a.py", "print(1)", transcript=True, bin_path="/fake/adde")
+    assert json.loads(mock_subprocess_run.call_args[0][0][2])["transcript"] is True
+    mock_subprocess_run.return_value = MagicMock(returncode=0, stdout='{"transcript":"=== ... ===\\n1\\n"}', stderr="")
+    out = get_transcript("cid", tail_lines=20, bin_path="/fake/adde")
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "get_transcript"
+    assert json.loads(args[2]) == {"container_id": "cid", "tail_lines": 20}
+    assert out["transcript"].endswith("1\n")
+
+
+def test_start_detached_exec_and_poll_exec_params(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0, stdout='{"exec_id":"e1","output_path":"/var/adde/detached/1.log"}', stderr=""
+    )
+    out = start_detached_exec("cid", ["python", "train.py"], bin_path="/fake/adde")
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "start_detached_exec"
+    assert json.loads(args[2]) == {"container_id": "cid", "command": ["python", "train.py"]}
+    assert out["exec_id"] == "e1"
+
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0, stdout='{"status":"finished","exit_code":0,"output":"done\\n"}', stderr=""
+    )
+    out = poll_exec("cid", "e1", bin_path="/fake/adde")
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "poll_exec"
+    assert json.loads(args[2]) == {"container_id": "cid", "exec_id": "e1"}
+    assert out == {"status": "finished", "exit_code": 0, "output": "done\n"}
+
+
+def test_wait_container_params(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(returncode=0, stdout='{"exit_code":0}', stderr="")
+    out = wait_container("abc123", timeout_sec=60, bin_path="/fake/adde")
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "wait_container"
+    assert json.loads(args[2]) == {"container_id": "abc123", "timeout_sec": 60}
+    assert out == {"exit_code": 0}
+
+
+def test_check_dependency_params(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0, stdout='{"installed":true,"version":"2.31.0"}', stderr=""
+    )
+    out = check_dependency("abc123", "python", "requests", bin_path="/fake/adde")
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "check_dependency"
+    assert json.loads(args[2]) == {"container_id": "abc123", "language": "python", "package": "requests"}
+    assert out == {"installed": True, "version": "2.31.0"}
+
+
+def test_tail_file_params(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(returncode=0, stdout='{"content":"b\\nc\\n"}', stderr="")
+    out = tail_file("abc123", "/var/log/app.log", lines=2, bin_path="/fake/adde")
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "tail_file"
+    assert json.loads(args[2]) == {"container_id": "abc123", "path": "/var/log/app.log", "lines": 2}
+    assert out["content"] == "b\nc\n"
+
+
+def test_copy_from_container_params(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(returncode=0, stdout='{"content":"YSxiCg==","size":4}', stderr="")
+    out = copy_from_container("abc123", "out/result.csv", bin_path="/fake/adde")
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "copy_from_container"
+    assert json.loads(args[2]) == {"container_id": "abc123", "path": "out/result.csv"}
+    assert base64.b64decode(out["content"]) == b"a,b\n"
+
+    copy_from_container("abc123", "out", as_tar=True, gzip=True, bin_path="/fake/adde")
+    args = mock_subprocess_run.call_args[0][0]
+    assert json.loads(args[2]) == {"container_id": "abc123", "path": "out", "as_tar": True, "gzip": True}
+
+    copy_from_container("abc123", "out", dest_dir="results", bin_path="/fake/adde")
+    args = mock_subprocess_run.call_args[0][0]
+    assert json.loads(args[2]) == {"container_id": "abc123", "path": "out", "dest_dir": "results"}
+
+
+def test_run_from_dockerfile_params(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0, stdout='{"build":{"status":"success"},"log":{"exit_code":0}}', stderr=""
+    )
+    run_from_dockerfile("FROM busybox\n", "t.sh", "echo hi", files={"a.txt": "1"}, bin_path="/fake/adde")
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "run_from_dockerfile"
+    assert json.loads(args[2]) == {
+        "dockerfile": "FROM busybox\n",
+        "filename": "t.sh",
+        "code_content": "echo hi",
+        "timeout_sec": 30,
+        "files": {"a.txt": "1"},
+    }
+
+
+def test_list_languages(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0,
+        stdout='{"languages":[{"extension":".py","language":"python","command":"python"}],"fallback":"sh -c"}',
+        stderr="",
+    )
+    out = list_languages(bin_path="/fake/adde")
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "list_languages"
+    assert out["languages"][0]["command"] == "python"
+
+
+def test_capabilities(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0,
+        stdout='{"version":"v1.4.0","tools":[{"name":"execute_code_block","params":["container_id","working_dir"]}]}',
+        stderr="",
+    )
+    out = capabilities(bin_path="/fake/adde")
+    assert mock_subprocess_run.call_args[0][0][1] == "capabilities"
+    assert "working_dir" in out["tools"][0]["params"]
+
+
+def test_execute_cells_params(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0, stdout='{"logs":[{"exit_code":0},{"exit_code":1}],"stopped":true}', stderr=""
+    )
+    cells = [{"filename": "a.py", "code_content": "x=1"}, {"filename": "b.py", "code_content": "1/0"}]
+    out = execute_cells("cid", cells, stop_on_error=True, bin_path="/fake/adde")
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "execute_cells"
+    assert json.loads(args[2]) == {"container_id": "cid", "cells": cells, "stop_on_error": True}
+    assert out["stopped"] is True
+
+
+def test_verify_workspace_params(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0, stdout='{"ok":true,"mount_present":true,"files":["main.py"],"problems":[]}', stderr=""
+    )
+    out = verify_workspace("cid", bin_path="/fake/adde")
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "verify_workspace"
+    assert json.loads(args[2]) == {"container_id": "cid"}
+    assert out["files"] == ["main.py"]
+
+
+def test_pause_unpause_container(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(returncode=0, stdout='{"ok":true}', stderr="")
+    assert pause_container("cid", bin_path="/fake/adde")["ok"] is True
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "pause_container"
+    assert json.loads(args[2]) == {"container_id": "cid"}
+    unpause_container("cid", bin_path="/fake/adde")
+    assert mock_subprocess_run.call_args[0][0][1] == "unpause_container"
+
+
+def test_check_port_params(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0, stdout='{"reachable":true,"host_address":"127.0.0.1:8080","status_code":200}', stderr=""
+    )
+    out = check_port("cid", "3000", path="/health", bin_path="/fake/adde")
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "check_port"
+    assert json.loads(args[2]) == {"container_id": "cid", "container_port": "3000", "path": "/health"}
+    assert out["status_code"] == 200
+
+
+def test_stop_container_params(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(returncode=0, stdout='{"ok":true}', stderr="")
+    assert stop_container("cid", bin_path="/fake/adde")["ok"] is True
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "stop_container"
+    assert json.loads(args[2]) == {"container_id": "cid"}
+    stop_container("cid", timeout_sec=30, bin_path="/fake/adde")
+    assert json.loads(mock_subprocess_run.call_args[0][0][2])["timeout_sec"] == 30
+
+
+def test_kill_container_signal(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(returncode=0, stdout='{"ok":true,"signal":"SIGUSR1"}', stderr="")
+    kill_container("cid", signal="SIGUSR1", bin_path="/fake/adde")
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "kill_container"
+    assert json.loads(args[2]) == {"container_id": "cid", "signal": "SIGUSR1"}
+
+
+def test_batch_sends_ndjson(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=1,
+        stdout='{"line":1,"tool":"list_languages","result":{}}\n{"line":2,"tool":"cleanup_env","failed":true,"result":{"ok":false}}\n',
+        stderr="",
+    )
+    out = batch(
+        [{"tool": "list_languages"}, {"tool": "cleanup_env", "payload": {"container_id": "x"}}],
+        bin_path="/fake/adde",
+    )
+    args, kwargs = mock_subprocess_run.call_args
+    assert args[0] == ["/fake/adde", "batch"]
+    lines = kwargs["input"].splitlines()
+    assert json.loads(lines[1]) == {"tool": "cleanup_env", "payload": {"container_id": "x"}}
+    assert len(out) == 2 and out[1]["failed"] is True
+
+
 def test_cleanup_env_params(mock_subprocess_run):
     mock_subprocess_run.return_value = MagicMock(returncode=0, stdout='{"ok":true}', stderr="")
     cleanup_env(container_id="cid", bin_path="/fake/adde")
@@ -166,6 +609,42 @@ def test_prepare_build_context_params(mock_subprocess_run):
     prepare_build_context(files={"main.py": "print(1)", "requirements.txt": "requests"}, bin_path="/fake/adde")
     call_args = json.loads(mock_subprocess_run.call_args[0][0][2])
     assert call_args["files"] == {"main.py": "print(1)", "requirements.txt": "requests"}
+    assert "extra_dockerignore" not in call_args
+
+
+def test_prepare_build_context_extra_dockerignore(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0, stdout='{"context_id":"/tmp/adde-build-xyz"}', stderr=""
+    )
+    prepare_build_context(files={"main.py": "print(1)"}, extra_dockerignore=["dist/", "*.tmp"], bin_path="/fake/adde")
+    call_args = json.loads(mock_subprocess_run.call_args[0][0][2])
+    assert call_args["extra_dockerignore"] == ["dist/", "*.tmp"]
+
+
+def test_prepare_build_context_base_images(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0, stdout='{"context_id":"/tmp/adde-build-xyz"}', stderr=""
+    )
+    prepare_build_context(
+        files={"requirements.txt": "numpy"},
+        python_base_image="python:3.12-bookworm",
+        node_base_image="node:22-slim",
+        bin_path="/fake/adde",
+    )
+    call_args = json.loads(mock_subprocess_run.call_args[0][0][2])
+    assert call_args["python_base_image"] == "python:3.12-bookworm"
+    assert call_args["node_base_image"] == "node:22-slim"
+
+
+def test_hash_build_context_params(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0, stdout='{"content_hash":"sha256:ab","files":2}', stderr=""
+    )
+    result = hash_build_context("/tmp/adde-build-xyz", bin_path="/fake/adde")
+    assert mock_subprocess_run.call_args[0][0][1] == "hash_build_context"
+    call_args = json.loads(mock_subprocess_run.call_args[0][0][2])
+    assert call_args == {"context_id": "/tmp/adde-build-xyz"}
+    assert result["content_hash"] == "sha256:ab"
 
 
 def test_build_image_from_context_params(mock_subprocess_run):
@@ -178,12 +657,28 @@ def test_build_image_from_context_params(mock_subprocess_run):
         context_id="/tmp/ctx",
         tag="agent-env:task-1",
         build_args={"FOO": "bar"},
+        cache_from=["registry.example.com/app:cache"],
+        pull_cache_from=True,
         bin_path="/fake/adde",
     )
     call_args = json.loads(mock_subprocess_run.call_args[0][0][2])
     assert call_args["context_id"] == "/tmp/ctx"
     assert call_args["tag"] == "agent-env:task-1"
     assert call_args["build_args"] == {"FOO": "bar"}
+    assert call_args["cache_from"] == ["registry.example.com/app:cache"]
+    assert call_args["pull_cache_from"] is True
+
+
+def test_build_image_from_context_hash_tag(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0,
+        stdout='{"status":"success","image_id":"sha256:abc","tag":"agent-env:0123456789ab"}',
+        stderr="",
+    )
+    result = build_image_from_context("/tmp/ctx", hash_tag=True, bin_path="/fake/adde")
+    call_args = json.loads(mock_subprocess_run.call_args[0][0][2])
+    assert call_args == {"context_id": "/tmp/ctx", "tag": "", "hash_tag": True}
+    assert result["tag"] == "agent-env:0123456789ab"
 
 
 def test_build_image_from_path_params(mock_subprocess_run):
@@ -196,12 +691,16 @@ def test_build_image_from_path_params(mock_subprocess_run):
         path="/home/user/myproject",
         tag="agent-env:myapp-1",
         build_args={"VERSION": "1.0"},
+        task_id="task-7",
+        labels={"team": "ml"},
         bin_path="/fake/adde",
     )
     call_args = json.loads(mock_subprocess_run.call_args[0][0][2])
     assert call_args["path"] == "/home/user/myproject"
     assert call_args["tag"] == "agent-env:myapp-1"
     assert call_args["build_args"] == {"VERSION": "1.0"}
+    assert call_args["task_id"] == "task-7"
+    assert call_args["labels"] == {"team": "ml"}
 
 
 def test_list_agent_images_params(mock_subprocess_run):
@@ -212,6 +711,9 @@ def test_list_agent_images_params(mock_subprocess_run):
     call_args = json.loads(mock_subprocess_run.call_args[0][0][2])
     assert call_args["filter_tag"] == "agent-env"
 
+    list_agent_images(match_label=True, bin_path="/fake/adde")
+    assert json.loads(mock_subprocess_run.call_args[0][0][2]) == {"match_label": True}
+
 
 def test_prune_build_cache_params(mock_subprocess_run):
     mock_subprocess_run.return_value = MagicMock(
@@ -222,6 +724,44 @@ def test_prune_build_cache_params(mock_subprocess_run):
     assert call_args["older_than_hrs"] == 24
 
 
+def test_prune_all_params(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0, stdout='{"dry_run":true,"containers":["c1"],"total_reclaimed_mb":1.5}', stderr=""
+    )
+    out = prune_all(older_than_hrs=24, dry_run=True, bin_path="/fake/adde")
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "prune_all"
+    assert json.loads(args[2]) == {"older_than_hrs": 24, "dry_run": True}
+    assert out["containers"] == ["c1"]
+
+    prune_all(include_unmanaged=True, bin_path="/fake/adde")
+    assert json.loads(mock_subprocess_run.call_args[0][0][2]) == {"include_unmanaged": True}
+
+
+def test_list_and_prune_volumes(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0, stdout='{"volumes":[{"name":"pip-cache","size_mb":3}]}', stderr=""
+    )
+    assert list_volumes(bin_path="/fake/adde")["volumes"][0]["name"] == "pip-cache"
+    assert mock_subprocess_run.call_args[0][0][1] == "list_volumes"
+    mock_subprocess_run.return_value = MagicMock(returncode=0, stdout='{"removed":[],"space_reclaimed_mb":0}', stderr="")
+    prune_volumes(dry_run=True, bin_path="/fake/adde")
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "prune_volumes"
+    assert json.loads(args[2]) == {"managed_only": True, "dry_run": True}
+
+
+def test_gc_workspaces_params(mock_subprocess_run):
+    mock_subprocess_run.return_value = MagicMock(
+        returncode=0, stdout='{"removed":["/tmp/adde-build-1"],"count":1,"reclaimed_mb":0.5}', stderr=""
+    )
+    out = gc_workspaces(older_than_hrs=6, bin_path="/fake/adde")
+    args = mock_subprocess_run.call_args[0][0]
+    assert args[1] == "gc_workspaces"
+    assert json.loads(args[2]) == {"older_than_hrs": 6}
+    assert out["count"] == 1
+
+
 def test_delete_image_params(mock_subprocess_run):
     mock_subprocess_run.return_value = MagicMock(
         returncode=0,